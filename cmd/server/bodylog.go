@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxLoggedBodyBytes caps how much of each body is captured for logging
+const maxLoggedBodyBytes = 4096
+
+const redactedValue = "[REDACTED]"
+
+// cappedBuffer keeps at most limit bytes and silently discards the rest,
+// so capturing a large body for logging never copies it in full
+type cappedBuffer struct {
+	buf       []byte
+	limit     int
+	truncated bool
+}
+
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - len(b.buf); remaining > 0 {
+		if len(p) > remaining {
+			b.buf = append(b.buf, p[:remaining]...)
+			b.truncated = true
+		} else {
+			b.buf = append(b.buf, p...)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	// Always report a full write so the tee never fails the real request
+	return len(p), nil
+}
+
+// redactedBody renders a captured JSON body with the configured fields masked.
+// Bodies that were truncated or aren't valid JSON are summarized rather than logged,
+// since they can't be redacted reliably.
+func redactedBody(b *cappedBuffer, redact map[string]bool) string {
+	if len(b.buf) == 0 {
+		return "<empty>"
+	}
+	if b.truncated {
+		return fmt.Sprintf("<%d+ bytes, truncated>", len(b.buf))
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(b.buf, &body); err != nil {
+		return fmt.Sprintf("<%d bytes, not JSON>", len(b.buf))
+	}
+
+	redacted, err := json.Marshal(redactValue(body, redact))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(b.buf))
+	}
+	return string(redacted)
+}
+
+// redactValue masks the values of redacted keys at any depth
+func redactValue(value interface{}, redact map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if redact[key] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(inner, redact)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner, redact)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isMutating reports whether a request method changes server state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
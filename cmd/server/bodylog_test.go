@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestRedactedBody(t *testing.T) {
+	redact := map[string]bool{"amount": true, "source_account_id": true}
+
+	tests := []struct {
+		name     string
+		body     string
+		limit    int
+		expected string
+	}{
+		{
+			name:     "redacts top-level fields",
+			body:     `{"amount":"100.00","reference":"rent"}`,
+			limit:    maxLoggedBodyBytes,
+			expected: `{"amount":"[REDACTED]","reference":"rent"}`,
+		},
+		{
+			name:     "redacts nested fields in arrays",
+			body:     `{"transfers":[{"amount":"1","source_account_id":"abc"}]}`,
+			limit:    maxLoggedBodyBytes,
+			expected: `{"transfers":[{"amount":"[REDACTED]","source_account_id":"[REDACTED]"}]}`,
+		},
+		{
+			name:     "summarizes truncated bodies",
+			body:     `{"amount":"100.00"}`,
+			limit:    5,
+			expected: "<5+ bytes, truncated>",
+		},
+		{
+			name:     "summarizes non-JSON bodies",
+			body:     "amount=100",
+			limit:    maxLoggedBodyBytes,
+			expected: "<10 bytes, not JSON>",
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			limit:    maxLoggedBodyBytes,
+			expected: "<empty>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := newCappedBuffer(tt.limit)
+			n, err := buf.Write([]byte(tt.body))
+
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.body), n)
+			assert.LessOrEqual(t, len(buf.buf), tt.limit)
+			assert.Equal(t, tt.expected, redactedBody(buf, redact))
+			assert.False(t, strings.Contains(redactedBody(buf, redact), "100.00"))
+		})
+	}
+}
+
+// TestRedactedBody_RequestModels logs every mutating request model, and the transfer
+// and account responses, with all their amounts and account IDs set, and checks the
+// default redact fields mask each of them
+func TestRedactedBody_RequestModels(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("LOG_REDACT_FIELDS", "")
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	redact := make(map[string]bool, len(cfg.Logger.RedactFields))
+	for _, field := range cfg.Logger.RedactFields {
+		redact[field] = true
+	}
+
+	accountID := uuid.MustParse("2235a24b-3f70-46a3-9776-29747cdbabba")
+	amount := decimal.RequireFromString("987.65")
+	transfer := model.CreateTransactionRequest{
+		SourceAccountID:       &accountID,
+		DestinationAccountID:  &accountID,
+		Amount:                amount,
+		Fee:                   &amount,
+		FeeAccountID:          &accountID,
+		ExpectedSourceBalance: &amount,
+	}
+
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{"create account", model.CreateAccountRequest{InitialBalance: &amount, MinBalance: &amount, MaxBalance: &amount, FundingSourceAccountID: &accountID}},
+		{"create transfer", transfer},
+		{"bulk transfer", model.BulkTransferRequest{Transfers: []model.CreateTransactionRequest{transfer}}},
+		{"adjust balance", model.AdjustBalanceRequest{Amount: amount, Reason: "correction", Actor: "ops"}},
+		{"reverse transfer", model.ReverseTransactionRequest{Amount: &amount}},
+		{"get balances", model.GetBalancesRequest{AccountIDs: []uuid.UUID{accountID}}},
+		{"transfer response", model.CreateTransactionResponse{SourceAccountID: &accountID, DestinationAccountID: &accountID, Amount: amount, Fee: &amount, FeeAccountID: &accountID, DestinationAmount: &amount, FXRate: &amount}},
+		{"account response", model.CreateAccountResponse{Balance: amount}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+			buf := newCappedBuffer(maxLoggedBodyBytes)
+			_, err = buf.Write(body)
+			require.NoError(t, err)
+
+			logged := redactedBody(buf, redact)
+			assert.Contains(t, logged, redactedValue)
+			assert.NotContains(t, logged, amount.String())
+			assert.NotContains(t, logged, accountID.String())
+		})
+	}
+}
@@ -4,20 +4,26 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"internal-transfers-api/internal/config"
 	"internal-transfers-api/internal/handler"
+	"internal-transfers-api/internal/model"
 	"internal-transfers-api/internal/repository"
 	"internal-transfers-api/internal/service"
+	"internal-transfers-api/internal/tracing"
 )
 
 const version = "1.0.0"
@@ -28,30 +34,171 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	model.SetAmountFormat(cfg.Server.AmountFormat)
 
-	// Initialize database connection
-	db, err := initDatabase(cfg.Database)
+	// Install the tracer provider before anything creates spans
+	shutdownTracing, err := tracing.Setup(context.Background(), version)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Initialize the database pool; connections are made lazily, and failed ones start
+	// a reconnect once the service is up
+	reconnector := newDBReconnector(cfg.Database.MaxIdleConns)
+	db, err := openDatabase(cfg.Database.DSN(), cfg.Database, reconnector.connectFailed)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
+	// The read replica, if any, serves the read-only endpoints; without one they read the
+	// primary. Its failed connections fail those reads but leave the primary's health alone.
+	var replica *sql.DB
+	if cfg.Database.ReplicaDSN != "" {
+		replica, err = openDatabase(cfg.Database.ReplicaDSN, cfg.Database, func(err error) {
+			log.Printf("Read replica connection failed: %v", err)
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize read replica: %v", err)
+		}
+		defer replica.Close()
+	}
+
 	// Initialize repositories
-	accountRepo := repository.NewAccountRepository(db)
-	transactionRepo := repository.NewTransactionRepository(db)
+	repository.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
+	repository.SetAcquireTimeout(cfg.Database.AcquireTimeout)
+	dbErrors := repository.NewErrorMapping(cfg.Database.ErrorCodes, cfg.Database.RetryableErrorCodes)
+	accountRepo := repository.NewAccountRepositoryWithReplica(db, replica, dbErrors)
+	transactionRepo := repository.NewTransactionRepositoryWithReplica(db, replica, dbErrors)
 	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	batchRepo := repository.NewBatchRepository(db)
+
+	// Audit log sinks; with neither, changes aren't audited
+	var auditRepo repository.AuditRepo
+	if cfg.Audit.DB {
+		auditRepo = repository.NewAuditRepository(db)
+	}
+	var auditOut io.Writer
+	if cfg.Audit.Stdout {
+		auditOut = os.Stdout
+	}
+	auditor := service.NewAuditor(auditRepo, auditOut)
 
 	// Initialize services
-	accountService := service.NewAccountService(accountRepo, db)
-	transactionService := service.NewTransactionService(accountRepo, transactionRepo, idempotencyRepo, db)
+	jobs := service.NewJobQueue(cfg.Jobs)
+	balanceWatcher := service.NewBalanceWatcher(jobs)
+	balanceCache := service.NewBalanceCache(cfg.Cache.BalanceTTL)
+	transactionService := service.NewTransactionService(accountRepo, transactionRepo, idempotencyRepo, db, cfg.Transfers, cfg.Idempotency, balanceWatcher, balanceCache, auditor, cfg.Features)
+	accountService := service.NewAccountService(accountRepo, db, balanceWatcher, balanceCache, cfg.Transfers.Currencies, cfg.Accounts, auditor, transactionService)
+	batchService := service.NewBatchService(batchRepo, transactionService, cfg.Batch)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo)
+	interestService := service.NewInterestService(accountRepo, transactionService, cfg.Interest, cfg.Transfers.RoundingMode, cfg.Transfers.Currencies)
+	orphanService := service.NewOrphanService(transactionService, cfg.Orphans)
+	snapshotService := service.NewSnapshotService(accountRepo, cfg.Snapshots)
+
+	// Completed-transfer notifications for the event stream, subscribed once the database is up
+	transferEvents := service.NewTransferEvents(cfg.Database.DSN())
+	defer transferEvents.Close()
 
 	// Initialize handlers
-	healthHandler := handler.NewHealthHandler(db, version)
-	accountHandler := handler.NewAccountHandler(accountService)
-	transactionHandler := handler.NewTransactionHandler(transactionService)
+	limiter := handler.NewConcurrencyLimiter(cfg.Server.MaxInFlightWrites, cfg.Server.MaxInFlightReads)
+	handlers := &handlers{
+		limiter:     limiter,
+		health:      handler.NewHealthHandler(db, version, cfg.Health, limiter),
+		account:     handler.NewAccountHandler(accountService),
+		transaction: handler.NewTransactionHandler(transactionService),
+		stream:      handler.NewTransactionStreamHandler(transferEvents),
+		batch:       handler.NewBatchHandler(batchService),
+		idempotency: handler.NewIdempotencyHandler(idempotencyService),
+	}
 
 	// Initialize HTTP server
-	server := initServer(cfg, healthHandler, accountHandler, transactionHandler)
+	requests := newRequestTracker()
+	server := initServer(cfg, handlers, requests)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	reconnector.start(workerCtx, db, handlers.health, &workers)
+
+	// start runs everything that needs the database, then marks the service ready:
+	// the event stream subscription, the system accounts and the background workers
+	// for asynchronous batches, daily interest accrual, orphaned transactions and balance
+	// snapshots
+	start := func() error {
+		if err := transferEvents.Start(); err != nil {
+			return fmt.Errorf("failed to initialize transfer events: %w", err)
+		}
+		if err := checkNumericColumns(workerCtx, db, cfg.Database); err != nil {
+			if cfg.Database.NumericStrict {
+				return err
+			}
+			log.Printf("WARN %v", err)
+		}
+		if err := transactionService.PrepareSystemAccounts(workerCtx); err != nil {
+			return fmt.Errorf("failed to prepare system accounts: %w", err)
+		}
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			batchService.Run(workerCtx)
+		}()
+		if cfg.Interest.Enabled {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				interestService.Run(workerCtx)
+			}()
+		}
+		if cfg.Orphans.Enabled {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				orphanService.Run(workerCtx)
+			}()
+		}
+		if cfg.Snapshots.Enabled {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				snapshotService.Run(workerCtx)
+			}()
+		}
+
+		if cfg.Database.WarmupConnections > 0 {
+			if err := warmDatabase(workerCtx, db, cfg.Database.WarmupConnections); err != nil {
+				log.Printf("WARN database warmup incomplete, remaining connections are made on demand: %v", err)
+			}
+		}
+
+		handlers.health.SetDatabaseReady(true)
+		log.Println("Database connection established")
+		return nil
+	}
+
+	if cfg.Database.StartWithoutDB {
+		// Serve health probes straight away and keep retrying the database in the background
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := connectWithBackoff(workerCtx, db); err != nil {
+				return
+			}
+			if err := start(); err != nil {
+				log.Fatalf("Failed to start: %v", err)
+			}
+		}()
+	} else {
+		if err := pingDatabase(workerCtx, db); err != nil {
+			log.Fatalf("Failed to initialize database: %v", err)
+		}
+		if err := start(); err != nil {
+			log.Fatalf("Failed to start: %v", err)
+		}
+	}
+
+	// End open event streams so shutdown doesn't wait on them
+	server.RegisterOnShutdown(transferEvents.Close)
 
 	// Start server in a goroutine
 	go func() {
@@ -69,108 +216,430 @@ func main() {
 	log.Println("Shutting down server...")
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		log.Printf("Graceful shutdown timed out after %v with %d requests still in flight: %v",
+			cfg.Server.ShutdownTimeout, requests.inFlight(), err)
+
+		// Cancelling the stragglers rolls back their open database transactions
+		requests.cancelAndWait(5 * time.Second)
+	}
+
+	// Run the side effects still queued by the requests that finished
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelDrain()
+	if err := jobs.Drain(drainCtx); err != nil {
+		log.Printf("Job queue drain incomplete: %v", err)
+	}
+
+	// Stop the background workers; an interrupted batch resumes on next start
+	stopWorkers()
+	workers.Wait()
+
+	// Flush spans still buffered for export
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Tracer provider shutdown failed: %v", err)
 	}
 
 	log.Println("Server exited")
 }
 
-func initDatabase(cfg config.DatabaseConfig) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.DSN())
+// openDatabase configures the connection pool to dsn without connecting. connectFailed is
+// called with the error of every connection that can't be made.
+func openDatabase(dsn string, cfg config.DatabaseConfig, connectFailed func(error)) (*sql.DB, error) {
+	connector, err := newSchemaConnector(dsn, cfg.Schema, connectFailed)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db := sql.OpenDB(connector)
 
 	// Configure connection pool
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetConnMaxLifetime(time.Hour)
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	return db, nil
+}
+
+// pingDatabase tests the connection
+func pingDatabase(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return fmt.Errorf("failed to ping database: %w", err)
 	}
+	return nil
+}
 
-	log.Println("Database connection established")
-	return db, nil
+// warmupTimeout bounds the startup warmup, so a database that stops answering delays
+// readiness by at most this long
+const warmupTimeout = 10 * time.Second
+
+// warmDatabase opens n connections at once and runs a trivial query on each, leaving
+// them idle in the pool so the first requests don't pay for connecting
+func warmDatabase(ctx context.Context, db *sql.DB, n int) error {
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	// Hold every connection until all are open, otherwise the pool would reuse the first
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for len(conns) < n {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("opened %d of %d connections: %w", len(conns), n, err)
+		}
+		conns = append(conns, conn)
+		if _, err := conn.ExecContext(ctx, "SELECT 1"); err != nil {
+			return fmt.Errorf("failed to prime connection %d: %w", len(conns), err)
+		}
+	}
+	return nil
+}
+
+// checkNumericColumns logs the declared type of the balance and amount columns and
+// reports those that don't have the configured precision and scale, or are missing.
+// A column with a smaller scale than expected rounds amounts the service accepts.
+func checkNumericColumns(ctx context.Context, db *sql.DB, cfg config.DatabaseConfig) error {
+	columns, err := repository.GetNumericColumns(ctx, db, repository.AmountColumns)
+	if err != nil {
+		return fmt.Errorf("failed to check numeric columns: %w", err)
+	}
+
+	var mismatched []string
+	found := make(map[string]bool)
+	for _, c := range columns {
+		name := c.Table + "." + c.Column
+		found[name] = true
+		if !c.Precision.Valid {
+			log.Printf("Column %s is unconstrained NUMERIC", name)
+			mismatched = append(mismatched, name+" NUMERIC")
+			continue
+		}
+		log.Printf("Column %s is NUMERIC(%d,%d)", name, c.Precision.Int64, c.Scale.Int64)
+		if c.Precision.Int64 != int64(cfg.NumericPrecision) || c.Scale.Int64 != int64(cfg.NumericScale) {
+			mismatched = append(mismatched, fmt.Sprintf("%s NUMERIC(%d,%d)", name, c.Precision.Int64, c.Scale.Int64))
+		}
+	}
+	for _, c := range repository.AmountColumns {
+		if name := c.Table + "." + c.Column; !found[name] {
+			mismatched = append(mismatched, name+" missing")
+		}
+	}
+
+	if len(mismatched) > 0 {
+		return fmt.Errorf("numeric columns don't match the expected NUMERIC(%d,%d): %s",
+			cfg.NumericPrecision, cfg.NumericScale, strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// handlers groups the HTTP handlers routed by initServer
+type handlers struct {
+	limiter     *handler.ConcurrencyLimiter
+	health      *handler.HealthHandler
+	account     *handler.AccountHandler
+	transaction *handler.TransactionHandler
+	stream      *handler.TransactionStreamHandler
+	batch       *handler.BatchHandler
+	idempotency *handler.IdempotencyHandler
 }
 
-func initServer(cfg *config.Config, healthHandler *handler.HealthHandler, accountHandler *handler.AccountHandler, transactionHandler *handler.TransactionHandler) *http.Server {
+func initServer(cfg *config.Config, h *handlers, requests *requestTracker) *http.Server {
 	mux := http.NewServeMux()
 
-	// Health check endpoint
-	mux.Handle("/healthz", healthHandler)
+	// route registers a handler behind an HTTP server span named after its pattern,
+	// continuing any trace started by the caller's traceparent header, and under the
+	// pattern's deadline if ROUTE_TIMEOUTS sets one
+	routed := make(map[string]bool)
+	route := func(pattern string, next http.Handler) {
+		routed[pattern] = true
+		if timeout, ok := cfg.Server.RouteTimeouts[pattern]; ok {
+			next = handler.TimeoutMiddleware(timeout, next)
+		}
+		mux.Handle(pattern, otelhttp.NewHandler(next, pattern,
+			otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+				return r.Method + " " + operation
+			}),
+		))
+	}
+
+	// Health check endpoints
+	route("/livez", http.HandlerFunc(h.health.Live))
+	route("/healthz", h.health)
+	route("/readyz", http.HandlerFunc(h.health.Ready))
 
 	// API v1 endpoints
-	mux.HandleFunc("/v1/accounts", func(w http.ResponseWriter, r *http.Request) {
+	route("/v1/accounts", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Route based on method and path
 		if r.Method == http.MethodPost {
-			accountHandler.CreateAccount(w, r)
+			h.account.CreateAccount(w, r)
 		} else {
-			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "INVALID_INPUT")
+			handler.WriteMethodNotAllowed(w, r, http.MethodPost)
 		}
-	})
+	}))
+
+	// GET /v1/currencies
+	route("/v1/currencies", http.HandlerFunc(h.account.ListCurrencies))
 
-	mux.HandleFunc("/v1/accounts/", func(w http.ResponseWriter, r *http.Request) {
+	// GET /v1/fx/preview
+	route("/v1/fx/preview", http.HandlerFunc(h.transaction.PreviewConversion))
+
+	// POST /v1/accounts/balances
+	route("/v1/accounts/balances", http.HandlerFunc(h.account.GetBalances))
+
+	setAccountStatus := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.account.SetStatus))
+	adjustBalance := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.AdjustBalance))
+	reconcileAccount := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.account.ReconcileAccount))
+	route("/v1/accounts/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Handle account-specific routes
-		path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
-		
-		if strings.HasSuffix(path, "/transactions") {
-			// GET /v1/accounts/{id}/transactions
-			transactionHandler.GetAccountTransactions(w, r)
-		} else {
+		_, sub, ok := resourcePath(r.URL.Path, "/v1/accounts/")
+		if !ok {
+			handler.WriteNotFound(w, r)
+			return
+		}
+
+		switch sub {
+		case "":
 			// GET /v1/accounts/{id}
-			accountHandler.GetAccount(w, r)
+			h.account.GetAccount(w, r)
+		case "transactions":
+			// GET /v1/accounts/{id}/transactions
+			h.transaction.GetAccountTransactions(w, r)
+		case "counterparties":
+			// GET /v1/accounts/{id}/counterparties
+			h.transaction.GetCounterparties(w, r)
+		case "balance-history":
+			// GET /v1/accounts/{id}/balance-history
+			h.account.GetBalanceHistory(w, r)
+		case "balance/watch":
+			// GET /v1/accounts/{id}/balance/watch
+			h.account.WatchBalance(w, r)
+		case "reconcile":
+			// POST /v1/accounts/{id}/reconcile
+			reconcileAccount.ServeHTTP(w, r)
+		case "status":
+			// POST /v1/accounts/{id}/status
+			setAccountStatus.ServeHTTP(w, r)
+		case "adjustments":
+			// POST /v1/accounts/{id}/adjustments
+			adjustBalance.ServeHTTP(w, r)
+		default:
+			handler.WriteNotFound(w, r)
 		}
-	})
+	}))
+
+	route("/v1/owners/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Owner IDs may hold encoded slashes, so split the escaped path
+		if _, sub, ok := resourcePath(r.URL.EscapedPath(), "/v1/owners/"); !ok || sub != "accounts" {
+			handler.WriteNotFound(w, r)
+			return
+		}
+		// GET /v1/owners/{owner_id}/accounts
+		h.account.ListOwnerAccounts(w, r)
+	}))
 
-	mux.HandleFunc("/v1/transactions", func(w http.ResponseWriter, r *http.Request) {
+	var createTransaction http.Handler = http.HandlerFunc(h.transaction.CreateTransaction)
+	if cfg.Idempotency.Required {
+		createTransaction = handler.RequireIdempotencyKey(createTransaction)
+	}
+	route("/v1/transactions", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
-			transactionHandler.CreateTransaction(w, r)
+			createTransaction.ServeHTTP(w, r)
 		} else {
-			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "INVALID_INPUT")
+			handler.WriteMethodNotAllowed(w, r, http.MethodPost)
 		}
-	})
+	}))
 
-	mux.HandleFunc("/v1/transactions/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			transactionHandler.GetTransaction(w, r)
-		} else {
-			writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", "INVALID_INPUT")
+	// POST /v1/transactions/get
+	route("/v1/transactions/get", http.HandlerFunc(h.transaction.GetTransactions))
+
+	// GET /v1/transactions/stream (server-sent events)
+	route("/v1/transactions/stream", h.stream)
+
+	// POST /v1/transactions/batch (asynchronous bulk transfers)
+	route("/v1/transactions/batch", http.HandlerFunc(h.batch.CreateBatch))
+
+	// GET /v1/transactions/batch/{id}
+	route("/v1/transactions/batch/", leafRoute("/v1/transactions/batch/", http.HandlerFunc(h.batch.GetBatch)))
+
+	// POST /v1/transactions/reverse-by-reference
+	route("/v1/transactions/reverse-by-reference", handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.ReverseByReference)))
+
+	reverseTransaction := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.ReverseTransaction))
+	addNote := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.AddNote))
+	disputeTransaction := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.DisputeTransaction))
+	resolveDispute := handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.ResolveDispute))
+	route("/v1/transactions/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, sub, ok := resourcePath(r.URL.Path, "/v1/transactions/")
+		if !ok {
+			handler.WriteNotFound(w, r)
+			return
 		}
-	})
+
+		switch sub {
+		case "":
+			// GET /v1/transactions/{id}
+			if r.Method == http.MethodGet {
+				h.transaction.GetTransaction(w, r)
+			} else {
+				handler.WriteMethodNotAllowed(w, r, http.MethodGet)
+			}
+		case "reverse":
+			// POST /v1/transactions/{id}/reverse
+			reverseTransaction.ServeHTTP(w, r)
+		case "notes":
+			// POST /v1/transactions/{id}/notes
+			addNote.ServeHTTP(w, r)
+		case "resolve":
+			// POST /v1/transactions/{id}/resolve
+			resolveDispute.ServeHTTP(w, r)
+		case "dispute":
+			// POST /v1/transactions/{id}/dispute
+			disputeTransaction.ServeHTTP(w, r)
+		case "retry":
+			// POST /v1/transactions/{id}/retry
+			h.transaction.RetryTransaction(w, r)
+		case "status":
+			// GET /v1/transactions/{id}/status
+			h.transaction.GetTransactionStatus(w, r)
+		default:
+			handler.WriteNotFound(w, r)
+		}
+	}))
+
+	// Admin endpoints
+	// GET /v1/idempotency/{key}
+	route("/v1/idempotency/", handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.idempotency.GetKeyStatus)))
+	// GET /v1/admin/transactions/{id}
+	route("/v1/admin/transactions/", leafRoute("/v1/admin/transactions/", handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.transaction.GetTransactionAdmin))))
+	// GET /v1/reports/reconciliation
+	route("/v1/reports/reconciliation", handler.RequireAdmin(cfg.Admin.Token, http.HandlerFunc(h.account.ReconciliationReport)))
+
+	// Anything else is a path no route serves, answered with the JSON envelope rather
+	// than the mux's plain text
+	route("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteNotFound(w, r)
+	}))
+
+	for pattern := range cfg.Server.RouteTimeouts {
+		if !routed[pattern] {
+			log.Printf("WARN ROUTE_TIMEOUTS names %s, which is not a route", pattern)
+		}
+	}
 
 	// Basic middleware
-	handlerWithMiddleware := corsMiddleware(loggingMiddleware(mux))
+	var handlerWithMiddleware http.Handler = requests.middleware(handler.RequestIDMiddleware(corsMiddleware(loggingMiddleware(cfg.Logger, handler.PrettyJSONMiddleware(cfg.Server.PrettyJSON, handler.ErrorDetailMiddleware(cfg.Server.VerboseErrors, readinessMiddleware(h.health, h.limiter.Middleware(handler.Authenticate(cfg.Auth.APIKeys, cfg.Admin.Token, handler.IdempotencyKeyMiddleware(cfg.Idempotency.KeyFormat, mux))))))))))
+
+	h2s := &http2.Server{IdleTimeout: cfg.Server.IdleTimeout}
+	if cfg.Server.H2C {
+		// Accept HTTP/2 without TLS, by prior knowledge or upgrade, from a proxy in front
+		handlerWithMiddleware = h2c.NewHandler(handlerWithMiddleware, h2s)
+	}
 
-	return &http.Server{
-		Addr:         ":" + cfg.Server.Port,
-		Handler:      handlerWithMiddleware,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+	server := &http.Server{
+		Addr:           ":" + cfg.Server.Port,
+		Handler:        handlerWithMiddleware,
+		BaseContext:    requests.baseContext,
+		ConnState:      h.health.ConnState,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
+		IdleTimeout:    cfg.Server.IdleTimeout,
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
 	}
+
+	if cfg.Server.H2C {
+		// Registers h2s with the server so graceful shutdown sends GOAWAY on h2c connections
+		if err := http2.ConfigureServer(server, h2s); err != nil {
+			log.Fatalf("Failed to configure HTTP/2: %v", err)
+		}
+	}
+
+	return server
 }
 
-// loggingMiddleware logs HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
+// resourcePath splits a path under prefix into the resource ID and whatever follows it,
+// e.g. "/v1/accounts/{id}/balance/watch" into the ID and "balance/watch". ok is false when
+// the ID is missing or the path ends in a slash, neither of which names a resource.
+func resourcePath(path, prefix string) (id, sub string, ok bool) {
+	rest := strings.TrimPrefix(path, prefix)
+	id, sub, _ = strings.Cut(rest, "/")
+	return id, sub, id != "" && !strings.HasSuffix(rest, "/")
+}
+
+// leafRoute serves next only for a single non-empty segment under prefix, answering
+// anything deeper with a 404 rather than handing the handler an ID with slashes in it
+func leafRoute(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, sub, ok := resourcePath(r.URL.Path, prefix); !ok || sub != "" {
+			handler.WriteNotFound(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loggingMiddleware logs HTTP requests. With LOG_BODIES at debug level it also logs
+// the redacted request and response bodies of mutating requests.
+func loggingMiddleware(cfg config.LoggerConfig, next http.Handler) http.Handler {
+	logBodies := cfg.LogBodies && cfg.Level == "debug"
+	redact := make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redact[field] = true
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		
+
 		// Create a response writer wrapper to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: 200}
-		
+
+		// Capture bodies as they stream through rather than buffering them up front
+		var requestBody *cappedBuffer
+		if logBodies && isMutating(r.Method) {
+			requestBody = newCappedBuffer(maxLoggedBodyBytes)
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, requestBody), r.Body}
+			wrapped.body = newCappedBuffer(maxLoggedBodyBytes)
+		}
+
 		next.ServeHTTP(wrapped, r)
-		
+
 		duration := time.Since(start)
-		log.Printf("%s %s %d %v", r.Method, r.URL.Path, wrapped.statusCode, duration)
+		log.Printf("%s %s %d %v request_id=%s", r.Method, r.URL.Path, wrapped.statusCode, duration, w.Header().Get(handler.RequestIDHeader))
+
+		if requestBody != nil {
+			log.Printf("DEBUG %s %s request=%s response=%s", r.Method, r.URL.Path,
+				redactedBody(requestBody, redact), redactedBody(wrapped.body, redact))
+		}
+	})
+}
+
+// readinessMiddleware rejects API requests with 503 until the service has connected
+// to the database; health probes are always served
+func readinessMiddleware(health *handler.HealthHandler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/livez", "/healthz", "/readyz":
+		default:
+			if !health.DatabaseReady() {
+				w.Header().Set("Retry-After", "5")
+				handler.WriteErrorResponse(w, r, http.StatusServiceUnavailable, "Service is waiting for the database", model.ErrCodeServiceUnavailable)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -179,7 +648,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, X-Request-ID, traceparent, tracestate")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -193,6 +663,14 @@ func corsMiddleware(next http.Handler) http.Handler {
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	body       *cappedBuffer // set when the response body is logged
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.body != nil {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -200,16 +678,7 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// writeErrorResponse helper function
-func writeErrorResponse(w http.ResponseWriter, statusCode int, message, code string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	response := map[string]string{
-		"error": message,
-		"code":  code,
-	}
-	
-	// Simple JSON encoding without importing json package again
-	fmt.Fprintf(w, `{"error": "%s", "code": "%s"}`, response["error"], response["code"])
-} 
\ No newline at end of file
+// Unwrap exposes the underlying writer so http.ResponseController can flush streams
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"internal-transfers-api/internal/handler"
+)
+
+// Backoff between connection attempts, both at startup in START_WITHOUT_DB mode and
+// when reconnecting after the database was lost
+const (
+	initialConnectBackoff = time.Second
+	maxConnectBackoff     = 30 * time.Second
+)
+
+// connectWithBackoff pings the database until it answers, doubling the wait between
+// attempts up to maxConnectBackoff. It only fails when ctx is cancelled.
+func connectWithBackoff(ctx context.Context, db *sql.DB) error {
+	backoff := initialConnectBackoff
+	for {
+		err := pingDatabase(ctx, db)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Database unavailable, retrying in %v: %v", backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// dbReconnector heals the service after the database goes away at runtime. Told of a
+// failed connection attempt, it checks the database and, if it is down, marks the
+// service unready and reconnects with connectWithBackoff in the background. Once the
+// database answers again the idle connections, which may predate the outage, are
+// dropped and the service turns ready.
+type dbReconnector struct {
+	maxIdleConns int         // the pool's idle connection limit, restored after dropping them
+	running      atomic.Bool // a reconnect is in progress
+	mu           sync.Mutex  // guards the fields below, set by start
+	ctx          context.Context
+	db           *sql.DB
+	health       *handler.HealthHandler
+	workers      *sync.WaitGroup
+}
+
+func newDBReconnector(maxIdleConns int) *dbReconnector {
+	return &dbReconnector{maxIdleConns: maxIdleConns}
+}
+
+// start enables reconnecting db until ctx is cancelled, tracking the reconnect loop in
+// workers. Failures reported before start, or before the service first turned ready,
+// are left to startup.
+func (r *dbReconnector) start(ctx context.Context, db *sql.DB, health *handler.HealthHandler, workers *sync.WaitGroup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx, r.db, r.health, r.workers = ctx, db, health, workers
+}
+
+// connectFailed is called by the connector when a new connection can't be made
+func (r *dbReconnector) connectFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.db == nil || !r.health.DatabaseReady() || r.ctx.Err() != nil {
+		return
+	}
+	if !r.running.CompareAndSwap(false, true) {
+		return
+	}
+
+	log.Printf("Database connection failed, checking the database: %v", err)
+	r.workers.Add(1)
+	go func() {
+		defer r.workers.Done()
+		defer r.running.Store(false)
+		r.reconnect()
+	}()
+}
+
+// reconnect waits for the database to answer, keeping the service unready meanwhile
+func (r *dbReconnector) reconnect() {
+	// A single failed attempt, such as one refused for too many connections, doesn't
+	// mean the database is gone
+	if err := pingDatabase(r.ctx, r.db); err == nil {
+		return
+	}
+
+	log.Println("Database lost, reconnecting")
+	r.health.SetDatabaseReady(false)
+	if err := connectWithBackoff(r.ctx, r.db); err != nil {
+		return
+	}
+
+	// Close the idle connections, which may have been broken by the outage
+	r.db.SetMaxIdleConns(0)
+	r.db.SetMaxIdleConns(r.maxIdleConns)
+
+	r.health.SetDatabaseReady(true)
+	log.Println("Database connection re-established")
+}
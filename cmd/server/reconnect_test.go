@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/handler"
+)
+
+// flakyConnector fails to connect while down, reporting the failures like schemaConnector
+type flakyConnector struct {
+	down          atomic.Bool
+	connectFailed func(error)
+	connects      atomic.Int64 // connections made
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) {
+	if c.down.Load() {
+		err := errors.New("connection refused")
+		c.connectFailed(err)
+		return nil, err
+	}
+	c.connects.Add(1)
+	return idleConn{}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return nil
+}
+
+// idleConn is a connection that is never used beyond being pinged or running SELECT 1
+type idleConn struct{}
+
+func (idleConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.ResultNoRows, nil
+}
+
+func (idleConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (idleConn) Close() error                        { return nil }
+func (idleConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func TestDBReconnector(t *testing.T) {
+	reconnector := newDBReconnector(2)
+	connector := &flakyConnector{connectFailed: reconnector.connectFailed}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	health := handler.NewHealthHandler(db, version, config.HealthConfig{}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		cancel()
+		workers.Wait()
+	}()
+
+	// Failures during startup are left to startup
+	connector.down.Store(true)
+	require.Error(t, db.Ping())
+	reconnector.start(ctx, db, health, &workers)
+	require.Error(t, db.Ping())
+	assert.False(t, reconnector.running.Load())
+
+	connector.down.Store(false)
+	require.NoError(t, db.Ping())
+	health.SetDatabaseReady(true)
+
+	// A failed attempt while the database still answers keeps the service ready
+	reconnector.connectFailed(errors.New("too many connections"))
+	assert.Eventually(t, func() bool { return !reconnector.running.Load() }, time.Second, 5*time.Millisecond)
+	assert.True(t, health.DatabaseReady())
+
+	// Losing the database takes the service out of readiness until it answers again
+	connector.down.Store(true)
+	db.SetMaxIdleConns(0)
+	require.Error(t, db.Ping())
+	assert.Eventually(t, func() bool { return !health.DatabaseReady() }, time.Second, 5*time.Millisecond)
+
+	connector.down.Store(false)
+	assert.Eventually(t, health.DatabaseReady, 2*initialConnectBackoff, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return !reconnector.running.Load() }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/handler"
+)
+
+// newTestServer builds the server around handlers without services, enough to exercise
+// routing up to the point a handler would call one
+func newTestServer() *http.Server {
+	cfg := &config.Config{Admin: config.AdminConfig{Token: "admin-token"}}
+	limiter := handler.NewConcurrencyLimiter(0, 0)
+	health := handler.NewHealthHandler(nil, version, config.HealthConfig{}, limiter)
+	health.SetDatabaseReady(true)
+	return initServer(cfg, &handlers{
+		limiter:     limiter,
+		health:      health,
+		account:     handler.NewAccountHandler(nil),
+		transaction: handler.NewTransactionHandler(nil),
+		stream:      handler.NewTransactionStreamHandler(nil),
+		batch:       handler.NewBatchHandler(nil),
+		idempotency: handler.NewIdempotencyHandler(nil),
+	}, newRequestTracker())
+}
+
+func TestInitServer_MethodNotAllowed(t *testing.T) {
+	server := newTestServer()
+
+	id := "2235a24b-3f70-46a3-9776-29747cdbabba"
+	tests := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodPost, "/livez", "GET"},
+		{http.MethodPost, "/healthz", "GET"},
+		{http.MethodPost, "/readyz", "GET"},
+		{http.MethodGet, "/v1/accounts", "POST"},
+		{http.MethodPost, "/v1/currencies", "GET"},
+		{http.MethodPost, "/v1/fx/preview", "GET"},
+		{http.MethodGet, "/v1/accounts/balances", "POST"},
+		{http.MethodDelete, "/v1/accounts/" + id, "GET"},
+		{http.MethodPost, "/v1/accounts/" + id + "/transactions", "GET"},
+		{http.MethodPost, "/v1/accounts/" + id + "/counterparties", "GET"},
+		{http.MethodPost, "/v1/accounts/" + id + "/balance-history", "GET"},
+		{http.MethodPost, "/v1/accounts/" + id + "/balance/watch", "GET"},
+		{http.MethodGet, "/v1/accounts/" + id + "/reconcile", "POST"},
+		{http.MethodGet, "/v1/accounts/" + id + "/status", "POST"},
+		{http.MethodGet, "/v1/accounts/" + id + "/adjustments", "POST"},
+		{http.MethodPost, "/v1/owners/acme/accounts", "GET"},
+		{http.MethodGet, "/v1/transactions", "POST"},
+		{http.MethodDelete, "/v1/transactions/" + id, "GET"},
+		{http.MethodPost, "/v1/transactions/" + id + "/status", "GET"},
+		{http.MethodGet, "/v1/transactions/" + id + "/reverse", "POST"},
+		{http.MethodGet, "/v1/transactions/" + id + "/notes", "POST"},
+		{http.MethodGet, "/v1/transactions/" + id + "/dispute", "POST"},
+		{http.MethodGet, "/v1/transactions/" + id + "/resolve", "POST"},
+		{http.MethodGet, "/v1/transactions/reverse-by-reference", "POST"},
+		{http.MethodGet, "/v1/transactions/get", "POST"},
+		{http.MethodPost, "/v1/transactions/stream", "GET"},
+		{http.MethodGet, "/v1/transactions/batch", "POST"},
+		{http.MethodPost, "/v1/transactions/batch/" + id, "GET"},
+		{http.MethodPost, "/v1/idempotency/some-key", "GET"},
+		{http.MethodPost, "/v1/admin/transactions/" + id, "GET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer admin-token")
+			w := httptest.NewRecorder()
+			server.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+			assert.Equal(t, tt.allow, w.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestInitServer_NotFound(t *testing.T) {
+	server := newTestServer()
+
+	id := "2235a24b-3f70-46a3-9776-29747cdbabba"
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/"},
+		{http.MethodGet, "/v2/accounts"},
+		{http.MethodGet, "/v1/unknown"},
+		{http.MethodGet, "/v1/accounts/"},
+		{http.MethodGet, "/v1/accounts/" + id + "/"},
+		{http.MethodGet, "/v1/accounts/abc/foo"},
+		{http.MethodGet, "/v1/accounts/" + id + "/balance"},
+		{http.MethodGet, "/v1/accounts/" + id + "/transactions/extra"},
+		{http.MethodPost, "/v1/accounts/balances/extra"},
+		{http.MethodGet, "/v1/owners/acme"},
+		{http.MethodGet, "/v1/owners/acme/accounts/extra"},
+		{http.MethodGet, "/v1/transactions/"},
+		{http.MethodGet, "/v1/transactions/" + id + "/foo"},
+		{http.MethodPost, "/v1/transactions/" + id + "/reverse/again"},
+		{http.MethodGet, "/v1/transactions/stream/"},
+		{http.MethodGet, "/v1/transactions/batch/" + id + "/items"},
+		{http.MethodGet, "/v1/admin/transactions/" + id + "/notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer admin-token")
+			w := httptest.NewRecorder()
+			server.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.JSONEq(t, `{"error":"No route matches the requested path","code":"NOT_FOUND","request_id":"`+w.Header().Get("X-Request-ID")+`"}`, w.Body.String())
+		})
+	}
+}
+
+func TestInitServer_AdminOnly(t *testing.T) {
+	server := newTestServer()
+
+	id := "2235a24b-3f70-46a3-9776-29747cdbabba"
+	for _, path := range []string{
+		"/v1/accounts/" + id + "/reconcile",
+		"/v1/accounts/" + id + "/reconcile?fix=true",
+	} {
+		t.Run(path, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, path, nil)
+			w := httptest.NewRecorder()
+			server.Handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
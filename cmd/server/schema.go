@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// schemaConnector sets the search_path on every new connection so unqualified
+// table names in repository queries resolve to the configured schema, and reports
+// connections that can't be made to connectFailed
+type schemaConnector struct {
+	driver.Connector
+	schema        string
+	connectFailed func(error)
+}
+
+func newSchemaConnector(dsn, schema string, connectFailed func(error)) (*schemaConnector, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaConnector{Connector: connector, schema: schema, connectFailed: connectFailed}, nil
+}
+
+func (c *schemaConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		c.connectFailed(err)
+		return nil, err
+	}
+
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("connection does not support setting search_path")
+	}
+
+	// The schema name is validated at config load; quoting guards against anything else
+	if _, err := execer.ExecContext(ctx, "SET search_path TO "+pq.QuoteIdentifier(c.schema), nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set search_path: %w", err)
+	}
+
+	return conn, nil
+}
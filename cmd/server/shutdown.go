@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// requestTracker counts in-flight requests and serves them under a context that can be
+// cancelled when graceful shutdown times out, so that the database transactions they
+// have open are rolled back instead of left dangling
+type requestTracker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	active atomic.Int64
+}
+
+func newRequestTracker() *requestTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &requestTracker{ctx: ctx, cancel: cancel}
+}
+
+// baseContext is used as http.Server.BaseContext
+func (t *requestTracker) baseContext(net.Listener) context.Context {
+	return t.ctx
+}
+
+// middleware counts the requests passing through next
+func (t *requestTracker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.active.Add(1)
+		defer t.active.Add(-1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// inFlight returns how many requests are currently being served
+func (t *requestTracker) inFlight() int64 {
+	return t.active.Load()
+}
+
+// cancelAndWait cancels every in-flight request and waits up to timeout for them to return
+func (t *requestTracker) cancelAndWait(timeout time.Duration) {
+	t.cancel()
+
+	deadline := time.Now().Add(timeout)
+	for t.active.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmDatabase(t *testing.T) {
+	connector := &flakyConnector{connectFailed: func(error) {}}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxIdleConns(5)
+
+	require.NoError(t, warmDatabase(context.Background(), db, 3))
+	assert.Equal(t, int64(3), connector.connects.Load())
+	assert.Equal(t, 3, db.Stats().Idle)
+
+	// The warmed connections serve what comes next without connecting again
+	require.NoError(t, db.Ping())
+	assert.Equal(t, int64(3), connector.connects.Load())
+
+	// A database that is down fails the warmup rather than holding up startup
+	connector.down.Store(true)
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(5)
+	assert.Error(t, warmDatabase(context.Background(), db, 3))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	connector.down.Store(false)
+	assert.Error(t, warmDatabase(ctx, db, 3))
+}
@@ -1,94 +1,923 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+
+	"internal-transfers-api/internal/model"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Logger   LoggerConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Logger      LoggerConfig
+	Idempotency IdempotencyConfig
+	Transfers   TransferConfig
+	Accounts    AccountConfig
+	Health      HealthConfig
+	Batch       BatchConfig
+	Interest    InterestConfig
+	Orphans     OrphanConfig
+	Snapshots   SnapshotConfig
+	Admin       AdminConfig
+	Auth        AuthConfig
+	Cache       CacheConfig
+	Jobs        JobsConfig
+	Audit       AuditConfig
+	Features    Features
 }
 
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Port              string
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration            // how long in-flight requests get to finish on shutdown
+	MaxHeaderBytes    int                      // largest request header accepted, in bytes
+	H2C               bool                     // serve HTTP/2 over plaintext (h2c) alongside HTTP/1.1, for use behind a proxy
+	RouteTimeouts     map[string]time.Duration // deadline per route pattern, e.g. /v1/transactions; unlisted routes have none
+	MaxInFlightWrites int                      // most mutating API requests served at once; zero is unlimited
+	MaxInFlightReads  int                      // most read API requests served at once; zero is unlimited
+	VerboseErrors     bool                     // 500 responses carry the underlying error; only for development
+	PrettyJSON        bool                     // ?pretty=true indents JSON responses; only for development
+	AmountFormat      model.AmountFormat       // whether amounts in responses are JSON strings or numbers
 }
 
 type DatabaseConfig struct {
-	Host         string
-	Port         string
-	User         string
-	Password     string
-	Database     string
-	SSLMode      string
-	MaxOpenConns int
-	MaxIdleConns int
+	Host           string
+	Port           string
+	User           string
+	Password       string
+	Database       string
+	SSLMode        string
+	Schema         string // set as the search_path of every connection
+	ReplicaDSN     string // read replica serving the read-only endpoints; empty reads the primary
+	StartWithoutDB bool   // serve health probes and keep retrying when the database is down at startup
+	MaxOpenConns   int
+	MaxIdleConns   int
+
+	WarmupConnections int // connections opened and primed at startup before the service turns ready
+
+	NumericPrecision int  // expected precision of the balance and amount columns, checked at startup
+	NumericScale     int  // expected scale of the balance and amount columns
+	NumericStrict    bool // fail startup when the columns don't match instead of logging a warning
+
+	SlowQueryThreshold time.Duration // queries running longer are logged at warn level; zero disables the log
+	AcquireTimeout     time.Duration // how long a transaction waits for a pooled connection before SERVICE_BUSY; zero waits for the request deadline
+
+	ErrorCodes          map[string]string // Postgres SQLSTATE codes reported as the given service error codes
+	RetryableErrorCodes []string          // SQLSTATE codes of transient failures, reported as SERVICE_UNAVAILABLE unless mapped
 }
 
 type LoggerConfig struct {
-	Level  string
-	Format string // json or text
+	Level        string
+	Format       string   // json or text
+	LogBodies    bool     // log request/response bodies of mutating requests at debug level
+	RedactFields []string // JSON fields whose values are masked in logged bodies
+}
+
+type IdempotencyConfig struct {
+	TTL       time.Duration              // how long a stored key can be replayed
+	KeyFormat model.IdempotencyKeyFormat // lengths and characters accepted in idempotency keys
+	Required  bool                       // refuse transfers sent without an Idempotency-Key header
+}
+
+type HealthConfig struct {
+	Dependencies []DependencyConfig
+	ProbeTimeout time.Duration
+	CacheTTL     time.Duration // how long a healthy database check is reused; zero checks on every probe
+}
+
+// DependencyConfig is a downstream service probed by the readiness check
+type DependencyConfig struct {
+	Name     string
+	URL      string
+	Critical bool // an unreachable critical dependency fails readiness instead of degrading it
+}
+
+type BatchConfig struct {
+	MaxSize      int           // most transfers accepted in one asynchronous batch
+	PollInterval time.Duration // how often the worker looks for pending batches
+}
+
+type OrphanConfig struct {
+	Enabled  bool
+	MaxAge   time.Duration // how long a transaction may stay pending before the checker resolves it
+	Interval time.Duration // how often the checker looks for stale pending transactions
+}
+
+// Features switches the features that are rolled out gradually. A request needing one
+// that is off fails with 501 NOT_IMPLEMENTED.
+type Features struct {
+	CrossCurrency bool // transfers between accounts of different currencies, and FX previews
+	BulkTransfers bool // several transfers in one POST /v1/transactions
+	Batches       bool // asynchronous batches submitted to POST /v1/transactions/batch
+}
+
+// DefaultFeatures returns the features as they are unless configured otherwise
+func DefaultFeatures() Features {
+	return Features{
+		CrossCurrency: true,
+		BulkTransfers: true,
+		Batches:       true,
+	}
+}
+
+type AuditConfig struct {
+	DB     bool // record each change in the audit_log table, in the change's own transaction
+	Stdout bool // write each change to stdout as a JSON line once committed
+}
+
+type AdminConfig struct {
+	Token string // bearer token for the admin endpoints; empty disables them
+}
+
+type AuthConfig struct {
+	APIKeys map[string]string // API key -> owner ID of the accounts it may debit; empty disables API keys
+}
+
+type CacheConfig struct {
+	BalanceTTL time.Duration // how long account reads are cached; zero disables the cache
+}
+
+type JobsConfig struct {
+	Workers      int  // goroutines running post-commit side effects
+	QueueSize    int  // side effects waiting for a worker before the queue is full
+	DropWhenFull bool // drop side effects queued while the queue is full instead of waiting for room
+}
+
+type InterestConfig struct {
+	Enabled  bool
+	RunAt    time.Duration // time of day, after UTC midnight, at which the daily accrual runs
+	DayCount string        // day-count convention: actual/365, actual/360 or actual/actual
+	Scale    int32         // decimal places interest is rounded to, using the transfer rounding mode
 }
 
+type SnapshotConfig struct {
+	Enabled bool
+	RunAt   time.Duration // time of day, after UTC midnight, at which the previous day is snapshotted
+}
+
+type AccountConfig struct {
+	MaxInitialBalanceDigits int                 // most digits before the decimal point an initial balance may have
+	DefaultCurrency         string              // currency of accounts opened without one
+	BalanceFormat           model.BalanceFormat // whether account balances are padded to their currency's decimal places
+}
+
+type TransferConfig struct {
+	RecordDeclined      bool                   // persist declined transfers as failed transactions
+	MaxBalance          decimal.Decimal        // largest balance magnitude an account may reach
+	AllowFrozenDeposits bool                   // let frozen accounts still receive credits
+	AllowDeposits       bool                   // let deposits credit an account without a system account to draw them from
+	RoundingMode        model.RoundingMode     // how fees and interest are quantized to the stored scale
+	ExcessPrecision     model.ExcessPrecision  // whether amounts with more decimal places than their currency are rejected or rounded
+	ReferenceCharset    model.TextCharset      // characters allowed in transaction references and descriptions
+	Currencies          model.CurrencyRegistry // decimal places allowed per currency
+	RetryBudget         time.Duration          // how long a transfer retries serialization failures for; zero disables retries
+	Rates               model.RateProvider     // exchange rates for transfers between accounts of different currencies
+	SystemAccounts      map[string]uuid.UUID   // currency -> account deposits are drawn from and withdrawals paid into
+	DuplicateWindow     time.Duration          // how far back a transfer with the same accounts and amount is a possible duplicate; zero disables the check
+	VelocityLimit       int                    // most transfers an account may make within VelocityWindow; zero disables the limit
+	VelocityWindow      time.Duration          // the sliding window VelocityLimit counts transfers in
+}
+
+// Load reads the configuration from the environment and, when CONFIG_FILE names one,
+// a YAML or JSON file of the same settings. Environment variables override the file.
 func Load() (*Config, error) {
+	env, err := newEnvReader(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	features := DefaultFeatures()
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "8080"),
-			ReadTimeout:  getDurationEnv("READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getDurationEnv("WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getDurationEnv("IDLE_TIMEOUT", 120*time.Second),
+			Port:              env.get("PORT", "8080"),
+			ReadTimeout:       env.getDuration("READ_TIMEOUT", 30*time.Second),
+			WriteTimeout:      env.getDuration("WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       env.getDuration("IDLE_TIMEOUT", 120*time.Second),
+			ShutdownTimeout:   env.getDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+			MaxHeaderBytes:    env.getInt("MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes),
+			H2C:               env.getBool("H2C_ENABLED", false),
+			PrettyJSON:        env.getBool("PRETTY_JSON", false),
+			MaxInFlightWrites: env.getInt("MAX_INFLIGHT_WRITES", 100),
+			MaxInFlightReads:  env.getInt("MAX_INFLIGHT_READS", 500),
 		},
 		Database: DatabaseConfig{
-			Host:         getEnv("DB_HOST", "localhost"),
-			Port:         getEnv("DB_PORT", "5432"),
-			User:         getEnv("DB_USER", "postgres"),
-			Password:     getEnv("DB_PASSWORD", "postgres"),
-			Database:     getEnv("DB_NAME", "transfers"),
-			SSLMode:      getEnv("DB_SSLMODE", "disable"),
-			MaxOpenConns: getIntEnv("DB_MAX_OPEN_CONNS", 25),
-			MaxIdleConns: getIntEnv("DB_MAX_IDLE_CONNS", 5),
+			Host:           env.get("DB_HOST", "localhost"),
+			Port:           env.get("DB_PORT", "5432"),
+			User:           env.get("DB_USER", "postgres"),
+			Password:       env.get("DB_PASSWORD", "postgres"),
+			Database:       env.get("DB_NAME", "transfers"),
+			SSLMode:        env.get("DB_SSLMODE", "disable"),
+			Schema:         env.get("DB_SCHEMA", "public"),
+			ReplicaDSN:     env.get("DB_REPLICA_DSN", ""),
+			StartWithoutDB: env.getBool("START_WITHOUT_DB", false),
+			MaxOpenConns:   env.getInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:   env.getInt("DB_MAX_IDLE_CONNS", 5),
+
+			WarmupConnections: env.getInt("WARMUP_CONNECTIONS", 0),
+
+			NumericPrecision: env.getInt("DB_NUMERIC_PRECISION", model.MaxIntegerDigits+model.AmountScale),
+			NumericScale:     env.getInt("DB_NUMERIC_SCALE", model.AmountScale),
+			NumericStrict:    env.getBool("DB_NUMERIC_STRICT", false),
+
+			SlowQueryThreshold: env.getDuration("SLOW_QUERY_THRESHOLD", 0),
+			AcquireTimeout:     env.getDuration("DB_ACQUIRE_TIMEOUT", time.Second),
 		},
 		Logger: LoggerConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:        env.get("LOG_LEVEL", "info"),
+			Format:       env.get("LOG_FORMAT", "json"),
+			LogBodies:    env.getBool("LOG_BODIES", false),
+			RedactFields: withDefaultRedactFields(env.getList("LOG_REDACT_FIELDS", nil)),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: env.getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+			KeyFormat: model.IdempotencyKeyFormat{
+				MinLength: env.getInt("IDEMPOTENCY_KEY_MIN_LENGTH", 1),
+				MaxLength: env.getInt("IDEMPOTENCY_KEY_MAX_LENGTH", 255),
+			},
+			Required: env.getBool("REQUIRE_IDEMPOTENCY_KEY", false),
+		},
+		Transfers: TransferConfig{
+			RecordDeclined:      env.getBool("RECORD_DECLINED_TRANSFERS", false),
+			AllowFrozenDeposits: env.getBool("ALLOW_FROZEN_DEPOSITS", false),
+			AllowDeposits:       env.getBool("ALLOW_DEPOSITS", false),
+			RetryBudget:         env.getDuration("TX_RETRY_BUDGET", 2*time.Second),
+			DuplicateWindow:     env.getDuration("DUPLICATE_WINDOW", 0),
+			VelocityLimit:       env.getInt("VELOCITY_LIMIT", 0),
+			VelocityWindow:      env.getDuration("VELOCITY_WINDOW", time.Hour),
+			// Largest value NUMERIC(38,10) can hold
+			MaxBalance: env.getDecimal("MAX_BALANCE", decimal.RequireFromString("9999999999999999999999999999.9999999999")),
+		},
+		Accounts: AccountConfig{
+			MaxInitialBalanceDigits: env.getInt("MAX_INITIAL_BALANCE_DIGITS", model.MaxIntegerDigits),
+			DefaultCurrency:         env.get("DEFAULT_CURRENCY", model.DefaultCurrency),
+		},
+		Batch: BatchConfig{
+			MaxSize:      env.getInt("BATCH_MAX_SIZE", 10000),
+			PollInterval: env.getDuration("BATCH_POLL_INTERVAL", 5*time.Second),
+		},
+		Interest: InterestConfig{
+			Enabled:  env.getBool("INTEREST_ACCRUAL_ENABLED", false),
+			DayCount: env.get("INTEREST_DAY_COUNT", "actual/365"),
+			Scale:    int32(env.getInt("INTEREST_SCALE", 2)),
+		},
+		Snapshots: SnapshotConfig{
+			Enabled: env.getBool("BALANCE_SNAPSHOTS_ENABLED", true),
 		},
+		Orphans: OrphanConfig{
+			Enabled:  env.getBool("ORPHAN_CHECK_ENABLED", false),
+			MaxAge:   env.getDuration("ORPHAN_CHECK_MAX_AGE", 5*time.Minute),
+			Interval: env.getDuration("ORPHAN_CHECK_INTERVAL", time.Minute),
+		},
+		Admin: AdminConfig{
+			Token: env.get("ADMIN_TOKEN", ""),
+		},
+		Cache: CacheConfig{
+			BalanceTTL: env.getDuration("BALANCE_CACHE_TTL", 0),
+		},
+		Jobs: JobsConfig{
+			Workers:   env.getInt("JOB_WORKERS", 4),
+			QueueSize: env.getInt("JOB_QUEUE_SIZE", 1000),
+		},
+		Features: Features{
+			CrossCurrency: env.getBool("FEATURE_CROSS_CURRENCY", features.CrossCurrency),
+			BulkTransfers: env.getBool("FEATURE_BULK_TRANSFERS", features.BulkTransfers),
+			Batches:       env.getBool("FEATURE_BATCHES", features.Batches),
+		},
+		Health: HealthConfig{
+			ProbeTimeout: env.getDuration("HEALTH_PROBE_TIMEOUT", 2*time.Second),
+			CacheTTL:     env.getDuration("HEALTH_CACHE_TTL", time.Second),
+		},
+	}
+
+	// Report every malformed value at once rather than one per restart
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, err
+	}
+
+	if err := validatePort("PORT", cfg.Server.Port); err != nil {
+		return nil, err
+	}
+	if err := validatePort("DB_PORT", cfg.Database.Port); err != nil {
+		return nil, err
+	}
+
+	if cfg.Server.ReadTimeout <= 0 || cfg.Server.WriteTimeout <= 0 || cfg.Server.IdleTimeout <= 0 {
+		return nil, fmt.Errorf("READ_TIMEOUT, WRITE_TIMEOUT and IDLE_TIMEOUT must be positive durations, got %s, %s and %s", cfg.Server.ReadTimeout, cfg.Server.WriteTimeout, cfg.Server.IdleTimeout)
+	}
+
+	// Zero open connections would mean no limit to database/sql, which is never intended here
+	if open, idle := cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns; open < 1 || idle < 0 || idle > open {
+		return nil, fmt.Errorf("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must satisfy 0 <= idle <= open and open >= 1, got %d and %d", open, idle)
+	}
+
+	switch cfg.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return nil, fmt.Errorf("DB_SSLMODE must be disable, allow, prefer, require, verify-ca or verify-full, got %q", cfg.Database.SSLMode)
+	}
+
+	switch cfg.Logger.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		return nil, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", cfg.Logger.Level)
+	}
+
+	switch cfg.Logger.Format {
+	case "json", "text":
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT must be json or text, got %q", cfg.Logger.Format)
+	}
+
+	if cfg.Batch.MaxSize < 1 || cfg.Batch.PollInterval <= 0 {
+		return nil, fmt.Errorf("BATCH_MAX_SIZE and BATCH_POLL_INTERVAL must be positive, got %d and %s", cfg.Batch.MaxSize, cfg.Batch.PollInterval)
+	}
+
+	if cfg.Orphans.MaxAge <= 0 || cfg.Orphans.Interval <= 0 {
+		return nil, fmt.Errorf("ORPHAN_CHECK_MAX_AGE and ORPHAN_CHECK_INTERVAL must be positive, got %s and %s", cfg.Orphans.MaxAge, cfg.Orphans.Interval)
+	}
+
+	if cfg.Health.ProbeTimeout <= 0 {
+		return nil, fmt.Errorf("HEALTH_PROBE_TIMEOUT must be a positive duration, got %s", cfg.Health.ProbeTimeout)
+	}
+
+	if !schemaNamePattern.MatchString(cfg.Database.Schema) {
+		return nil, fmt.Errorf("DB_SCHEMA must be an unquoted Postgres identifier, got %q", cfg.Database.Schema)
+	}
+
+	errorCodes, err := parseErrorCodes(env.get("DB_ERROR_CODES", "23505=CONFLICT,23503=VALIDATION_ERROR"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_ERROR_CODES: %w", err)
+	}
+	cfg.Database.ErrorCodes = errorCodes
+
+	cfg.Database.RetryableErrorCodes = env.getList("DB_RETRYABLE_ERROR_CODES", []string{"40001", "40P01"})
+	for _, code := range cfg.Database.RetryableErrorCodes {
+		if !sqlStatePattern.MatchString(code) {
+			return nil, fmt.Errorf("invalid DB_RETRYABLE_ERROR_CODES: %q is not a SQLSTATE code", code)
+		}
+	}
+
+	roundingMode, err := model.ParseRoundingMode(env.get("ROUNDING_MODE", string(model.RoundHalfUp)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ROUNDING_MODE: %w", err)
+	}
+	cfg.Transfers.RoundingMode = roundingMode
+
+	excessPrecision, err := model.ParseExcessPrecision(env.get("AMOUNT_EXCESS_PRECISION", string(model.ExcessPrecisionReject)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AMOUNT_EXCESS_PRECISION: %w", err)
+	}
+	cfg.Transfers.ExcessPrecision = excessPrecision
+
+	referenceCharset, err := model.ParseTextCharset(env.get("REFERENCE_CHARSET", string(model.TextCharsetUnicode)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REFERENCE_CHARSET: %w", err)
+	}
+	cfg.Transfers.ReferenceCharset = referenceCharset
+
+	currencyScales, err := model.ParseCurrencyScales(env.get("CURRENCY_SCALES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CURRENCY_SCALES: %w", err)
+	}
+	cfg.Transfers.Currencies = model.DefaultCurrencies().Merge(currencyScales)
+	if _, ok := cfg.Transfers.Currencies.Scale(cfg.Accounts.DefaultCurrency); !ok {
+		return nil, fmt.Errorf("DEFAULT_CURRENCY %q is not a supported currency; add it to CURRENCY_SCALES", cfg.Accounts.DefaultCurrency)
+	}
+
+	fxRates, err := model.ParseFXRates(env.get("FX_RATES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FX_RATES: %w", err)
+	}
+	cfg.Transfers.Rates = fxRates
+
+	systemAccounts, err := parseSystemAccounts(env.get("SYSTEM_ACCOUNTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SYSTEM_ACCOUNTS: %w", err)
+	}
+	cfg.Transfers.SystemAccounts = systemAccounts
+
+	runAt, err := parseTimeOfDay(env.get("INTEREST_ACCRUAL_TIME", "00:05"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid INTEREST_ACCRUAL_TIME: %w", err)
+	}
+	cfg.Interest.RunAt = runAt
+
+	snapshotAt, err := parseTimeOfDay(env.get("BALANCE_SNAPSHOT_TIME", "00:10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BALANCE_SNAPSHOT_TIME: %w", err)
+	}
+	cfg.Snapshots.RunAt = snapshotAt
+
+	switch cfg.Interest.DayCount {
+	case "actual/365", "actual/360", "actual/actual":
+	default:
+		return nil, fmt.Errorf("INTEREST_DAY_COUNT must be actual/365, actual/360 or actual/actual, got %q", cfg.Interest.DayCount)
+	}
+
+	if cfg.Interest.Scale < 0 || cfg.Interest.Scale > model.AmountScale {
+		return nil, fmt.Errorf("INTEREST_SCALE must be between 0 and %d, got %d", model.AmountScale, cfg.Interest.Scale)
+	}
+
+	if !cfg.Transfers.MaxBalance.IsPositive() {
+		return nil, fmt.Errorf("MAX_BALANCE must be positive, got %s", cfg.Transfers.MaxBalance)
+	}
+
+	// Warmed connections beyond the idle limit would be closed again straight away
+	if n := cfg.Database.WarmupConnections; n < 0 || n > cfg.Database.MaxIdleConns {
+		return nil, fmt.Errorf("WARMUP_CONNECTIONS must be between 0 and DB_MAX_IDLE_CONNS (%d), got %d", cfg.Database.MaxIdleConns, n)
+	}
+
+	if p, s := cfg.Database.NumericPrecision, cfg.Database.NumericScale; p < 1 || p > 1000 || s < 0 || s > p {
+		return nil, fmt.Errorf("DB_NUMERIC_PRECISION and DB_NUMERIC_SCALE must satisfy 0 <= scale <= precision <= 1000, got %d and %d", p, s)
+	}
+
+	if cfg.Server.MaxInFlightWrites < 0 || cfg.Server.MaxInFlightReads < 0 {
+		return nil, fmt.Errorf("MAX_INFLIGHT_WRITES and MAX_INFLIGHT_READS cannot be negative, got %d and %d", cfg.Server.MaxInFlightWrites, cfg.Server.MaxInFlightReads)
+	}
+
+	if digits := cfg.Accounts.MaxInitialBalanceDigits; digits < 1 || digits > model.MaxIntegerDigits {
+		return nil, fmt.Errorf("MAX_INITIAL_BALANCE_DIGITS must be between 1 and %d, got %d", model.MaxIntegerDigits, digits)
+	}
+
+	dependencies, err := parseDependencies(env.get("HEALTH_DEPENDENCIES", ""), env.get("HEALTH_CRITICAL_DEPENDENCIES", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Health.Dependencies = dependencies
+
+	if cfg.Idempotency.TTL <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_TTL must be a positive duration, got %s", cfg.Idempotency.TTL)
+	}
+
+	apiKeys, err := parseAPIKeys(env.get("API_KEYS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_KEYS: %w", err)
+	}
+	cfg.Auth.APIKeys = apiKeys
+	if _, ok := apiKeys[cfg.Admin.Token]; ok && cfg.Admin.Token != "" {
+		return nil, fmt.Errorf("API_KEYS must not contain ADMIN_TOKEN")
+	}
+
+	if cfg.Database.SlowQueryThreshold < 0 {
+		return nil, fmt.Errorf("SLOW_QUERY_THRESHOLD must not be negative, got %s", cfg.Database.SlowQueryThreshold)
+	}
+
+	if cfg.Database.AcquireTimeout < 0 {
+		return nil, fmt.Errorf("DB_ACQUIRE_TIMEOUT must not be negative, got %s", cfg.Database.AcquireTimeout)
+	}
+
+	if cfg.Transfers.RetryBudget < 0 {
+		return nil, fmt.Errorf("TX_RETRY_BUDGET must not be negative, got %s", cfg.Transfers.RetryBudget)
+	}
+
+	if cfg.Transfers.DuplicateWindow < 0 {
+		return nil, fmt.Errorf("DUPLICATE_WINDOW must not be negative, got %s", cfg.Transfers.DuplicateWindow)
+	}
+
+	if cfg.Transfers.VelocityLimit < 0 {
+		return nil, fmt.Errorf("VELOCITY_LIMIT must not be negative, got %d", cfg.Transfers.VelocityLimit)
+	}
+	if cfg.Transfers.VelocityWindow <= 0 {
+		return nil, fmt.Errorf("VELOCITY_WINDOW must be a positive duration, got %s", cfg.Transfers.VelocityWindow)
+	}
+
+	if cfg.Health.CacheTTL < 0 {
+		return nil, fmt.Errorf("HEALTH_CACHE_TTL must not be negative, got %s", cfg.Health.CacheTTL)
+	}
+
+	if cfg.Cache.BalanceTTL < 0 {
+		return nil, fmt.Errorf("BALANCE_CACHE_TTL must not be negative, got %s", cfg.Cache.BalanceTTL)
+	}
+
+	if cfg.Jobs.Workers < 1 || cfg.Jobs.QueueSize < 0 {
+		return nil, fmt.Errorf("JOB_WORKERS must be positive and JOB_QUEUE_SIZE not negative, got %d and %d", cfg.Jobs.Workers, cfg.Jobs.QueueSize)
+	}
+
+	balanceFormat, err := model.ParseBalanceFormat(env.get("BALANCE_FORMAT", string(model.BalanceFormatExact)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BALANCE_FORMAT: %w", err)
+	}
+	cfg.Accounts.BalanceFormat = balanceFormat
+
+	amountFormat, err := model.ParseAmountFormat(env.get("AMOUNT_FORMAT", string(model.AmountFormatString)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AMOUNT_FORMAT: %w", err)
+	}
+	cfg.Server.AmountFormat = amountFormat
+
+	switch verbosity := env.get("ERROR_VERBOSITY", "safe"); verbosity {
+	case "safe", "verbose":
+		cfg.Server.VerboseErrors = verbosity == "verbose"
+	default:
+		return nil, fmt.Errorf("ERROR_VERBOSITY must be safe or verbose, got %q", verbosity)
+	}
+
+	for _, sink := range env.getList("AUDIT_SINKS", []string{"db"}) {
+		switch sink {
+		case "db":
+			cfg.Audit.DB = true
+		case "stdout":
+			cfg.Audit.Stdout = true
+		case "none":
+		default:
+			return nil, fmt.Errorf("AUDIT_SINKS must list db and/or stdout, or be none, got %q", sink)
+		}
+	}
+
+	switch full := env.get("JOB_QUEUE_FULL", "block"); full {
+	case "block", "drop":
+		cfg.Jobs.DropWhenFull = full == "drop"
+	default:
+		return nil, fmt.Errorf("JOB_QUEUE_FULL must be block or drop, got %q", full)
+	}
+
+	keyCharset, err := model.ParseIdempotencyKeyCharset(env.get("IDEMPOTENCY_KEY_CHARSET", string(model.IdempotencyKeyToken)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_KEY_CHARSET: %w", err)
+	}
+	cfg.Idempotency.KeyFormat.Charset = keyCharset
+
+	if format := cfg.Idempotency.KeyFormat; format.MinLength < 1 || format.MaxLength < format.MinLength || format.MaxLength > 255 {
+		return nil, fmt.Errorf("IDEMPOTENCY_KEY_MIN_LENGTH and IDEMPOTENCY_KEY_MAX_LENGTH must satisfy 1 <= min <= max <= 255, got %d and %d", format.MinLength, format.MaxLength)
+	}
+
+	if cfg.Server.ShutdownTimeout <= 0 {
+		return nil, fmt.Errorf("SHUTDOWN_TIMEOUT must be a positive duration, got %s", cfg.Server.ShutdownTimeout)
+	}
+
+	routeTimeouts, err := parseRouteTimeouts(env.get("ROUTE_TIMEOUTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ROUTE_TIMEOUTS: %w", err)
+	}
+	cfg.Server.RouteTimeouts = routeTimeouts
+
+	if cfg.Server.MaxHeaderBytes <= 0 {
+		return nil, fmt.Errorf("MAX_HEADER_BYTES must be positive, got %d", cfg.Server.MaxHeaderBytes)
+	}
+
+	if err := env.unknown(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// defaultRedactFields are masked in logged bodies whatever LOG_REDACT_FIELDS lists
+var defaultRedactFields = []string{
+	"amount", "balance", "initial_balance", "fee", "expected_source_balance",
+	"destination_amount", "fx_rate", "min_balance", "max_balance",
+	"source_account_id", "destination_account_id", "fee_account_id",
+	"funding_source_account_id", "account_ids",
+}
+
+// withDefaultRedactFields returns defaultRedactFields followed by the configured fields
+// not already among them
+func withDefaultRedactFields(configured []string) []string {
+	fields := append([]string(nil), defaultRedactFields...)
+	seen := make(map[string]bool, len(fields)+len(configured))
+	for _, field := range fields {
+		seen[field] = true
+	}
+	for _, field := range configured {
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// schemaNamePattern matches plain Postgres identifiers of at most 63 bytes
+var schemaNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// sqlStatePattern matches five-character Postgres SQLSTATE error codes
+var sqlStatePattern = regexp.MustCompile(`^[0-9A-Z]{5}$`)
+
+// mappableErrorCodes are the service error codes a database error may be reported as
+var mappableErrorCodes = map[string]bool{
+	model.ErrCodeConflict:           true,
+	model.ErrCodeValidation:         true,
+	model.ErrCodeInvalidInput:       true,
+	model.ErrCodeNotFound:           true,
+	model.ErrCodePreconditionFailed: true,
+	model.ErrCodeServiceUnavailable: true,
+}
+
+// parseErrorCodes parses a comma-separated list of SQLSTATE=SERVICE_CODE pairs
+func parseErrorCodes(value string) (map[string]string, error) {
+	codes := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		sqlState, code, ok := strings.Cut(entry, "=")
+		if !ok || !sqlStatePattern.MatchString(sqlState) {
+			return nil, fmt.Errorf("expected SQLSTATE=CODE, got %q", entry)
+		}
+		if !mappableErrorCodes[code] {
+			return nil, fmt.Errorf("%s cannot be mapped to %q", sqlState, code)
+		}
+		codes[sqlState] = code
+	}
+	return codes, nil
+}
+
+// parseAPIKeys parses a comma-separated list of KEY=OWNER_ID pairs
+func parseAPIKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Keys are secrets, so errors identify an entry by position or owner only
+		key, owner, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || owner == "" {
+			return nil, fmt.Errorf("entry %d is not a KEY=OWNER_ID pair", i+1)
+		}
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("API key for %s is listed more than once", owner)
+		}
+		keys[key] = owner
+	}
+	return keys, nil
+}
+
+// parseSystemAccounts parses a comma-separated list of CURRENCY=ACCOUNT_ID pairs
+func parseSystemAccounts(value string) (map[string]uuid.UUID, error) {
+	accounts := make(map[string]uuid.UUID)
+	seen := make(map[uuid.UUID]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, rawID, ok := strings.Cut(entry, "=")
+		if !ok || currency == "" {
+			return nil, fmt.Errorf("expected CURRENCY=ACCOUNT_ID, got %q", entry)
+		}
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("system account of %s is not a UUID: %q", currency, rawID)
+		}
+		if _, ok := accounts[currency]; ok {
+			return nil, fmt.Errorf("%s has more than one system account", currency)
+		}
+		if other, ok := seen[id]; ok {
+			return nil, fmt.Errorf("account %s is the system account of both %s and %s", id, other, currency)
+		}
+		accounts[currency] = id
+		seen[id] = currency
+	}
+	return accounts, nil
+}
+
+// parseRouteTimeouts parses a comma-separated list of PATTERN=DURATION pairs
+func parseRouteTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, duration, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("expected /route=duration, got %q", entry)
+		}
+		timeout, err := time.ParseDuration(duration)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("timeout for %s must be a positive duration, got %q", pattern, duration)
+		}
+		timeouts[pattern] = timeout
+	}
+	return timeouts, nil
+}
+
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		c.User, c.Password, c.Host, c.Port, c.Database, c.SSLMode)
 }
 
-func getEnv(key, defaultValue string) string {
+// parseDependencies parses a comma-separated list of name=url pairs and marks
+// the names listed in critical as critical dependencies
+func parseDependencies(value, critical string) ([]DependencyConfig, error) {
+	criticalNames := make(map[string]bool)
+	for _, name := range strings.Split(critical, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			criticalNames[name] = true
+		}
+	}
+
+	var dependencies []DependencyConfig
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid HEALTH_DEPENDENCIES entry %q, expected name=url", entry)
+		}
+		dependencies = append(dependencies, DependencyConfig{
+			Name:     name,
+			URL:      url,
+			Critical: criticalNames[name],
+		})
+	}
+
+	return dependencies, nil
+}
+
+// validatePort checks that a port setting is a TCP port number
+func validatePort(key, value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%s must be a port number between 1 and 65535, got %q", key, value)
+	}
+	return nil
+}
+
+// parseTimeOfDay parses an HH:MM time of day into its offset from midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// envReader reads typed settings from the environment, falling back to the config
+// file, and collects the values that don't parse so Load can report them all instead
+// of quietly using the defaults
+type envReader struct {
+	file map[string]string // settings from CONFIG_FILE
+	read map[string]bool   // settings Load has looked up
+	errs []error
+}
+
+// newEnvReader returns a reader over the environment and the config file at path, if
+// path isn't empty
+func newEnvReader(path string) (*envReader, error) {
+	e := &envReader{file: make(map[string]string), read: make(map[string]bool)}
+	if path == "" {
+		return e, nil
+	}
+
+	file, err := readConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONFIG_FILE: %w", err)
+	}
+	e.file = file
+	return e, nil
+}
+
+// readConfigFile parses a YAML or JSON object of settings named as their environment
+// variables. Lists may be written as sequences; every other value is kept as written,
+// so decimals don't pass through a float.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := make(map[string]string, len(nodes))
+	for key, node := range nodes {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if node.Tag != "!!null" {
+				settings[key] = node.Value
+			}
+		case yaml.SequenceNode:
+			items := make([]string, 0, len(node.Content))
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("%s: %s must be a list of plain values", path, key)
+				}
+				items = append(items, item.Value)
+			}
+			settings[key] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("%s: %s must be a plain value or a list", path, key)
+		}
+	}
+	return settings, nil
+}
+
+// lookup returns the value of key, from the environment if it is set there
+func (e *envReader) lookup(key string) string {
+	e.read[key] = true
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
+	return e.file[key]
+}
+
+// unknown reports the config file settings Load never looked up, which are most
+// likely misspelled
+func (e *envReader) unknown() error {
+	var keys []string
+	for key := range e.file {
+		if !e.read[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("invalid CONFIG_FILE: unknown settings %s", strings.Join(keys, ", "))
+}
+
+// invalid records that key holds a value that isn't a kind
+func (e *envReader) invalid(key, value, kind string) {
+	e.errs = append(e.errs, fmt.Errorf("%s must be %s, got %q", key, kind, value))
+}
+
+func (e *envReader) get(key, defaultValue string) string {
+	if value := e.lookup(key); value != "" {
+		return value
+	}
 	return defaultValue
 }
 
-func getIntEnv(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+func (e *envReader) getInt(key string, defaultValue int) int {
+	if value := e.lookup(key); value != "" {
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			e.invalid(key, value, "an integer")
+			return defaultValue
 		}
+		return intValue
 	}
 	return defaultValue
 }
 
-func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+func (e *envReader) getBool(key string, defaultValue bool) bool {
+	if value := e.lookup(key); value != "" {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			e.invalid(key, value, "true or false")
+			return defaultValue
+		}
+		return boolValue
+	}
+	return defaultValue
+}
+
+func (e *envReader) getList(key string, defaultValue []string) []string {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (e *envReader) getDecimal(key string, defaultValue decimal.Decimal) decimal.Decimal {
+	if value := e.lookup(key); value != "" {
+		decimalValue, err := decimal.NewFromString(value)
+		if err != nil {
+			e.invalid(key, value, "a decimal number")
+			return defaultValue
+		}
+		return decimalValue
+	}
+	return defaultValue
+}
+
+func (e *envReader) getDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := e.lookup(key); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			e.invalid(key, value, "a duration such as 30s")
+			return defaultValue
 		}
+		return duration
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Validation(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedError string
+	}{
+		{name: "defaults"},
+		{name: "non-numeric port", env: map[string]string{"PORT": "http"}, expectedError: `PORT must be a port number between 1 and 65535, got "http"`},
+		{name: "port out of range", env: map[string]string{"DB_PORT": "70000"}, expectedError: `DB_PORT must be a port number`},
+		{name: "malformed values reported together", env: map[string]string{"DB_MAX_OPEN_CONNS": "many", "READ_TIMEOUT": "30"}, expectedError: "READ_TIMEOUT must be a duration such as 30s, got \"30\"\nDB_MAX_OPEN_CONNS must be an integer, got \"many\""},
+		{name: "negative timeout", env: map[string]string{"WRITE_TIMEOUT": "-1s"}, expectedError: "READ_TIMEOUT, WRITE_TIMEOUT and IDLE_TIMEOUT must be positive"},
+		{name: "zero pool", env: map[string]string{"DB_MAX_OPEN_CONNS": "0", "DB_MAX_IDLE_CONNS": "0"}, expectedError: "DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS"},
+		{name: "more idle than open", env: map[string]string{"DB_MAX_OPEN_CONNS": "4", "DB_MAX_IDLE_CONNS": "5"}, expectedError: "got 4 and 5"},
+		{name: "unknown SSL mode", env: map[string]string{"DB_SSLMODE": "on"}, expectedError: `DB_SSLMODE must be`},
+		{name: "unknown log level", env: map[string]string{"LOG_LEVEL": "verbose"}, expectedError: `LOG_LEVEL must be debug, info, warn or error, got "verbose"`},
+		{name: "unknown log format", env: map[string]string{"LOG_FORMAT": "xml"}, expectedError: `LOG_FORMAT must be json or text, got "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load()
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "8080", cfg.Server.Port)
+				// Jobs that move money or rewrite transactions are opt-in
+				assert.False(t, cfg.Interest.Enabled)
+				assert.False(t, cfg.Orphans.Enabled)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestLoad_ConfigFile(t *testing.T) {
+	writeFile := func(t *testing.T, name, content string) string {
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("yaml with environment overrides", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", `
+PORT: 9090
+LOG_LEVEL: debug
+DB_MAX_OPEN_CONNS: 10
+READ_TIMEOUT: 5s
+MAX_BALANCE: 1000000000000000000000.0000000001
+LOG_REDACT_FIELDS: [owner_id, amount]
+`))
+		t.Setenv("LOG_LEVEL", "warn")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "9090", cfg.Server.Port)
+		assert.Equal(t, "warn", cfg.Logger.Level)
+		assert.Equal(t, 10, cfg.Database.MaxOpenConns)
+		assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+		assert.Equal(t, "1000000000000000000000.0000000001", cfg.Transfers.MaxBalance.String())
+		// Configured fields are masked on top of the defaults
+		assert.Equal(t, append(append([]string(nil), defaultRedactFields...), "owner_id"), cfg.Logger.RedactFields)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", writeFile(t, "config.json", `{"DB_SSLMODE": "require", "H2C_ENABLED": true}`))
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "require", cfg.Database.SSLMode)
+		assert.True(t, cfg.Server.H2C)
+	})
+
+	t.Run("file values are validated", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "DB_PORT: 0\n"))
+
+		_, err := Load()
+		assert.ErrorContains(t, err, `DB_PORT must be a port number between 1 and 65535, got "0"`)
+	})
+
+	t.Run("unknown settings", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "PORT: 8081\nLOG_LEVLE: debug\n"))
+
+		_, err := Load()
+		assert.EqualError(t, err, "invalid CONFIG_FILE: unknown settings LOG_LEVLE")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+		_, err := Load()
+		assert.ErrorContains(t, err, "invalid CONFIG_FILE")
+	})
+}
@@ -3,14 +3,13 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
-
 	"internal-transfers-api/internal/model"
 	"internal-transfers-api/internal/service"
 )
@@ -30,62 +29,60 @@ func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
 // CreateAccount handles POST /v1/accounts
 func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+		WriteMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
 	// Check for idempotency key
-	idempotencyKey := r.Header.Get("Idempotency-Key")
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
 	_ = idempotencyKey // TODO: Implement idempotency logic
 
 	var req model.CreateAccountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
 		return
 	}
 
 	response, err := h.accountService.CreateAccount(r.Context(), &req)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
-		return
+	// An account already created with the same external_ref is returned with 200
+	status := http.StatusCreated
+	if !response.Created {
+		status = http.StatusOK
 	}
+
+	writeJSON(w, r, status, response)
 }
 
 // GetAccount handles GET /v1/accounts/{id}
 func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+		WriteMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	// Extract account ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
-	if path == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Account ID is required", model.ErrCodeInvalidInput)
-		return
-	}
-
-	accountID, err := uuid.Parse(path)
-	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid account ID format", model.ErrCodeInvalidInput)
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
 		return
 	}
 
-	// Check for historical balance query
+	// Check for historical balance query; a date asks for the balance at the end of that
+	// day, the midnight (UTC) closing it
 	var atTime *time.Time
 	if atParam := r.URL.Query().Get("at"); atParam != "" {
 		if t, err := time.Parse(time.RFC3339, atParam); err == nil {
 			atTime = &t
+		} else if day, err := time.Parse(time.DateOnly, atParam); err == nil {
+			endOfDay := day.Add(24 * time.Hour)
+			atTime = &endOfDay
 		} else {
-			writeErrorResponse(w, http.StatusBadRequest, "Invalid timestamp format. Use RFC3339", model.ErrCodeInvalidInput)
+			WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid timestamp format. Use RFC3339 or a date (YYYY-MM-DD)", model.ErrCodeInvalidInput)
 			return
 		}
 	}
@@ -94,7 +91,7 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 		// Return historical balance
 		balance, err := h.accountService.GetAccountBalance(r.Context(), accountID, atTime)
 		if err != nil {
-			handleServiceError(w, err)
+			handleServiceError(w, r, err)
 			return
 		}
 
@@ -104,25 +101,19 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 			"balance_at": *atTime,
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			// Log the error, but don't change status since headers are already sent
-			// In production, you might want to log this error properly
-			return
-		}
+		writeJSON(w, r, http.StatusOK, response)
 		return
 	}
 
 	// Return current account details
 	response, err := h.accountService.GetAccount(r.Context(), accountID)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
 	// Set ETag for caching
-	etag := fmt.Sprintf(`"%s-%d"`, accountID.String(), response.UpdatedAt.Unix())
+	etag := accountETag(response)
 	w.Header().Set("ETag", etag)
 
 	// Check If-None-Match header
@@ -131,36 +122,258 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	body := selectFields(w, r, response)
 	w.Header().Set("Cache-Control", "max-age=60") // Cache for 1 minute
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+const (
+	defaultWatchTimeout = 25 * time.Second
+	maxWatchTimeout     = 60 * time.Second
+)
+
+// WatchBalance handles GET /v1/accounts/{id}/balance/watch?since=<etag>.
+// It long-polls until the account's ETag differs from since, returning 304 on timeout.
+func (h *AccountHandler) WatchBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Extract account ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	path = strings.TrimSuffix(path, "/balance/watch")
+
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
+		return
+	}
+
+	timeout := defaultWatchTimeout
+	if timeoutParam := r.URL.Query().Get("timeout"); timeoutParam != "" {
+		var err error
+		if timeout, err = time.ParseDuration(timeoutParam); err != nil || timeout <= 0 || timeout > maxWatchTimeout {
+			WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid timeout parameter", model.ErrCodeInvalidInput)
+			return
+		}
+	}
+
+	// Accept the ETag with or without its quotes
+	since := r.URL.Query().Get("since")
+	if since != "" && !strings.HasPrefix(since, `"`) {
+		since = `"` + since + `"`
+	}
+
+	// Keep the connection writable for the whole wait
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 5*time.Second))
+
+	account, changed, err := h.accountService.WatchAccount(r.Context(), accountID, func(a *model.GetAccountResponse) bool {
+		return since == "" || accountETag(a) != since
+	}, timeout)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", accountETag(account))
+	if !changed {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, account)
+}
+
+// GetBalances handles POST /v1/accounts/balances
+func (h *AccountHandler) GetBalances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var req model.GetBalancesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	response, err := h.accountService.GetBalances(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// ListOwnerAccounts handles GET /v1/owners/{owner_id}/accounts
+func (h *AccountHandler) ListOwnerAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Owner IDs are opaque client strings, so take the escaped path to keep any encoded slashes
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/v1/owners/")
+	ownerID, err := url.PathUnescape(strings.TrimSuffix(path, "/accounts"))
+	if err != nil || ownerID == "" {
+		writeError(w, r, http.StatusBadRequest, model.ErrorResponse{Error: "owner_id is required", Code: model.ErrCodeInvalidInput, Field: "owner_id"})
+		return
+	}
+
+	response, err := h.accountService.ListOwnerAccounts(r.Context(), ownerID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// GetBalanceHistory handles GET /v1/accounts/{id}/balance-history?points=t1,t2,t3
+func (h *AccountHandler) GetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Extract account ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	path = strings.TrimSuffix(path, "/balance-history")
+
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
+		return
+	}
+
+	response, err := h.accountService.GetBalanceHistory(r.Context(), accountID, r.URL.Query().Get("points"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// ListCurrencies handles GET /v1/currencies
+func (h *AccountHandler) ListCurrencies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, h.accountService.ListCurrencies())
+}
+
+// accountETag derives the ETag of an account from its last update time
+func accountETag(account *model.GetAccountResponse) string {
+	return fmt.Sprintf(`"%s-%d"`, account.ID.String(), account.UpdatedAt.Unix())
+}
+
+// ReconcileAccount handles POST /v1/accounts/{id}/reconcile
+func (h *AccountHandler) ReconcileAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract account ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	path = strings.TrimSuffix(path, "/reconcile")
+
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
+		return
+	}
+
+	fix := false
+	if fixParam := r.URL.Query().Get("fix"); fixParam != "" {
+		var err error
+		if fix, err = strconv.ParseBool(fixParam); err != nil {
+			WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid fix parameter", model.ErrCodeInvalidInput)
+			return
+		}
+	}
+
+	response, err := h.accountService.ReconcileAccount(r.Context(), accountID, fix)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// SetStatus handles POST /v1/accounts/{id}/status
+func (h *AccountHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
+
+	// Extract account ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	path = strings.TrimSuffix(path, "/status")
+
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
+		return
+	}
+
+	var req model.SetAccountStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	response, err := h.accountService.SetStatus(r.Context(), accountID, &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
 }
 
 // handleServiceError converts service errors to HTTP responses
-func handleServiceError(w http.ResponseWriter, err error) {
-	if serviceErr, ok := err.(*service.ServiceError); ok {
+func handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if serviceErr := service.AsServiceError(err); serviceErr != nil {
 		switch serviceErr.Code {
 		case model.ErrCodeNotFound:
-			writeErrorResponse(w, http.StatusNotFound, serviceErr.Message, serviceErr.Code)
-		case model.ErrCodeValidation, model.ErrCodeInvalidInput:
-			writeErrorResponse(w, http.StatusBadRequest, serviceErr.Message, serviceErr.Code)
-		case model.ErrCodeInsufficientFunds:
-			writeErrorResponse(w, http.StatusUnprocessableEntity, serviceErr.Message, serviceErr.Code)
-		case model.ErrCodeConflict:
-			writeErrorResponse(w, http.StatusConflict, serviceErr.Message, serviceErr.Code)
+			WriteErrorResponse(w, r, http.StatusNotFound, serviceErr.Message, serviceErr.Code)
+		case model.ErrCodeForbidden, model.ErrCodeDepositsDisabled:
+			WriteErrorResponse(w, r, http.StatusForbidden, serviceErr.Message, serviceErr.Code)
+		case model.ErrCodeValidation, model.ErrCodeInvalidInput, model.ErrCodeInvalidIdempotencyKey:
+			writeError(w, r, http.StatusBadRequest, model.ErrorResponse{Error: serviceErr.Message, Code: serviceErr.Code, Field: serviceErr.Field})
+		case model.ErrCodeInsufficientFunds, model.ErrCodeAmountOutOfRange,
+			model.ErrCodeAccountClosed, model.ErrCodeAccountFrozen, model.ErrCodeCurrencyMismatch,
+			model.ErrCodeBelowMinBalance, model.ErrCodeAboveMaxBalance, model.ErrCodeReversalExceeded:
+			WriteErrorResponse(w, r, http.StatusUnprocessableEntity, serviceErr.Message, serviceErr.Code)
+		case model.ErrCodePreconditionFailed, model.ErrCodeInvalidTransition:
+			WriteErrorResponse(w, r, http.StatusConflict, serviceErr.Message, serviceErr.Code)
+		case model.ErrCodeConflict, model.ErrCodePossibleDuplicate:
+			writeError(w, r, http.StatusConflict, model.ErrorResponse{Error: serviceErr.Message, Code: serviceErr.Code, TransactionID: serviceErr.TransactionID})
+		case model.ErrCodeVersionMismatch:
+			WriteErrorResponse(w, r, http.StatusPreconditionFailed, serviceErr.Message, serviceErr.Code)
+		case model.ErrCodeVelocityExceeded:
+			if serviceErr.ResetAt != nil {
+				wait := math.Max(1, math.Ceil(time.Until(*serviceErr.ResetAt).Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait)))
+			}
+			writeError(w, r, http.StatusTooManyRequests, model.ErrorResponse{Error: serviceErr.Message, Code: serviceErr.Code, ResetAt: serviceErr.ResetAt})
+		case model.ErrCodeNotImplemented:
+			WriteErrorResponse(w, r, http.StatusNotImplemented, serviceErr.Message, serviceErr.Code)
+		case model.ErrCodeServiceUnavailable, model.ErrCodeTryAgain, model.ErrCodeServiceBusy:
+			w.Header().Set("Retry-After", "1")
+			WriteErrorResponse(w, r, http.StatusServiceUnavailable, serviceErr.Message, serviceErr.Code)
 		default:
-			writeErrorResponse(w, http.StatusInternalServerError, "Internal server error", model.ErrCodeInternalError)
+			writeInternalError(w, r, err.Error())
 		}
 		return
 	}
 
 	// Unknown error
-	writeErrorResponse(w, http.StatusInternalServerError, "Internal server error", model.ErrCodeInternalError)
+	writeInternalError(w, r, err.Error())
 }
 
 // parseQueryParams extracts and validates query parameters
@@ -181,4 +394,4 @@ func parseQueryParams(values url.Values) (limit, offset int, err error) {
 	}
 
 	return limit, offset, nil
-} 
\ No newline at end of file
+}
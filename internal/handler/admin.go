@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"internal-transfers-api/internal/model"
+)
+
+// RequireAdmin only lets requests through to next that present the admin bearer token.
+// With no token configured the admin endpoints are disabled.
+func RequireAdmin(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			WriteErrorResponse(w, r, http.StatusForbidden, "Admin endpoints are disabled", model.ErrCodeForbidden)
+			return
+		}
+
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			WriteErrorResponse(w, r, http.StatusUnauthorized, "Admin credentials required", model.ErrCodeUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/service"
+)
+
+// Authenticate requires every /v1 request to present an API key or the admin token as a
+// bearer token, and attaches the caller's identity for the services to authorize
+// against. With no API keys configured requests pass through unauthenticated, except
+// that one presenting the admin token is still known as the admin.
+func Authenticate(apiKeys map[string]string, adminToken string, next http.Handler) http.Handler {
+	if len(apiKeys) == 0 {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if identity := identify(nil, adminToken, presented); identity != nil {
+				r = r.WithContext(service.WithIdentity(r.Context(), identity))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Health and readiness probes stay open
+		if !strings.HasPrefix(r.URL.Path, "/v1/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		presented, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		identity := identify(apiKeys, adminToken, presented)
+		if identity == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			WriteErrorResponse(w, r, http.StatusUnauthorized, "API key required", model.ErrCodeUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(service.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// identify returns the identity a bearer token belongs to, or nil for an unknown token
+func identify(apiKeys map[string]string, adminToken, presented string) *service.Identity {
+	if presented == "" {
+		return nil
+	}
+	if adminToken != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(adminToken)) == 1 {
+		return &service.Identity{Admin: true}
+	}
+
+	// Compare against every key so the time taken doesn't reveal which one matched
+	var identity *service.Identity
+	for key, owner := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+			identity = &service.Identity{OwnerID: owner}
+		}
+	}
+	return identity
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/service"
+)
+
+// BatchHandler handles asynchronous batch transfer HTTP requests
+type BatchHandler struct {
+	batchService *service.BatchService
+}
+
+// NewBatchHandler creates a new batch handler
+func NewBatchHandler(batchService *service.BatchService) *BatchHandler {
+	return &BatchHandler{
+		batchService: batchService,
+	}
+}
+
+// CreateBatch handles POST /v1/transactions/batch
+func (h *BatchHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var req model.BulkTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err, "Invalid batch request")
+		return
+	}
+
+	response, err := h.batchService.SubmitBatch(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/transactions/batch/"+response.BatchID.String())
+	writeJSON(w, r, http.StatusAccepted, response)
+}
+
+// GetBatch handles GET /v1/transactions/batch/{id}
+func (h *BatchHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Extract batch ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/batch/")
+	batchID, ok := parseUUIDParam(w, r, "batch_id", path)
+	if !ok {
+		return
+	}
+
+	response, err := h.batchService.GetBatch(r.Context(), batchID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
\ No newline at end of file
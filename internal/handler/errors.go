@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+)
+
+// RequestIDHeader carries the ID that correlates a request with its logs and error responses
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a client-supplied request ID
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware assigns every request an ID, reusing a well-formed one sent by the
+// client, and returns it in the X-Request-ID response header
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength || !model.TextCharsetASCII.Allows(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IdempotencyKeyHeader carries the client's idempotency key on mutating requests
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKeyMiddleware rejects requests whose Idempotency-Key header does not have
+// the configured format with 400 INVALID_IDEMPOTENCY_KEY
+func IdempotencyKeyMiddleware(format model.IdempotencyKeyFormat, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if keys := r.Header.Values(IdempotencyKeyHeader); len(keys) > 0 {
+			if err := format.Check(keys[0]); err != nil {
+				WriteErrorResponse(w, r, http.StatusBadRequest, err.Error(), model.ErrCodeInvalidIdempotencyKey)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireIdempotencyKey only lets POST requests through to next that carry an
+// Idempotency-Key header, so every transfer a client makes is one it can safely retry
+func RequireIdempotencyKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.Header.Get(IdempotencyKeyHeader) == "" {
+			writeError(w, r, http.StatusBadRequest, model.ErrorResponse{
+				Error: IdempotencyKeyHeader + " header is required",
+				Code:  model.ErrCodeIdempotencyKeyRequired,
+				Field: IdempotencyKeyHeader,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verboseErrorsKey marks a request whose 500 responses may carry the underlying error
+type verboseErrorsKey struct{}
+
+// ErrorDetailMiddleware turns a panic in next into a 500 response and sets how much of an
+// internal error the response reveals. Safe responses carry only the generic message and
+// the request ID, under which the detail, and a panic's stack, are logged; verbose ones add
+// the underlying error as detail, which can leak internals and is meant for development.
+func ErrorDetailMiddleware(verbose bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if verbose {
+			r = r.WithContext(context.WithValue(r.Context(), verboseErrorsKey{}, true))
+		}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			log.Printf("ERROR panic stack request_id=%s:\n%s", w.Header().Get(RequestIDHeader), debug.Stack())
+			writeInternalError(w, r, fmt.Sprintf("panic: %v", recovered))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeInternalError writes a 500 response for an unexpected error, logging its detail
+// and including it in the response only when the request allows verbose errors
+func writeInternalError(w http.ResponseWriter, r *http.Request, detail string) {
+	log.Printf("ERROR %s %s request_id=%s: %s", r.Method, r.URL.Path, w.Header().Get(RequestIDHeader), detail)
+
+	response := model.ErrorResponse{Error: "Internal server error", Code: model.ErrCodeInternalError}
+	if verbose, _ := r.Context().Value(verboseErrorsKey{}).(bool); verbose {
+		response.Detail = detail
+	}
+	writeError(w, r, http.StatusInternalServerError, response)
+}
+
+// WriteErrorResponse writes the standard JSON error envelope, including the request ID
+// assigned by RequestIDMiddleware
+func WriteErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, message, code string) {
+	writeError(w, r, statusCode, model.ErrorResponse{Error: message, Code: code})
+}
+
+// writeDecodeError writes a 400 for a request body that didn't decode, naming the field
+// at fault when the model reported one and falling back to message otherwise
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		writeError(w, r, http.StatusBadRequest, model.ErrorResponse{Error: validationErr.Message, Code: model.ErrCodeInvalidInput, Field: validationErr.Field})
+		return
+	}
+	WriteErrorResponse(w, r, http.StatusBadRequest, message, model.ErrCodeInvalidInput)
+}
+
+// WriteMethodNotAllowed writes a 405 error with the Allow header HTTP requires, listing
+// the methods the resource supports
+func WriteMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	WriteErrorResponse(w, r, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+}
+
+// WriteNotFound writes a 404 for a path that matches no route, worded apart from the 404
+// of a resource that doesn't exist so clients can tell a wrong URL from a missing record
+func WriteNotFound(w http.ResponseWriter, r *http.Request) {
+	WriteErrorResponse(w, r, http.StatusNotFound, "No route matches the requested path", model.ErrCodeNotFound)
+}
+
+// writeError writes an error envelope that may carry more than a message and code,
+// indented like writeJSON's responses when PrettyJSONMiddleware marked the request
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, response model.ErrorResponse) {
+	response.RequestID = w.Header().Get(RequestIDHeader)
+	var body []byte
+	var err error
+	if prettyJSON(r) {
+		body, err = json.MarshalIndent(response, "", "  ")
+	} else {
+		body, err = json.Marshal(response)
+	}
+	if err != nil {
+		log.Printf("failed to encode error response: %v", err)
+		body = []byte(`{"error":"Internal server error","code":"` + model.ErrCodeInternalError + `"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(append(body, '\n'))
+}
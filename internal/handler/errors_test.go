@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/model"
+)
+
+func TestErrorDetailMiddleware(t *testing.T) {
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleServiceError(w, r, errors.New("pq: relation \"accounts\" does not exist"))
+	})
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map")
+	})
+
+	tests := []struct {
+		name           string
+		verbose        bool
+		handler        http.Handler
+		expectedDetail string
+	}{
+		{name: "safe error", handler: failing},
+		{name: "safe panic", handler: panicking},
+		{name: "verbose error", verbose: true, handler: failing, expectedDetail: `pq: relation "accounts" does not exist`},
+		{name: "verbose panic", verbose: true, handler: panicking, expectedDetail: "panic: nil map"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set(RequestIDHeader, "req-1")
+			ErrorDetailMiddleware(tt.verbose, tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts", nil))
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			var response model.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "Internal server error", response.Error)
+			assert.Equal(t, model.ErrCodeInternalError, response.Code)
+			assert.Equal(t, "req-1", response.RequestID)
+			assert.Equal(t, tt.expectedDetail, response.Detail)
+		})
+	}
+}
+
+func TestRequireIdempotencyKey(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name         string
+		method       string
+		key          string
+		expectedCode int
+	}{
+		{name: "post with key", method: http.MethodPost, key: "transfer-1", expectedCode: http.StatusCreated},
+		{name: "post without key", method: http.MethodPost, expectedCode: http.StatusBadRequest},
+		{name: "other methods pass", method: http.MethodGet, expectedCode: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/v1/transactions", nil)
+			if tt.key != "" {
+				r.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			RequireIdempotencyKey(ok).ServeHTTP(w, r)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusBadRequest {
+				var response model.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, model.ErrCodeIdempotencyKeyRequired, response.Code)
+				assert.Equal(t, IdempotencyKeyHeader, response.Field)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"internal-transfers-api/internal/model"
+)
+
+// selectFields trims a response to the top-level fields named in the request's
+// ?fields= parameter, e.g. ?fields=id,balance. Without the parameter v is returned as
+// is. Names that aren't fields of v are ignored and listed in a Warning header, which
+// is why it must be called before the status is written; if none of the names are
+// fields, the whole response is returned.
+func selectFields(w http.ResponseWriter, r *http.Request, v interface{}) interface{} {
+	selected := requestedFields(w, r, reflect.TypeOf(v))
+	if selected == nil {
+		return v
+	}
+	filtered, err := filterFields(v, selected)
+	if err != nil {
+		return v
+	}
+	return filtered
+}
+
+// selectItemFields is selectFields for a list, trimming each of its items
+func selectItemFields(w http.ResponseWriter, r *http.Request, items interface{}) interface{} {
+	selected := requestedFields(w, r, reflect.TypeOf(items).Elem())
+	if selected == nil {
+		return items
+	}
+
+	list := reflect.ValueOf(items)
+	filtered := make([]map[string]json.RawMessage, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		item, err := filterFields(list.Index(i).Interface(), selected)
+		if err != nil {
+			return items
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+// selectPageFields is selectItemFields for a page of a list, trimming each of its items
+// and keeping the page's envelope
+func selectPageFields[T any](w http.ResponseWriter, r *http.Request, page *model.Page[T]) interface{} {
+	items, ok := selectItemFields(w, r, page.Data).([]map[string]json.RawMessage)
+	if !ok {
+		return page
+	}
+	return &model.Page[map[string]json.RawMessage]{
+		Data:    items,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		Total:   page.Total,
+		HasMore: page.HasMore,
+	}
+}
+
+// requestedFields returns the fields of t named in ?fields=, warning about the others,
+// or nil when all fields should be returned
+func requestedFields(w http.ResponseWriter, r *http.Request, t reflect.Type) map[string]bool {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil
+	}
+
+	known := jsonFieldNames(t)
+	selected := make(map[string]bool)
+	var unknown []string
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case known[name]:
+			selected[name] = true
+		default:
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		w.Header().Set("Warning", fmt.Sprintf("299 - %q", "Unknown fields ignored: "+echoed(strings.Join(unknown, ","))))
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// filterFields marshals v and keeps only the selected fields. Selected fields left out
+// by omitempty stay left out.
+func filterFields(v interface{}, selected map[string]bool) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if !selected[name] {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// jsonFieldNames lists the JSON names of a struct's fields, following pointers and
+// embedded structs as encoding/json does
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embedded := range jsonFieldNames(field.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/model"
+)
+
+func TestSelectFields(t *testing.T) {
+	account := &model.GetAccountResponse{
+		ID:       uuid.New(),
+		Balance:  decimal.RequireFromString("74.5"),
+		Currency: "USD",
+	}
+
+	encode := func(v interface{}) map[string]interface{} {
+		t.Helper()
+		encoded, err := json.Marshal(v)
+		require.NoError(t, err)
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(encoded, &fields))
+		return fields
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string // fields in the response; nil for the whole response
+		warning  string
+	}{
+		{name: "no parameter", query: ""},
+		{name: "selected", query: "?fields=id,balance", expected: []string{"id", "balance"}},
+		{name: "spaces and empty names", query: "?fields=+id,,balance+", expected: []string{"id", "balance"}},
+		{name: "unknown ignored", query: "?fields=id,nope,Balance", expected: []string{"id"}, warning: `299 - "Unknown fields ignored: Balance,nope"`},
+		{name: "only unknown", query: "?fields=nope", warning: `299 - "Unknown fields ignored: nope"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body := encode(selectFields(w, httptest.NewRequest("GET", "/v1/accounts/x"+tt.query, nil), account))
+
+			assert.Equal(t, tt.warning, w.Header().Get("Warning"))
+			if tt.expected == nil {
+				assert.Equal(t, encode(account), body)
+				return
+			}
+			assert.Len(t, body, len(tt.expected))
+			for _, name := range tt.expected {
+				assert.Contains(t, body, name)
+			}
+		})
+	}
+}
+
+func TestSelectItemFields(t *testing.T) {
+	transactions := []*model.Transaction{
+		{ID: uuid.New(), Amount: decimal.RequireFromString("10"), Status: model.TransactionStatusCompleted},
+		{ID: uuid.New(), Amount: decimal.RequireFromString("20"), Status: model.TransactionStatusPending},
+	}
+
+	w := httptest.NewRecorder()
+	selected := selectItemFields(w, httptest.NewRequest("GET", "/v1/accounts/x/transactions?fields=id,status", nil), transactions)
+
+	encoded, err := json.Marshal(selected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id":"`+transactions[0].ID.String()+`","status":"completed"},
+		{"id":"`+transactions[1].ID.String()+`","status":"pending"}
+	]`, string(encoded))
+	assert.Empty(t, w.Header().Get("Warning"))
+}
+
+func TestSelectPageFields(t *testing.T) {
+	id := uuid.New()
+	page := model.NewPage([]*model.Transaction{
+		{ID: id, Amount: decimal.RequireFromString("10"), Status: model.TransactionStatusCompleted},
+	}, 1, 0, 3)
+
+	w := httptest.NewRecorder()
+	selected := selectPageFields(w, httptest.NewRequest("GET", "/v1/accounts/x/transactions?fields=id", nil), page)
+
+	encoded, err := json.Marshal(selected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":[{"id":"`+id.String()+`"}],"limit":1,"offset":0,"total":3,"has_more":true}`, string(encoded))
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+)
+
+// PreviewConversion handles GET /v1/fx/preview?from=USD&to=EUR&amount=100
+func (h *TransactionHandler) PreviewConversion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" || to == "" {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "from and to parameters are required", model.ErrCodeInvalidInput)
+		return
+	}
+	amount, err := decimal.NewFromString(query.Get("amount"))
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid amount parameter", model.ErrCodeInvalidInput)
+		return
+	}
+
+	response, err := h.transactionService.PreviewConversion(r.Context(), from, to, amount)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
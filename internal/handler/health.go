@@ -3,53 +3,279 @@ package handler
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"internal-transfers-api/internal/config"
 	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
 )
 
 type HealthHandler struct {
 	db      *sql.DB
 	version string
+	cfg     config.HealthConfig
+	client  *http.Client
+	limiter *ConcurrencyLimiter // whose in-flight counts the health payload reports
+	dbReady atomic.Bool         // set once startup against the database has completed
+
+	conns  sync.Map // net.Conn -> last http.ConnState
+	open   atomic.Int64
+	active atomic.Int64
+
+	dbCheckMu sync.Mutex
+	dbCheck   *databaseCheck // latest database check, nil when there is none to reuse
 }
 
-func NewHealthHandler(db *sql.DB, version string) *HealthHandler {
+// databaseCheck is one database check, shared by the probes that arrive while it runs
+// and, if it found the database healthy, by those within HEALTH_CACHE_TTL after
+type databaseCheck struct {
+	done   chan struct{} // closed once health is set
+	health model.DatabaseHealth
+	at     time.Time
+}
+
+func NewHealthHandler(db *sql.DB, version string, cfg config.HealthConfig, limiter *ConcurrencyLimiter) *HealthHandler {
 	return &HealthHandler{
 		db:      db,
 		version: version,
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.ProbeTimeout},
+		limiter: limiter,
 	}
 }
 
 func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+		WriteMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	response := model.HealthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now().UTC(),
-		Version:   h.version,
-		Database:  h.checkDatabase(),
+		Status:      "healthy",
+		Timestamp:   time.Now().UTC(),
+		Version:     h.version,
+		Database:    h.cachedDatabaseCheck(),
+		Connections: h.connectionStats(),
+		InFlight:    h.limiter.Stats(),
+		Pool:        h.poolStats(),
 	}
 
 	// If database is unhealthy, mark overall status as unhealthy
+	statusCode := http.StatusOK
 	if response.Database.Status != "healthy" {
 		response.Status = "unhealthy"
-		w.WriteHeader(http.StatusServiceUnavailable)
-	} else {
-		w.WriteHeader(http.StatusOK)
+		statusCode = http.StatusServiceUnavailable
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
+	writeJSON(w, r, statusCode, response)
+}
+
+// SetDatabaseReady marks whether the service has finished starting up against the
+// database, or has lost it and is reconnecting. While not ready the database, and so
+// the service, reports unhealthy.
+func (h *HealthHandler) SetDatabaseReady(ready bool) {
+	h.dbReady.Store(ready)
+
+	h.dbCheckMu.Lock()
+	h.dbCheck = nil
+	h.dbCheckMu.Unlock()
+}
+
+// DatabaseReady reports whether SetDatabaseReady(true) has been called
+func (h *HealthHandler) DatabaseReady() bool {
+	return h.dbReady.Load()
+}
+
+// ConnState is used as http.Server.ConnState to count open and active connections.
+// Connections taken over by h2c leave the count once hijacked.
+func (h *HealthHandler) ConnState(conn net.Conn, state http.ConnState) {
+	var previous http.ConnState = -1
+	if last, ok := h.conns.Load(conn); ok {
+		previous = last.(http.ConnState)
+	}
+	if previous == http.StateActive {
+		h.active.Add(-1)
+	}
+
+	switch state {
+	case http.StateNew:
+		h.open.Add(1)
+	case http.StateActive:
+		h.active.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		h.open.Add(-1)
+		h.conns.Delete(conn)
 		return
 	}
+	h.conns.Store(conn, state)
+}
+
+// connectionStats reports the connections counted by ConnState
+func (h *HealthHandler) connectionStats() model.ConnectionStats {
+	return model.ConnectionStats{
+		Open:   h.open.Load(),
+		Active: h.active.Load(),
+	}
+}
+
+// poolStats reports the database connection pool, or nil without a database. It is
+// read on every request rather than cached with the database check, since waits for
+// connections are what to look at when requests fail SERVICE_BUSY.
+func (h *HealthHandler) poolStats() *model.PoolStats {
+	if h.db == nil {
+		return nil
+	}
+	stats := h.db.Stats()
+	return &model.PoolStats{
+		Open:            stats.OpenConnections,
+		InUse:           stats.InUse,
+		Idle:            stats.Idle,
+		MaxOpen:         stats.MaxOpenConnections,
+		WaitCount:       stats.WaitCount,
+		WaitDurationMs:  stats.WaitDuration.Milliseconds(),
+		AcquireTimeouts: repository.AcquireTimeouts(),
+	}
+}
+
+// Live handles GET /livez: the process is up and serving, whatever the state of its dependencies
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	response := model.LivenessResponse{
+		Status:    "alive",
+		Timestamp: time.Now().UTC(),
+		Version:   h.version,
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// Ready handles GET /readyz: the database check plus downstream dependency probes.
+// An unreachable critical dependency fails readiness, others only degrade it.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	response := model.HealthResponse{
+		Status:      "healthy",
+		Timestamp:   time.Now().UTC(),
+		Version:     h.version,
+		Database:    h.cachedDatabaseCheck(),
+		Connections: h.connectionStats(),
+		InFlight:    h.limiter.Stats(),
+		Pool:        h.poolStats(),
+	}
+
+	dependencies, criticalDown, degraded := h.probeDependencies(r.Context())
+	if len(dependencies) > 0 {
+		response.Dependencies = dependencies
+	}
+
+	statusCode := http.StatusOK
+	switch {
+	case response.Database.Status != "healthy" || criticalDown:
+		response.Status = "unhealthy"
+		statusCode = http.StatusServiceUnavailable
+	case degraded:
+		response.Status = "degraded"
+	}
+
+	writeJSON(w, r, statusCode, response)
+}
+
+// probeDependencies checks every configured dependency concurrently and reports
+// per-dependency status and whether any critical or non-critical one is down
+func (h *HealthHandler) probeDependencies(ctx context.Context) (statuses map[string]string, criticalDown, degraded bool) {
+	statuses = make(map[string]string, len(h.cfg.Dependencies))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, dep := range h.cfg.Dependencies {
+		wg.Add(1)
+		go func(dep config.DependencyConfig) {
+			defer wg.Done()
+
+			status := "healthy"
+			if err := h.probe(ctx, dep.URL); err != nil {
+				status = "unhealthy"
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[dep.Name] = status
+			if status != "healthy" {
+				if dep.Critical {
+					criticalDown = true
+				} else {
+					degraded = true
+				}
+			}
+		}(dep)
+	}
+	wg.Wait()
+
+	return statuses, criticalDown, degraded
+}
+
+// probe performs a GET against a dependency and treats any non-2xx response as a failure
+func (h *HealthHandler) probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// cachedDatabaseCheck returns the result of the database check in flight, or of the
+// last one if it was healthy and is younger than the cache TTL, and otherwise checks
+// again. Unhealthy results are never reused, so a recovery shows on the next probe.
+func (h *HealthHandler) cachedDatabaseCheck() model.DatabaseHealth {
+	if h.cfg.CacheTTL <= 0 {
+		return h.checkDatabase()
+	}
+
+	h.dbCheckMu.Lock()
+	if check := h.dbCheck; check != nil {
+		select {
+		case <-check.done:
+			if check.health.Status == "healthy" && time.Since(check.at) < h.cfg.CacheTTL {
+				h.dbCheckMu.Unlock()
+				return check.health
+			}
+		default:
+			h.dbCheckMu.Unlock()
+			<-check.done
+			return check.health
+		}
+	}
+	check := &databaseCheck{done: make(chan struct{})}
+	h.dbCheck = check
+	h.dbCheckMu.Unlock()
+
+	check.health = h.checkDatabase()
+	check.at = time.Now()
+	close(check.done)
+	return check.health
 }
 
 func (h *HealthHandler) checkDatabase() model.DatabaseHealth {
@@ -57,7 +283,7 @@ func (h *HealthHandler) checkDatabase() model.DatabaseHealth {
 		Status: "unhealthy",
 	}
 
-	if h.db == nil {
+	if h.db == nil || !h.dbReady.Load() {
 		return dbHealth
 	}
 
@@ -84,19 +310,3 @@ func (h *HealthHandler) checkDatabase() model.DatabaseHealth {
 	dbHealth.Status = "healthy"
 	return dbHealth
 }
-
-func writeErrorResponse(w http.ResponseWriter, statusCode int, message, code string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	response := model.ErrorResponse{
-		Error: message,
-		Code:  code,
-	}
-	
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error since we can't return it at this point
-		// In production, you might want to log this error properly
-		return
-	}
-} 
\ No newline at end of file
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/service"
+)
+
+// IdempotencyHandler handles idempotency key HTTP requests
+type IdempotencyHandler struct {
+	idempotencyService *service.IdempotencyService
+}
+
+// NewIdempotencyHandler creates a new idempotency handler
+func NewIdempotencyHandler(idempotencyService *service.IdempotencyService) *IdempotencyHandler {
+	return &IdempotencyHandler{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// GetKeyStatus handles GET /v1/idempotency/{key}?include_body=true
+func (h *IdempotencyHandler) GetKeyStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Keys are opaque client strings, so take the escaped path to keep any encoded slashes
+	key, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/v1/idempotency/"))
+	if err != nil || key == "" {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Idempotency key is required", model.ErrCodeInvalidInput)
+		return
+	}
+
+	includeBody := false
+	if raw := r.URL.Query().Get("include_body"); raw != "" {
+		if includeBody, err = strconv.ParseBool(raw); err != nil {
+			WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid include_body parameter", model.ErrCodeInvalidInput)
+			return
+		}
+	}
+
+	response, err := h.idempotencyService.GetKeyStatus(r.Context(), key, includeBody)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
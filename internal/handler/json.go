@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// prettyJSONKey marks a request whose JSON response is indented for reading
+type prettyJSONKey struct{}
+
+// PrettyJSONMiddleware lets a request ask for an indented JSON response with ?pretty=true,
+// when enabled is set. It is meant for debugging by hand, so it is off by default, and
+// otherwise the parameter is ignored.
+func PrettyJSONMiddleware(enabled bool, next http.Handler) http.Handler {
+	if !enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty")); err == nil && pretty {
+			r = r.WithContext(context.WithValue(r.Context(), prettyJSONKey{}, true))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as the JSON body of a response with the given status, indented when
+// PrettyJSONMiddleware marked the request
+func writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	encoder := json.NewEncoder(w)
+	if prettyJSON(r) {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(v); err != nil {
+		// The status is already sent, so the failure can only be logged
+		log.Printf("failed to encode response: %v", err)
+	}
+}
+
+// prettyJSON reports whether PrettyJSONMiddleware marked the request for an indented response
+func prettyJSON(r *http.Request) bool {
+	pretty, _ := r.Context().Value(prettyJSONKey{}).(bool)
+	return pretty
+}
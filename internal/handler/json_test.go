@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyJSONMiddleware(t *testing.T) {
+	respond := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	tests := []struct {
+		name         string
+		enabled      bool
+		query        string
+		expectedBody string
+	}{
+		{name: "disabled ignores the parameter", query: "?pretty=true", expectedBody: "{\"status\":\"ok\"}\n"},
+		{name: "enabled without the parameter", enabled: true, expectedBody: "{\"status\":\"ok\"}\n"},
+		{name: "enabled with pretty=false", enabled: true, query: "?pretty=false", expectedBody: "{\"status\":\"ok\"}\n"},
+		{name: "enabled with a malformed value", enabled: true, query: "?pretty=please", expectedBody: "{\"status\":\"ok\"}\n"},
+		{name: "enabled with pretty=true", enabled: true, query: "?pretty=true", expectedBody: "{\n  \"status\": \"ok\"\n}\n"},
+		{name: "enabled with pretty=1", enabled: true, query: "?pretty=1", expectedBody: "{\n  \"status\": \"ok\"\n}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			PrettyJSONMiddleware(tt.enabled, respond).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/currencies"+tt.query, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestPrettyJSONMiddleware_Error(t *testing.T) {
+	respond := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteNotFound(w, r)
+	})
+
+	w := httptest.NewRecorder()
+	PrettyJSONMiddleware(true, respond).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/nowhere?pretty=true", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\n  \"error\": \"No route matches the requested path\",\n  \"code\": \"NOT_FOUND\"\n}\n", w.Body.String())
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"internal-transfers-api/internal/model"
+)
+
+// ConcurrencyLimiter caps how many API requests are served at once, with separate limits
+// for mutating and read requests, so that a load spike is turned away with 503 OVERLOADED
+// instead of piling up goroutines waiting on database connections
+type ConcurrencyLimiter struct {
+	writes requestLimit
+	reads  requestLimit
+}
+
+// requestLimit is a semaphore of max slots that also counts the requests holding one
+type requestLimit struct {
+	max      int
+	slots    chan struct{} // nil when unlimited
+	inFlight atomic.Int64
+}
+
+// NewConcurrencyLimiter creates a limiter allowing maxWrites mutating and maxReads other
+// requests in flight; zero leaves that kind unlimited
+func NewConcurrencyLimiter(maxWrites, maxReads int) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{}
+	l.writes.init(maxWrites)
+	l.reads.init(maxReads)
+	return l
+}
+
+func (l *requestLimit) init(max int) {
+	l.max = max
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+}
+
+// acquire takes a slot without waiting, reporting whether one was free
+func (l *requestLimit) acquire() bool {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	l.inFlight.Add(1)
+	return true
+}
+
+func (l *requestLimit) release() {
+	l.inFlight.Add(-1)
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// Middleware rejects requests arriving while their kind is at its limit. Health probes
+// and the event stream, whose connections stay open without holding database
+// connections, aren't limited.
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/livez", "/healthz", "/readyz", "/v1/transactions/stream":
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit := &l.reads
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			limit = &l.writes
+		}
+		if !limit.acquire() {
+			w.Header().Set("Retry-After", "1")
+			WriteErrorResponse(w, r, http.StatusServiceUnavailable, "Too many requests in flight, retry shortly", model.ErrCodeOverloaded)
+			return
+		}
+		defer limit.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stats reports the requests in flight and the limits they count against
+func (l *ConcurrencyLimiter) Stats() model.InFlightStats {
+	if l == nil {
+		return model.InFlightStats{}
+	}
+	return model.InFlightStats{
+		Writes:    l.writes.inFlight.Load(),
+		Reads:     l.reads.inFlight.Load(),
+		MaxWrites: l.writes.max,
+		MaxReads:  l.reads.max,
+	}
+}
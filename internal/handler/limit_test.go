@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/model"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1, 2)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	blocking := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	ok := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(h http.Handler, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// Hold the only write slot
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(blocking, http.MethodPost, "/v1/transactions")
+	}()
+	<-entered
+	assert.Equal(t, model.InFlightStats{Writes: 1, MaxWrites: 1, MaxReads: 2}, limiter.Stats())
+
+	w := serve(ok, http.MethodPost, "/v1/transactions")
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), model.ErrCodeOverloaded)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(ok, http.MethodDelete, "/v1/accounts/1").Code)
+
+	// Reads have their own limit, and probes and the event stream aren't limited
+	assert.Equal(t, http.StatusOK, serve(ok, http.MethodGet, "/v1/transactions/1").Code)
+	assert.Equal(t, http.StatusOK, serve(ok, http.MethodPost, "/healthz").Code)
+	assert.Equal(t, http.StatusOK, serve(ok, http.MethodPost, "/v1/transactions/stream").Code)
+
+	close(release)
+	<-done
+	assert.Equal(t, int64(0), limiter.Stats().Writes)
+	assert.Equal(t, http.StatusOK, serve(ok, http.MethodPost, "/v1/transactions").Code)
+
+	// Zero is unlimited
+	unlimited := NewConcurrencyLimiter(0, 0)
+	assert.Equal(t, http.StatusOK, serve(unlimited.Middleware(ok), http.MethodPost, "/v1/transactions").Code)
+}
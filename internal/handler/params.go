@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+)
+
+// maxEchoedParamLength bounds how much of a malformed parameter an error echoes back
+const maxEchoedParamLength = 40
+
+// parseUUIDParam parses the UUID given as the parameter name. On failure it writes a
+// 400 INVALID_INPUT naming the parameter and the offending value, and returns false.
+func parseUUIDParam(w http.ResponseWriter, r *http.Request, name, value string) (uuid.UUID, bool) {
+	if value == "" {
+		writeError(w, r, http.StatusBadRequest, model.ErrorResponse{
+			Error: name + " is required",
+			Code:  model.ErrCodeInvalidInput,
+			Field: name,
+		})
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, model.ErrorResponse{
+			Error: fmt.Sprintf("%s %q is not a valid UUID: %s", name, echoed(value), err),
+			Code:  model.ErrCodeInvalidInput,
+			Field: name,
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parseVersionParam parses the account version given as the parameter name, quoted as an
+// entity tag or bare. On failure it writes a 400 INVALID_INPUT naming the parameter and
+// the offending value, and returns false.
+func parseVersionParam(w http.ResponseWriter, r *http.Request, name, value string) (int64, bool) {
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(value), `"`), 10, 64)
+	if err != nil || version < 1 {
+		writeError(w, r, http.StatusBadRequest, model.ErrorResponse{
+			Error: fmt.Sprintf("%s %q is not an account version", name, echoed(value)),
+			Code:  model.ErrCodeInvalidInput,
+			Field: name,
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+// parseIncludeParam parses ?include=, the related data to return along with a transaction,
+// reporting whether it asks for notes, the only kind there is. On failure it writes a 400
+// INVALID_INPUT naming the unknown value, and returns false.
+func parseIncludeParam(w http.ResponseWriter, r *http.Request, value string) (notes, ok bool) {
+	for _, name := range strings.Split(value, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "notes":
+			notes = true
+		default:
+			writeError(w, r, http.StatusBadRequest, model.ErrorResponse{
+				Error: fmt.Sprintf("include %q is not one of: notes", echoed(name)),
+				Code:  model.ErrCodeInvalidInput,
+				Field: "include",
+			})
+			return false, false
+		}
+	}
+	return notes, true
+}
+
+// echoed cuts a malformed parameter to the length an error may echo back
+func echoed(value string) string {
+	if len(value) > maxEchoedParamLength {
+		return value[:maxEchoedParamLength] + "..."
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/model"
+)
+
+func TestParseUUIDParam(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expectedError string
+	}{
+		{name: "empty", value: "", expectedError: `account_id is required`},
+		{name: "non-hex", value: "g63686ca-7c2d-4ce3-a0d4-d904d25637ad", expectedError: `account_id "g63686ca-7c2d-4ce3-a0d4-d904d25637ad" is not a valid UUID: invalid UUID format`},
+		{name: "too short", value: "363686ca", expectedError: `account_id "363686ca" is not a valid UUID: invalid UUID length: 8`},
+		{name: "too long", value: "363686ca-7c2d-4ce3-a0d4-d904d25637ad0", expectedError: `account_id "363686ca-7c2d-4ce3-a0d4-d904d25637ad0" is not a valid UUID: invalid UUID length: 37`},
+		{name: "long value is truncated", value: strings.Repeat("a", 100), expectedError: `account_id "` + strings.Repeat("a", maxEchoedParamLength) + `..." is not a valid UUID: invalid UUID length: 100`},
+		{name: "control characters are escaped", value: "\x1b[31m", expectedError: `account_id "\x1b[31m" is not a valid UUID: invalid UUID length: 5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set(RequestIDHeader, "req-1")
+			r := httptest.NewRequest(http.MethodGet, "/v1/accounts/x", nil)
+
+			id, ok := parseUUIDParam(w, r, "account_id", tt.value)
+			assert.False(t, ok)
+			assert.Equal(t, uuid.Nil, id)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response model.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedError, response.Error)
+			assert.Equal(t, model.ErrCodeInvalidInput, response.Code)
+			assert.Equal(t, "account_id", response.Field)
+			assert.Equal(t, "req-1", response.RequestID)
+		})
+	}
+}
+
+func TestParseUUIDParam_Valid(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/transactions/x", nil)
+	expected := uuid.New()
+
+	id, ok := parseUUIDParam(w, r, "transaction_id", expected.String())
+	assert.True(t, ok)
+	assert.Equal(t, expected, id)
+	assert.Empty(t, w.Body.String(), "nothing is written for a valid UUID")
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"internal-transfers-api/internal/model"
+)
+
+// reconciliationError is the line that ends a reconciliation report cut short by an error
+type reconciliationError struct {
+	Type string `json:"type"` // always "error"
+	model.ErrorResponse
+}
+
+// ReconciliationReport handles GET /v1/reports/reconciliation. The report is streamed
+// as newline-delimited JSON: a line for each account whose stored balance has drifted
+// from its ledger, as it is found, then a summary line with the totals per currency. A
+// failure once lines have been sent ends the report with an error line instead.
+func (h *AccountHandler) ReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// A report over every account may outlive the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		WriteErrorResponse(w, r, http.StatusInternalServerError, "Streaming not supported", model.ErrCodeInternalError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	started := false
+	writeLine := func(line interface{}) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil && err != http.ErrNotSupported {
+			return err
+		}
+		return nil
+	}
+
+	summary, err := h.accountService.ReconciliationReport(r.Context(), func(discrepancy *model.ReconciliationDiscrepancy) error {
+		return writeLine(discrepancy)
+	})
+	if err != nil {
+		if !started {
+			handleServiceError(w, r, err)
+			return
+		}
+		log.Printf("ERROR reconciliation report cut short request_id=%s: %v", w.Header().Get(RequestIDHeader), err)
+		writeLine(reconciliationError{
+			Type: "error",
+			ErrorResponse: model.ErrorResponse{
+				Error:     "Report interrupted; the lines above are incomplete",
+				Code:      model.ErrCodeInternalError,
+				RequestID: w.Header().Get(RequestIDHeader),
+			},
+		})
+		return
+	}
+
+	writeLine(summary)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/service"
+)
+
+// streamHeartbeatInterval is how often an idle stream sends a comment to detect disconnected clients
+const streamHeartbeatInterval = 15 * time.Second
+
+// TransactionStreamHandler streams completed transfers to clients as server-sent events
+type TransactionStreamHandler struct {
+	events *service.TransferEvents
+}
+
+// NewTransactionStreamHandler creates a new transaction stream handler
+func NewTransactionStreamHandler(events *service.TransferEvents) *TransactionStreamHandler {
+	return &TransactionStreamHandler{
+		events: events,
+	}
+}
+
+// ServeHTTP handles GET /v1/transactions/stream
+func (h *TransactionStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// The stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		WriteErrorResponse(w, r, http.StatusInternalServerError, "Streaming not supported", model.ErrCodeInternalError)
+		return
+	}
+
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			// Client disconnected
+			return
+		case payload, ok := <-events:
+			if !ok {
+				// Event source shut down
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: transfer\ndata: %s\n\n", payload); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
\ No newline at end of file
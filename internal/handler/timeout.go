@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"internal-transfers-api/internal/model"
+)
+
+// TimeoutMiddleware gives next a deadline of timeout. The request's context is cancelled
+// at the deadline and, if next hasn't responded by then, the client gets 503 TIMEOUT
+// instead and anything next writes afterwards is discarded. next's response is buffered,
+// so streaming handlers should not be wrapped.
+func TimeoutMiddleware(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(model.ErrorResponse{
+			Error:     "The request took longer than " + timeout.String() + " to process",
+			Code:      model.ErrCodeTimeout,
+			RequestID: w.Header().Get(RequestIDHeader),
+		})
+
+		// Only reaches the client on timeout; next's own headers replace it otherwise
+		w.Header().Set("Content-Type", "application/json")
+		http.TimeoutHandler(next, timeout, string(body)+"\n").ServeHTTP(w, r)
+	})
+}
@@ -2,11 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 
 	"internal-transfers-api/internal/model"
 	"internal-transfers-api/internal/service"
@@ -27,18 +34,14 @@ func NewTransactionHandler(transactionService *service.TransactionService) *Tran
 // CreateTransaction handles POST /v1/transactions
 func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+		WriteMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
-	// Check for idempotency key
-	idempotencyKey := r.Header.Get("Idempotency-Key")
-	_ = idempotencyKey // TODO: Implement idempotency logic
-
 	// Determine if this is a bulk transfer or single transfer
 	contentType := r.Header.Get("Content-Type")
 	if !strings.Contains(contentType, "application/json") {
-		writeErrorResponse(w, http.StatusBadRequest, "Content-Type must be application/json", model.ErrCodeInvalidInput)
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Content-Type must be application/json", model.ErrCodeInvalidInput)
 		return
 	}
 
@@ -48,7 +51,7 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 	// Peek at the request to determine format
 	var rawRequest interface{}
 	if err := decoder.Decode(&rawRequest); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
 		return
 	}
 
@@ -58,6 +61,10 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 	// Check if it's a bulk transfer request
 	if rawMap, ok := rawRequest.(map[string]interface{}); ok {
 		if _, hasBulk := rawMap["transfers"]; hasBulk {
+			if r.Header.Get("If-Match") != "" {
+				writeError(w, r, http.StatusBadRequest, model.ErrorResponse{Error: "If-Match applies to single transfers only", Code: model.ErrCodeInvalidInput, Field: "If-Match"})
+				return
+			}
 			h.handleBulkTransfer(w, r, requestBytes)
 			return
 		}
@@ -74,41 +81,63 @@ func (h *TransactionHandler) handleSingleTransfer(w http.ResponseWriter, r *http
 	var req model.CreateTransactionRequest
 	if err := json.Unmarshal(requestBytes, &req); err != nil {
 		log.Printf("DEBUG: JSON unmarshal error: %v", err)
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid transaction request", model.ErrCodeInvalidInput)
+		writeDecodeError(w, r, err, "Invalid transaction request")
 		return
 	}
 
 	log.Printf("DEBUG: Successfully parsed request: %+v", req)
+	req.ClientIP = clientIP(r)
+
+	// The Idempotency-Key header makes a retry of the transfer replay it instead
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		if req.IdempotencyKey != nil && *req.IdempotencyKey != key {
+			writeError(w, r, http.StatusBadRequest, model.ErrorResponse{Error: "idempotency_key does not match the " + IdempotencyKeyHeader + " header", Code: model.ErrCodeInvalidInput, Field: "idempotency_key"})
+			return
+		}
+		req.IdempotencyKey = &key
+	}
+
+	// If-Match makes the transfer conditional on the source account's version
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		version, ok := parseVersionParam(w, r, "If-Match", ifMatch)
+		if !ok {
+			return
+		}
+		req.ExpectedSourceVersion = &version
+	}
 
 	response, err := h.transactionService.CreateTransaction(r.Context(), &req)
 	if err != nil {
 		log.Printf("DEBUG: Transaction service error: %v", err)
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
 	log.Printf("DEBUG: Transaction successful: %+v", response)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
-		return
+	// An amount with more decimal places than its currency was rounded rather than rejected
+	if !req.Sweep && !response.Amount.Equal(req.Amount) {
+		w.Header().Set("Warning", fmt.Sprintf("299 - %q", fmt.Sprintf("amount %s rounded to %s", req.Amount, response.Amount)))
 	}
+
+	writeJSON(w, r, http.StatusCreated, response)
 }
 
 // handleBulkTransfer processes a bulk transfer request
 func (h *TransactionHandler) handleBulkTransfer(w http.ResponseWriter, r *http.Request, requestBytes []byte) {
 	var req model.BulkTransferRequest
 	if err := json.Unmarshal(requestBytes, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid bulk transfer request", model.ErrCodeInvalidInput)
+		writeDecodeError(w, r, err, "Invalid bulk transfer request")
 		return
 	}
+	ip := clientIP(r)
+	for i := range req.Transfers {
+		req.Transfers[i].ClientIP = ip
+	}
 
 	response, err := h.transactionService.ProcessBulkTransfers(r.Context(), &req)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
@@ -124,54 +153,157 @@ func (h *TransactionHandler) handleBulkTransfer(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
-		return
-	}
+	writeJSON(w, r, statusCode, response)
 }
 
 // GetTransaction handles GET /v1/transactions/{id}
 func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+		WriteMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	// Extract transaction ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
-	if path == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Transaction ID is required", model.ErrCodeInvalidInput)
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	includeNotes, ok := parseIncludeParam(w, r, r.URL.Query().Get("include"))
+	if !ok {
 		return
 	}
 
-	transactionID, err := uuid.Parse(path)
+	var transaction interface{}
+	var err error
+	if includeNotes {
+		transaction, err = h.transactionService.GetTransactionWithNotes(r.Context(), transactionID)
+	} else {
+		transaction, err = h.transactionService.GetTransaction(r.Context(), transactionID)
+	}
 	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid transaction ID format", model.ErrCodeInvalidInput)
+		handleServiceError(w, r, err)
 		return
 	}
 
-	transaction, err := h.transactionService.GetTransaction(r.Context(), transactionID)
+	body := selectFields(w, r, transaction)
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+// GetTransactions handles POST /v1/transactions/get
+func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var req model.GetTransactionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	response, err := h.transactionService.GetTransactions(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// GetTransactionStatus handles GET /v1/transactions/{id}/status. The ETag changes with the
+// status, so a poller sending it back in If-None-Match gets 304 until the transfer moves on.
+func (h *TransactionHandler) GetTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	// Extract transaction ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
+	path = strings.TrimSuffix(path, "/status")
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	status, err := h.transactionService.GetTransactionStatus(r.Context(), transactionID)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
+		return
+	}
+
+	etag := transactionStatusETag(status)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch == etag {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(transaction); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
+	writeJSON(w, r, http.StatusOK, status)
+}
+
+// transactionStatusETag derives the ETag of a transaction's status from the status and
+// when it completed
+func transactionStatusETag(status *model.TransactionStatusResponse) string {
+	var completedAt int64
+	if status.CompletedAt != nil {
+		completedAt = status.CompletedAt.UnixNano()
+	}
+	return fmt.Sprintf(`"%s-%s-%d"`, status.ID, status.Status, completedAt)
+}
+
+// GetTransactionAdmin handles GET /v1/admin/transactions/{id}, which adds the channel
+// and client IP a transfer was made from
+func (h *TransactionHandler) GetTransactionAdmin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", strings.TrimPrefix(r.URL.Path, "/v1/admin/transactions/"))
+	if !ok {
+		return
+	}
+
+	transaction, err := h.transactionService.GetTransaction(r.Context(), transactionID)
+	if err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
+
+	body := selectFields(w, r, model.NewAdminTransaction(transaction))
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+// clientIP returns the caller's address: the first X-Forwarded-For entry, as set by a
+// proxy in front of the service, otherwise the connection's remote address
+func clientIP(r *http.Request) *string {
+	candidates := []string{}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		candidates = append(candidates, strings.TrimSpace(first))
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		candidates = append(candidates, host)
+	}
+
+	for _, candidate := range candidates {
+		if ip := net.ParseIP(candidate); ip != nil {
+			address := ip.String()
+			return &address
+		}
+	}
+	return nil
 }
 
 // GetAccountTransactions handles GET /v1/accounts/{id}/transactions
 func (h *TransactionHandler) GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed", model.ErrCodeInvalidInput)
+		WriteMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
@@ -179,45 +311,332 @@ func (h *TransactionHandler) GetAccountTransactions(w http.ResponseWriter, r *ht
 	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
 	path = strings.TrimSuffix(path, "/transactions")
 	
-	if path == "" {
-		writeErrorResponse(w, http.StatusBadRequest, "Account ID is required", model.ErrCodeInvalidInput)
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
 		return
 	}
 
-	accountID, err := uuid.Parse(path)
+	// Parse query parameters
+	limit, offset, err := parseQueryParams(r.URL.Query())
 	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid account ID format", model.ErrCodeInvalidInput)
+		WriteErrorResponse(w, r, http.StatusBadRequest, err.Error(), model.ErrCodeInvalidInput)
 		return
 	}
 
-	// Parse query parameters
-	limit, offset, err := parseQueryParams(r.URL.Query())
+	filter, err := parseTransactionFilter(r.URL.Query())
 	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error(), model.ErrCodeInvalidInput)
+		WriteErrorResponse(w, r, http.StatusBadRequest, err.Error(), model.ErrCodeInvalidInput)
 		return
 	}
 
-	transactions, err := h.transactionService.GetAccountTransactions(r.Context(), accountID, limit, offset)
+	page, err := h.transactionService.GetAccountTransactions(r.Context(), accountID, filter, limit, offset)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
+		return
+	}
+
+	response := selectPageFields(w, r, page)
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// parseTransactionFilter parses the min_amount, max_amount, counterparty and disputed query
+// parameters
+func parseTransactionFilter(values url.Values) (model.TransactionFilter, error) {
+	var filter model.TransactionFilter
+
+	if minStr := values.Get("min_amount"); minStr != "" {
+		minAmount, err := decimal.NewFromString(minStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid min_amount parameter")
+		}
+		filter.MinAmount = &minAmount
+	}
+
+	if maxStr := values.Get("max_amount"); maxStr != "" {
+		maxAmount, err := decimal.NewFromString(maxStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid max_amount parameter")
+		}
+		filter.MaxAmount = &maxAmount
+	}
+
+	if counterpartyStr := values.Get("counterparty"); counterpartyStr != "" {
+		counterparty, err := uuid.Parse(counterpartyStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid counterparty parameter")
+		}
+		filter.Counterparty = &counterparty
+	}
+
+	if disputedStr := values.Get("disputed"); disputedStr != "" {
+		disputed, err := strconv.ParseBool(disputedStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid disputed parameter")
+		}
+		filter.Disputed = &disputed
+	}
+
+	return filter, nil
+}
+
+// GetCounterparties handles GET /v1/accounts/{id}/counterparties?from=&to=&limit=
+func (h *TransactionHandler) GetCounterparties(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
-	response := map[string]interface{}{
-		"account_id":   accountID,
-		"transactions": transactions,
-		"pagination": map[string]interface{}{
-			"limit":  limit,
-			"offset": offset,
-			"count":  len(transactions),
-		},
+	// Extract account ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	path = strings.TrimSuffix(path, "/counterparties")
+
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		// Log the error, but don't change status since headers are already sent
-		// In production, you might want to log this error properly
+	filter, err := parseCounterpartyFilter(r.URL.Query())
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, err.Error(), model.ErrCodeInvalidInput)
 		return
 	}
-} 
\ No newline at end of file
+
+	response, err := h.transactionService.GetCounterparties(r.Context(), accountID, filter)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// parseCounterpartyFilter parses the from, to and limit query parameters
+func parseCounterpartyFilter(values url.Values) (model.CounterpartyFilter, error) {
+	filter := model.CounterpartyFilter{Limit: 20}
+
+	if fromStr := values.Get("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from parameter. Use RFC3339")
+		}
+		from = from.UTC()
+		filter.From = &from
+	}
+
+	if toStr := values.Get("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to parameter. Use RFC3339")
+		}
+		to = to.UTC()
+		filter.To = &to
+	}
+
+	if limitStr := values.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 100 {
+			return filter, fmt.Errorf("invalid limit parameter")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// ReverseTransaction handles POST /v1/transactions/{id}/reverse
+func (h *TransactionHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract transaction ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
+	path = strings.TrimSuffix(path, "/reverse")
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	// The body is optional; without one the whole transaction is reversed
+	var req model.ReverseTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	reversal, err := h.transactionService.ReverseTransaction(r.Context(), transactionID, &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, reversal)
+}
+
+// RetryTransaction handles POST /v1/transactions/{id}/retry
+func (h *TransactionHandler) RetryTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract transaction ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
+	path = strings.TrimSuffix(path, "/retry")
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	retry, err := h.transactionService.RetryTransaction(r.Context(), transactionID, clientIP(r))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, retry)
+}
+
+// ReverseByReference handles POST /v1/transactions/reverse-by-reference
+func (h *TransactionHandler) ReverseByReference(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var req model.ReverseByReferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	response, err := h.transactionService.ReverseByReference(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// AddNote handles POST /v1/transactions/{id}/notes
+func (h *TransactionHandler) AddNote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract transaction ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
+	path = strings.TrimSuffix(path, "/notes")
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	var req model.AddNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	note, err := h.transactionService.AddNote(r.Context(), transactionID, &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, note)
+}
+
+// DisputeTransaction handles POST /v1/transactions/{id}/dispute
+func (h *TransactionHandler) DisputeTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract transaction ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
+	path = strings.TrimSuffix(path, "/dispute")
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	var req model.DisputeTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	transaction, err := h.transactionService.DisputeTransaction(r.Context(), transactionID, &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, transaction)
+}
+
+// ResolveDispute handles POST /v1/transactions/{id}/resolve
+func (h *TransactionHandler) ResolveDispute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract transaction ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transactions/")
+	path = strings.TrimSuffix(path, "/resolve")
+
+	transactionID, ok := parseUUIDParam(w, r, "transaction_id", path)
+	if !ok {
+		return
+	}
+
+	transaction, err := h.transactionService.ResolveDispute(r.Context(), transactionID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, transaction)
+}
+
+// AdjustBalance handles POST /v1/accounts/{id}/adjustments
+func (h *TransactionHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	// Extract account ID from URL path
+	path := strings.TrimPrefix(r.URL.Path, "/v1/accounts/")
+	path = strings.TrimSuffix(path, "/adjustments")
+
+	accountID, ok := parseUUIDParam(w, r, "account_id", path)
+	if !ok {
+		return
+	}
+
+	var req model.AdjustBalanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "Invalid JSON", model.ErrCodeInvalidInput)
+		return
+	}
+
+	response, err := h.transactionService.AdjustBalance(r.Context(), accountID, &req)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, response)
+}
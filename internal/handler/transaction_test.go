@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository/memory"
+	"internal-transfers-api/internal/service"
+)
+
+func TestTransactionHandler_GetTransactionStatus(t *testing.T) {
+	ctx := context.Background()
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	transactionService := service.NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(),
+		config.TransferConfig{Currencies: model.DefaultCurrencies()}, config.IdempotencyConfig{TTL: time.Hour}, service.NewBalanceWatcher(nil), nil, service.NewAuditor(nil, nil), config.DefaultFeatures())
+	h := NewTransactionHandler(transactionService)
+
+	dest := uuid.New()
+	transaction, err := transactionRepo.Create(ctx, nil, &model.CreateTransactionRequest{
+		DestinationAccountID: &dest,
+		Amount:               decimal.RequireFromString("10"),
+	})
+	require.NoError(t, err)
+
+	get := func(id, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/v1/transactions/"+id+"/status", nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h.GetTransactionStatus(w, r)
+		return w
+	}
+
+	w := get(transaction.ID.String(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status model.TransactionStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, model.TransactionStatusPending, status.Status)
+	assert.Nil(t, status.CompletedAt)
+	pendingETag := w.Header().Get("ETag")
+	require.NotEmpty(t, pendingETag)
+
+	// Unchanged, the poller is told so without a body
+	w = get(transaction.ID.String(), pendingETag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Once the transfer completes the old ETag no longer matches
+	require.NoError(t, transactionRepo.UpdateStatus(ctx, nil, transaction.ID, model.TransactionStatusCompleted))
+	w = get(transaction.ID.String(), pendingETag)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, model.TransactionStatusCompleted, status.Status)
+	assert.NotNil(t, status.CompletedAt)
+	assert.NotEqual(t, pendingETag, w.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNotFound, get(uuid.NewString(), "").Code)
+	assert.Equal(t, http.StatusBadRequest, get("not-a-uuid", "").Code)
+}
+
+func TestTransactionHandler_CreateTransaction_IfMatch(t *testing.T) {
+	ctx := context.Background()
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	transactionService := service.NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(),
+		config.TransferConfig{Currencies: model.DefaultCurrencies(), MaxBalance: decimal.RequireFromString("1000000")}, config.IdempotencyConfig{TTL: time.Hour}, service.NewBalanceWatcher(nil), nil, service.NewAuditor(nil, nil), config.DefaultFeatures())
+	h := NewTransactionHandler(transactionService)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), source.Version)
+
+	post := func(body, ifMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		h.CreateTransaction(w, r)
+		return w
+	}
+	transfer := `{"source_account_id":"` + source.ID.String() + `","destination_account_id":"` + dest.ID.String() + `","amount":"10"}`
+
+	// The version the client read matches, so the transfer goes ahead and advances it
+	require.Equal(t, http.StatusCreated, post(transfer, `"1"`).Code)
+	updated, err := accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+
+	// A stale read is rejected without moving money
+	w := post(transfer, `"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	var response model.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, model.ErrCodeVersionMismatch, response.Code)
+	updated, err = accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.True(t, updated.Balance.Equal(decimal.RequireFromString("90")), "balance %s", updated.Balance)
+
+	assert.Equal(t, http.StatusCreated, post(transfer, "2").Code)
+
+	for _, invalid := range []string{"abc", `"0"`, "W/\"3\""} {
+		w = post(transfer, invalid)
+		assert.Equal(t, http.StatusBadRequest, w.Code, invalid)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "If-Match", response.Field)
+	}
+
+	// There's no source account to hold a version for deposits, nor one for a whole batch
+	w = post(`{"destination_account_id":"`+dest.ID.String()+`","amount":"10"}`, "1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post(`{"transfers":[`+transfer+`]}`, "3")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTransactionHandler_CreateTransaction_InvalidField(t *testing.T) {
+	// The body is rejected before the service is reached
+	h := NewTransactionHandler(nil)
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedField string
+	}{
+		{name: "non-numeric amount", body: `{"destination_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11","amount":"ten"}`, expectedField: "amount"},
+		{name: "malformed source", body: `{"source_account_id":"123","destination_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11","amount":"10"}`, expectedField: "source_account_id"},
+		{name: "malformed source in a bulk transfer", body: `{"transfers":[{"source_account_id":"123","destination_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11","amount":"10"}]}`, expectedField: "source_account_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.CreateTransaction(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			var response model.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, model.ErrCodeInvalidInput, response.Code)
+			assert.Equal(t, tt.expectedField, response.Field)
+		})
+	}
+}
+
+func TestTransactionHandler_CreateTransaction_ExcessPrecision(t *testing.T) {
+	ctx := context.Background()
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	transactionService := service.NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(),
+		config.TransferConfig{Currencies: model.DefaultCurrencies(), MaxBalance: decimal.RequireFromString("1000000"), ExcessPrecision: model.ExcessPrecisionRound, RoundingMode: model.RoundHalfUp},
+		config.IdempotencyConfig{TTL: time.Hour}, service.NewBalanceWatcher(nil), nil, service.NewAuditor(nil, nil), config.DefaultFeatures())
+	h := NewTransactionHandler(transactionService)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	post := func(amount string) *httptest.ResponseRecorder {
+		body := `{"source_account_id":"` + source.ID.String() + `","destination_account_id":"` + dest.ID.String() + `","amount":"` + amount + `"}`
+		r := httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.CreateTransaction(w, r)
+		return w
+	}
+
+	// The rounded amount is what was transferred, and the header says so
+	w := post("1.005")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var response model.CreateTransactionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "1.01", response.Amount.String())
+	assert.Equal(t, `299 - "amount 1.005 rounded to 1.01"`, w.Header().Get("Warning"))
+
+	// An amount that fits needs no warning
+	w = post("2.5")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Warning"))
+}
+
+func TestTransactionHandler_CreateTransaction_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	transactionService := service.NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(),
+		config.TransferConfig{Currencies: model.DefaultCurrencies(), MaxBalance: decimal.RequireFromString("1000000")}, config.IdempotencyConfig{TTL: time.Hour}, service.NewBalanceWatcher(nil), nil, service.NewAuditor(nil, nil), config.DefaultFeatures())
+	h := NewTransactionHandler(transactionService)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	post := func(body, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		h.CreateTransaction(w, r)
+		return w
+	}
+	transfer := `{"source_account_id":"` + source.ID.String() + `","destination_account_id":"` + dest.ID.String() + `","amount":"10"}`
+
+	// A retry whose first response was lost gets the same transaction back
+	w := post(transfer, "transfer-1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var first, retry model.CreateTransactionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	w = post(transfer, "transfer-1")
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &retry))
+	assert.Equal(t, first.ID, retry.ID)
+	updated, err := accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.True(t, updated.Balance.Equal(decimal.RequireFromString("90")), "balance %s", updated.Balance)
+
+	// A body idempotency_key has to agree with the header
+	w = post(`{"source_account_id":"`+source.ID.String()+`","destination_account_id":"`+dest.ID.String()+`","amount":"10","idempotency_key":"other"}`, "transfer-2")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response model.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "idempotency_key", response.Field)
+}
@@ -1,37 +1,399 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+// AccountStatus represents whether an account can be credited or debited
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+// accountStatusTransitions lists the statuses each status may move to. A closed
+// account can never be reopened.
+var accountStatusTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusActive: {AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusFrozen: {AccountStatusActive, AccountStatusClosed},
+}
+
+// Valid reports whether s is a known account status
+func (s AccountStatus) Valid() bool {
+	switch s {
+	case AccountStatusActive, AccountStatusFrozen, AccountStatusClosed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an account may move from s to status. Staying
+// in the same status is allowed, so repeating a status change is a no-op.
+func (s AccountStatus) CanTransitionTo(status AccountStatus) bool {
+	if s == status {
+		return true
+	}
+	for _, allowed := range accountStatusTransitions[s] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountType distinguishes interest-bearing savings accounts from checking accounts
+type AccountType string
+
+const (
+	AccountTypeChecking AccountType = "checking"
+	AccountTypeSavings  AccountType = "savings"
+)
+
 // Account represents a bank account
 type Account struct {
-	ID        uuid.UUID       `json:"id" db:"id"`
-	Balance   decimal.Decimal `json:"balance" db:"balance"`
-	CreatedAt time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+	ID           uuid.UUID        `json:"id" db:"id"`
+	Balance      decimal.Decimal  `json:"balance" db:"balance"`
+	Currency     string           `json:"currency" db:"currency"`
+	Status       AccountStatus    `json:"status" db:"status"`
+	AccountType  AccountType      `json:"account_type" db:"account_type"`
+	InterestRate decimal.Decimal  `json:"interest_rate" db:"interest_rate"`         // annual, as a fraction
+	MinBalance   *decimal.Decimal `json:"min_balance,omitempty" db:"min_balance"`   // lowest balance a debit may leave, if set
+	MaxBalance   *decimal.Decimal `json:"max_balance,omitempty" db:"max_balance"`   // highest balance a credit may leave, if set
+	OwnerID      *string          `json:"owner_id,omitempty" db:"owner_id"`         // client whose API key may debit the account, if any
+	ExternalRef  *string          `json:"external_ref,omitempty" db:"external_ref"` // client's unique reference for the account, if any
+	System       bool             `json:"system,omitempty" db:"is_system"`          // stands in for the outside world; may go negative
+	Version      int64            `json:"version" db:"version"`                     // advances with every balance change
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at" db:"updated_at"`
 }
 
 // CreateAccountRequest represents the request to create a new account
 type CreateAccountRequest struct {
 	InitialBalance *decimal.Decimal `json:"initial_balance,omitempty"`
+	Currency       string           `json:"currency,omitempty"`
+	AccountType    AccountType      `json:"account_type,omitempty"`
+	InterestRate   *decimal.Decimal `json:"interest_rate,omitempty"`
+	MinBalance     *decimal.Decimal `json:"min_balance,omitempty"`
+	MaxBalance     *decimal.Decimal `json:"max_balance,omitempty"`
+	OwnerID        *string          `json:"owner_id,omitempty"`
+	// ExternalRef makes creation idempotent: a second create with the same reference
+	// returns the account the first one created
+	ExternalRef *string `json:"external_ref,omitempty"`
+	// FundingSourceAccountID funds the initial balance with a transfer from an existing
+	// account, made in the same database transaction that creates the account
+	FundingSourceAccountID *uuid.UUID `json:"funding_source_account_id,omitempty"`
 }
 
 // CreateAccountResponse represents the response after creating an account
 type CreateAccountResponse struct {
-	ID      uuid.UUID       `json:"id"`
-	Balance decimal.Decimal `json:"balance"`
+	ID          uuid.UUID       `json:"id"`
+	Balance     decimal.Decimal `json:"balance"`
+	Currency    string          `json:"currency"`
+	OwnerID     *string         `json:"owner_id,omitempty"`
+	ExternalRef *string         `json:"external_ref,omitempty"`
+	Created     bool            `json:"-"` // false when an account with the same external_ref already existed
+
+	FundingTransactionID *uuid.UUID `json:"funding_transaction_id,omitempty"` // transfer that funded the initial balance
+
+	BalanceScale *int32 `json:"-"` // decimal places the balance is written with at least, when set
+}
+
+// MarshalJSON writes the balance padded to BalanceScale decimal places when it is set
+func (r CreateAccountResponse) MarshalJSON() ([]byte, error) {
+	type plain CreateAccountResponse
+	return json.Marshal(struct {
+		plain
+		Balance scaledAmount `json:"balance"`
+	}{plain(r), scaledAmount{r.Balance, r.BalanceScale}})
 }
 
 // GetAccountResponse represents the response for getting an account
 type GetAccountResponse struct {
-	ID        uuid.UUID       `json:"id"`
-	Balance   decimal.Decimal `json:"balance"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	ID           uuid.UUID        `json:"id"`
+	Balance      decimal.Decimal  `json:"balance"`
+	Currency     string           `json:"currency"`
+	Status       AccountStatus    `json:"status"`
+	AccountType  AccountType      `json:"account_type"`
+	InterestRate decimal.Decimal  `json:"interest_rate"`
+	MinBalance   *decimal.Decimal `json:"min_balance,omitempty"`
+	MaxBalance   *decimal.Decimal `json:"max_balance,omitempty"`
+	OwnerID      *string          `json:"owner_id,omitempty"`
+	ExternalRef  *string          `json:"external_ref,omitempty"`
+	Version      int64            `json:"version"` // send as If-Match to make a transfer from the account conditional
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+
+	BalanceScale *int32 `json:"-"` // decimal places the balance is written with at least, when set
+}
+
+// MarshalJSON writes the balance padded to BalanceScale decimal places when it is set
+func (r GetAccountResponse) MarshalJSON() ([]byte, error) {
+	type plain GetAccountResponse
+	return json.Marshal(struct {
+		plain
+		Balance scaledAmount `json:"balance"`
+	}{plain(r), scaledAmount{r.Balance, r.BalanceScale}})
+}
+
+// BalanceFormat is how account balances are written in account responses
+type BalanceFormat string
+
+const (
+	BalanceFormatExact    BalanceFormat = "exact"    // as few decimal places as the value needs: 100
+	BalanceFormatCurrency BalanceFormat = "currency" // padded to the currency's decimal places: 100.00
+)
+
+// ParseBalanceFormat parses a balance format name
+func ParseBalanceFormat(s string) (BalanceFormat, error) {
+	switch format := BalanceFormat(s); format {
+	case BalanceFormatExact, BalanceFormatCurrency:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown balance format %q, expected exact or currency", s)
+}
+
+// scaledAmount is an amount written with at least scale decimal places, in the
+// configured amount format. An amount with more places than scale, such as unrounded
+// interest, keeps them rather than being rounded for display.
+type scaledAmount struct {
+	amount decimal.Decimal
+	scale  *int32
+}
+
+func (a scaledAmount) MarshalJSON() ([]byte, error) {
+	if a.scale == nil {
+		return a.amount.MarshalJSON()
+	}
+
+	places := *a.scale
+	if _, fraction, ok := strings.Cut(a.amount.String(), "."); ok && int32(len(fraction)) > places {
+		places = int32(len(fraction))
+	}
+	formatted := a.amount.StringFixed(places)
+	if decimal.MarshalJSONWithoutQuotes {
+		return []byte(formatted), nil
+	}
+	return []byte(`"` + formatted + `"`), nil
+}
+
+// ReconcileAccountResponse reports the difference between the stored and ledger-computed balance
+type ReconcileAccountResponse struct {
+	ID            uuid.UUID       `json:"id"`
+	StoredBalance decimal.Decimal `json:"stored_balance"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+	Discrepancy   decimal.Decimal `json:"discrepancy"`
+	Corrected     bool            `json:"corrected"`
+}
+
+// AccountLedger is an account's stored balance beside the balance its opening balance and
+// completed transactions add up to
+type AccountLedger struct {
+	ID            uuid.UUID
+	Currency      string
+	StoredBalance decimal.Decimal
+	LedgerBalance decimal.Decimal
+}
+
+// ReconciliationDiscrepancy is a line of the reconciliation report naming an account whose
+// stored balance has drifted from its ledger balance
+type ReconciliationDiscrepancy struct {
+	Type          string          `json:"type"` // always "discrepancy"
+	ID            uuid.UUID       `json:"id"`
+	Currency      string          `json:"currency"`
+	StoredBalance decimal.Decimal `json:"stored_balance"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+	Discrepancy   decimal.Decimal `json:"discrepancy"`
+}
+
+// CurrencyReconciliation totals the stored and ledger balances of every account in a
+// currency beside what the ledger total should be: the opening balances plus the money
+// that entered or left the currency. Transfers within the currency net to zero, so
+// Difference, the ledger total less what it should be, is zero unless some transaction's
+// legs don't add up.
+type CurrencyReconciliation struct {
+	Currency       string          `json:"currency"`
+	Accounts       int             `json:"accounts"`
+	StoredBalance  decimal.Decimal `json:"stored_balance"`
+	LedgerBalance  decimal.Decimal `json:"ledger_balance"`
+	OpeningBalance decimal.Decimal `json:"opening_balance"`
+	Movements      decimal.Decimal `json:"movements"` // deposits, withdrawals and cross-currency transfers, net
+	Difference     decimal.Decimal `json:"difference"`
+}
+
+// CurrencyMovements are a currency's opening balances and the net of the completed
+// deposits, withdrawals and cross-currency transfers in or out of it
+type CurrencyMovements struct {
+	OpeningBalance decimal.Decimal
+	Movements      decimal.Decimal
+}
+
+// ReconciliationSummary is the last line of the reconciliation report
+type ReconciliationSummary struct {
+	Type          string                   `json:"type"` // always "summary"
+	Balanced      bool                     `json:"balanced"`
+	Accounts      int                      `json:"accounts"`
+	Discrepancies int                      `json:"discrepancies"`
+	Currencies    []CurrencyReconciliation `json:"currencies"`
+}
+
+// MaxBalanceQueryAccounts is the most accounts one balance query may ask for
+const MaxBalanceQueryAccounts = 100
+
+// GetBalancesRequest represents a request for the balances of several accounts
+type GetBalancesRequest struct {
+	AccountIDs []uuid.UUID `json:"account_ids"`
+}
+
+// GetBalancesResponse maps each account found to its balance and lists the
+// requested accounts that don't exist
+type GetBalancesResponse struct {
+	Balances map[uuid.UUID]decimal.Decimal `json:"balances"`
+	NotFound []uuid.UUID                   `json:"not_found"`
+}
+
+// OwnerBalance totals the balances of an owner's accounts in one currency
+type OwnerBalance struct {
+	Currency string          `json:"currency"`
+	Accounts int             `json:"accounts"`
+	Balance  decimal.Decimal `json:"balance"`
+
+	BalanceScale *int32 `json:"-"` // decimal places the balance is written with at least, when set
+}
+
+// MarshalJSON writes the balance padded to BalanceScale decimal places when it is set
+func (b OwnerBalance) MarshalJSON() ([]byte, error) {
+	type plain OwnerBalance
+	return json.Marshal(struct {
+		plain
+		Balance scaledAmount `json:"balance"`
+	}{plain(b), scaledAmount{b.Balance, b.BalanceScale}})
+}
+
+// OwnerAccountsResponse lists every account of an owner, oldest first, along with their
+// balances summed per currency, by currency code
+type OwnerAccountsResponse struct {
+	OwnerID  string               `json:"owner_id"`
+	Accounts []GetAccountResponse `json:"accounts"`
+	Totals   []OwnerBalance       `json:"totals"`
+}
+
+// MaxBalanceHistoryPoints is the most timestamps one balance history query may ask for
+const MaxBalanceHistoryPoints = 100
+
+// BalanceHistoryPoint is an account's balance at one point in time
+type BalanceHistoryPoint struct {
+	At      time.Time       `json:"at"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// BalanceHistoryResponse lists an account's balance at each requested time, in request order
+type BalanceHistoryResponse struct {
+	ID     uuid.UUID             `json:"id"`
+	Points []BalanceHistoryPoint `json:"points"`
+}
+
+// ParseBalanceHistoryPoints parses a comma-separated list of RFC3339 timestamps
+func ParseBalanceHistoryPoints(value string) ([]time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, &ValidationError{
+			Field:   "points",
+			Message: "at least one point is required",
+		}
+	}
+
+	entries := strings.Split(value, ",")
+	if len(entries) > MaxBalanceHistoryPoints {
+		return nil, &ValidationError{
+			Field:   "points",
+			Message: fmt.Sprintf("cannot query more than %d points at once", MaxBalanceHistoryPoints),
+		}
+	}
+
+	points := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		at, err := time.Parse(time.RFC3339, strings.TrimSpace(entry))
+		if err != nil {
+			return nil, &ValidationError{
+				Field:   "points",
+				Message: fmt.Sprintf("point %q is not an RFC3339 timestamp", strings.TrimSpace(entry)),
+			}
+		}
+		points = append(points, at)
+	}
+	return points, nil
+}
+
+// Validate validates the get balances request
+func (r *GetBalancesRequest) Validate() error {
+	if len(r.AccountIDs) == 0 {
+		return &ValidationError{
+			Field:   "account_ids",
+			Message: "at least one account ID is required",
+		}
+	}
+
+	if len(r.AccountIDs) > MaxBalanceQueryAccounts {
+		return &ValidationError{
+			Field:   "account_ids",
+			Message: fmt.Sprintf("cannot query more than %d accounts at once", MaxBalanceQueryAccounts),
+		}
+	}
+
+	return nil
+}
+
+// SetAccountStatusRequest represents a request to change an account's status
+type SetAccountStatusRequest struct {
+	Status AccountStatus `json:"status"`
+	Actor  string        `json:"actor"`
+	Reason *string       `json:"reason,omitempty"`
+}
+
+// SetAccountStatusResponse reports an account's status after a status change
+type SetAccountStatusResponse struct {
+	ID             uuid.UUID     `json:"id"`
+	Status         AccountStatus `json:"status"`
+	PreviousStatus AccountStatus `json:"previous_status"`
+	Changed        bool          `json:"changed"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// AccountStatusChange is the audit record of a status change
+type AccountStatusChange struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	AccountID  uuid.UUID     `json:"account_id" db:"account_id"`
+	FromStatus AccountStatus `json:"from_status" db:"from_status"`
+	ToStatus   AccountStatus `json:"to_status" db:"to_status"`
+	Actor      string        `json:"actor" db:"actor"`
+	Reason     *string       `json:"reason,omitempty" db:"reason"`
+	ChangedAt  time.Time     `json:"changed_at" db:"changed_at"`
+}
+
+// Validate validates the set account status request
+func (r *SetAccountStatusRequest) Validate() error {
+	if !r.Status.Valid() {
+		return &ValidationError{
+			Field:   "status",
+			Message: "status must be active, frozen or closed",
+		}
+	}
+
+	if strings.TrimSpace(r.Actor) == "" {
+		return &ValidationError{
+			Field:   "actor",
+			Message: "actor is required",
+		}
+	}
+
+	return nil
 }
 
 // Validate validates the create account request
@@ -42,6 +404,90 @@ func (r *CreateAccountRequest) Validate() error {
 			Message: "initial balance cannot be negative",
 		}
 	}
+
+	if r.Currency != "" && !currencyCodePattern.MatchString(r.Currency) {
+		return &ValidationError{
+			Field:   "currency",
+			Message: "currency must be an uppercase currency code such as USD",
+		}
+	}
+
+	switch r.AccountType {
+	case "", AccountTypeChecking, AccountTypeSavings:
+	default:
+		return &ValidationError{
+			Field:   "account_type",
+			Message: "account type must be checking or savings",
+		}
+	}
+
+	if r.InterestRate != nil {
+		if r.InterestRate.IsNegative() {
+			return &ValidationError{
+				Field:   "interest_rate",
+				Message: "interest rate cannot be negative",
+			}
+		}
+		if !r.InterestRate.IsZero() && r.AccountType != AccountTypeSavings {
+			return &ValidationError{
+				Field:   "interest_rate",
+				Message: "only savings accounts can earn interest",
+			}
+		}
+	}
+
+	if r.OwnerID != nil && (strings.TrimSpace(*r.OwnerID) == "" || len(*r.OwnerID) > 255) {
+		return &ValidationError{
+			Field:   "owner_id",
+			Message: "owner_id must be between 1 and 255 characters",
+		}
+	}
+
+	if r.ExternalRef != nil && (strings.TrimSpace(*r.ExternalRef) == "" || len(*r.ExternalRef) > 255) {
+		return &ValidationError{
+			Field:   "external_ref",
+			Message: "external_ref must be between 1 and 255 characters",
+		}
+	}
+
+	if r.MinBalance != nil && r.MinBalance.IsNegative() {
+		return &ValidationError{
+			Field:   "min_balance",
+			Message: "min_balance cannot be negative",
+		}
+	}
+
+	if r.MinBalance != nil && r.MaxBalance != nil && r.MinBalance.GreaterThan(*r.MaxBalance) {
+		return &ValidationError{
+			Field:   "min_balance",
+			Message: "min_balance cannot be greater than max_balance",
+		}
+	}
+
+	initialBalance := decimal.Zero
+	if r.InitialBalance != nil {
+		initialBalance = *r.InitialBalance
+	}
+	if r.MinBalance != nil && initialBalance.LessThan(*r.MinBalance) {
+		return &ValidationError{
+			Field:   "initial_balance",
+			Message: "initial balance cannot be below min_balance",
+		}
+	}
+	if r.MaxBalance != nil && initialBalance.GreaterThan(*r.MaxBalance) {
+		return &ValidationError{
+			Field:   "initial_balance",
+			Message: "initial balance cannot be above max_balance",
+		}
+	}
+
+	if r.FundingSourceAccountID != nil && !initialBalance.IsPositive() {
+		return &ValidationError{
+			Field:   "initial_balance",
+			Message: "initial balance must be positive when funded from another account",
+		}
+	}
+
 	return nil
 }
 
@@ -53,4 +499,4 @@ type ValidationError struct {
 
 func (e *ValidationError) Error() string {
 	return e.Message
-} 
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// AdjustBalanceRequest represents an operator's manual correction of an account balance
+type AdjustBalanceRequest struct {
+	Amount decimal.Decimal `json:"amount"` // signed: positive credits the account, negative debits it
+	Reason string          `json:"reason"`
+	Actor  string          `json:"actor"`
+	// Force applies the adjustment even if it takes the balance outside the account's
+	// min_balance and max_balance
+	Force bool `json:"force,omitempty"`
+}
+
+// Validate validates the adjust balance request
+func (r *AdjustBalanceRequest) Validate() error {
+	if r.Amount.IsZero() {
+		return &ValidationError{
+			Field:   "amount",
+			Message: "amount must not be zero",
+		}
+	}
+
+	if strings.TrimSpace(r.Reason) == "" {
+		return &ValidationError{
+			Field:   "reason",
+			Message: "reason is required",
+		}
+	}
+	if len(r.Reason) > MaxDescriptionLength {
+		return &ValidationError{
+			Field:   "reason",
+			Message: "reason cannot exceed 1000 characters",
+		}
+	}
+
+	if strings.TrimSpace(r.Actor) == "" || len(r.Actor) > 255 {
+		return &ValidationError{
+			Field:   "actor",
+			Message: "actor must be between 1 and 255 characters",
+		}
+	}
+
+	return nil
+}
+
+// AccountAdjustment is the audit record of a manual balance adjustment. The money moves
+// in TransactionID, a deposit or withdrawal of the adjustment's magnitude.
+type AccountAdjustment struct {
+	ID              uuid.UUID       `json:"id" db:"id"`
+	AccountID       uuid.UUID       `json:"account_id" db:"account_id"`
+	TransactionID   uuid.UUID       `json:"transaction_id" db:"transaction_id"`
+	Amount          decimal.Decimal `json:"amount" db:"amount"`
+	PreviousBalance decimal.Decimal `json:"previous_balance" db:"previous_balance"`
+	Reason          string          `json:"reason" db:"reason"`
+	Actor           string          `json:"actor" db:"actor"`
+	Forced          bool            `json:"forced" db:"forced"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AdjustBalanceResponse reports an adjustment and the balance it left
+type AdjustBalanceResponse struct {
+	*AccountAdjustment
+	Balance decimal.Decimal `json:"balance"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditOperation names a kind of change recorded in the audit log
+type AuditOperation string
+
+const (
+	AuditAccountCreate    AuditOperation = "account.create"
+	AuditAccountStatus    AuditOperation = "account.status"
+	AuditAccountAdjust    AuditOperation = "account.adjust"
+	AuditAccountReconcile AuditOperation = "account.reconcile"
+	AuditTransfer         AuditOperation = "transaction.create"
+	AuditReversal         AuditOperation = "transaction.reverse"
+	AuditResolve          AuditOperation = "transaction.resolve"
+	AuditNote             AuditOperation = "transaction.note"
+	AuditDispute          AuditOperation = "transaction.dispute"
+	AuditDisputeResolve   AuditOperation = "transaction.dispute_resolve"
+)
+
+// AuditEntry records who made a change, when, and which accounts and transactions it
+// touched
+type AuditEntry struct {
+	Actor       string         `json:"actor" db:"actor"`
+	Operation   AuditOperation `json:"operation" db:"operation"`
+	ResourceIDs []uuid.UUID    `json:"resource_ids" db:"resource_ids"`
+	Timestamp   time.Time      `json:"timestamp" db:"created_at"`
+}
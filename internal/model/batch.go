@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BatchStatus represents the processing status of an asynchronous transfer batch
+type BatchStatus string
+
+const (
+	BatchStatusPending    BatchStatus = "pending"
+	BatchStatusProcessing BatchStatus = "processing"
+	BatchStatusCompleted  BatchStatus = "completed"
+)
+
+// Batch represents an asynchronously processed bulk transfer
+type Batch struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	Status      BatchStatus `json:"status" db:"status"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty" db:"completed_at"`
+
+	SubmittedByAdmin bool `json:"-" db:"submitted_by_admin"` // the worker processes the items as an admin
+}
+
+// BatchItem represents a single transfer of a batch and its outcome
+type BatchItem struct {
+	BatchID       uuid.UUID         `json:"-" db:"batch_id"`
+	Index         int               `json:"index" db:"item_index"`
+	TransactionID uuid.UUID         `json:"transaction_id" db:"transaction_id"`
+	RequestBody   string            `json:"-" db:"request_body"`
+	Status        TransactionStatus `json:"status" db:"status"`
+	Error         *string           `json:"error,omitempty" db:"error"`
+	Code          *string           `json:"code,omitempty" db:"error_code"`
+}
+
+// CreateBatchResponse represents the response after accepting a batch
+type CreateBatchResponse struct {
+	BatchID uuid.UUID   `json:"batch_id"`
+	Status  BatchStatus `json:"status"`
+}
+
+// GetBatchResponse represents a batch with the status of each of its transfers
+type GetBatchResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Status      BatchStatus `json:"status"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	Items       []BatchItem `json:"items"`
+}
\ No newline at end of file
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextCharset is the set of characters allowed in free-text fields such as a
+// transaction's reference and description
+type TextCharset string
+
+const (
+	TextCharsetUnicode TextCharset = "unicode" // any printable text, no control or bidi override characters
+	TextCharsetASCII   TextCharset = "ascii"   // printable ASCII only
+)
+
+// ParseTextCharset parses a text charset name
+func ParseTextCharset(s string) (TextCharset, error) {
+	switch charset := TextCharset(s); charset {
+	case TextCharsetUnicode, TextCharsetASCII:
+		return charset, nil
+	}
+	return "", fmt.Errorf("unknown charset %q, expected ascii or unicode", s)
+}
+
+// Allows reports whether every character of s is in the charset. Control characters,
+// including newlines and tabs, are never allowed since they enable log injection.
+func (c TextCharset) Allows(s string) bool {
+	if c == TextCharsetASCII {
+		for i := 0; i < len(s); i++ {
+			if s[i] < 0x20 || s[i] > 0x7e {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		// Bidi controls can make displayed text read differently from what is stored
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// describe names the charset in validation messages
+func (c TextCharset) describe() string {
+	if c == TextCharsetASCII {
+		return "printable ASCII characters"
+	}
+	return "printable characters"
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextCharset_Allows(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		unicode bool
+		ascii   bool
+	}{
+		{name: "plain text", value: "Invoice #42 - March", unicode: true, ascii: true},
+		{name: "empty", value: "", unicode: true, ascii: true},
+		{name: "accented letters", value: "Café rent", unicode: true, ascii: false},
+		{name: "emoji", value: "Pizza 🍕", unicode: true, ascii: false},
+		{name: "emoji sequence with joiner", value: "👩‍💻 payroll", unicode: true, ascii: false},
+		{name: "newline", value: "rent\nINFO forged log line", unicode: false, ascii: false},
+		{name: "carriage return", value: "rent\r", unicode: false, ascii: false},
+		{name: "tab", value: "rent\tMarch", unicode: false, ascii: false},
+		{name: "delete", value: "rent\x7f", unicode: false, ascii: false},
+		{name: "C1 control", value: "rent\u0085", unicode: false, ascii: false},
+		{name: "bidi override", value: "rent‮gnp.exe", unicode: false, ascii: false},
+		{name: "invalid UTF-8", value: "rent\xff", unicode: false, ascii: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.unicode, TextCharsetUnicode.Allows(tt.value), "unicode")
+			assert.Equal(t, tt.ascii, TextCharsetASCII.Allows(tt.value), "ascii")
+		})
+	}
+}
+
+func TestCreateTransactionRequest_ValidateCharset(t *testing.T) {
+	emoji := "Pizza 🍕"
+	newline := "line one\nline two"
+
+	tests := []struct {
+		name     string
+		req      CreateTransactionRequest
+		charset  TextCharset
+		errField string
+	}{
+		{name: "emoji reference allowed as unicode", req: CreateTransactionRequest{Reference: &emoji}, charset: TextCharsetUnicode},
+		{name: "emoji reference rejected as ascii", req: CreateTransactionRequest{Reference: &emoji}, charset: TextCharsetASCII, errField: "reference"},
+		{name: "emoji description rejected as ascii", req: CreateTransactionRequest{Description: &emoji}, charset: TextCharsetASCII, errField: "description"},
+		{name: "newline in description rejected as unicode", req: CreateTransactionRequest{Description: &newline}, charset: TextCharsetUnicode, errField: "description"},
+		{name: "newline in reference rejected as ascii", req: CreateTransactionRequest{Reference: &newline}, charset: TextCharsetASCII, errField: "reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.ValidateCharset(tt.charset)
+
+			if tt.errField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.IsType(t, &ValidationError{}, err) {
+				assert.Equal(t, tt.errField, err.(*ValidationError).Field)
+			}
+		})
+	}
+}
+
+func TestParseTextCharset(t *testing.T) {
+	charset, err := ParseTextCharset("ascii")
+	assert.NoError(t, err)
+	assert.Equal(t, TextCharsetASCII, charset)
+
+	_, err = ParseTextCharset("latin1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCurrency is the currency of accounts opened without one
+const DefaultCurrency = "USD"
+
+// currencyCodePattern matches ISO 4217 codes as well as longer codes for custom assets
+var currencyCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{2,11}$`)
+
+// CurrencyRegistry maps currency codes to the number of decimal places (minor unit
+// scale) their amounts may carry
+type CurrencyRegistry map[string]int32
+
+// DefaultCurrencies returns a registry of ISO 4217 currencies with their standard scales
+func DefaultCurrencies() CurrencyRegistry {
+	registry := make(CurrencyRegistry)
+	for _, code := range strings.Fields(`
+		AED ARS AUD BDT BGN BRL CAD CHF CNY COP CZK DKK DZD EGP EUR GBP GHS HKD HUF IDR
+		ILS INR KES KZT LKR MAD MXN MYR NGN NOK NZD PEN PHP PKR PLN QAR RON RSD RUB SAR
+		SEK SGD THB TRY TWD UAH USD ZAR`) {
+		registry[code] = 2
+	}
+	for _, code := range strings.Fields(`BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX VND VUV XAF XOF XPF`) {
+		registry[code] = 0
+	}
+	for _, code := range strings.Fields(`BHD IQD JOD KWD LYD OMR TND`) {
+		registry[code] = 3
+	}
+	for _, code := range strings.Fields(`CLF UYW`) {
+		registry[code] = 4
+	}
+	return registry
+}
+
+// ParseCurrencyScales parses a comma-separated list of CODE=scale pairs, such as
+// "BTC=8,USDC=6", into a registry of custom or overridden currencies
+func ParseCurrencyScales(s string) (CurrencyRegistry, error) {
+	registry := make(CurrencyRegistry)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		code, rawScale, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected CODE=scale, got %q", pair)
+		}
+		code = strings.TrimSpace(code)
+		if !currencyCodePattern.MatchString(code) {
+			return nil, fmt.Errorf("invalid currency code %q", code)
+		}
+		scale, err := strconv.Atoi(strings.TrimSpace(rawScale))
+		if err != nil || scale < 0 || scale > AmountScale {
+			return nil, fmt.Errorf("scale of %s must be between 0 and %d, got %q", code, AmountScale, rawScale)
+		}
+		registry[code] = int32(scale)
+	}
+	return registry, nil
+}
+
+// Merge returns a registry with the currencies of both, overrides taking precedence
+func (r CurrencyRegistry) Merge(overrides CurrencyRegistry) CurrencyRegistry {
+	merged := make(CurrencyRegistry, len(r)+len(overrides))
+	for code, scale := range r {
+		merged[code] = scale
+	}
+	for code, scale := range overrides {
+		merged[code] = scale
+	}
+	return merged
+}
+
+// CurrencyInfo describes a supported currency
+type CurrencyInfo struct {
+	Code  string `json:"code"`
+	Scale int32  `json:"scale"` // decimal places amounts in the currency may carry
+}
+
+// CurrenciesResponse lists the supported currencies and the one accounts default to
+type CurrenciesResponse struct {
+	Default    string         `json:"default"`
+	Currencies []CurrencyInfo `json:"currencies"`
+}
+
+// Scale returns the number of decimal places amounts in the currency may carry
+func (r CurrencyRegistry) Scale(code string) (int32, bool) {
+	scale, ok := r[code]
+	return scale, ok
+}
+
+// FitsScale reports whether amount has no more significant decimal places than scale
+func FitsScale(amount decimal.Decimal, scale int32) bool {
+	return amount.Equal(amount.Truncate(scale))
+}
+
+// FitsIntegerDigits reports whether amount has no more digits before the decimal point
+// than digits
+func FitsIntegerDigits(amount decimal.Decimal, digits int) bool {
+	return amount.Abs().LessThan(decimal.New(1, int32(digits)))
+}
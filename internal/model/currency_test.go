@@ -0,0 +1,60 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultCurrencies(t *testing.T) {
+	registry := DefaultCurrencies()
+
+	for code, expected := range map[string]int32{"USD": 2, "JPY": 0, "BHD": 3, "CLF": 4} {
+		scale, ok := registry.Scale(code)
+		assert.True(t, ok, code)
+		assert.Equal(t, expected, scale, code)
+	}
+
+	_, ok := registry.Scale("BTC")
+	assert.False(t, ok)
+}
+
+func TestParseCurrencyScales(t *testing.T) {
+	overrides, err := ParseCurrencyScales("BTC=8, USDC=6,JPY=2")
+	require.NoError(t, err)
+
+	registry := DefaultCurrencies().Merge(overrides)
+	for code, expected := range map[string]int32{"BTC": 8, "USDC": 6, "JPY": 2, "USD": 2} {
+		scale, ok := registry.Scale(code)
+		assert.True(t, ok, code)
+		assert.Equal(t, expected, scale, code)
+	}
+
+	for _, invalid := range []string{"BTC", "btc=8", "BTC=-1", "BTC=11", "BTC=x"} {
+		_, err := ParseCurrencyScales(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFitsScale(t *testing.T) {
+	tests := []struct {
+		amount   string
+		scale    int32
+		expected bool
+	}{
+		{amount: "100", scale: 0, expected: true},
+		{amount: "100.5", scale: 0, expected: false},
+		{amount: "10.25", scale: 2, expected: true},
+		{amount: "10.250000", scale: 2, expected: true},
+		{amount: "10.255", scale: 2, expected: false},
+		{amount: "0.125", scale: 3, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FitsScale(decimal.RequireFromString(tt.amount), tt.scale))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DisputeTransactionRequest flags a transaction for review. A dispute moves no money; the
+// transaction stays as it is until it's resolved, or reversed if the dispute is upheld.
+type DisputeTransactionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Validate validates the dispute transaction request
+func (r *DisputeTransactionRequest) Validate() error {
+	if strings.TrimSpace(r.Reason) == "" {
+		return &ValidationError{
+			Field:   "reason",
+			Message: "reason is required",
+		}
+	}
+	if len(r.Reason) > MaxDescriptionLength {
+		return &ValidationError{
+			Field:   "reason",
+			Message: fmt.Sprintf("reason cannot exceed %d characters", MaxDescriptionLength),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// FXRateScale is the number of decimal places stored for an exchange rate
+const FXRateScale = 18
+
+// RateProvider supplies the exchange rates cross-currency transfers are converted at
+type RateProvider interface {
+	// Rate returns how many units of the to currency one unit of the from currency
+	// buys, and false when no rate is available for the pair
+	Rate(from, to string) (decimal.Decimal, bool)
+}
+
+// CurrencyPair is the direction of an exchange, from one currency into another
+type CurrencyPair struct {
+	From string
+	To   string
+}
+
+// FXRates is a fixed table of exchange rates per currency pair
+type FXRates map[CurrencyPair]decimal.Decimal
+
+// ParseFXRates parses a comma-separated list of FROM/TO=rate entries, such as
+// "EUR/USD=1.08,USD/EUR=0.92". Each direction is configured separately, since buy
+// and sell rates usually differ.
+func ParseFXRates(s string) (FXRates, error) {
+	rates := make(FXRates)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rawPair, rawRate, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected FROM/TO=rate, got %q", entry)
+		}
+		from, to, ok := strings.Cut(strings.TrimSpace(rawPair), "/")
+		if !ok {
+			return nil, fmt.Errorf("expected FROM/TO=rate, got %q", entry)
+		}
+		for _, code := range []string{from, to} {
+			if !currencyCodePattern.MatchString(code) {
+				return nil, fmt.Errorf("invalid currency code %q", code)
+			}
+		}
+		if from == to {
+			return nil, fmt.Errorf("rate of %s into itself cannot be configured", from)
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(rawRate))
+		if err != nil || !rate.IsPositive() || !FitsScale(rate, FXRateScale) {
+			return nil, fmt.Errorf("rate of %s/%s must be a positive number with at most %d decimal places, got %q", from, to, FXRateScale, rawRate)
+		}
+		rates[CurrencyPair{From: from, To: to}] = rate
+	}
+	return rates, nil
+}
+
+// Rate returns the configured rate from one currency into another. Rates aren't
+// inverted from the opposite direction.
+func (r FXRates) Rate(from, to string) (decimal.Decimal, bool) {
+	rate, ok := r[CurrencyPair{From: from, To: to}]
+	return rate, ok
+}
+
+// FXPreviewResponse is what an amount is worth in another currency at the current rate
+type FXPreviewResponse struct {
+	From            string          `json:"from"`
+	To              string          `json:"to"`
+	Amount          decimal.Decimal `json:"amount"`
+	Rate            decimal.Decimal `json:"rate"`
+	ConvertedAmount decimal.Decimal `json:"converted_amount"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFXRates(t *testing.T) {
+	rates, err := ParseFXRates("EUR/USD=1.08, USD/EUR = 0.92,USD/JPY=151.234")
+	require.NoError(t, err)
+
+	rate, ok := rates.Rate("EUR", "USD")
+	assert.True(t, ok)
+	assert.Equal(t, "1.08", rate.String())
+
+	rate, ok = rates.Rate("USD", "JPY")
+	assert.True(t, ok)
+	assert.Equal(t, "151.234", rate.String())
+
+	_, ok = rates.Rate("JPY", "USD")
+	assert.False(t, ok, "rates are not inverted")
+
+	empty, err := ParseFXRates("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, invalid := range []string{
+		"EUR/USD", "EURUSD=1.08", "eur/USD=1.08", "EUR/usd=1.08", "EUR/EUR=1",
+		"EUR/USD=0", "EUR/USD=-1.08", "EUR/USD=x", "EUR/USD=0.0000000000000000001",
+	} {
+		_, err := ParseFXRates(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyCharset is the shape of idempotency keys clients may send
+type IdempotencyKeyCharset string
+
+const (
+	IdempotencyKeyToken     IdempotencyKeyCharset = "token"     // letters, digits and - _ . : ~ + / =, covering UUIDs, ULIDs and base64
+	IdempotencyKeyUUID      IdempotencyKeyCharset = "uuid"      // a UUID in its canonical 36-character form
+	IdempotencyKeyPrintable IdempotencyKeyCharset = "printable" // any printable ASCII
+)
+
+// idempotencyTokenPattern matches keys in the token charset
+var idempotencyTokenPattern = regexp.MustCompile(`^[A-Za-z0-9._~:+/=-]*$`)
+
+// ParseIdempotencyKeyCharset parses an idempotency key charset name
+func ParseIdempotencyKeyCharset(s string) (IdempotencyKeyCharset, error) {
+	switch charset := IdempotencyKeyCharset(s); charset {
+	case IdempotencyKeyToken, IdempotencyKeyUUID, IdempotencyKeyPrintable:
+		return charset, nil
+	}
+	return "", fmt.Errorf("unknown idempotency key charset %q, expected token, uuid or printable", s)
+}
+
+// IdempotencyKeyFormat restricts the idempotency keys clients may send. A zero
+// MaxLength or empty Charset leaves that aspect unrestricted.
+type IdempotencyKeyFormat struct {
+	MinLength int
+	MaxLength int
+	Charset   IdempotencyKeyCharset
+}
+
+// Check reports why key does not have the format, or nil if it does
+func (f IdempotencyKeyFormat) Check(key string) error {
+	if len(key) < f.MinLength {
+		return fmt.Errorf("idempotency key must be at least %d characters", f.MinLength)
+	}
+	if f.MaxLength > 0 && len(key) > f.MaxLength {
+		return fmt.Errorf("idempotency key must be at most %d characters", f.MaxLength)
+	}
+
+	switch f.Charset {
+	case IdempotencyKeyToken:
+		if !idempotencyTokenPattern.MatchString(key) {
+			return fmt.Errorf("idempotency key may only contain letters, digits and - _ . : ~ + / =")
+		}
+	case IdempotencyKeyUUID:
+		if _, err := uuid.Parse(key); err != nil || len(key) != 36 {
+			return fmt.Errorf("idempotency key must be a UUID")
+		}
+	case IdempotencyKeyPrintable:
+		if !TextCharsetASCII.Allows(key) {
+			return fmt.Errorf("idempotency key may only contain printable ASCII characters")
+		}
+	}
+	return nil
+}
+
+// IdempotencyKeyStatusResponse describes what is stored for an idempotency key
+type IdempotencyKeyStatusResponse struct {
+	Key            string          `json:"key"`
+	Exists         bool            `json:"exists"`
+	Expired        bool            `json:"expired"`
+	ResponseStatus *int            `json:"response_status,omitempty"` // unset while the request is still in progress
+	CreatedAt      *time.Time      `json:"created_at,omitempty"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	ResponseBody   json.RawMessage `json:"response_body,omitempty"` // only included on request
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeyFormat_Check(t *testing.T) {
+	token := IdempotencyKeyFormat{MinLength: 8, MaxLength: 64, Charset: IdempotencyKeyToken}
+	uuidOnly := IdempotencyKeyFormat{MinLength: 1, MaxLength: 255, Charset: IdempotencyKeyUUID}
+	printable := IdempotencyKeyFormat{MinLength: 1, MaxLength: 255, Charset: IdempotencyKeyPrintable}
+
+	tests := []struct {
+		name   string
+		format IdempotencyKeyFormat
+		key    string
+		valid  bool
+	}{
+		{name: "uuid as token", format: token, key: "0b6f1c5e-3f0a-4a52-9d59-8c1c2f1f7a10", valid: true},
+		{name: "base64 token", format: token, key: "dGhpcyBpcyBhIGtleQ==", valid: true},
+		{name: "prefixed token", format: token, key: "order:1234_retry.2", valid: true},
+		{name: "too short", format: token, key: "abc", valid: false},
+		{name: "too long", format: token, key: strings.Repeat("a", 65), valid: false},
+		{name: "request body as key", format: token, key: `{"amount":"10.00"}`, valid: false},
+		{name: "space in token", format: token, key: "order 1234", valid: false},
+		{name: "uuid", format: uuidOnly, key: "0b6f1c5e-3f0a-4a52-9d59-8c1c2f1f7a10", valid: true},
+		{name: "uuid without dashes", format: uuidOnly, key: "0b6f1c5e3f0a4a529d598c1c2f1f7a10", valid: false},
+		{name: "not a uuid", format: uuidOnly, key: "order-1234", valid: false},
+		{name: "printable with space", format: printable, key: "order 1234", valid: true},
+		{name: "printable with newline", format: printable, key: "order\n1234", valid: false},
+		{name: "unrestricted", format: IdempotencyKeyFormat{}, key: "anything at all", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.format.Check(tt.key)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
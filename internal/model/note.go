@@ -0,0 +1,58 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AddNoteRequest represents an operator's note on a transaction, e.g. while handling a
+// dispute about it
+type AddNoteRequest struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+}
+
+// Validate validates the add note request
+func (r *AddNoteRequest) Validate() error {
+	if strings.TrimSpace(r.Author) == "" || len(r.Author) > 255 {
+		return &ValidationError{
+			Field:   "author",
+			Message: "author must be between 1 and 255 characters",
+		}
+	}
+
+	if strings.TrimSpace(r.Body) == "" {
+		return &ValidationError{
+			Field:   "body",
+			Message: "body is required",
+		}
+	}
+	if len(r.Body) > MaxDescriptionLength {
+		return &ValidationError{
+			Field:   "body",
+			Message: "body cannot exceed 1000 characters",
+		}
+	}
+
+	return nil
+}
+
+// TransactionNote is a note appended to a transaction after it was created. Notes are
+// never edited or removed, unlike a transaction's reference and description, which are
+// fixed when it is created.
+type TransactionNote struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	TransactionID uuid.UUID `json:"transaction_id" db:"transaction_id"`
+	Author        string    `json:"author" db:"author"`
+	Body          string    `json:"body" db:"body"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// TransactionWithNotes is a transaction returned with its notes, oldest first, for
+// ?include=notes
+type TransactionWithNotes struct {
+	*Transaction
+	Notes []TransactionNote `json:"notes"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// Page is the envelope of every paginated list: one page of items along with the limit and
+// offset it was fetched with and the total number of items across all pages
+type Page[T any] struct {
+	Data    []T  `json:"data"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	Total   int  `json:"total"`
+	HasMore bool `json:"has_more"` // whether items remain past this page
+}
+
+// NewPage creates a page of data, an empty list rather than null when there are no items
+func NewPage[T any](data []T, limit, offset, total int) *Page[T] {
+	if data == nil {
+		data = []T{}
+	}
+	return &Page[T]{
+		Data:    data,
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		HasMore: offset+len(data) < total,
+	}
+}
@@ -1,11 +1,21 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Error string `json:"error"`
-	Code  string `json:"code"`
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Field     string `json:"field,omitempty"` // the request parameter or field at fault, when there is one
+	RequestID string `json:"request_id,omitempty"`
+	Detail    string `json:"detail,omitempty"` // the underlying error of a 500, with ERROR_VERBOSITY=verbose
+
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"` // the earlier transfer a POSSIBLE_DUPLICATE matched, or the retry of a transaction already retried
+	ResetAt       *time.Time `json:"reset_at,omitempty"`       // when the source of a VELOCITY_EXCEEDED transfer may transfer again
 }
 
 // HealthResponse represents the health check response
@@ -15,6 +25,42 @@ type HealthResponse struct {
 	Version      string            `json:"version"`
 	Database     DatabaseHealth    `json:"database"`
 	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Connections  ConnectionStats   `json:"connections"`
+	InFlight     InFlightStats     `json:"in_flight"`
+	Pool         *PoolStats        `json:"pool,omitempty"` // omitted when there is no database
+}
+
+// PoolStats reports the database connection pool, including how long requests have
+// waited for a connection since startup
+type PoolStats struct {
+	Open            int   `json:"open"`
+	InUse           int   `json:"in_use"`
+	Idle            int   `json:"idle"`
+	MaxOpen         int   `json:"max_open"`
+	WaitCount       int64 `json:"wait_count"`       // connections that had to be waited for
+	WaitDurationMs  int64 `json:"wait_duration_ms"` // total time spent waiting for them
+	AcquireTimeouts int64 `json:"acquire_timeouts"` // requests refused SERVICE_BUSY
+}
+
+// ConnectionStats counts the HTTP connections the server has open
+type ConnectionStats struct {
+	Open   int64 `json:"open"`
+	Active int64 `json:"active"` // connections currently serving a request
+}
+
+// InFlightStats counts the API requests being served against their concurrency limits
+type InFlightStats struct {
+	Writes    int64 `json:"writes"`
+	Reads     int64 `json:"reads"`
+	MaxWrites int   `json:"max_writes,omitempty"` // omitted when unlimited
+	MaxReads  int   `json:"max_reads,omitempty"`
+}
+
+// LivenessResponse represents the liveness check response
+type LivenessResponse struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Version   string    `json:"version"`
 }
 
 // DatabaseHealth represents database connectivity status
@@ -26,10 +72,33 @@ type DatabaseHealth struct {
 
 // Common error codes
 const (
-	ErrCodeValidation     = "VALIDATION_ERROR"
-	ErrCodeNotFound       = "NOT_FOUND"
-	ErrCodeInternalError  = "INTERNAL_ERROR"
-	ErrCodeInsufficientFunds = "INSUFFICIENT_FUNDS"
-	ErrCodeInvalidInput   = "INVALID_INPUT"
-	ErrCodeConflict       = "CONFLICT"
-) 
\ No newline at end of file
+	ErrCodeValidation             = "VALIDATION_ERROR"
+	ErrCodeNotFound               = "NOT_FOUND"
+	ErrCodeInternalError          = "INTERNAL_ERROR"
+	ErrCodeInsufficientFunds      = "INSUFFICIENT_FUNDS"
+	ErrCodeInvalidInput           = "INVALID_INPUT"
+	ErrCodeConflict               = "CONFLICT"
+	ErrCodeAmountOutOfRange       = "AMOUNT_OUT_OF_RANGE"
+	ErrCodePreconditionFailed     = "PRECONDITION_FAILED"
+	ErrCodeAccountClosed          = "ACCOUNT_CLOSED"
+	ErrCodeAccountFrozen          = "ACCOUNT_FROZEN"
+	ErrCodeServiceUnavailable     = "SERVICE_UNAVAILABLE"
+	ErrCodeUnauthorized           = "UNAUTHORIZED"
+	ErrCodeForbidden              = "FORBIDDEN"
+	ErrCodeCurrencyMismatch       = "CURRENCY_MISMATCH"
+	ErrCodeBelowMinBalance        = "BELOW_MIN_BALANCE"
+	ErrCodeAboveMaxBalance        = "ABOVE_MAX_BALANCE"
+	ErrCodeInvalidIdempotencyKey  = "INVALID_IDEMPOTENCY_KEY"
+	ErrCodeInvalidTransition      = "INVALID_TRANSITION"
+	ErrCodeTryAgain               = "TRY_AGAIN"
+	ErrCodeOverloaded             = "OVERLOADED"
+	ErrCodeVersionMismatch        = "VERSION_MISMATCH"
+	ErrCodeReversalExceeded       = "REVERSAL_EXCEEDS_ORIGINAL"
+	ErrCodeTimeout                = "TIMEOUT"
+	ErrCodePossibleDuplicate      = "POSSIBLE_DUPLICATE"
+	ErrCodeServiceBusy            = "SERVICE_BUSY"
+	ErrCodeNotImplemented         = "NOT_IMPLEMENTED"
+	ErrCodeDepositsDisabled       = "DEPOSITS_DISABLED"
+	ErrCodeIdempotencyKeyRequired = "IDEMPOTENCY_KEY_REQUIRED"
+	ErrCodeVelocityExceeded       = "VELOCITY_EXCEEDED"
+)
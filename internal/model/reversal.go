@@ -0,0 +1,72 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// MaxReversalsByReference is the most transactions one reverse-by-reference call will
+// process, since they are all reversed in a single database transaction
+const MaxReversalsByReference = 1000
+
+// ReverseTransactionRequest represents a request to reverse a transaction, in full or in part
+type ReverseTransactionRequest struct {
+	Amount *decimal.Decimal `json:"amount,omitempty"` // part of the original amount to reverse; omitted reverses what is left
+}
+
+// Validate validates the reverse-transaction request
+func (r *ReverseTransactionRequest) Validate() error {
+	if r.Amount != nil && !r.Amount.IsPositive() {
+		return &ValidationError{
+			Field:   "amount",
+			Message: "amount must be positive",
+		}
+	}
+	return nil
+}
+
+// ReversalOutcome is what became of one transaction of a reverse-by-reference call
+type ReversalOutcome string
+
+const (
+	ReversalOutcomeReversed ReversalOutcome = "reversed"
+	ReversalOutcomeSkipped  ReversalOutcome = "skipped"
+)
+
+// ReverseByReferenceRequest represents a request to reverse every transaction with a reference
+type ReverseByReferenceRequest struct {
+	Reference string `json:"reference"`
+}
+
+// Validate validates the reverse-by-reference request
+func (r *ReverseByReferenceRequest) Validate() error {
+	if r.Reference == "" {
+		return &ValidationError{
+			Field:   "reference",
+			Message: "reference is required",
+		}
+	}
+	if len(r.Reference) > 255 {
+		return &ValidationError{
+			Field:   "reference",
+			Message: "reference cannot exceed 255 characters",
+		}
+	}
+	return nil
+}
+
+// ReversalResult is the outcome for one transaction with the reference
+type ReversalResult struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	Outcome       ReversalOutcome `json:"outcome"`
+	ReversalID    *uuid.UUID      `json:"reversal_id,omitempty"` // the reversing transaction, once reversed
+	Reason        string          `json:"reason,omitempty"`      // why the transaction was skipped
+}
+
+// ReverseByReferenceResponse lists the transactions with the reference, oldest first
+type ReverseByReferenceResponse struct {
+	Reference string           `json:"reference"`
+	Reversed  int              `json:"reversed"`
+	Skipped   int              `json:"skipped"`
+	Results   []ReversalResult `json:"results"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// AmountScale is the number of decimal places stored for every amount and balance
+const AmountScale = 10
+
+// MaxIntegerDigits is the most digits before the decimal point an amount or balance
+// stored as NUMERIC(38,10) can have
+const MaxIntegerDigits = 38 - AmountScale
+
+// RoundingMode is the policy used when an amount is quantized to a fixed scale
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half-up"   // ties round away from zero
+	RoundHalfEven RoundingMode = "half-even" // ties round to the even digit (banker's rounding)
+	RoundFloor    RoundingMode = "floor"     // always round towards negative infinity
+)
+
+// ParseRoundingMode parses a rounding mode name
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch mode := RoundingMode(s); mode {
+	case RoundHalfUp, RoundHalfEven, RoundFloor:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q, expected half-up, half-even or floor", s)
+}
+
+// ExcessPrecision is what happens to a transfer amount with more decimal places than its
+// currency allows
+type ExcessPrecision string
+
+const (
+	ExcessPrecisionReject ExcessPrecision = "reject" // fail the transfer with a validation error
+	ExcessPrecisionRound  ExcessPrecision = "round"  // quantize the amount with the rounding mode
+)
+
+// ParseExcessPrecision parses an excess precision policy name
+func ParseExcessPrecision(s string) (ExcessPrecision, error) {
+	switch policy := ExcessPrecision(s); policy {
+	case ExcessPrecisionReject, ExcessPrecisionRound:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown excess precision policy %q, expected reject or round", s)
+}
+
+// Round quantizes d to scale decimal places using the rounding mode
+func (m RoundingMode) Round(d decimal.Decimal, scale int32) decimal.Decimal {
+	switch m {
+	case RoundHalfEven:
+		return d.RoundBank(scale)
+	case RoundFloor:
+		return d.RoundFloor(scale)
+	default:
+		return d.Round(scale)
+	}
+}
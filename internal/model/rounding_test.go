@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundingMode_Round(t *testing.T) {
+	tests := []struct {
+		value    string
+		mode     RoundingMode
+		expected string
+	}{
+		{value: "2.675", mode: RoundHalfUp, expected: "2.68"},
+		{value: "2.675", mode: RoundHalfEven, expected: "2.68"},
+		{value: "2.675", mode: RoundFloor, expected: "2.67"},
+		{value: "2.665", mode: RoundHalfUp, expected: "2.67"},
+		{value: "2.665", mode: RoundHalfEven, expected: "2.66"},
+		{value: "2.665", mode: RoundFloor, expected: "2.66"},
+		{value: "-2.675", mode: RoundHalfUp, expected: "-2.68"},
+		{value: "-2.675", mode: RoundHalfEven, expected: "-2.68"},
+		{value: "-2.671", mode: RoundFloor, expected: "-2.68"},
+		{value: "2.6", mode: RoundFloor, expected: "2.6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+" "+tt.value, func(t *testing.T) {
+			rounded := tt.mode.Round(decimal.RequireFromString(tt.value), 2)
+
+			assert.True(t, rounded.Equal(decimal.RequireFromString(tt.expected)), "got %s, want %s", rounded, tt.expected)
+		})
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	for _, name := range []string{"half-up", "half-even", "floor"} {
+		mode, err := ParseRoundingMode(name)
+		assert.NoError(t, err)
+		assert.Equal(t, RoundingMode(name), mode)
+	}
+
+	_, err := ParseRoundingMode("ceiling")
+	assert.Error(t, err)
+}
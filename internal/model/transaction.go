@@ -2,6 +2,8 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,14 +12,49 @@ import (
 
 // Transaction represents a money transfer between accounts
 type Transaction struct {
-	ID                   uuid.UUID        `json:"id" db:"id"`
-	SourceAccountID      *uuid.UUID       `json:"source_account_id" db:"source_account_id"`
-	DestinationAccountID uuid.UUID        `json:"destination_account_id" db:"destination_account_id"`
-	Amount               decimal.Decimal  `json:"amount" db:"amount"`
-	Reference            *string          `json:"reference,omitempty" db:"reference"`
+	ID                   uuid.UUID         `json:"id" db:"id"`
+	SourceAccountID      *uuid.UUID        `json:"source_account_id" db:"source_account_id"`
+	DestinationAccountID *uuid.UUID        `json:"destination_account_id" db:"destination_account_id"`
+	Amount               decimal.Decimal   `json:"amount" db:"amount"`
+	Reference            *string           `json:"reference,omitempty" db:"reference"`
+	Description          *string           `json:"description,omitempty" db:"description"`
+	Fee                  *decimal.Decimal  `json:"fee,omitempty" db:"fee"`
+	FeeAccountID         *uuid.UUID        `json:"fee_account_id,omitempty" db:"fee_account_id"`
+	DestinationAmount    *decimal.Decimal  `json:"destination_amount,omitempty" db:"destination_amount"` // credited amount of a cross-currency transfer
+	FXRate               *decimal.Decimal  `json:"fx_rate,omitempty" db:"fx_rate"`                       // rate a cross-currency transfer converted at
+	ReversalOf           *uuid.UUID        `json:"reversal_of,omitempty" db:"reversal_of"`               // transaction this one reverses
+	ReversedBy           *uuid.UUID        `json:"reversed_by,omitempty" db:"reversed_by"`               // transaction that reversed this one
+	RetryOf              *uuid.UUID        `json:"retry_of,omitempty" db:"retry_of"`                     // failed transaction this one retries
 	Status               TransactionStatus `json:"status" db:"status"`
-	CreatedAt            time.Time        `json:"created_at" db:"created_at"`
-	CompletedAt          *time.Time       `json:"completed_at,omitempty" db:"completed_at"`
+	FailureReason        *string           `json:"failure_reason,omitempty" db:"failure_reason"`
+	Channel              *string           `json:"-" db:"channel"`   // admin only, see AdminTransaction
+	ClientIP             *string           `json:"-" db:"client_ip"` // admin only, see AdminTransaction
+	CreatedAt            time.Time         `json:"created_at" db:"created_at"`
+	CompletedAt          *time.Time        `json:"completed_at,omitempty" db:"completed_at"`
+	Disputed             bool              `json:"disputed" db:"disputed"`                                 // flagged for review, see DisputeTransactionRequest
+	DisputeReason        *string           `json:"dispute_reason,omitempty" db:"dispute_reason"`           // why the latest dispute was raised
+	DisputedAt           *time.Time        `json:"disputed_at,omitempty" db:"disputed_at"`                 // when the latest dispute was raised
+	DisputeResolvedAt    *time.Time        `json:"dispute_resolved_at,omitempty" db:"dispute_resolved_at"` // when the latest dispute was resolved
+}
+
+// CreditedAmount returns the amount the destination account received, in its currency
+func (t *Transaction) CreditedAmount() decimal.Decimal {
+	if t.DestinationAmount != nil {
+		return *t.DestinationAmount
+	}
+	return t.Amount
+}
+
+// AdminTransaction is a transaction as admins see it, including where it came from
+type AdminTransaction struct {
+	*Transaction
+	Channel  *string `json:"channel,omitempty"`
+	ClientIP *string `json:"client_ip,omitempty"`
+}
+
+// NewAdminTransaction wraps a transaction for an admin response
+func NewAdminTransaction(t *Transaction) *AdminTransaction {
+	return &AdminTransaction{Transaction: t, Channel: t.Channel, ClientIP: t.ClientIP}
 }
 
 // TransactionStatus represents the status of a transaction
@@ -31,64 +68,352 @@ const (
 
 // CreateTransactionRequest represents the request to create a transfer
 type CreateTransactionRequest struct {
-	SourceAccountID      *uuid.UUID      `json:"source_account_id,omitempty"`
-	DestinationAccountID uuid.UUID       `json:"destination_account_id"`
-	Amount               decimal.Decimal `json:"amount"`
-	Reference            *string         `json:"reference,omitempty"`
+	ID                   *uuid.UUID       `json:"id,omitempty"`
+	SourceAccountID      *uuid.UUID       `json:"source_account_id,omitempty"`
+	DestinationAccountID *uuid.UUID       `json:"destination_account_id,omitempty"`
+	Amount               decimal.Decimal  `json:"amount"`
+	Reference            *string          `json:"reference,omitempty"`
+	Description          *string          `json:"description,omitempty"`
+	Fee                  *decimal.Decimal `json:"fee,omitempty"`
+	FeeAccountID         *uuid.UUID       `json:"fee_account_id,omitempty"`
+	// ExpectedSourceBalance aborts the transfer unless the locked source balance equals it
+	ExpectedSourceBalance *decimal.Decimal `json:"expected_source_balance,omitempty"`
+	// ExpectedSourceVersion, set from the If-Match header, aborts the transfer unless the
+	// locked source account is still at that version
+	ExpectedSourceVersion *int64 `json:"-"`
+	// IdempotencyKey lets a retried transfer be replayed instead of re-executed; the
+	// Idempotency-Key header sets it on a single transfer
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+	// Sweep transfers the source's whole available balance, less any fee, instead of Amount
+	Sweep bool `json:"sweep,omitempty"`
+	// Channel names the client surface the transfer was made from, e.g. web, mobile or api
+	Channel *string `json:"channel,omitempty"`
+	// AllowDuplicate skips the check for a recent transfer with the same accounts and amount
+	AllowDuplicate bool `json:"allow_duplicate,omitempty"`
+	// ClientIP is the caller's address, filled in by the handler rather than the client
+	ClientIP *string `json:"-"`
+	// DestinationAmount and FXRate are filled in by the service for a cross-currency transfer
+	DestinationAmount *decimal.Decimal `json:"-"`
+	FXRate            *decimal.Decimal `json:"-"`
+	// ReversalOf is set by the service on the transfer that reverses another
+	ReversalOf *uuid.UUID `json:"-"`
+	// RetryOf is set by the service on the transfer that retries a failed one
+	RetryOf *uuid.UUID `json:"-"`
+	// Accrual is set by the interest job on the interest it credits, which is allowed
+	// even where deposits are disabled
+	Accrual bool `json:"-"`
 }
 
 // UnmarshalJSON implements custom JSON unmarshaling for CreateTransactionRequest
 func (r *CreateTransactionRequest) UnmarshalJSON(data []byte) error {
 	// Define a temporary struct with string types for JSON parsing
 	var temp struct {
-		SourceAccountID      *string `json:"source_account_id,omitempty"`
-		DestinationAccountID string  `json:"destination_account_id"`
-		Amount               string  `json:"amount"`
-		Reference            *string `json:"reference,omitempty"`
+		ID                    *string         `json:"id,omitempty"`
+		SourceAccountID       *string         `json:"source_account_id,omitempty"`
+		DestinationAccountID  string          `json:"destination_account_id"`
+		Amount                json.RawMessage `json:"amount"`
+		Reference             *string         `json:"reference,omitempty"`
+		Description           *string         `json:"description,omitempty"`
+		Fee                   json.RawMessage `json:"fee,omitempty"`
+		FeeAccountID          *string         `json:"fee_account_id,omitempty"`
+		ExpectedSourceBalance json.RawMessage `json:"expected_source_balance,omitempty"`
+		IdempotencyKey        *string         `json:"idempotency_key,omitempty"`
+		Sweep                 bool            `json:"sweep,omitempty"`
+		Channel               *string         `json:"channel,omitempty"`
+		AllowDuplicate        bool            `json:"allow_duplicate,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &temp); err != nil {
 		return err
 	}
 
-	// Parse destination account ID (required)
-	destID, err := uuid.Parse(temp.DestinationAccountID)
-	if err != nil {
-		return err
+	// Values that don't parse are reported as a ValidationError naming the field
+
+	// Parse destination account ID, which a withdrawal leaves out
+	if temp.DestinationAccountID != "" {
+		destID, err := parseUUIDField("destination_account_id", temp.DestinationAccountID)
+		if err != nil {
+			return err
+		}
+		r.DestinationAccountID = &destID
+	}
+
+	// Parse client-supplied transaction ID (optional)
+	if temp.ID != nil {
+		id, err := parseUUIDField("id", *temp.ID)
+		if err != nil {
+			return err
+		}
+		r.ID = &id
 	}
-	r.DestinationAccountID = destID
 
 	// Parse source account ID (optional)
 	if temp.SourceAccountID != nil {
-		sourceID, err := uuid.Parse(*temp.SourceAccountID)
+		sourceID, err := parseUUIDField("source_account_id", *temp.SourceAccountID)
 		if err != nil {
 			return err
 		}
 		r.SourceAccountID = &sourceID
 	}
 
-	// Parse amount
-	amount, err := decimal.NewFromString(temp.Amount)
-	if err != nil {
-		return err
+	// Parse amount, which a sweep leaves out
+	r.Sweep = temp.Sweep
+	amountGiven := len(temp.Amount) > 0 && string(temp.Amount) != "null" && string(temp.Amount) != `""`
+	if amountGiven || !temp.Sweep {
+		amount, err := parseJSONDecimal("amount", temp.Amount)
+		if err != nil {
+			return &ValidationError{Field: "amount", Message: err.Error()}
+		}
+		r.Amount = amount
 	}
-	r.Amount = amount
 
-	// Copy reference
+	// Copy free-text fields, idempotency key and channel
 	r.Reference = temp.Reference
+	r.Description = temp.Description
+	r.IdempotencyKey = temp.IdempotencyKey
+	r.Channel = temp.Channel
+	r.AllowDuplicate = temp.AllowDuplicate
+
+	// Parse fee and fee account (optional)
+	if jsonValueGiven(temp.Fee) {
+		fee, err := parseJSONDecimal("fee", temp.Fee)
+		if err != nil {
+			return &ValidationError{Field: "fee", Message: err.Error()}
+		}
+		r.Fee = &fee
+	}
+
+	if temp.FeeAccountID != nil {
+		feeAccountID, err := parseUUIDField("fee_account_id", *temp.FeeAccountID)
+		if err != nil {
+			return err
+		}
+		r.FeeAccountID = &feeAccountID
+	}
+
+	// Parse expected source balance (optional)
+	if jsonValueGiven(temp.ExpectedSourceBalance) {
+		expected, err := parseJSONDecimal("expected_source_balance", temp.ExpectedSourceBalance)
+		if err != nil {
+			return &ValidationError{Field: "expected_source_balance", Message: err.Error()}
+		}
+		r.ExpectedSourceBalance = &expected
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the request with its amounts as strings whatever the amount format.
+// The request is stored for batches and idempotency keys and read back with UnmarshalJSON,
+// so its encoding mustn't change with AMOUNT_FORMAT or lose digits a number can't carry.
+func (r CreateTransactionRequest) MarshalJSON() ([]byte, error) {
+	type plain CreateTransactionRequest
+	stored := struct {
+		plain
+		Amount                string  `json:"amount"`
+		Fee                   *string `json:"fee,omitempty"`
+		ExpectedSourceBalance *string `json:"expected_source_balance,omitempty"`
+	}{plain: plain(r), Amount: r.Amount.String()}
+	if r.Fee != nil {
+		fee := r.Fee.String()
+		stored.Fee = &fee
+	}
+	if r.ExpectedSourceBalance != nil {
+		expected := r.ExpectedSourceBalance.String()
+		stored.ExpectedSourceBalance = &expected
+	}
+	return json.Marshal(stored)
+}
+
+// jsonValueGiven reports whether an optional field was sent with a value other than null
+func jsonValueGiven(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
+
+// maxEchoedValueLength bounds how much of a malformed field an error echoes back, as
+// errors about path parameters do
+const maxEchoedValueLength = 40
+
+// echoed cuts a malformed field to the length an error may echo back
+func echoed(value string) string {
+	if len(value) > maxEchoedValueLength {
+		return value[:maxEchoedValueLength] + "..."
+	}
+	return value
+}
+
+// parseUUIDField parses the UUID sent in a request field
+func parseUUIDField(field, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("%s %q is not a valid UUID: %s", field, echoed(value), err),
+		}
+	}
+	return id, nil
+}
+
+// AmountFormat is how amounts are written in JSON responses
+type AmountFormat string
+
+const (
+	AmountFormatString AmountFormat = "string" // "12.5", exact in every client
+	AmountFormatNumber AmountFormat = "number" // 12.5, which clients decoding into floats may round
+)
+
+// ParseAmountFormat parses an amount format name
+func ParseAmountFormat(s string) (AmountFormat, error) {
+	switch format := AmountFormat(s); format {
+	case AmountFormatString, AmountFormatNumber:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown amount format %q, expected string or number", s)
+}
+
+// SetAmountFormat makes every amount encoded from now on use format. Amounts are
+// decimal.Decimal throughout, whose encoding is process-wide, so it is set once at startup.
+// Requests take amounts in either format regardless.
+func SetAmountFormat(format AmountFormat) {
+	decimal.MarshalJSONWithoutQuotes = format == AmountFormatNumber
+}
+
+// maxExactNumberDigits is the most significant digits a float64 is guaranteed to carry
+// exactly, so a JSON number with more may have been rounded by the client's encoder
+const maxExactNumberDigits = 15
+
+// parseJSONDecimal parses the named amount sent either as a JSON string or as a JSON number.
+// Numbers are read as written rather than through float64, and ones with more
+// significant digits than a float64 holds are rejected rather than trusted.
+func parseJSONDecimal(name string, raw json.RawMessage) (decimal.Decimal, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return decimal.Zero, err
+		}
+		amount, err := decimal.NewFromString(text)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("%s %q is not a valid decimal number", name, echoed(text))
+		}
+		return amount, nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err != nil || number == "" {
+		return decimal.Zero, fmt.Errorf("%s must be a string or a number", name)
+	}
+	amount, err := decimal.NewFromString(number.String())
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%s %s is not a valid decimal number", name, number)
+	}
+	coefficient := amount.Coefficient()
+	if len(coefficient.Abs(coefficient).String()) > maxExactNumberDigits {
+		return decimal.Zero, fmt.Errorf("%s %s has more than %d significant digits, send it as a string", name, number, maxExactNumberDigits)
+	}
+	return amount, nil
+}
+
+// channelPattern matches channel names such as web, mobile or partner-api
+var channelPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// MaxDescriptionLength is the longest description a transaction can carry, in bytes
+const MaxDescriptionLength = 1000
 
+// ValidateCharset checks the request's free-text fields contain only characters in charset
+func (r *CreateTransactionRequest) ValidateCharset(charset TextCharset) error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"reference", r.Reference},
+		{"description", r.Description},
+	}
+	for _, field := range fields {
+		if field.value != nil && !charset.Allows(*field.value) {
+			return &ValidationError{
+				Field:   field.name,
+				Message: fmt.Sprintf("%s can only contain %s", field.name, charset.describe()),
+			}
+		}
+	}
 	return nil
 }
 
+// IsWithdrawal reports whether the request moves money out of the system, having no
+// destination account
+func (r *CreateTransactionRequest) IsWithdrawal() bool {
+	return r.DestinationAccountID == nil
+}
+
+// HasFee reports whether the request charges a non-zero fee
+func (r *CreateTransactionRequest) HasFee() bool {
+	return r.Fee != nil && !r.Fee.IsZero()
+}
+
+// TotalDebit returns the amount debited from the source account, including any fee
+func (r *CreateTransactionRequest) TotalDebit() decimal.Decimal {
+	if r.Fee == nil {
+		return r.Amount
+	}
+	return r.Amount.Add(*r.Fee)
+}
+
 // CreateTransactionResponse represents the response after creating a transaction
 type CreateTransactionResponse struct {
-	ID                   uuid.UUID        `json:"id"`
-	SourceAccountID      *uuid.UUID       `json:"source_account_id"`
-	DestinationAccountID uuid.UUID        `json:"destination_account_id"`
-	Amount               decimal.Decimal  `json:"amount"`
-	Reference            *string          `json:"reference,omitempty"`
+	ID                   uuid.UUID         `json:"id"`
+	SourceAccountID      *uuid.UUID        `json:"source_account_id"`
+	DestinationAccountID *uuid.UUID        `json:"destination_account_id"`
+	Amount               decimal.Decimal   `json:"amount"`
+	Reference            *string           `json:"reference,omitempty"`
+	Description          *string           `json:"description,omitempty"`
+	Fee                  *decimal.Decimal  `json:"fee,omitempty"`
+	FeeAccountID         *uuid.UUID        `json:"fee_account_id,omitempty"`
+	DestinationAmount    *decimal.Decimal  `json:"destination_amount,omitempty"`
+	FXRate               *decimal.Decimal  `json:"fx_rate,omitempty"`
+	ReversalOf           *uuid.UUID        `json:"reversal_of,omitempty"`
+	ReversedBy           *uuid.UUID        `json:"reversed_by,omitempty"`
+	RetryOf              *uuid.UUID        `json:"retry_of,omitempty"`
 	Status               TransactionStatus `json:"status"`
-	CreatedAt            time.Time        `json:"created_at"`
+	CreatedAt            time.Time         `json:"created_at"`
+}
+
+// TransactionStatusResponse is a transaction's current status and timestamps, for clients
+// polling a transfer without fetching all of it
+type TransactionStatusResponse struct {
+	ID          uuid.UUID         `json:"id"`
+	Status      TransactionStatus `json:"status"`
+	CreatedAt   time.Time         `json:"created_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
+
+// Matches reports whether an existing transaction was created from the same parameters as the request
+func (r *CreateTransactionRequest) Matches(t *Transaction) bool {
+	if (r.SourceAccountID == nil) != (t.SourceAccountID == nil) {
+		return false
+	}
+	if r.SourceAccountID != nil && *r.SourceAccountID != *t.SourceAccountID {
+		return false
+	}
+	if (r.Reference == nil) != (t.Reference == nil) {
+		return false
+	}
+	if r.Reference != nil && *r.Reference != *t.Reference {
+		return false
+	}
+	if r.HasFee() != (t.Fee != nil && !t.Fee.IsZero()) {
+		return false
+	}
+	if r.HasFee() && (!r.Fee.Equal(*t.Fee) || t.FeeAccountID == nil || *r.FeeAccountID != *t.FeeAccountID) {
+		return false
+	}
+	if (r.DestinationAccountID == nil) != (t.DestinationAccountID == nil) {
+		return false
+	}
+	if r.DestinationAccountID != nil && *r.DestinationAccountID != *t.DestinationAccountID {
+		return false
+	}
+	// A sweep's amount is only known once the source balance is read
+	return r.Sweep || r.Amount.Equal(t.Amount)
 }
 
 // BulkTransferRequest represents a request for multiple transfers
@@ -96,29 +421,65 @@ type BulkTransferRequest struct {
 	Transfers []CreateTransactionRequest `json:"transfers"`
 }
 
-// BulkTransferResponse represents the response for bulk transfers
+// BulkTransferResponse represents the response for bulk transfers.
+// Transfers and Failed are both in the order of the original request.
 type BulkTransferResponse struct {
 	Transfers []CreateTransactionResponse `json:"transfers"`
 	Failed    []TransferError             `json:"failed,omitempty"`
+	Summary   *BulkTransferSummary        `json:"summary,omitempty"`
+}
+
+// BulkTransferSummary counts the outcomes of a bulk transfer
+type BulkTransferSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
 }
 
 // TransferError represents an error in a bulk transfer
 type TransferError struct {
-	Index   int    `json:"index"`
-	Error   string `json:"error"`
-	Code    string `json:"code"`
+	Index int    `json:"index"`
+	Error string `json:"error"`
+	Code  string `json:"code"`
 }
 
 // Validate validates the create transaction request
 func (r *CreateTransactionRequest) Validate() error {
-	if r.Amount.IsZero() || r.Amount.IsNegative() {
+	if r.ID != nil && *r.ID == uuid.Nil {
+		return &ValidationError{
+			Field:   "id",
+			Message: "id cannot be the nil UUID",
+		}
+	}
+
+	if r.Sweep {
+		if !r.Amount.IsZero() {
+			return &ValidationError{
+				Field:   "amount",
+				Message: "amount cannot be set on a sweep",
+			}
+		}
+		if r.SourceAccountID == nil {
+			return &ValidationError{
+				Field:   "sweep",
+				Message: "sweep requires a source account",
+			}
+		}
+	} else if r.Amount.IsZero() || r.Amount.IsNegative() {
 		return &ValidationError{
 			Field:   "amount",
 			Message: "amount must be positive",
 		}
 	}
 
-	if r.SourceAccountID != nil && *r.SourceAccountID == r.DestinationAccountID {
+	if r.SourceAccountID == nil && r.DestinationAccountID == nil {
+		return &ValidationError{
+			Field:   "destination_account_id",
+			Message: "source_account_id or destination_account_id is required",
+		}
+	}
+
+	if r.SourceAccountID != nil && r.DestinationAccountID != nil && *r.SourceAccountID == *r.DestinationAccountID {
 		return &ValidationError{
 			Field:   "destination_account_id",
 			Message: "source and destination accounts cannot be the same",
@@ -132,6 +493,88 @@ func (r *CreateTransactionRequest) Validate() error {
 		}
 	}
 
+	if r.Description != nil && len(*r.Description) > MaxDescriptionLength {
+		return &ValidationError{
+			Field:   "description",
+			Message: fmt.Sprintf("description cannot exceed %d characters", MaxDescriptionLength),
+		}
+	}
+
+	if r.Channel != nil && !channelPattern.MatchString(*r.Channel) {
+		return &ValidationError{
+			Field:   "channel",
+			Message: "channel must be a lowercase name of at most 32 characters, such as web, mobile or api",
+		}
+	}
+
+	// Control characters are rejected whatever the configured charset
+	if err := r.ValidateCharset(TextCharsetUnicode); err != nil {
+		return err
+	}
+
+	if r.Fee != nil && r.Fee.IsNegative() {
+		return &ValidationError{
+			Field:   "fee",
+			Message: "fee cannot be negative",
+		}
+	}
+
+	if r.HasFee() && r.FeeAccountID == nil {
+		return &ValidationError{
+			Field:   "fee_account_id",
+			Message: "fee_account_id is required when a fee is charged",
+		}
+	}
+
+	if r.FeeAccountID != nil && r.Fee == nil {
+		return &ValidationError{
+			Field:   "fee",
+			Message: "fee is required when fee_account_id is set",
+		}
+	}
+
+	if r.FeeAccountID != nil && !r.HasFee() {
+		return &ValidationError{
+			Field:   "fee",
+			Message: "fee must be positive when fee_account_id is set",
+		}
+	}
+
+	if r.HasFee() && r.SourceAccountID == nil {
+		return &ValidationError{
+			Field:   "fee",
+			Message: "fees can only be charged on transfers with a source account",
+		}
+	}
+
+	if r.ExpectedSourceBalance != nil && r.SourceAccountID == nil {
+		return &ValidationError{
+			Field:   "expected_source_balance",
+			Message: "expected_source_balance requires a source account",
+		}
+	}
+
+	if r.ExpectedSourceVersion != nil && r.SourceAccountID == nil {
+		return &ValidationError{
+			Field:   "If-Match",
+			Message: "If-Match requires a source account",
+		}
+	}
+
+	if r.FeeAccountID != nil && r.SourceAccountID != nil && *r.FeeAccountID == *r.SourceAccountID {
+		return &ValidationError{
+			Field:   "fee_account_id",
+			Message: "fee account cannot be the same as the source account",
+		}
+	}
+
+	if r.IdempotencyKey != nil && (*r.IdempotencyKey == "" || len(*r.IdempotencyKey) > 255) {
+		return &ValidationError{
+			Field:   "idempotency_key",
+			Message: "idempotency_key must be between 1 and 255 characters",
+		}
+	}
+
 	return nil
 }
 
@@ -163,5 +606,105 @@ func (r *BulkTransferRequest) Validate() error {
 		}
 	}
 
+	seenKeys := make(map[string]int)
+	for i, transfer := range r.Transfers {
+		if transfer.IdempotencyKey == nil {
+			continue
+		}
+		if first, ok := seenKeys[*transfer.IdempotencyKey]; ok {
+			return &ValidationError{
+				Field:   fmt.Sprintf("transfers[%d].idempotency_key", i),
+				Message: fmt.Sprintf("idempotency_key is already used by transfers[%d]", first),
+			}
+		}
+		seenKeys[*transfer.IdempotencyKey] = i
+	}
+
+	return nil
+}
+
+// TransactionFilter narrows an account's transaction history
+type TransactionFilter struct {
+	MinAmount    *decimal.Decimal // inclusive lower bound on amount
+	MaxAmount    *decimal.Decimal // inclusive upper bound on amount
+	Counterparty *uuid.UUID       // the other side of the transfer relative to the account
+	Disputed     *bool            // only transactions with an open dispute, or only those without
+}
+
+// Validate validates the transaction filter
+func (f *TransactionFilter) Validate() error {
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.GreaterThan(*f.MaxAmount) {
+		return &ValidationError{
+			Field:   "min_amount",
+			Message: "min_amount cannot be greater than max_amount",
+		}
+	}
+	return nil
+}
+
+// CounterpartyFilter bounds the transfers summarised per counterparty
+type CounterpartyFilter struct {
+	From  *time.Time // inclusive lower bound on created_at
+	To    *time.Time // exclusive upper bound on created_at
+	Limit int        // most counterparties returned, by transfer volume
+}
+
+// Validate validates the counterparty filter
+func (f *CounterpartyFilter) Validate() error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return &ValidationError{
+			Field:   "from",
+			Message: "from must be before to",
+		}
+	}
+	return nil
+}
+
+// Counterparty summarises the completed transfers between an account and one other account
+type Counterparty struct {
+	AccountID        uuid.UUID       `json:"account_id"`
+	Sent             decimal.Decimal `json:"sent"`     // total transferred to the counterparty
+	Received         decimal.Decimal `json:"received"` // total received from the counterparty
+	NetFlow          decimal.Decimal `json:"net_flow"` // sent minus received
+	TransactionCount int             `json:"transaction_count"`
+}
+
+// CounterpartiesResponse lists an account's counterparties, largest transfer volume first
+type CounterpartiesResponse struct {
+	AccountID      uuid.UUID      `json:"account_id"`
+	Counterparties []Counterparty `json:"counterparties"`
+}
+
+// MaxTransactionQueryIDs is the most transactions one bulk lookup may ask for
+const MaxTransactionQueryIDs = 100
+
+// GetTransactionsRequest represents a request for several transactions by ID
+type GetTransactionsRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// Validate validates the get transactions request
+func (r *GetTransactionsRequest) Validate() error {
+	if len(r.IDs) == 0 {
+		return &ValidationError{
+			Field:   "ids",
+			Message: "at least one transaction ID is required",
+		}
+	}
+
+	if len(r.IDs) > MaxTransactionQueryIDs {
+		return &ValidationError{
+			Field:   "ids",
+			Message: fmt.Sprintf("cannot query more than %d transactions at once", MaxTransactionQueryIDs),
+		}
+	}
+
 	return nil
-} 
\ No newline at end of file
+}
+
+// GetTransactionsResponse lists the requested transactions found, in request order, and
+// the requested IDs that don't exist
+type GetTransactionsResponse struct {
+	Transactions []*Transaction `json:"transactions"`
+	NotFound     []uuid.UUID    `json:"not_found"`
+}
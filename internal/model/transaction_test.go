@@ -0,0 +1,190 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransactionRequest_UnmarshalJSON_Amount(t *testing.T) {
+	const destination = `"destination_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11"`
+
+	tests := []struct {
+		name     string
+		amount   string
+		expected string
+		valid    bool
+	}{
+		{name: "string", amount: `"100.50"`, expected: "100.5", valid: true},
+		{name: "decimal number", amount: `100.50`, expected: "100.5", valid: true},
+		{name: "integer number", amount: `100`, expected: "100", valid: true},
+		{name: "exponent number", amount: `1.5e2`, expected: "150", valid: true},
+		{name: "number with float rounding noise", amount: `0.30000000000000004`, valid: false},
+		{name: "number beyond float precision", amount: `12345678901234567`, valid: false},
+		{name: "long amount as string", amount: `"12345678901234567.0123456789"`, expected: "12345678901234567.0123456789", valid: true},
+		{name: "boolean", amount: `true`, valid: false},
+		{name: "missing", amount: `null`, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req CreateTransactionRequest
+			err := json.Unmarshal([]byte(`{`+destination+`,"amount":`+tt.amount+`}`), &req)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, req.Amount.String())
+		})
+	}
+
+	t.Run("sweep without amount", func(t *testing.T) {
+		var req CreateTransactionRequest
+		require.NoError(t, json.Unmarshal([]byte(`{`+destination+`,"sweep":true}`), &req))
+		assert.True(t, req.Amount.IsZero())
+	})
+}
+
+func TestCreateTransactionRequest_UnmarshalJSON_Withdrawal(t *testing.T) {
+	var req CreateTransactionRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"source_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11","amount":"10"}`), &req))
+	assert.Nil(t, req.DestinationAccountID)
+	assert.True(t, req.IsWithdrawal())
+	assert.NoError(t, req.Validate())
+
+	req = CreateTransactionRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"10"}`), &req))
+	err := req.Validate()
+	if assert.Error(t, err) {
+		assert.Equal(t, "destination_account_id", err.(*ValidationError).Field)
+	}
+}
+
+func TestCreateTransactionRequest_MarshalJSON(t *testing.T) {
+	// A stored request reads back the same whatever the amount format
+	for _, format := range []AmountFormat{AmountFormatString, AmountFormatNumber} {
+		t.Run(string(format), func(t *testing.T) {
+			SetAmountFormat(format)
+			defer SetAmountFormat(AmountFormatString)
+
+			fee := decimal.RequireFromString("0.25")
+			expected := decimal.RequireFromString("1234567890123456789.5")
+			req := CreateTransactionRequest{
+				Amount:                decimal.RequireFromString("12345678901234567.0123456789"),
+				Fee:                   &fee,
+				ExpectedSourceBalance: &expected,
+			}
+			encoded, err := json.Marshal(&req)
+			require.NoError(t, err)
+			assert.Contains(t, string(encoded), `"amount":"12345678901234567.0123456789"`)
+			assert.Contains(t, string(encoded), `"fee":"0.25"`)
+
+			var decoded CreateTransactionRequest
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			assert.Equal(t, req.Amount.String(), decoded.Amount.String())
+			assert.Equal(t, "0.25", decoded.Fee.String())
+			assert.Equal(t, expected.String(), decoded.ExpectedSourceBalance.String())
+		})
+	}
+}
+
+func TestCreateTransactionRequest_UnmarshalJSON_NumberFields(t *testing.T) {
+	const accounts = `"source_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11","destination_account_id":"363686ca-7c2d-4ce3-a0d4-d904d25637ad"`
+
+	var req CreateTransactionRequest
+	require.NoError(t, json.Unmarshal([]byte(`{`+accounts+`,"amount":10,"fee":0.5,"expected_source_balance":100}`), &req))
+	assert.Equal(t, "0.5", req.Fee.String())
+	assert.Equal(t, "100", req.ExpectedSourceBalance.String())
+
+	req = CreateTransactionRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{`+accounts+`,"amount":"10","fee":null}`), &req))
+	assert.Nil(t, req.Fee)
+
+	err := json.Unmarshal([]byte(`{`+accounts+`,"amount":"10","fee":true}`), &req)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "fee", validationErr.Field)
+}
+
+func TestCreateTransactionRequest_Validate_Fee(t *testing.T) {
+	const accounts = `"source_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11","destination_account_id":"363686ca-7c2d-4ce3-a0d4-d904d25637ad","amount":"10"`
+
+	tests := []struct {
+		name            string
+		fee             string
+		expectedField   string
+		expectedMessage string
+	}{
+		{name: "fee with fee account", fee: `"fee":"1","fee_account_id":"5f0c3b1e-8d2a-4c47-9a61-2b1f0e7d9c33"`},
+		{name: "fee without fee account", fee: `"fee":"1"`, expectedField: "fee_account_id", expectedMessage: "fee_account_id is required when a fee is charged"},
+		{name: "fee account without fee", fee: `"fee_account_id":"5f0c3b1e-8d2a-4c47-9a61-2b1f0e7d9c33"`, expectedField: "fee", expectedMessage: "fee is required when fee_account_id is set"},
+		{name: "zero fee with fee account", fee: `"fee":"0","fee_account_id":"5f0c3b1e-8d2a-4c47-9a61-2b1f0e7d9c33"`, expectedField: "fee", expectedMessage: "fee must be positive when fee_account_id is set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req CreateTransactionRequest
+			require.NoError(t, json.Unmarshal([]byte(`{`+accounts+`,`+tt.fee+`}`), &req))
+			err := req.Validate()
+			if tt.expectedField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.expectedField, validationErr.Field)
+			assert.Equal(t, tt.expectedMessage, validationErr.Message)
+		})
+	}
+}
+
+func TestCreateTransactionRequest_UnmarshalJSON_InvalidField(t *testing.T) {
+	const destination = `"destination_account_id":"82847968-4a3a-4bd6-8a2b-2f1c5e0d9b11"`
+
+	tests := []struct {
+		name            string
+		body            string
+		expectedField   string
+		expectedMessage string
+	}{
+		{name: "non-numeric amount", body: `{` + destination + `,"amount":"ten"}`, expectedField: "amount", expectedMessage: `amount "ten" is not a valid decimal number`},
+		{name: "amount of the wrong type", body: `{` + destination + `,"amount":true}`, expectedField: "amount", expectedMessage: "amount must be a string or a number"},
+		{name: "long non-numeric amount", body: `{` + destination + `,"amount":"` + strings.Repeat("9", 50) + `x"}`, expectedField: "amount", expectedMessage: `amount "` + strings.Repeat("9", 40) + `..." is not a valid decimal number`},
+		{name: "non-numeric fee", body: `{` + destination + `,"amount":"10","fee":"1,5"}`, expectedField: "fee", expectedMessage: `fee "1,5" is not a valid decimal number`},
+		{name: "malformed source", body: `{` + destination + `,"source_account_id":"not-a-uuid","amount":"10"}`, expectedField: "source_account_id", expectedMessage: `source_account_id "not-a-uuid" is not a valid UUID: invalid UUID length: 10`},
+		{name: "malformed destination", body: `{"destination_account_id":"8284796","amount":"10"}`, expectedField: "destination_account_id", expectedMessage: `destination_account_id "8284796" is not a valid UUID: invalid UUID length: 7`},
+		{name: "malformed fee account", body: `{` + destination + `,"amount":"10","fee":"1","fee_account_id":"x"}`, expectedField: "fee_account_id", expectedMessage: `fee_account_id "x" is not a valid UUID: invalid UUID length: 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req CreateTransactionRequest
+			err := json.Unmarshal([]byte(tt.body), &req)
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.expectedField, validationErr.Field)
+			assert.Equal(t, tt.expectedMessage, validationErr.Message)
+		})
+	}
+}
+
+func TestAdminTransaction_MarshalJSON(t *testing.T) {
+	channel, ip := "mobile", "203.0.113.7"
+	transaction := &Transaction{Channel: &channel, ClientIP: &ip, Status: TransactionStatusCompleted}
+
+	client, err := json.Marshal(transaction)
+	require.NoError(t, err)
+	assert.NotContains(t, string(client), "channel")
+	assert.NotContains(t, string(client), ip)
+
+	admin, err := json.Marshal(NewAdminTransaction(transaction))
+	require.NoError(t, err)
+	assert.Contains(t, string(admin), `"channel":"mobile"`)
+	assert.Contains(t, string(admin), `"client_ip":"203.0.113.7"`)
+	assert.Contains(t, string(admin), `"status":"completed"`)
+}
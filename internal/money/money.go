@@ -0,0 +1,63 @@
+// Package money does arithmetic and comparisons on amounts at the fixed scale the
+// ledger stores them at, so that results match what the database would hold and
+// amounts written with different numbers of decimal places compare as equal.
+package money
+
+import (
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+)
+
+// Scale is the number of decimal places every amount and balance is kept at
+const Scale = model.AmountScale
+
+// Quantize rounds an amount to Scale decimal places, half away from zero as Postgres
+// does when storing a NUMERIC, and gives it exactly that scale
+func Quantize(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(Scale)
+}
+
+// Add returns the sum of amounts at Scale
+func Add(amounts ...decimal.Decimal) decimal.Decimal {
+	sum := decimal.Zero
+	for _, amount := range amounts {
+		sum = sum.Add(Quantize(amount))
+	}
+	return Quantize(sum)
+}
+
+// Sub returns a less every amount in b at Scale
+func Sub(a decimal.Decimal, b ...decimal.Decimal) decimal.Decimal {
+	difference := Quantize(a)
+	for _, amount := range b {
+		difference = difference.Sub(Quantize(amount))
+	}
+	return Quantize(difference)
+}
+
+// Cmp compares two amounts at Scale, returning -1, 0 or +1 as a is less than, equal
+// to or greater than b
+func Cmp(a, b decimal.Decimal) int {
+	return Quantize(a).Cmp(Quantize(b))
+}
+
+// Equal reports whether two amounts are equal at Scale
+func Equal(a, b decimal.Decimal) bool {
+	return Cmp(a, b) == 0
+}
+
+// Less reports whether a is less than b at Scale
+func Less(a, b decimal.Decimal) bool {
+	return Cmp(a, b) < 0
+}
+
+// Greater reports whether a is greater than b at Scale
+func Greater(a, b decimal.Decimal) bool {
+	return Cmp(a, b) > 0
+}
+
+// IsPositive reports whether an amount is greater than zero at Scale
+func IsPositive(amount decimal.Decimal) bool {
+	return Quantize(amount).IsPositive()
+}
@@ -0,0 +1,116 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		amount   string
+		expected string
+	}{
+		{"100", "100.0000000000"},
+		{"100.5", "100.5000000000"},
+		{"0.00000000004", "0.0000000000"},
+		{"0.00000000005", "0.0000000001"},
+		{"-0.00000000005", "-0.0000000001"},
+		{"1.123456789012345", "1.1234567890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			quantized := Quantize(d(tt.amount))
+			assert.Equal(t, tt.expected, quantized.StringFixed(Scale))
+			assert.Equal(t, int32(-Scale), quantized.Exponent())
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		amounts  []string
+		expected string
+	}{
+		{name: "no amounts", amounts: nil, expected: "0"},
+		{name: "mixed scales", amounts: []string{"100.0", "0.05", "25"}, expected: "125.05"},
+		{name: "negative", amounts: []string{"10", "-12.5"}, expected: "-2.5"},
+		{name: "sub-scale digits are rounded per amount", amounts: []string{"0.00000000004", "0.00000000004"}, expected: "0"},
+		{name: "largest stored balance", amounts: []string{"9999999999999999999999999999.9999999998", "0.0000000001"}, expected: "9999999999999999999999999999.9999999999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amounts := make([]decimal.Decimal, len(tt.amounts))
+			for i, amount := range tt.amounts {
+				amounts[i] = d(amount)
+			}
+			assert.True(t, Add(amounts...).Equal(d(tt.expected)), "got %s", Add(amounts...))
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        []string
+		expected string
+	}{
+		{name: "nothing subtracted", a: "100.00", expected: "100"},
+		{name: "amount and fee", a: "100.00", b: []string{"25.5", "0.25"}, expected: "74.25"},
+		{name: "below zero", a: "1", b: []string{"1.0000000001"}, expected: "-0.0000000001"},
+		{name: "to exactly zero", a: "0.3", b: []string{"0.1", "0.2"}, expected: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := make([]decimal.Decimal, len(tt.b))
+			for i, amount := range tt.b {
+				b[i] = d(amount)
+			}
+			result := Sub(d(tt.a), b...)
+			assert.True(t, result.Equal(d(tt.expected)), "got %s", result)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		cmp  int
+	}{
+		{"100.0", "100.00", 0},
+		{"100", "100.0000000000", 0},
+		{"100.00000000001", "100", 0},
+		{"100.0000000001", "100", 1},
+		{"99.99", "100", -1},
+		{"-0", "0", 0},
+		{"-1", "0.5", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, b := d(tt.a), d(tt.b)
+			assert.Equal(t, tt.cmp, Cmp(a, b))
+			assert.Equal(t, -tt.cmp, Cmp(b, a))
+			assert.Equal(t, tt.cmp == 0, Equal(a, b))
+			assert.Equal(t, tt.cmp < 0, Less(a, b))
+			assert.Equal(t, tt.cmp > 0, Greater(a, b))
+		})
+	}
+}
+
+func TestIsPositive(t *testing.T) {
+	assert.True(t, IsPositive(d("0.0000000001")))
+	assert.False(t, IsPositive(d("0.00000000004")))
+	assert.False(t, IsPositive(decimal.Zero))
+	assert.False(t, IsPositive(d("-5")))
+}
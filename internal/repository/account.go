@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 
 	"internal-transfers-api/internal/model"
@@ -14,32 +15,126 @@ import (
 
 // AccountRepository handles account-related database operations
 type AccountRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB // serves reads marked by ReadFromReplica; nil sends them to db
+	errs    ErrorMapping
 }
 
-// NewAccountRepository creates a new account repository
-func NewAccountRepository(db *sql.DB) *AccountRepository {
-	return &AccountRepository{db: db}
+// NewAccountRepository creates a new account repository whose writes and row locks
+// report database errors classified by errs
+func NewAccountRepository(db *sql.DB, errs ErrorMapping) *AccountRepository {
+	return NewAccountRepositoryWithReplica(db, nil, errs)
 }
 
-// Create creates a new account with the given initial balance
-func (r *AccountRepository) Create(ctx context.Context, initialBalance decimal.Decimal) (*model.Account, error) {
-	query := `
-		INSERT INTO accounts (balance, created_at, updated_at)
-		VALUES ($1, NOW(), NOW())
-		RETURNING id, balance, created_at, updated_at
-	`
+// NewAccountRepositoryWithReplica creates a new account repository that serves reads
+// marked by ReadFromReplica from replica, and everything else from db
+func NewAccountRepositoryWithReplica(db, replica *sql.DB, errs ErrorMapping) *AccountRepository {
+	return &AccountRepository{db: db, replica: replica, errs: errs}
+}
 
+// accountColumns is the column list shared by every query returning a full account
+const accountColumns = `id, balance, currency, status, account_type, interest_rate, min_balance, max_balance, owner_id, external_ref, is_system, version, created_at, updated_at`
+
+// scanAccount scans a row selected with accountColumns
+func scanAccount(row rowScanner) (*model.Account, error) {
 	account := &model.Account{}
-	err := r.db.QueryRowContext(ctx, query, initialBalance).Scan(
+	err := row.Scan(
 		&account.ID,
 		&account.Balance,
+		&account.Currency,
+		&account.Status,
+		&account.AccountType,
+		&account.InterestRate,
+		&account.MinBalance,
+		&account.MaxBalance,
+		&account.OwnerID,
+		&account.ExternalRef,
+		&account.System,
+		&account.Version,
 		&account.CreatedAt,
 		&account.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Create creates a new account with the balance, currency, type, interest rate,
+// balance limits, owner and external reference of the given account
+func (r *AccountRepository) Create(ctx context.Context, account *model.Account) (*model.Account, error) {
+	query := `
+		INSERT INTO accounts (balance, opening_balance, currency, account_type, interest_rate, min_balance, max_balance, owner_id, external_ref, created_at, updated_at)
+		VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING ` + accountColumns
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.Create", query)
+	defer span.End()
+
+	created, err := scanAccount(timed(r.db, "AccountRepository.Create").QueryRowContext(ctx, query, account.Balance, account.Currency, account.AccountType, account.InterestRate, account.MinBalance, account.MaxBalance, account.OwnerID, account.ExternalRef))
+	if err != nil {
+		return nil, r.errs.translate(fmt.Errorf("failed to create account: %w", err))
+	}
+
+	return created, nil
+}
+
+// CreateOrGet creates an account like Create unless one with the same external
+// reference exists, in which case that account is returned instead. created reports
+// which happened. Concurrent calls with the same reference create one account between
+// them: the losers' inserts wait for the winner to commit and then find its account.
+func (r *AccountRepository) CreateOrGet(ctx context.Context, account *model.Account) (*model.Account, bool, error) {
+	return r.createOrGet(ctx, r.db, "AccountRepository.CreateOrGet", account)
+}
+
+// CreateOrGetTx is CreateOrGet within a transaction, so the account is only created if
+// the transaction commits
+func (r *AccountRepository) CreateOrGetTx(ctx context.Context, tx *sql.Tx, account *model.Account) (*model.Account, bool, error) {
+	return r.createOrGet(ctx, tx, "AccountRepository.CreateOrGetTx", account)
+}
+
+func (r *AccountRepository) createOrGet(ctx context.Context, q querier, name string, account *model.Account) (_ *model.Account, created bool, err error) {
+	query := `
+		INSERT INTO accounts (balance, opening_balance, currency, account_type, interest_rate, min_balance, max_balance, owner_id, external_ref, created_at, updated_at)
+		VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (external_ref) DO NOTHING
+		RETURNING ` + accountColumns
+
+	ctx, span := startQuerySpan(ctx, name, query)
+	defer span.End()
+
+	inserted, err := scanAccount(timed(q, name).QueryRowContext(ctx, query, account.Balance, account.Currency, account.AccountType, account.InterestRate, account.MinBalance, account.MaxBalance, account.OwnerID, account.ExternalRef))
+	if err == nil {
+		return inserted, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, r.errs.translate(fmt.Errorf("failed to create account: %w", err))
+	}
+
+	existing, err := r.getByExternalRef(ctx, q, *account.ExternalRef)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// getByExternalRef retrieves an account by its external reference
+func (r *AccountRepository) getByExternalRef(ctx context.Context, q querier, externalRef string) (*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE external_ref = $1
+	`
 
+	ctx, span := startQuerySpan(ctx, "AccountRepository.getByExternalRef", query)
+	defer span.End()
+
+	account, err := scanAccount(timed(q, "AccountRepository.getByExternalRef").QueryRowContext(ctx, query, externalRef))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create account: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get account by external reference: %w", err)
 	}
 
 	return account, nil
@@ -48,19 +143,15 @@ func (r *AccountRepository) Create(ctx context.Context, initialBalance decimal.D
 // GetByID retrieves an account by its ID
 func (r *AccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Account, error) {
 	query := `
-		SELECT id, balance, created_at, updated_at
+		SELECT ` + accountColumns + `
 		FROM accounts
 		WHERE id = $1
 	`
 
-	account := &model.Account{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&account.ID,
-		&account.Balance,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-	)
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetByID", query)
+	defer span.End()
 
+	account, err := scanAccount(timed(reader(ctx, r.db, r.replica), "AccountRepository.GetByID").QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrAccountNotFound
@@ -71,6 +162,180 @@ func (r *AccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.A
 	return account, nil
 }
 
+// ListInterestBearing retrieves the savings accounts that earn interest and aren't closed
+func (r *AccountRepository) ListInterestBearing(ctx context.Context) ([]*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE account_type = $1 AND interest_rate > 0 AND status <> $2
+		ORDER BY id
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.ListInterestBearing", query)
+	defer span.End()
+
+	rows, err := timed(r.db, "AccountRepository.ListInterestBearing").QueryContext(ctx, query, model.AccountTypeSavings, model.AccountStatusClosed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interest-bearing accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// ListByOwner retrieves every account of an owner, oldest first
+func (r *AccountRepository) ListByOwner(ctx context.Context, ownerID string) ([]*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE owner_id = $1
+		ORDER BY created_at, id
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.ListByOwner", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "AccountRepository.ListByOwner").QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list owner accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// GetOwnerBalances sums the balances of an owner's accounts per currency, ordered by
+// currency code
+func (r *AccountRepository) GetOwnerBalances(ctx context.Context, ownerID string) ([]model.OwnerBalance, error) {
+	query := `
+		SELECT currency, COUNT(*), COALESCE(SUM(balance), 0)
+		FROM accounts
+		WHERE owner_id = $1
+		GROUP BY currency
+		ORDER BY currency
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetOwnerBalances", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "AccountRepository.GetOwnerBalances").QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owner balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []model.OwnerBalance
+	for rows.Next() {
+		var balance model.OwnerBalance
+		if err := rows.Scan(&balance.Currency, &balance.Accounts, &balance.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan owner balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating owner balances: %w", err)
+	}
+
+	return balances, nil
+}
+
+// GetCurrencies retrieves the currency of each of the given accounts that exists.
+// An account's currency never changes, so no lock is taken.
+func (r *AccountRepository) GetCurrencies(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]string, error) {
+	query := `
+		SELECT id, currency
+		FROM accounts
+		WHERE id = ANY($1::uuid[])
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetCurrencies", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "AccountRepository.GetCurrencies").QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account currencies: %w", err)
+	}
+	defer rows.Close()
+
+	currencies := make(map[uuid.UUID]string, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var currency string
+		if err := rows.Scan(&id, &currency); err != nil {
+			return nil, fmt.Errorf("failed to scan account currency: %w", err)
+		}
+		currencies[id] = currency
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating account currencies: %w", err)
+	}
+
+	return currencies, nil
+}
+
+// GetBalances retrieves the balance of each of the given accounts that exists, without
+// locking them
+func (r *AccountRepository) GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	query := `
+		SELECT id, balance
+		FROM accounts
+		WHERE id = ANY($1::uuid[])
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetBalances", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "AccountRepository.GetBalances").QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make(map[uuid.UUID]decimal.Decimal, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var balance decimal.Decimal
+		if err := rows.Scan(&id, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan account balance: %w", err)
+		}
+		balances[id] = balance
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating account balances: %w", err)
+	}
+
+	return balances, nil
+}
+
 // GetBalanceForUpdate retrieves an account's balance with row-level locking
 // This is used during transactions to prevent concurrent modifications
 func (r *AccountRepository) GetBalanceForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
@@ -81,29 +346,59 @@ func (r *AccountRepository) GetBalanceForUpdate(ctx context.Context, tx *sql.Tx,
 		FOR UPDATE
 	`
 
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetBalanceForUpdate", query)
+	defer span.End()
+
 	var balance decimal.Decimal
-	err := tx.QueryRowContext(ctx, query, id).Scan(&balance)
+	err := timed(tx, "AccountRepository.GetBalanceForUpdate").QueryRowContext(ctx, query, id).Scan(&balance)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return decimal.Zero, ErrAccountNotFound
 		}
-		return decimal.Zero, fmt.Errorf("failed to get account balance for update: %w", err)
+		return decimal.Zero, r.errs.translate(fmt.Errorf("failed to get account balance for update: %w", err))
 	}
 
 	return balance, nil
 }
 
-// UpdateBalance updates an account's balance within a transaction
+// GetForUpdate retrieves an account with a row-level lock, for transfers that
+// must check its status and balance limits before changing its balance
+func (r *AccountRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetForUpdate", query)
+	defer span.End()
+
+	account, err := scanAccount(timed(tx, "AccountRepository.GetForUpdate").QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountNotFound
+		}
+		return nil, r.errs.translate(fmt.Errorf("failed to get account for update: %w", err))
+	}
+
+	return account, nil
+}
+
+// UpdateBalance updates an account's balance within a transaction, advancing its version
 func (r *AccountRepository) UpdateBalance(ctx context.Context, tx *sql.Tx, id uuid.UUID, newBalance decimal.Decimal) error {
 	query := `
 		UPDATE accounts
-		SET balance = $1, updated_at = NOW()
+		SET balance = $1, version = version + 1, updated_at = NOW()
 		WHERE id = $2
 	`
 
-	result, err := tx.ExecContext(ctx, query, newBalance, id)
+	ctx, span := startQuerySpan(ctx, "AccountRepository.UpdateBalance", query)
+	defer span.End()
+
+	result, err := timed(tx, "AccountRepository.UpdateBalance").ExecContext(ctx, query, newBalance, id)
 	if err != nil {
-		return fmt.Errorf("failed to update account balance: %w", err)
+		return r.errs.translate(fmt.Errorf("failed to update account balance: %w", err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -118,20 +413,264 @@ func (r *AccountRepository) UpdateBalance(ctx context.Context, tx *sql.Tx, id uu
 	return nil
 }
 
-// GetBalanceAt retrieves the account balance at a specific timestamp
+// UpdateStatus updates an account's status within a transaction
+func (r *AccountRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status model.AccountStatus) (time.Time, error) {
+	query := `
+		UPDATE accounts
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.UpdateStatus", query)
+	defer span.End()
+
+	var updatedAt time.Time
+	err := timed(tx, "AccountRepository.UpdateStatus").QueryRowContext(ctx, query, status, id).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, ErrAccountNotFound
+		}
+		return time.Time{}, r.errs.translate(fmt.Errorf("failed to update account status: %w", err))
+	}
+
+	return updatedAt, nil
+}
+
+// MarkSystem flags an account as a system account, which the balance constraint lets go
+// negative, and returns it
+func (r *AccountRepository) MarkSystem(ctx context.Context, id uuid.UUID) (*model.Account, error) {
+	query := `
+		UPDATE accounts
+		SET is_system = TRUE
+		WHERE id = $1
+		RETURNING ` + accountColumns
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.MarkSystem", query)
+	defer span.End()
+
+	account, err := scanAccount(timed(r.db, "AccountRepository.MarkSystem").QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountNotFound
+		}
+		return nil, r.errs.translate(fmt.Errorf("failed to mark system account: %w", err))
+	}
+
+	return account, nil
+}
+
+// RecordStatusChange inserts the audit record of a status change within a transaction
+func (r *AccountRepository) RecordStatusChange(ctx context.Context, tx *sql.Tx, change *model.AccountStatusChange) error {
+	query := `
+		INSERT INTO account_status_changes (account_id, from_status, to_status, actor, reason, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.RecordStatusChange", query)
+	defer span.End()
+
+	err := timed(tx, "AccountRepository.RecordStatusChange").QueryRowContext(ctx, query,
+		change.AccountID, change.FromStatus, change.ToStatus, change.Actor, change.Reason, change.ChangedAt,
+	).Scan(&change.ID)
+	if err != nil {
+		return r.errs.translate(fmt.Errorf("failed to record account status change: %w", err))
+	}
+
+	return nil
+}
+
+// RecordAdjustment inserts the audit record of a balance adjustment within a transaction
+func (r *AccountRepository) RecordAdjustment(ctx context.Context, tx *sql.Tx, adjustment *model.AccountAdjustment) error {
+	query := `
+		INSERT INTO account_adjustments (account_id, transaction_id, amount, previous_balance, reason, actor, forced, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.RecordAdjustment", query)
+	defer span.End()
+
+	err := timed(tx, "AccountRepository.RecordAdjustment").QueryRowContext(ctx, query,
+		adjustment.AccountID, adjustment.TransactionID, adjustment.Amount, adjustment.PreviousBalance,
+		adjustment.Reason, adjustment.Actor, adjustment.Forced, adjustment.CreatedAt,
+	).Scan(&adjustment.ID)
+	if err != nil {
+		return r.errs.translate(fmt.Errorf("failed to record balance adjustment: %w", err))
+	}
+
+	return nil
+}
+
+// GetLedgerBalance recomputes an account's balance from its opening balance and
+// all completed transactions (including fees), within the given transaction
+func (r *AccountRepository) GetLedgerBalance(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
+	query := `
+		SELECT a.opening_balance
+			+ COALESCE((SELECT SUM(COALESCE(t.destination_amount, t.amount)) FROM transactions t
+				WHERE t.destination_account_id = a.id AND t.status = 'completed'), 0)
+			+ COALESCE((SELECT SUM(t.fee) FROM transactions t
+				WHERE t.fee_account_id = a.id AND t.status = 'completed'), 0)
+			- COALESCE((SELECT SUM(t.amount + COALESCE(t.fee, 0)) FROM transactions t
+				WHERE t.source_account_id = a.id AND t.status = 'completed'), 0)
+		FROM accounts a
+		WHERE a.id = $1
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetLedgerBalance", query)
+	defer span.End()
+
+	var balance decimal.Decimal
+	err := timed(tx, "AccountRepository.GetLedgerBalance").QueryRowContext(ctx, query, id).Scan(&balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return decimal.Zero, ErrAccountNotFound
+		}
+		return decimal.Zero, fmt.Errorf("failed to compute ledger balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// ScanLedgers calls fn with the stored and ledger balance of every account, in ID order,
+// stopping at the first error fn returns. Rows are streamed from a single query, so run it
+// in a repeatable read transaction to check every account against the same snapshot.
+func (r *AccountRepository) ScanLedgers(ctx context.Context, tx *sql.Tx, fn func(model.AccountLedger) error) error {
+	query := `
+		WITH movements AS (
+			SELECT destination_account_id AS account_id, COALESCE(destination_amount, amount) AS delta
+			FROM transactions WHERE destination_account_id IS NOT NULL AND status = 'completed'
+			UNION ALL
+			SELECT fee_account_id, fee
+			FROM transactions WHERE fee_account_id IS NOT NULL AND status = 'completed'
+			UNION ALL
+			SELECT source_account_id, -(amount + COALESCE(fee, 0))
+			FROM transactions WHERE source_account_id IS NOT NULL AND status = 'completed'
+		), ledger AS (
+			SELECT account_id, SUM(delta) AS net FROM movements GROUP BY account_id
+		)
+		SELECT a.id, a.currency, a.balance, a.opening_balance + COALESCE(l.net, 0)
+		FROM accounts a
+		LEFT JOIN ledger l ON l.account_id = a.id
+		ORDER BY a.id
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.ScanLedgers", query)
+	defer span.End()
+
+	rows, err := timed(tx, "AccountRepository.ScanLedgers").QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to scan account ledgers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ledger model.AccountLedger
+		if err := rows.Scan(&ledger.ID, &ledger.Currency, &ledger.StoredBalance, &ledger.LedgerBalance); err != nil {
+			return fmt.Errorf("failed to scan account ledger: %w", err)
+		}
+		if err := fn(ledger); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating account ledgers: %w", err)
+	}
+
+	return nil
+}
+
+// SumCurrencyMovements totals, per currency, the accounts' opening balances and the
+// completed transactions that move money into or out of the currency: deposits, withdrawals
+// and cross-currency transfers. It reads the transactions rather than the account ledgers,
+// so the reconciliation report can check the ledgers against it.
+func (r *AccountRepository) SumCurrencyMovements(ctx context.Context, tx *sql.Tx) (map[string]model.CurrencyMovements, error) {
+	query := `
+		WITH legs AS (
+			SELECT currency, opening_balance AS opening, 0 AS movement FROM accounts
+			UNION ALL
+			SELECT d.currency, 0, COALESCE(t.destination_amount, t.amount)
+			FROM transactions t
+			JOIN accounts d ON d.id = t.destination_account_id
+			LEFT JOIN accounts s ON s.id = t.source_account_id
+			WHERE t.status = 'completed' AND (s.id IS NULL OR s.currency <> d.currency)
+			UNION ALL
+			SELECT s.currency, 0, -t.amount
+			FROM transactions t
+			JOIN accounts s ON s.id = t.source_account_id
+			LEFT JOIN accounts d ON d.id = t.destination_account_id
+			WHERE t.status = 'completed' AND (d.id IS NULL OR d.currency <> s.currency)
+		)
+		SELECT currency, SUM(opening), SUM(movement)
+		FROM legs
+		GROUP BY currency
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.SumCurrencyMovements", query)
+	defer span.End()
+
+	rows, err := timed(tx, "AccountRepository.SumCurrencyMovements").QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum currency movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := make(map[string]model.CurrencyMovements)
+	for rows.Next() {
+		var currency string
+		var totals model.CurrencyMovements
+		if err := rows.Scan(&currency, &totals.OpeningBalance, &totals.Movements); err != nil {
+			return nil, fmt.Errorf("failed to scan currency movements: %w", err)
+		}
+		movements[currency] = totals
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating currency movements: %w", err)
+	}
+
+	return movements, nil
+}
+
+// GetBalanceAt computes the account's ledger balance at a timestamp, starting from the
+// latest balance snapshot taken by then and replaying only the completed transactions
+// since. Without a snapshot the whole ledger is replayed from the opening balance. The
+// balance before the account was opened is zero.
+//
+// The TIMESTAMP columns hold NOW() in the session time zone, so the timestamp is passed
+// as timestamptz, which they are converted to in that same zone, and snapshot days end
+// at midnight UTC whatever the session's zone is.
 func (r *AccountRepository) GetBalanceAt(ctx context.Context, id uuid.UUID, timestamp time.Time) (decimal.Decimal, error) {
-	// This is a simplified version - in a real system, you might implement
-	// this with ledger entries or transaction history
 	query := `
-		SELECT balance
-		FROM accounts
-		WHERE id = $1 AND updated_at <= $2
-		ORDER BY updated_at DESC
-		LIMIT 1
+		SELECT CASE WHEN a.created_at > $2::timestamptz THEN 0 ELSE
+			COALESCE(s.balance, a.opening_balance) + COALESCE((
+				SELECT SUM(
+					CASE WHEN t.destination_account_id = a.id THEN COALESCE(t.destination_amount, t.amount) ELSE 0 END
+						+ CASE WHEN t.fee_account_id = a.id THEN COALESCE(t.fee, 0) ELSE 0 END
+						- CASE WHEN t.source_account_id = a.id THEN t.amount + COALESCE(t.fee, 0) ELSE 0 END)
+				FROM transactions t
+				WHERE (t.source_account_id = a.id OR t.destination_account_id = a.id OR t.fee_account_id = a.id)
+					AND t.status = 'completed'
+					AND COALESCE(t.completed_at, t.created_at) <= $2::timestamptz
+					AND (s.day IS NULL OR COALESCE(t.completed_at, t.created_at) > (s.day + 1)::timestamp AT TIME ZONE 'UTC')
+			), 0) END
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT day, balance FROM balance_snapshots
+			WHERE account_id = a.id AND (day + 1)::timestamp AT TIME ZONE 'UTC' <= $2::timestamptz
+			ORDER BY day DESC
+			LIMIT 1
+		) s ON TRUE
+		WHERE a.id = $1
 	`
 
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetBalanceAt", query)
+	defer span.End()
+
 	var balance decimal.Decimal
-	err := r.db.QueryRowContext(ctx, query, id, timestamp).Scan(&balance)
+	err := timed(reader(ctx, r.db, r.replica), "AccountRepository.GetBalanceAt").QueryRowContext(ctx, query, id, timestamp).Scan(&balance)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return decimal.Zero, ErrAccountNotFound
@@ -142,12 +681,141 @@ func (r *AccountRepository) GetBalanceAt(ctx context.Context, id uuid.UUID, time
 	return balance, nil
 }
 
+// WriteBalanceSnapshots records every account's balance at the midnight closing day,
+// returning how many it recorded. Each is the account's previous snapshot plus the
+// completed transactions since, or its whole ledger for its first. Accounts that already
+// have the day's snapshot keep it, so writing a day again only fills in the missing ones.
+// The day's bounds are passed as timestamptz, as in GetBalanceAt.
+func (r *AccountRepository) WriteBalanceSnapshots(ctx context.Context, day time.Time) (int, error) {
+	query := `
+		INSERT INTO balance_snapshots (account_id, day, balance)
+		SELECT a.id, ($1::timestamptz AT TIME ZONE 'UTC')::date,
+			COALESCE(s.balance, a.opening_balance) + COALESCE((
+				SELECT SUM(
+					CASE WHEN t.destination_account_id = a.id THEN COALESCE(t.destination_amount, t.amount) ELSE 0 END
+						+ CASE WHEN t.fee_account_id = a.id THEN COALESCE(t.fee, 0) ELSE 0 END
+						- CASE WHEN t.source_account_id = a.id THEN t.amount + COALESCE(t.fee, 0) ELSE 0 END)
+				FROM transactions t
+				WHERE (t.source_account_id = a.id OR t.destination_account_id = a.id OR t.fee_account_id = a.id)
+					AND t.status = 'completed'
+					AND COALESCE(t.completed_at, t.created_at) <= $2::timestamptz
+					AND (s.day IS NULL OR COALESCE(t.completed_at, t.created_at) > (s.day + 1)::timestamp AT TIME ZONE 'UTC')
+			), 0)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT day, balance FROM balance_snapshots
+			WHERE account_id = a.id AND day < ($1::timestamptz AT TIME ZONE 'UTC')::date
+			ORDER BY day DESC
+			LIMIT 1
+		) s ON TRUE
+		WHERE a.created_at <= $2::timestamptz
+		ON CONFLICT (account_id, day) DO NOTHING
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.WriteBalanceSnapshots", query)
+	defer span.End()
+
+	day = day.UTC().Truncate(24 * time.Hour)
+	result, err := timed(r.db, "AccountRepository.WriteBalanceSnapshots").ExecContext(ctx, query, day, day.Add(24*time.Hour))
+	if err != nil {
+		return 0, r.errs.translate(fmt.Errorf("failed to write balance snapshots: %w", err))
+	}
+
+	written, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(written), nil
+}
+
+// GetBalanceHistory computes the account's ledger balance at each of points, which must be
+// in ascending order. Completed transactions are read once, each summed into the bucket of
+// points it precedes, and the buckets accumulated, instead of querying every point.
+// Points before the account was opened have a zero balance. Points are passed as
+// timestamptz, as in GetBalanceAt.
+func (r *AccountRepository) GetBalanceHistory(ctx context.Context, id uuid.UUID, points []time.Time) ([]decimal.Decimal, error) {
+	accountQuery := `SELECT opening_balance, created_at FROM accounts WHERE id = $1`
+
+	// bucket is the number of points before the transaction, which counts towards the
+	// balance at every later point
+	query := `
+		WITH entries AS (
+			SELECT
+				(SELECT COUNT(*) FROM unnest($2::timestamptz[]) AS p(at) WHERE p.at < COALESCE(t.completed_at, t.created_at)) AS bucket,
+				CASE WHEN t.destination_account_id = $1 THEN COALESCE(t.destination_amount, t.amount) ELSE 0 END
+					+ CASE WHEN t.fee_account_id = $1 THEN COALESCE(t.fee, 0) ELSE 0 END
+					- CASE WHEN t.source_account_id = $1 THEN t.amount + COALESCE(t.fee, 0) ELSE 0 END AS delta
+			FROM transactions t
+			WHERE (t.source_account_id = $1 OR t.destination_account_id = $1 OR t.fee_account_id = $1)
+				AND t.status = 'completed'
+				AND COALESCE(t.completed_at, t.created_at) <= $3::timestamptz
+		)
+		SELECT bucket, SUM(delta)
+		FROM entries
+		GROUP BY bucket
+	`
+
+	ctx, span := startQuerySpan(ctx, "AccountRepository.GetBalanceHistory", query)
+	defer span.End()
+
+	var openingBalance decimal.Decimal
+	var openedAt time.Time
+	err := timed(reader(ctx, r.db, r.replica), "AccountRepository.GetBalanceHistory").QueryRowContext(ctx, accountQuery, id).Scan(&openingBalance, &openedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get account for balance history: %w", err)
+	}
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "AccountRepository.GetBalanceHistory").QueryContext(ctx, query, id, pq.Array(points), points[len(points)-1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance history: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]decimal.Decimal, len(points))
+	for rows.Next() {
+		var bucket int
+		var delta decimal.Decimal
+		if err := rows.Scan(&bucket, &delta); err != nil {
+			return nil, fmt.Errorf("failed to scan balance history: %w", err)
+		}
+		buckets[bucket] = delta
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance history: %w", err)
+	}
+
+	return accumulateBalanceHistory(points, openingBalance, openedAt, buckets), nil
+}
+
+// accumulateBalanceHistory turns per-bucket ledger movements into the balance at each
+// point: the opening balance, once opened, plus every bucket up to the point's own
+func accumulateBalanceHistory(points []time.Time, openingBalance decimal.Decimal, openedAt time.Time, buckets []decimal.Decimal) []decimal.Decimal {
+	balances := make([]decimal.Decimal, len(points))
+	running := decimal.Zero
+	for i, at := range points {
+		running = running.Add(buckets[i])
+		if at.Before(openedAt) {
+			balances[i] = decimal.Zero
+			continue
+		}
+		balances[i] = openingBalance.Add(running)
+	}
+	return balances
+}
+
 // Exists checks if an account exists
 func (r *AccountRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
 	query := `SELECT 1 FROM accounts WHERE id = $1 LIMIT 1`
 
+	ctx, span := startQuerySpan(ctx, "AccountRepository.Exists", query)
+	defer span.End()
+
 	var exists int
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&exists)
+	err := timed(reader(ctx, r.db, r.replica), "AccountRepository.Exists").QueryRowContext(ctx, query, id).Scan(&exists)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -156,4 +824,4 @@ func (r *AccountRepository) Exists(ctx context.Context, id uuid.UUID) (bool, err
 	}
 
 	return true, nil
-} 
\ No newline at end of file
+}
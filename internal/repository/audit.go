@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"internal-transfers-api/internal/model"
+)
+
+// AuditRepository handles audit log operations
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record inserts an audit entry within a transaction, so it is only kept if the change
+// it describes commits
+func (r *AuditRepository) Record(ctx context.Context, tx *sql.Tx, entry *model.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor, operation, resource_ids, created_at)
+		VALUES ($1, $2, $3::uuid[], $4)
+	`
+
+	ctx, span := startQuerySpan(ctx, "AuditRepository.Record", query)
+	defer span.End()
+
+	_, err := timed(tx, "AuditRepository.Record").ExecContext(ctx, query, entry.Actor, string(entry.Operation), pq.Array(entry.ResourceIDs), entry.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
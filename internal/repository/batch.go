@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+)
+
+// BatchRepository handles asynchronous transfer batch operations
+type BatchRepository struct {
+	db *sql.DB
+}
+
+// NewBatchRepository creates a new batch repository
+func NewBatchRepository(db *sql.DB) *BatchRepository {
+	return &BatchRepository{db: db}
+}
+
+// Create persists a pending batch together with its items
+func (r *BatchRepository) Create(ctx context.Context, submittedByAdmin bool, items []model.BatchItem) (*model.Batch, error) {
+	batchQuery := `
+		INSERT INTO batches (status, submitted_by_admin, created_at)
+		VALUES ($1, $2, NOW())
+		RETURNING id, status, created_at, completed_at, submitted_by_admin
+	`
+	itemQuery := `
+		INSERT INTO batch_items (batch_id, item_index, transaction_id, request_body, status, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+
+	tx, release, err := BeginTx(ctx, r.db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer tx.Rollback()
+
+	batchCtx, span := startQuerySpan(ctx, "BatchRepository.Create", batchQuery)
+	batch := &model.Batch{}
+	err = timed(tx, "BatchRepository.Create").QueryRowContext(batchCtx, batchQuery, model.BatchStatusPending, submittedByAdmin).Scan(
+		&batch.ID,
+		&batch.Status,
+		&batch.CreatedAt,
+		&batch.CompletedAt,
+		&batch.SubmittedByAdmin,
+	)
+	span.End()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	// A single span covers every item insert rather than one per item
+	itemCtx, span := startQuerySpan(ctx, "BatchRepository.CreateItems", itemQuery)
+	defer span.End()
+
+	stmt, err := tx.PrepareContext(itemCtx, itemQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare batch item insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, item := range items {
+		if _, err := stmt.ExecContext(itemCtx, batch.ID, item.Index, item.TransactionID, item.RequestBody, model.TransactionStatusPending); err != nil {
+			return nil, fmt.Errorf("failed to create batch item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return batch, nil
+}
+
+// GetByID retrieves a batch by its ID
+func (r *BatchRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Batch, error) {
+	query := `
+		SELECT id, status, created_at, completed_at, submitted_by_admin
+		FROM batches
+		WHERE id = $1
+	`
+
+	ctx, span := startQuerySpan(ctx, "BatchRepository.GetByID", query)
+	defer span.End()
+
+	batch := &model.Batch{}
+	err := timed(r.db, "BatchRepository.GetByID").QueryRowContext(ctx, query, id).Scan(
+		&batch.ID,
+		&batch.Status,
+		&batch.CreatedAt,
+		&batch.CompletedAt,
+		&batch.SubmittedByAdmin,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBatchNotFound
+		}
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	return batch, nil
+}
+
+// GetItems retrieves the items of a batch in submission order
+func (r *BatchRepository) GetItems(ctx context.Context, batchID uuid.UUID) ([]model.BatchItem, error) {
+	query := `
+		SELECT batch_id, item_index, transaction_id, request_body, status, error, error_code
+		FROM batch_items
+		WHERE batch_id = $1
+		ORDER BY item_index
+	`
+
+	ctx, span := startQuerySpan(ctx, "BatchRepository.GetItems", query)
+	defer span.End()
+
+	rows, err := timed(r.db, "BatchRepository.GetItems").QueryContext(ctx, query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []model.BatchItem
+	for rows.Next() {
+		var item model.BatchItem
+		err := rows.Scan(
+			&item.BatchID,
+			&item.Index,
+			&item.TransactionID,
+			&item.RequestBody,
+			&item.Status,
+			&item.Error,
+			&item.Code,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan batch item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batch items: %w", err)
+	}
+
+	return items, nil
+}
+
+// ClaimPending marks the oldest pending batch as processing and returns it.
+// Returns ErrBatchNotFound when there is nothing to process.
+func (r *BatchRepository) ClaimPending(ctx context.Context) (*model.Batch, error) {
+	query := `
+		UPDATE batches
+		SET status = $1
+		WHERE id = (
+			SELECT id FROM batches
+			WHERE status = $2
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, status, created_at, completed_at, submitted_by_admin
+	`
+
+	ctx, span := startQuerySpan(ctx, "BatchRepository.ClaimPending", query)
+	defer span.End()
+
+	batch := &model.Batch{}
+	err := timed(r.db, "BatchRepository.ClaimPending").QueryRowContext(ctx, query, model.BatchStatusProcessing, model.BatchStatusPending).Scan(
+		&batch.ID,
+		&batch.Status,
+		&batch.CreatedAt,
+		&batch.CompletedAt,
+		&batch.SubmittedByAdmin,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBatchNotFound
+		}
+		return nil, fmt.Errorf("failed to claim batch: %w", err)
+	}
+
+	return batch, nil
+}
+
+// ResetProcessing returns batches left processing by a previous run to pending
+func (r *BatchRepository) ResetProcessing(ctx context.Context) (int64, error) {
+	query := `UPDATE batches SET status = $1 WHERE status = $2`
+
+	ctx, span := startQuerySpan(ctx, "BatchRepository.ResetProcessing", query)
+	defer span.End()
+
+	result, err := timed(r.db, "BatchRepository.ResetProcessing").ExecContext(ctx, query, model.BatchStatusPending, model.BatchStatusProcessing)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset processing batches: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// UpdateItem records the outcome of a batch item
+func (r *BatchRepository) UpdateItem(ctx context.Context, item *model.BatchItem) error {
+	query := `
+		UPDATE batch_items
+		SET status = $1, error = $2, error_code = $3, updated_at = NOW()
+		WHERE batch_id = $4 AND item_index = $5
+	`
+
+	ctx, span := startQuerySpan(ctx, "BatchRepository.UpdateItem", query)
+	defer span.End()
+
+	_, err := timed(r.db, "BatchRepository.UpdateItem").ExecContext(ctx, query, item.Status, item.Error, item.Code, item.BatchID, item.Index)
+	if err != nil {
+		return fmt.Errorf("failed to update batch item: %w", err)
+	}
+
+	return nil
+}
+
+// Complete marks a batch as completed
+func (r *BatchRepository) Complete(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE batches
+		SET status = $1, completed_at = NOW()
+		WHERE id = $2
+	`
+
+	ctx, span := startQuerySpan(ctx, "BatchRepository.Complete", query)
+	defer span.End()
+
+	_, err := timed(r.db, "BatchRepository.Complete").ExecContext(ctx, query, model.BatchStatusCompleted, id)
+	if err != nil {
+		return fmt.Errorf("failed to complete batch: %w", err)
+	}
+
+	return nil
+}
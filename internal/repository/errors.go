@@ -1,15 +1,85 @@
 package repository
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/lib/pq"
+
+	"internal-transfers-api/internal/model"
+)
 
 // Repository errors
 var (
-	ErrAccountNotFound       = errors.New("account not found")
-	ErrTransactionNotFound   = errors.New("transaction not found")
-	ErrInsufficientFunds     = errors.New("insufficient funds")
-	ErrAccountAlreadyExists  = errors.New("account already exists")
-	ErrConcurrentUpdate      = errors.New("concurrent update detected")
-	ErrInvalidAmount         = errors.New("invalid amount")
-	ErrSameAccount           = errors.New("source and destination accounts cannot be the same")
-	ErrIdempotencyKeyExists  = errors.New("idempotency key already exists")
-) 
\ No newline at end of file
+	ErrAccountNotFound      = errors.New("account not found")
+	ErrTransactionNotFound  = errors.New("transaction not found")
+	ErrTransactionExists    = errors.New("transaction already exists")
+	ErrBatchNotFound        = errors.New("batch not found")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrAccountAlreadyExists = errors.New("account already exists")
+	ErrConcurrentUpdate     = errors.New("concurrent update detected")
+	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrSameAccount          = errors.New("source and destination accounts cannot be the same")
+	ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+)
+
+// DBError is a Postgres error that the ErrorMapping recognised
+type DBError struct {
+	Code      string // service error code the failure is reported as
+	SQLState  string // Postgres error code, e.g. 23505
+	Retryable bool   // the failure is transient and the request may succeed if retried
+	Err       error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorMapping classifies Postgres errors by SQLSTATE code. Mapped codes are reported
+// with their service error code, retryable ones as SERVICE_UNAVAILABLE unless mapped,
+// and any other database error stays an internal error.
+type ErrorMapping struct {
+	Codes     map[string]string // SQLSTATE code to service error code
+	Retryable map[string]bool   // SQLSTATE codes of transient failures
+}
+
+// NewErrorMapping creates a mapping from SQLSTATE codes to service error codes and a
+// list of SQLSTATE codes of transient failures
+func NewErrorMapping(codes map[string]string, retryable []string) ErrorMapping {
+	m := ErrorMapping{
+		Codes:     codes,
+		Retryable: make(map[string]bool, len(retryable)),
+	}
+	for _, code := range retryable {
+		m.Retryable[code] = true
+	}
+	return m
+}
+
+// translate wraps err in a DBError when it carries a Postgres error code the mapping knows
+func (m ErrorMapping) translate(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	sqlState := string(pqErr.Code)
+	code, mapped := m.Codes[sqlState]
+	retryable := m.Retryable[sqlState]
+	if !mapped && !retryable {
+		return err
+	}
+	if !mapped {
+		code = model.ErrCodeServiceUnavailable
+	}
+
+	return &DBError{
+		Code:      code,
+		SQLState:  sqlState,
+		Retryable: retryable,
+		Err:       err,
+	}
+}
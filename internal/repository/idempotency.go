@@ -35,32 +35,68 @@ func GenerateKeyHash(requestBody string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// StoreRequest stores an idempotency key with the request body
-func (r *IdempotencyRepository) StoreRequest(ctx context.Context, keyHash, requestBody string) error {
+// ClaimRequest stores an idempotency key with the request body, reporting whether this
+// call claimed the key. A key already stored and unexpired by now isn't claimed, so of
+// two concurrent requests with the same key only one goes ahead; an expired key is taken
+// over as if it were new. The key can be replayed until ttl has elapsed from now. Expiry
+// is computed from the service's clock rather than the database's NOW(), so tests can
+// control it.
+func (r *IdempotencyRepository) ClaimRequest(ctx context.Context, keyHash, requestBody string, now time.Time, ttl time.Duration) (bool, error) {
 	query := `
 		INSERT INTO idempotency_keys (key_hash, request_body, created_at, expires_at)
-		VALUES ($1, $2, NOW(), NOW() + INTERVAL '24 hours')
-		ON CONFLICT (key_hash) DO NOTHING
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key_hash) DO UPDATE
+		SET request_body = EXCLUDED.request_body, response_body = NULL, response_status = NULL,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query, keyHash, requestBody)
+	ctx, span := startQuerySpan(ctx, "IdempotencyRepository.ClaimRequest", query)
+	defer span.End()
+
+	now = now.UTC()
+	result, err := timed(r.db, "IdempotencyRepository.ClaimRequest").ExecContext(ctx, query, keyHash, requestBody, now, now.Add(ttl))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// ReleaseRequest removes a claimed idempotency key that has no response stored yet, so
+// the request can be made again under it
+func (r *IdempotencyRepository) ReleaseRequest(ctx context.Context, keyHash string) error {
+	query := `DELETE FROM idempotency_keys WHERE key_hash = $1 AND response_body IS NULL`
+
+	ctx, span := startQuerySpan(ctx, "IdempotencyRepository.ReleaseRequest", query)
+	defer span.End()
+
+	_, err := timed(r.db, "IdempotencyRepository.ReleaseRequest").ExecContext(ctx, query, keyHash)
 	if err != nil {
-		return fmt.Errorf("failed to store idempotency key: %w", err)
+		return fmt.Errorf("failed to release idempotency key: %w", err)
 	}
 
 	return nil
 }
 
-// GetRequest retrieves a stored idempotency record
-func (r *IdempotencyRepository) GetRequest(ctx context.Context, keyHash string) (*IdempotencyRecord, error) {
+// GetRequest retrieves a stored idempotency record that hasn't expired by now
+func (r *IdempotencyRepository) GetRequest(ctx context.Context, keyHash string, now time.Time) (*IdempotencyRecord, error) {
 	query := `
 		SELECT key_hash, request_body, response_body, response_status, created_at, expires_at
 		FROM idempotency_keys
-		WHERE key_hash = $1 AND expires_at > NOW()
+		WHERE key_hash = $1 AND expires_at > $2
 	`
 
+	ctx, span := startQuerySpan(ctx, "IdempotencyRepository.GetRequest", query)
+	defer span.End()
+
 	record := &IdempotencyRecord{}
-	err := r.db.QueryRowContext(ctx, query, keyHash).Scan(
+	err := timed(r.db, "IdempotencyRepository.GetRequest").QueryRowContext(ctx, query, keyHash, now.UTC()).Scan(
 		&record.KeyHash,
 		&record.RequestBody,
 		&record.ResponseBody,
@@ -79,6 +115,38 @@ func (r *IdempotencyRepository) GetRequest(ctx context.Context, keyHash string)
 	return record, nil
 }
 
+// GetStatus retrieves a stored idempotency record whether or not it has expired,
+// returning nil if the key was never stored or has been cleaned up
+func (r *IdempotencyRepository) GetStatus(ctx context.Context, keyHash string) (*IdempotencyRecord, error) {
+	query := `
+		SELECT key_hash, request_body, response_body, response_status, created_at, expires_at
+		FROM idempotency_keys
+		WHERE key_hash = $1
+	`
+
+	ctx, span := startQuerySpan(ctx, "IdempotencyRepository.GetStatus", query)
+	defer span.End()
+
+	record := &IdempotencyRecord{}
+	err := timed(r.db, "IdempotencyRepository.GetStatus").QueryRowContext(ctx, query, keyHash).Scan(
+		&record.KeyHash,
+		&record.RequestBody,
+		&record.ResponseBody,
+		&record.ResponseStatus,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key status: %w", err)
+	}
+
+	return record, nil
+}
+
 // UpdateResponse updates the response for an idempotency key
 func (r *IdempotencyRepository) UpdateResponse(ctx context.Context, keyHash, responseBody string, status int) error {
 	query := `
@@ -87,7 +155,10 @@ func (r *IdempotencyRepository) UpdateResponse(ctx context.Context, keyHash, res
 		WHERE key_hash = $3
 	`
 
-	_, err := r.db.ExecContext(ctx, query, responseBody, status, keyHash)
+	ctx, span := startQuerySpan(ctx, "IdempotencyRepository.UpdateResponse", query)
+	defer span.End()
+
+	_, err := timed(r.db, "IdempotencyRepository.UpdateResponse").ExecContext(ctx, query, responseBody, status, keyHash)
 	if err != nil {
 		return fmt.Errorf("failed to update idempotency response: %w", err)
 	}
@@ -95,11 +166,14 @@ func (r *IdempotencyRepository) UpdateResponse(ctx context.Context, keyHash, res
 	return nil
 }
 
-// CleanupExpired removes expired idempotency keys
-func (r *IdempotencyRepository) CleanupExpired(ctx context.Context) (int64, error) {
-	query := `DELETE FROM idempotency_keys WHERE expires_at < NOW()`
+// CleanupExpired removes idempotency keys expired by now
+func (r *IdempotencyRepository) CleanupExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
 
-	result, err := r.db.ExecContext(ctx, query)
+	ctx, span := startQuerySpan(ctx, "IdempotencyRepository.CleanupExpired", query)
+	defer span.End()
+
+	result, err := timed(r.db, "IdempotencyRepository.CleanupExpired").ExecContext(ctx, query, now.UTC())
 	if err != nil {
 		return 0, fmt.Errorf("failed to cleanup expired idempotency keys: %w", err)
 	}
@@ -110,4 +184,4 @@ func (r *IdempotencyRepository) CleanupExpired(ctx context.Context) (int64, erro
 	}
 
 	return rowsAffected, nil
-} 
\ No newline at end of file
+}
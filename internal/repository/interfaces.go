@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+)
+
+// AccountRepo is the account storage used by the services
+type AccountRepo interface {
+	Create(ctx context.Context, account *model.Account) (*model.Account, error)
+	CreateOrGet(ctx context.Context, account *model.Account) (*model.Account, bool, error)
+	CreateOrGetTx(ctx context.Context, tx *sql.Tx, account *model.Account) (*model.Account, bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Account, error)
+	ListInterestBearing(ctx context.Context) ([]*model.Account, error)
+	ListByOwner(ctx context.Context, ownerID string) ([]*model.Account, error)
+	GetOwnerBalances(ctx context.Context, ownerID string) ([]model.OwnerBalance, error)
+	GetCurrencies(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]string, error)
+	GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
+	GetBalanceForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error)
+	GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Account, error)
+	UpdateBalance(ctx context.Context, tx *sql.Tx, id uuid.UUID, newBalance decimal.Decimal) error
+	UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status model.AccountStatus) (time.Time, error)
+	RecordStatusChange(ctx context.Context, tx *sql.Tx, change *model.AccountStatusChange) error
+	RecordAdjustment(ctx context.Context, tx *sql.Tx, adjustment *model.AccountAdjustment) error
+	MarkSystem(ctx context.Context, id uuid.UUID) (*model.Account, error)
+	GetLedgerBalance(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error)
+	ScanLedgers(ctx context.Context, tx *sql.Tx, fn func(model.AccountLedger) error) error
+	SumCurrencyMovements(ctx context.Context, tx *sql.Tx) (map[string]model.CurrencyMovements, error)
+	GetBalanceAt(ctx context.Context, id uuid.UUID, timestamp time.Time) (decimal.Decimal, error)
+	WriteBalanceSnapshots(ctx context.Context, day time.Time) (int, error)
+	GetBalanceHistory(ctx context.Context, id uuid.UUID, points []time.Time) ([]decimal.Decimal, error)
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// TransactionRepo is the transaction storage used by the services
+type TransactionRepo interface {
+	Create(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest) (*model.Transaction, error)
+	CreateFailed(ctx context.Context, req *model.CreateTransactionRequest, reason string) (*model.Transaction, error)
+	UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status model.TransactionStatus) error
+	SetReversedBy(ctx context.Context, tx *sql.Tx, id, reversalID uuid.UUID) error
+	NotifyCompleted(ctx context.Context, tx *sql.Tx, payload string) error
+	GetByID(ctx context.Context, id uuid.UUID) (*model.Transaction, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Transaction, error)
+	SetDisputed(ctx context.Context, tx *sql.Tx, id uuid.UUID, reason string, at time.Time) error
+	ResolveDispute(ctx context.Context, tx *sql.Tx, id uuid.UUID, at time.Time) error
+	GetStatus(ctx context.Context, id uuid.UUID) (*model.TransactionStatusResponse, error)
+	GetByReference(ctx context.Context, reference string) (*model.Transaction, error)
+	GetRetry(ctx context.Context, id uuid.UUID) (*model.Transaction, error)
+	GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Transaction, error)
+	GetAllByReference(ctx context.Context, tx *sql.Tx, reference string, limit int) ([]*model.Transaction, error)
+	GetReversedAmount(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error)
+	ListStalePending(ctx context.Context, before time.Time, limit int) ([]*model.Transaction, error)
+	CountRecentTransfers(ctx context.Context, tx *sql.Tx, accountID uuid.UUID, since time.Time) (int, time.Time, error)
+	FindRecentDuplicate(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest, since time.Time) (*model.Transaction, error)
+	GetAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter, limit, offset int) ([]*model.Transaction, error)
+	CountAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter) (int, error)
+	GetCounterparties(ctx context.Context, accountID uuid.UUID, filter model.CounterpartyFilter) ([]model.Counterparty, error)
+	AddNote(ctx context.Context, tx *sql.Tx, note *model.TransactionNote) error
+	GetNotes(ctx context.Context, transactionID uuid.UUID) ([]model.TransactionNote, error)
+}
+
+// IdempotencyRepo is the idempotency key storage used by the services
+type IdempotencyRepo interface {
+	ClaimRequest(ctx context.Context, keyHash, requestBody string, now time.Time, ttl time.Duration) (bool, error)
+	ReleaseRequest(ctx context.Context, keyHash string) error
+	GetRequest(ctx context.Context, keyHash string, now time.Time) (*IdempotencyRecord, error)
+	GetStatus(ctx context.Context, keyHash string) (*IdempotencyRecord, error)
+	UpdateResponse(ctx context.Context, keyHash, responseBody string, status int) error
+	CleanupExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// AuditRepo is the audit log storage used by the services
+type AuditRepo interface {
+	Record(ctx context.Context, tx *sql.Tx, entry *model.AuditEntry) error
+}
+
+var (
+	_ AccountRepo     = (*AccountRepository)(nil)
+	_ TransactionRepo = (*TransactionRepository)(nil)
+	_ IdempotencyRepo = (*IdempotencyRepository)(nil)
+	_ AuditRepo       = (*AuditRepository)(nil)
+)
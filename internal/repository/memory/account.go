@@ -0,0 +1,558 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// AccountRepository is an in-memory repository.AccountRepo
+type AccountRepository struct {
+	mu              sync.Mutex
+	accounts        map[uuid.UUID]*model.Account
+	openingBalances map[uuid.UUID]decimal.Decimal
+	statusChanges   []model.AccountStatusChange
+	adjustments     []model.AccountAdjustment
+	snapshots       map[uuid.UUID]map[time.Time]decimal.Decimal // balance at the midnight closing each day
+	transactions    *TransactionRepository
+}
+
+// NewAccountRepository creates an empty account repository. Ledger balances are
+// computed from the transactions stored in transactions.
+func NewAccountRepository(transactions *TransactionRepository) *AccountRepository {
+	return &AccountRepository{
+		accounts:        make(map[uuid.UUID]*model.Account),
+		openingBalances: make(map[uuid.UUID]decimal.Decimal),
+		snapshots:       make(map[uuid.UUID]map[time.Time]decimal.Decimal),
+		transactions:    transactions,
+	}
+}
+
+var _ repository.AccountRepo = (*AccountRepository)(nil)
+
+// Create creates a new active account with the balance, currency, type, interest rate,
+// balance limits, owner and external reference of the given account
+func (r *AccountRepository) Create(ctx context.Context, account *model.Account) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.create(account), nil
+}
+
+// CreateOrGet creates an account like Create unless one with the same external
+// reference exists, in which case that account is returned instead
+func (r *AccountRepository) CreateOrGet(ctx context.Context, account *model.Account) (*model.Account, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.accounts {
+		if existing.ExternalRef != nil && account.ExternalRef != nil && *existing.ExternalRef == *account.ExternalRef {
+			copied := *existing
+			return &copied, false, nil
+		}
+	}
+	return r.create(account), true, nil
+}
+
+// CreateOrGetTx is CreateOrGet; tx is ignored
+func (r *AccountRepository) CreateOrGetTx(ctx context.Context, tx *sql.Tx, account *model.Account) (*model.Account, bool, error) {
+	return r.CreateOrGet(ctx, account)
+}
+
+// create stores a new account; r.mu must be held
+func (r *AccountRepository) create(account *model.Account) *model.Account {
+	now := time.Now().UTC()
+	account = &model.Account{
+		ID:           uuid.New(),
+		Balance:      account.Balance,
+		Currency:     account.Currency,
+		Status:       model.AccountStatusActive,
+		AccountType:  account.AccountType,
+		InterestRate: account.InterestRate,
+		MinBalance:   account.MinBalance,
+		MaxBalance:   account.MaxBalance,
+		OwnerID:      account.OwnerID,
+		ExternalRef:  account.ExternalRef,
+		Version:      1,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if account.Currency == "" {
+		account.Currency = model.DefaultCurrency
+	}
+	if account.AccountType == "" {
+		account.AccountType = model.AccountTypeChecking
+	}
+	r.accounts[account.ID] = account
+	r.openingBalances[account.ID] = account.Balance
+
+	copied := *account
+	return &copied
+}
+
+// ListInterestBearing retrieves the savings accounts that earn interest and aren't closed
+func (r *AccountRepository) ListInterestBearing(ctx context.Context) ([]*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var accounts []*model.Account
+	for _, account := range r.accounts {
+		if account.AccountType == model.AccountTypeSavings && account.InterestRate.IsPositive() && account.Status != model.AccountStatusClosed {
+			copied := *account
+			accounts = append(accounts, &copied)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID.String() < accounts[j].ID.String()
+	})
+	return accounts, nil
+}
+
+// ListByOwner retrieves every account of an owner, oldest first
+func (r *AccountRepository) ListByOwner(ctx context.Context, ownerID string) ([]*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var accounts []*model.Account
+	for _, account := range r.accounts {
+		if account.OwnerID != nil && *account.OwnerID == ownerID {
+			copied := *account
+			accounts = append(accounts, &copied)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if !accounts[i].CreatedAt.Equal(accounts[j].CreatedAt) {
+			return accounts[i].CreatedAt.Before(accounts[j].CreatedAt)
+		}
+		return accounts[i].ID.String() < accounts[j].ID.String()
+	})
+	return accounts, nil
+}
+
+// GetOwnerBalances sums the balances of an owner's accounts per currency, ordered by
+// currency code
+func (r *AccountRepository) GetOwnerBalances(ctx context.Context, ownerID string) ([]model.OwnerBalance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	totals := make(map[string]*model.OwnerBalance)
+	for _, account := range r.accounts {
+		if account.OwnerID == nil || *account.OwnerID != ownerID {
+			continue
+		}
+		total, ok := totals[account.Currency]
+		if !ok {
+			total = &model.OwnerBalance{Currency: account.Currency}
+			totals[account.Currency] = total
+		}
+		total.Accounts++
+		total.Balance = total.Balance.Add(account.Balance)
+	}
+
+	balances := make([]model.OwnerBalance, 0, len(totals))
+	for _, total := range totals {
+		balances = append(balances, *total)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Currency < balances[j].Currency
+	})
+	return balances, nil
+}
+
+// SetCreatedAt changes when an account was opened
+func (r *AccountRepository) SetCreatedAt(id uuid.UUID, createdAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return repository.ErrAccountNotFound
+	}
+	account.CreatedAt = createdAt
+	return nil
+}
+
+// SetStatus changes an account's status
+func (r *AccountRepository) SetStatus(id uuid.UUID, status model.AccountStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return repository.ErrAccountNotFound
+	}
+	account.Status = status
+	account.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// GetByID retrieves an account by its ID
+func (r *AccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, repository.ErrAccountNotFound
+	}
+
+	copied := *account
+	return &copied, nil
+}
+
+// GetCurrencies retrieves the currency of each of the given accounts that exists
+func (r *AccountRepository) GetCurrencies(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	currencies := make(map[uuid.UUID]string, len(ids))
+	for _, id := range ids {
+		if account, ok := r.accounts[id]; ok {
+			currencies[id] = account.Currency
+		}
+	}
+	return currencies, nil
+}
+
+// GetBalances retrieves the balance of each of the given accounts that exists
+func (r *AccountRepository) GetBalances(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balances := make(map[uuid.UUID]decimal.Decimal, len(ids))
+	for _, id := range ids {
+		if account, ok := r.accounts[id]; ok {
+			balances[id] = account.Balance
+		}
+	}
+	return balances, nil
+}
+
+// GetBalanceForUpdate retrieves an account's balance; tx is ignored
+func (r *AccountRepository) GetBalanceForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
+	account, err := r.GetByID(ctx, id)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return account.Balance, nil
+}
+
+// GetForUpdate retrieves an account; tx is ignored
+func (r *AccountRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Account, error) {
+	return r.GetByID(ctx, id)
+}
+
+// UpdateBalance updates an account's balance, advancing its version; tx is ignored
+func (r *AccountRepository) UpdateBalance(ctx context.Context, tx *sql.Tx, id uuid.UUID, newBalance decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return repository.ErrAccountNotFound
+	}
+	account.Balance = newBalance
+	account.Version++
+	account.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// UpdateStatus updates an account's status; tx is ignored
+func (r *AccountRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status model.AccountStatus) (time.Time, error) {
+	if err := r.SetStatus(id, status); err != nil {
+		return time.Time{}, err
+	}
+	account, err := r.GetByID(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return account.UpdatedAt, nil
+}
+
+// RecordStatusChange stores the audit record of a status change; tx is ignored
+func (r *AccountRepository) RecordStatusChange(ctx context.Context, tx *sql.Tx, change *model.AccountStatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change.ID = uuid.New()
+	r.statusChanges = append(r.statusChanges, *change)
+	return nil
+}
+
+// RecordAdjustment stores the audit record of a balance adjustment; tx is ignored
+func (r *AccountRepository) RecordAdjustment(ctx context.Context, tx *sql.Tx, adjustment *model.AccountAdjustment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	adjustment.ID = uuid.New()
+	r.adjustments = append(r.adjustments, *adjustment)
+	return nil
+}
+
+// MarkSystem flags an account as a system account and returns it
+func (r *AccountRepository) MarkSystem(ctx context.Context, id uuid.UUID) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, repository.ErrAccountNotFound
+	}
+	account.System = true
+
+	copied := *account
+	return &copied, nil
+}
+
+// StatusChanges returns the recorded status changes of an account, oldest first
+func (r *AccountRepository) StatusChanges(id uuid.UUID) []model.AccountStatusChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changes []model.AccountStatusChange
+	for _, change := range r.statusChanges {
+		if change.AccountID == id {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Adjustments returns the recorded balance adjustments of an account, oldest first
+func (r *AccountRepository) Adjustments(id uuid.UUID) []model.AccountAdjustment {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var adjustments []model.AccountAdjustment
+	for _, adjustment := range r.adjustments {
+		if adjustment.AccountID == id {
+			adjustments = append(adjustments, adjustment)
+		}
+	}
+	return adjustments
+}
+
+// GetLedgerBalance recomputes an account's balance from its opening balance and
+// the completed transactions in the transaction repository
+func (r *AccountRepository) GetLedgerBalance(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
+	r.mu.Lock()
+	balance, ok := r.openingBalances[id]
+	r.mu.Unlock()
+	if !ok {
+		return decimal.Zero, repository.ErrAccountNotFound
+	}
+
+	for _, t := range r.transactions.all() {
+		if t.Status != model.TransactionStatusCompleted {
+			continue
+		}
+		if t.DestinationAccountID != nil && *t.DestinationAccountID == id {
+			balance = balance.Add(t.CreditedAmount())
+		}
+		if t.FeeAccountID != nil && *t.FeeAccountID == id && t.Fee != nil {
+			balance = balance.Add(*t.Fee)
+		}
+		if t.SourceAccountID != nil && *t.SourceAccountID == id {
+			balance = balance.Sub(t.Amount)
+			if t.Fee != nil {
+				balance = balance.Sub(*t.Fee)
+			}
+		}
+	}
+
+	return balance, nil
+}
+
+// ScanLedgers calls fn with the stored and ledger balance of every account, in ID order;
+// tx is ignored
+func (r *AccountRepository) ScanLedgers(ctx context.Context, tx *sql.Tx, fn func(model.AccountLedger) error) error {
+	r.mu.Lock()
+	ledgers := make([]model.AccountLedger, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		ledgers = append(ledgers, model.AccountLedger{ID: account.ID, Currency: account.Currency, StoredBalance: account.Balance})
+	}
+	r.mu.Unlock()
+	sort.Slice(ledgers, func(i, j int) bool {
+		return bytes.Compare(ledgers[i].ID[:], ledgers[j].ID[:]) < 0
+	})
+
+	for _, ledger := range ledgers {
+		balance, err := r.GetLedgerBalance(ctx, tx, ledger.ID)
+		if err != nil {
+			return err
+		}
+		ledger.LedgerBalance = balance
+		if err := fn(ledger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SumCurrencyMovements totals, per currency, the accounts' opening balances and the
+// completed deposits, withdrawals and cross-currency transfers; tx is ignored
+func (r *AccountRepository) SumCurrencyMovements(ctx context.Context, tx *sql.Tx) (map[string]model.CurrencyMovements, error) {
+	r.mu.Lock()
+	currencies := make(map[uuid.UUID]string, len(r.accounts))
+	movements := make(map[string]model.CurrencyMovements)
+	for id, account := range r.accounts {
+		currencies[id] = account.Currency
+		totals := movements[account.Currency]
+		totals.OpeningBalance = totals.OpeningBalance.Add(r.openingBalances[id])
+		movements[account.Currency] = totals
+	}
+	r.mu.Unlock()
+
+	currencyOf := func(id *uuid.UUID) (string, bool) {
+		if id == nil {
+			return "", false
+		}
+		currency, ok := currencies[*id]
+		return currency, ok
+	}
+	for _, t := range r.transactions.all() {
+		if t.Status != model.TransactionStatusCompleted {
+			continue
+		}
+		source, hasSource := currencyOf(t.SourceAccountID)
+		dest, hasDest := currencyOf(t.DestinationAccountID)
+		if hasDest && (!hasSource || source != dest) {
+			totals := movements[dest]
+			totals.Movements = totals.Movements.Add(t.CreditedAmount())
+			movements[dest] = totals
+		}
+		if hasSource && (!hasDest || dest != source) {
+			totals := movements[source]
+			totals.Movements = totals.Movements.Sub(t.Amount)
+			movements[source] = totals
+		}
+	}
+
+	return movements, nil
+}
+
+// GetBalanceAt computes the account's ledger balance at a timestamp from its latest
+// balance snapshot taken by then plus the ledger since, or from the ledger alone
+func (r *AccountRepository) GetBalanceAt(ctx context.Context, id uuid.UUID, timestamp time.Time) (decimal.Decimal, error) {
+	r.mu.Lock()
+	var snapshotAt time.Time
+	var snapshot decimal.Decimal
+	for day, balance := range r.snapshots[id] {
+		if end := day.Add(24 * time.Hour); !end.After(timestamp) && end.After(snapshotAt) {
+			snapshotAt, snapshot = end, balance
+		}
+	}
+	r.mu.Unlock()
+
+	if snapshotAt.IsZero() {
+		balances, err := r.GetBalanceHistory(ctx, id, []time.Time{timestamp})
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return balances[0], nil
+	}
+	balances, err := r.GetBalanceHistory(ctx, id, []time.Time{snapshotAt, timestamp})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return snapshot.Add(balances[1].Sub(balances[0])), nil
+}
+
+// WriteBalanceSnapshots records the ledger balance of every account opened by the
+// midnight closing day, at that midnight, keeping any snapshot already written for the day
+func (r *AccountRepository) WriteBalanceSnapshots(ctx context.Context, day time.Time) (int, error) {
+	day = day.UTC().Truncate(24 * time.Hour)
+	end := day.Add(24 * time.Hour)
+
+	r.mu.Lock()
+	var ids []uuid.UUID
+	for id, account := range r.accounts {
+		if _, ok := r.snapshots[id][day]; !ok && !account.CreatedAt.After(end) {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, id := range ids {
+		balances, err := r.GetBalanceHistory(ctx, id, []time.Time{end})
+		if err != nil {
+			return 0, err
+		}
+		r.mu.Lock()
+		if r.snapshots[id] == nil {
+			r.snapshots[id] = make(map[time.Time]decimal.Decimal)
+		}
+		r.snapshots[id][day] = balances[0]
+		r.mu.Unlock()
+	}
+	return len(ids), nil
+}
+
+// GetBalanceHistory computes the account's ledger balance at each of points from the
+// completed transactions in the transaction repository
+func (r *AccountRepository) GetBalanceHistory(ctx context.Context, id uuid.UUID, points []time.Time) ([]decimal.Decimal, error) {
+	r.mu.Lock()
+	account, ok := r.accounts[id]
+	var openedAt time.Time
+	if ok {
+		openedAt = account.CreatedAt
+	}
+	openingBalance := r.openingBalances[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil, repository.ErrAccountNotFound
+	}
+
+	balances := make([]decimal.Decimal, len(points))
+	for i, at := range points {
+		if !at.Before(openedAt) {
+			balances[i] = openingBalance
+		}
+	}
+	for _, t := range r.transactions.all() {
+		if t.Status != model.TransactionStatusCompleted {
+			continue
+		}
+		delta := decimal.Zero
+		if t.DestinationAccountID != nil && *t.DestinationAccountID == id {
+			delta = delta.Add(t.CreditedAmount())
+		}
+		if t.FeeAccountID != nil && *t.FeeAccountID == id && t.Fee != nil {
+			delta = delta.Add(*t.Fee)
+		}
+		if t.SourceAccountID != nil && *t.SourceAccountID == id {
+			delta = delta.Sub(t.Amount)
+			if t.Fee != nil {
+				delta = delta.Sub(*t.Fee)
+			}
+		}
+		completedAt := t.CreatedAt
+		if t.CompletedAt != nil {
+			completedAt = *t.CompletedAt
+		}
+		for i, at := range points {
+			if !completedAt.After(at) {
+				balances[i] = balances[i].Add(delta)
+			}
+		}
+	}
+	return balances, nil
+}
+
+// Exists checks if an account exists
+func (r *AccountRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.accounts[id]
+	return ok, nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// AuditRepository is an in-memory repository.AuditRepo
+type AuditRepository struct {
+	mu      sync.Mutex
+	entries []model.AuditEntry
+}
+
+// NewAuditRepository creates an empty audit repository
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+var _ repository.AuditRepo = (*AuditRepository)(nil)
+
+// Record stores an audit entry; tx is ignored
+func (r *AuditRepository) Record(ctx context.Context, tx *sql.Tx, entry *model.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// Entries returns the recorded audit entries, oldest first
+func (r *AuditRepository) Entries() []model.AuditEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]model.AuditEntry(nil), r.entries...)
+}
@@ -0,0 +1,42 @@
+// Package memory provides in-memory implementations of the repository
+// interfaces, for testing services without Postgres.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// NewDB returns a *sql.DB whose transactions do nothing, so services can begin,
+// commit and roll back transactions around in-memory repositories. The
+// repositories apply writes immediately; a rollback does not undo them.
+func NewDB() *sql.DB {
+	return sql.OpenDB(connector{})
+}
+
+type connector struct{}
+
+func (connector) Connect(context.Context) (driver.Conn, error) { return conn{}, nil }
+func (connector) Driver() driver.Driver                        { return noopDriver{} }
+
+type noopDriver struct{}
+
+func (noopDriver) Open(string) (driver.Conn, error) { return conn{}, nil }
+
+type conn struct{}
+
+func (conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("memory: queries are not supported")
+}
+func (conn) Close() error              { return nil }
+func (conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+// BeginTx accepts any isolation level, which the services always set
+func (conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
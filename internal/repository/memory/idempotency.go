@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"internal-transfers-api/internal/repository"
+)
+
+// IdempotencyRepository is an in-memory repository.IdempotencyRepo
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*repository.IdempotencyRecord
+}
+
+// NewIdempotencyRepository creates an empty idempotency repository
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{
+		records: make(map[string]*repository.IdempotencyRecord),
+	}
+}
+
+var _ repository.IdempotencyRepo = (*IdempotencyRepository)(nil)
+
+// ClaimRequest stores an idempotency key with the request body unless an unexpired
+// record already holds the key, reporting whether it did
+func (r *IdempotencyRepository) ClaimRequest(ctx context.Context, keyHash, requestBody string, now time.Time, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[keyHash]; ok && record.ExpiresAt.After(now) {
+		return false, nil
+	}
+	now = now.UTC()
+	r.records[keyHash] = &repository.IdempotencyRecord{
+		KeyHash:     keyHash,
+		RequestBody: requestBody,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	return true, nil
+}
+
+// ReleaseRequest removes an idempotency record that has no response stored yet
+func (r *IdempotencyRepository) ReleaseRequest(ctx context.Context, keyHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[keyHash]; ok && record.ResponseBody == nil {
+		delete(r.records, keyHash)
+	}
+	return nil
+}
+
+// GetRequest retrieves an idempotency record unexpired by now, or nil if there is none
+func (r *IdempotencyRepository) GetRequest(ctx context.Context, keyHash string, now time.Time) (*repository.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[keyHash]
+	if !ok || !record.ExpiresAt.After(now) {
+		return nil, nil
+	}
+
+	copied := *record
+	return &copied, nil
+}
+
+// GetStatus retrieves an idempotency record whether or not it has expired, or nil if there is none
+func (r *IdempotencyRepository) GetStatus(ctx context.Context, keyHash string) (*repository.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[keyHash]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *record
+	return &copied, nil
+}
+
+// UpdateResponse stores the response for an idempotency key
+func (r *IdempotencyRepository) UpdateResponse(ctx context.Context, keyHash, responseBody string, status int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[keyHash]; ok {
+		record.ResponseBody = &responseBody
+		record.ResponseStatus = &status
+	}
+	return nil
+}
+
+// CleanupExpired removes idempotency records expired by now
+func (r *IdempotencyRepository) CleanupExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed int64
+	for keyHash, record := range r.records {
+		if record.ExpiresAt.Before(now) {
+			delete(r.records, keyHash)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,492 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// TransactionRepository is an in-memory repository.TransactionRepo
+type TransactionRepository struct {
+	mu           sync.Mutex
+	transactions map[uuid.UUID]*model.Transaction
+	notes        []model.TransactionNote
+	notified     []string
+}
+
+// NewTransactionRepository creates an empty transaction repository
+func NewTransactionRepository() *TransactionRepository {
+	return &TransactionRepository{
+		transactions: make(map[uuid.UUID]*model.Transaction),
+	}
+}
+
+var _ repository.TransactionRepo = (*TransactionRepository)(nil)
+
+// Create creates a pending transaction; tx is ignored
+func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest) (*model.Transaction, error) {
+	return r.insert(req, model.TransactionStatusPending, nil)
+}
+
+// CreateFailed records a declined transfer with the decline reason
+func (r *TransactionRepository) CreateFailed(ctx context.Context, req *model.CreateTransactionRequest, reason string) (*model.Transaction, error) {
+	return r.insert(req, model.TransactionStatusFailed, &reason)
+}
+
+func (r *TransactionRepository) insert(req *model.CreateTransactionRequest, status model.TransactionStatus, reason *string) (*model.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := uuid.New()
+	if req.ID != nil {
+		id = *req.ID
+	}
+	if _, ok := r.transactions[id]; ok {
+		return nil, repository.ErrTransactionExists
+	}
+
+	now := time.Now().UTC()
+	transaction := &model.Transaction{
+		ID:                   id,
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		Reference:            req.Reference,
+		Description:          req.Description,
+		Fee:                  req.Fee,
+		FeeAccountID:         req.FeeAccountID,
+		DestinationAmount:    req.DestinationAmount,
+		FXRate:               req.FXRate,
+		ReversalOf:           req.ReversalOf,
+		RetryOf:              req.RetryOf,
+		Status:               status,
+		FailureReason:        reason,
+		Channel:              req.Channel,
+		ClientIP:             req.ClientIP,
+		CreatedAt:            now,
+	}
+	if status == model.TransactionStatusFailed {
+		transaction.CompletedAt = &now
+	}
+	r.transactions[id] = transaction
+
+	copied := *transaction
+	return &copied, nil
+}
+
+// SetCreatedAt changes when a transaction was created
+func (r *TransactionRepository) SetCreatedAt(id uuid.UUID, createdAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return repository.ErrTransactionNotFound
+	}
+	transaction.CreatedAt = createdAt
+	return nil
+}
+
+// SetCompletedAt changes when a transaction was completed
+func (r *TransactionRepository) SetCompletedAt(id uuid.UUID, completedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return repository.ErrTransactionNotFound
+	}
+	transaction.CompletedAt = &completedAt
+	return nil
+}
+
+// GetStatus retrieves just a transaction's status and timestamps
+func (r *TransactionRepository) GetStatus(ctx context.Context, id uuid.UUID) (*model.TransactionStatusResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return nil, repository.ErrTransactionNotFound
+	}
+
+	return &model.TransactionStatusResponse{
+		ID:          transaction.ID,
+		Status:      transaction.Status,
+		CreatedAt:   transaction.CreatedAt,
+		CompletedAt: transaction.CompletedAt,
+	}, nil
+}
+
+// UpdateStatus updates the status of a transaction; tx is ignored
+func (r *TransactionRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, status model.TransactionStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return repository.ErrTransactionNotFound
+	}
+	transaction.Status = status
+	if status == model.TransactionStatusCompleted || status == model.TransactionStatusFailed {
+		now := time.Now().UTC()
+		transaction.CompletedAt = &now
+	}
+	return nil
+}
+
+// SetReversedBy links a reversed transaction to the transaction that reversed it
+func (r *TransactionRepository) SetReversedBy(ctx context.Context, tx *sql.Tx, id, reversalID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return repository.ErrTransactionNotFound
+	}
+	transaction.ReversedBy = &reversalID
+	return nil
+}
+
+// SetDisputed raises a dispute about a transaction; tx is ignored
+func (r *TransactionRepository) SetDisputed(ctx context.Context, tx *sql.Tx, id uuid.UUID, reason string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return repository.ErrTransactionNotFound
+	}
+	transaction.Disputed = true
+	transaction.DisputeReason = &reason
+	transaction.DisputedAt = &at
+	transaction.DisputeResolvedAt = nil
+	return nil
+}
+
+// ResolveDispute clears a transaction's dispute, keeping its reason; tx is ignored
+func (r *TransactionRepository) ResolveDispute(ctx context.Context, tx *sql.Tx, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return repository.ErrTransactionNotFound
+	}
+	transaction.Disputed = false
+	transaction.DisputeResolvedAt = &at
+	return nil
+}
+
+// NotifyCompleted records the payload instead of publishing it
+func (r *TransactionRepository) NotifyCompleted(ctx context.Context, tx *sql.Tx, payload string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notified = append(r.notified, payload)
+	return nil
+}
+
+// Notified returns the payloads passed to NotifyCompleted, oldest first
+func (r *TransactionRepository) Notified() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.notified...)
+}
+
+// GetByID retrieves a transaction by its ID
+func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return nil, repository.ErrTransactionNotFound
+	}
+
+	copied := *transaction
+	return &copied, nil
+}
+
+// GetByIDs retrieves each of the given transactions that exists
+func (r *TransactionRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var transactions []*model.Transaction
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if transaction, ok := r.transactions[id]; ok && !seen[id] {
+			copied := *transaction
+			transactions = append(transactions, &copied)
+		}
+		seen[id] = true
+	}
+	return transactions, nil
+}
+
+// GetByReference retrieves the most recent transaction with the given reference
+func (r *TransactionRepository) GetByReference(ctx context.Context, reference string) (*model.Transaction, error) {
+	for _, t := range r.all() {
+		if t.Reference != nil && *t.Reference == reference {
+			return t, nil
+		}
+	}
+	return nil, repository.ErrTransactionNotFound
+}
+
+// GetRetry retrieves the retry of the given failed transaction that didn't fail itself
+func (r *TransactionRepository) GetRetry(ctx context.Context, id uuid.UUID) (*model.Transaction, error) {
+	for _, t := range r.all() {
+		if t.RetryOf != nil && *t.RetryOf == id && t.Status != model.TransactionStatusFailed {
+			return t, nil
+		}
+	}
+	return nil, repository.ErrTransactionNotFound
+}
+
+// GetForUpdate retrieves a transaction by its ID; tx is ignored and nothing is locked
+func (r *TransactionRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Transaction, error) {
+	return r.GetByID(ctx, id)
+}
+
+// GetAllByReference retrieves up to limit transactions with the given reference, oldest
+// first; tx is ignored
+func (r *TransactionRepository) GetAllByReference(ctx context.Context, tx *sql.Tx, reference string, limit int) ([]*model.Transaction, error) {
+	all := r.all()
+	var transactions []*model.Transaction
+	for i := len(all) - 1; i >= 0 && len(transactions) < limit; i-- {
+		if all[i].Reference != nil && *all[i].Reference == reference {
+			transactions = append(transactions, all[i])
+		}
+	}
+	return transactions, nil
+}
+
+// GetReversedAmount sums the completed reversals of the given transaction; tx is ignored
+func (r *TransactionRepository) GetReversedAmount(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
+	reversed := decimal.Zero
+	for _, t := range r.all() {
+		if t.ReversalOf != nil && *t.ReversalOf == id && t.Status == model.TransactionStatusCompleted {
+			reversed = reversed.Add(t.CreditedAmount())
+		}
+	}
+	return reversed, nil
+}
+
+// ListStalePending retrieves up to limit pending transactions created before the given
+// time, oldest first
+func (r *TransactionRepository) ListStalePending(ctx context.Context, before time.Time, limit int) ([]*model.Transaction, error) {
+	all := r.all()
+	var transactions []*model.Transaction
+	for i := len(all) - 1; i >= 0 && len(transactions) < limit; i-- {
+		if all[i].Status == model.TransactionStatusPending && all[i].CreatedAt.Before(before) {
+			transactions = append(transactions, all[i])
+		}
+	}
+	return transactions, nil
+}
+
+// FindRecentDuplicate retrieves the latest transfer created since the given time with
+// the request's source, destination and amount, other than failed ones and reversals;
+// tx is ignored
+func (r *TransactionRepository) FindRecentDuplicate(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest, since time.Time) (*model.Transaction, error) {
+	for _, t := range r.all() {
+		if t.CreatedAt.Before(since) {
+			break
+		}
+		if t.Status == model.TransactionStatusFailed || t.ReversalOf != nil || !t.Amount.Equal(req.Amount) {
+			continue
+		}
+		if sameAccount(t.SourceAccountID, req.SourceAccountID) && sameAccount(t.DestinationAccountID, req.DestinationAccountID) {
+			return t, nil
+		}
+	}
+	return nil, repository.ErrTransactionNotFound
+}
+
+// CountRecentTransfers counts the transfers an account has made since a time, along with
+// when the oldest of them was made; tx is ignored
+func (r *TransactionRepository) CountRecentTransfers(ctx context.Context, tx *sql.Tx, accountID uuid.UUID, since time.Time) (int, time.Time, error) {
+	count, oldest := 0, time.Time{}
+	for _, t := range r.all() {
+		if t.CreatedAt.Before(since) {
+			break
+		}
+		if t.SourceAccountID == nil || *t.SourceAccountID != accountID || t.Status == model.TransactionStatusFailed || t.ReversalOf != nil {
+			continue
+		}
+		count++
+		oldest = t.CreatedAt
+	}
+	return count, oldest, nil
+}
+
+// sameAccount reports whether two optional account IDs are both missing or equal
+func sameAccount(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// GetAccountTransactions retrieves transactions for a specific account that match the filter
+func (r *TransactionRepository) GetAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter, limit, offset int) ([]*model.Transaction, error) {
+	transactions := r.accountTransactions(accountID, filter)
+	if offset >= len(transactions) {
+		return nil, nil
+	}
+	transactions = transactions[offset:]
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	return transactions, nil
+}
+
+// CountAccountTransactions counts the transactions for a specific account that match the filter
+func (r *TransactionRepository) CountAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter) (int, error) {
+	return len(r.accountTransactions(accountID, filter)), nil
+}
+
+// accountTransactions returns every transaction for an account that matches the filter
+func (r *TransactionRepository) accountTransactions(accountID uuid.UUID, filter model.TransactionFilter) []*model.Transaction {
+	var transactions []*model.Transaction
+	for _, t := range r.all() {
+		isSource := t.SourceAccountID != nil && *t.SourceAccountID == accountID
+		isDestination := t.DestinationAccountID != nil && *t.DestinationAccountID == accountID
+		isFee := t.FeeAccountID != nil && *t.FeeAccountID == accountID
+		if !isSource && !isDestination && !isFee {
+			continue
+		}
+		if filter.MinAmount != nil && t.Amount.LessThan(*filter.MinAmount) {
+			continue
+		}
+		if filter.MaxAmount != nil && t.Amount.GreaterThan(*filter.MaxAmount) {
+			continue
+		}
+		if filter.Disputed != nil && t.Disputed != *filter.Disputed {
+			continue
+		}
+		if filter.Counterparty != nil {
+			counterparty := *filter.Counterparty
+			sentTo := isSource && t.DestinationAccountID != nil && *t.DestinationAccountID == counterparty
+			receivedFrom := isDestination && t.SourceAccountID != nil && *t.SourceAccountID == counterparty
+			if !sentTo && !receivedFrom {
+				continue
+			}
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions
+}
+
+// GetCounterparties aggregates the account's completed transfers by the account on the
+// other side, largest total volume first
+func (r *TransactionRepository) GetCounterparties(ctx context.Context, accountID uuid.UUID, filter model.CounterpartyFilter) ([]model.Counterparty, error) {
+	byID := make(map[uuid.UUID]*model.Counterparty)
+	for _, t := range r.all() {
+		if t.SourceAccountID == nil || t.DestinationAccountID == nil || t.Status != model.TransactionStatusCompleted {
+			continue
+		}
+		if (filter.From != nil && t.CreatedAt.Before(*filter.From)) || (filter.To != nil && !t.CreatedAt.Before(*filter.To)) {
+			continue
+		}
+
+		var counterpartyID uuid.UUID
+		switch accountID {
+		case *t.SourceAccountID:
+			counterpartyID = *t.DestinationAccountID
+		case *t.DestinationAccountID:
+			counterpartyID = *t.SourceAccountID
+		default:
+			continue
+		}
+
+		c, ok := byID[counterpartyID]
+		if !ok {
+			c = &model.Counterparty{AccountID: counterpartyID}
+			byID[counterpartyID] = c
+		}
+		if counterpartyID == *t.DestinationAccountID {
+			c.Sent = c.Sent.Add(t.Amount)
+		} else {
+			c.Received = c.Received.Add(t.CreditedAmount())
+		}
+		c.TransactionCount++
+	}
+
+	counterparties := make([]model.Counterparty, 0, len(byID))
+	for _, c := range byID {
+		c.NetFlow = c.Sent.Sub(c.Received)
+		counterparties = append(counterparties, *c)
+	}
+	sort.Slice(counterparties, func(i, j int) bool {
+		vi := counterparties[i].Sent.Add(counterparties[i].Received)
+		vj := counterparties[j].Sent.Add(counterparties[j].Received)
+		if !vi.Equal(vj) {
+			return vi.GreaterThan(vj)
+		}
+		return counterparties[i].AccountID.String() < counterparties[j].AccountID.String()
+	})
+	if len(counterparties) > filter.Limit {
+		counterparties = counterparties[:filter.Limit]
+	}
+	return counterparties, nil
+}
+
+// AddNote appends a note to a transaction; tx is ignored
+func (r *TransactionRepository) AddNote(ctx context.Context, tx *sql.Tx, note *model.TransactionNote) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.transactions[note.TransactionID]; !ok {
+		return repository.ErrTransactionNotFound
+	}
+	note.ID = uuid.New()
+	r.notes = append(r.notes, *note)
+	return nil
+}
+
+// GetNotes retrieves the notes on a transaction, oldest first
+func (r *TransactionRepository) GetNotes(ctx context.Context, transactionID uuid.UUID) ([]model.TransactionNote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notes := []model.TransactionNote{}
+	for _, note := range r.notes {
+		if note.TransactionID == transactionID {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+// all returns copies of every transaction, newest first and then by descending ID, as
+// the database orders them
+func (r *TransactionRepository) all() []*model.Transaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transactions := make([]*model.Transaction, 0, len(r.transactions))
+	for _, t := range r.transactions {
+		copied := *t
+		transactions = append(transactions, &copied)
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].CreatedAt.After(transactions[j].CreatedAt)
+		}
+		return bytes.Compare(transactions[i].ID[:], transactions[j].ID[:]) > 0
+	})
+	return transactions
+}
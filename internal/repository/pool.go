@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPoolExhausted is returned by BeginTx when no pooled connection frees up within
+// the acquisition timeout
+var ErrPoolExhausted = errors.New("no database connection available")
+
+// acquireTimeout is how long BeginTx waits for a pooled connection; zero waits for as
+// long as the context allows
+var acquireTimeout atomic.Int64
+
+// acquireTimeouts counts the transactions refused with ErrPoolExhausted
+var acquireTimeouts atomic.Int64
+
+// SetAcquireTimeout sets how long a transaction waits for a connection from the pool
+// before failing with ErrPoolExhausted. Zero, the default, waits until the context is
+// done.
+func SetAcquireTimeout(timeout time.Duration) {
+	acquireTimeout.Store(int64(timeout))
+}
+
+// AcquireTimeouts returns how many transactions have failed with ErrPoolExhausted
+// since startup
+func AcquireTimeouts() int64 {
+	return acquireTimeouts.Load()
+}
+
+// BeginTx starts a transaction on a connection taken from db's pool, waiting no longer
+// than the acquisition timeout for one, so an exhausted pool fails fast with
+// ErrPoolExhausted instead of at the request deadline like a slow query. The returned
+// release gives the connection back to the pool and must be called once the
+// transaction has been committed or rolled back.
+func BeginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (_ *sql.Tx, release func(), err error) {
+	timeout := time.Duration(acquireTimeout.Load())
+	if timeout <= 0 {
+		tx, err := db.BeginTx(ctx, opts)
+		return tx, func() {}, err
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, timeout)
+	conn, err := db.Conn(acquireCtx)
+	cancel()
+	if err != nil {
+		// Only our own deadline means the pool was exhausted; the caller's is theirs
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			acquireTimeouts.Add(1)
+			return nil, nil, ErrPoolExhausted
+		}
+		return nil, nil, err
+	}
+
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return tx, func() { conn.Close() }, nil
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// replicaKey marks a context whose reads may be served by the read replica
+type replicaKey struct{}
+
+// ReadFromReplica marks ctx so the reads it's passed to go to the read replica, when one
+// is configured. A replica lags the primary, so only reads answered straight back to the
+// client should be marked; a read a write depends on, or one that is cached, must see the
+// primary.
+func ReadFromReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey{}, true)
+}
+
+// reader returns the replica for a context marked by ReadFromReplica, and the primary for
+// any other or when no replica is configured
+func reader(ctx context.Context, primary, replica *sql.DB) *sql.DB {
+	if replica != nil {
+		if marked, _ := ctx.Value(replicaKey{}).(bool); marked {
+			return replica
+		}
+	}
+	return primary
+}
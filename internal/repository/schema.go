@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// NumericColumn is the declared precision and scale of a NUMERIC column; both are
+// invalid when the column is unconstrained NUMERIC
+type NumericColumn struct {
+	Table     string
+	Column    string
+	Precision sql.NullInt64
+	Scale     sql.NullInt64
+}
+
+// AmountColumns are the columns holding balances and transfer amounts, whose scale the
+// service's amount validation relies on
+var AmountColumns = []NumericColumn{
+	{Table: "accounts", Column: "balance"},
+	{Table: "transactions", Column: "amount"},
+}
+
+// GetNumericColumns reads the declared precision and scale of the given columns in the
+// connection's schema. Columns that don't exist are left out.
+func GetNumericColumns(ctx context.Context, db *sql.DB, columns []NumericColumn) ([]NumericColumn, error) {
+	query := `
+		SELECT numeric_precision, numeric_scale
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2
+	`
+
+	ctx, span := startQuerySpan(ctx, "GetNumericColumns", query)
+	defer span.End()
+
+	var found []NumericColumn
+	for _, column := range columns {
+		err := timed(db, "GetNumericColumns").QueryRowContext(ctx, query, column.Table, column.Column).Scan(&column.Precision, &column.Scale)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the type of %s.%s: %w", column.Table, column.Column, err)
+		}
+		found = append(found, column)
+	}
+	return found, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// slowQueryThreshold is how long a query may take before it is logged; zero disables the log
+var slowQueryThreshold atomic.Int64
+
+// SetSlowQueryThreshold sets how long a query may take before it is logged as slow.
+// Zero, the default, disables the slow-query log.
+func SetSlowQueryThreshold(threshold time.Duration) {
+	slowQueryThreshold.Store(int64(threshold))
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// timedQuerier runs queries against q and logs those slower than the threshold under name
+type timedQuerier struct {
+	q    querier
+	name string
+}
+
+// timed wraps q so the queries it runs for the named repository method are timed
+func timed(q querier, name string) timedQuerier {
+	return timedQuerier{q: q, name: name}
+}
+
+func (t timedQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer t.logIfSlow(time.Now())
+	return t.q.QueryContext(ctx, query, args...)
+}
+
+func (t timedQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer t.logIfSlow(time.Now())
+	return t.q.QueryRowContext(ctx, query, args...)
+}
+
+func (t timedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer t.logIfSlow(time.Now())
+	return t.q.ExecContext(ctx, query, args...)
+}
+
+// logIfSlow logs the query at warn level if it has run for longer than the threshold
+func (t timedQuerier) logIfSlow(start time.Time) {
+	threshold := time.Duration(slowQueryThreshold.Load())
+	if threshold <= 0 {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > threshold {
+		log.Printf("WARN slow query %s took %v (threshold %v)", t.name, elapsed, threshold)
+	}
+}
@@ -4,50 +4,152 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 
 	"internal-transfers-api/internal/model"
 )
 
 // TransactionRepository handles transaction-related database operations
 type TransactionRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB // serves reads marked by ReadFromReplica; nil sends them to db
+	errs    ErrorMapping
 }
 
-// NewTransactionRepository creates a new transaction repository
-func NewTransactionRepository(db *sql.DB) *TransactionRepository {
-	return &TransactionRepository{db: db}
+// NewTransactionRepository creates a new transaction repository whose writes report
+// database errors classified by errs
+func NewTransactionRepository(db *sql.DB, errs ErrorMapping) *TransactionRepository {
+	return NewTransactionRepositoryWithReplica(db, nil, errs)
 }
 
-// Create creates a new transaction
-func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest) (*model.Transaction, error) {
-	query := `
-		INSERT INTO transactions (source_account_id, destination_account_id, amount, reference, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
-		RETURNING id, source_account_id, destination_account_id, amount, reference, status, created_at, completed_at
-	`
+// NewTransactionRepositoryWithReplica creates a new transaction repository that serves
+// reads marked by ReadFromReplica from replica, and everything else from db
+func NewTransactionRepositoryWithReplica(db, replica *sql.DB, errs ErrorMapping) *TransactionRepository {
+	return &TransactionRepository{db: db, replica: replica, errs: errs}
+}
+
+// transactionColumns is the column list shared by every query returning a full transaction
+const transactionColumns = `id, source_account_id, destination_account_id, amount, reference, description, fee, fee_account_id, destination_amount, fx_rate, reversal_of, reversed_by, retry_of, status, failure_reason, channel, client_ip, created_at, completed_at, disputed, dispute_reason, disputed_at, dispute_resolved_at`
 
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTransaction scans a row selected with transactionColumns
+func scanTransaction(row rowScanner) (*model.Transaction, error) {
 	transaction := &model.Transaction{}
-	err := tx.QueryRowContext(ctx, query,
-		req.SourceAccountID,
-		req.DestinationAccountID,
-		req.Amount,
-		req.Reference,
-		model.TransactionStatusPending,
-	).Scan(
+	err := row.Scan(
 		&transaction.ID,
 		&transaction.SourceAccountID,
 		&transaction.DestinationAccountID,
 		&transaction.Amount,
 		&transaction.Reference,
+		&transaction.Description,
+		&transaction.Fee,
+		&transaction.FeeAccountID,
+		&transaction.DestinationAmount,
+		&transaction.FXRate,
+		&transaction.ReversalOf,
+		&transaction.ReversedBy,
+		&transaction.RetryOf,
 		&transaction.Status,
+		&transaction.FailureReason,
+		&transaction.Channel,
+		&transaction.ClientIP,
 		&transaction.CreatedAt,
 		&transaction.CompletedAt,
+		&transaction.Disputed,
+		&transaction.DisputeReason,
+		&transaction.DisputedAt,
+		&transaction.DisputeResolvedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Create creates a new transaction, using the client-supplied ID when present.
+// Returns ErrTransactionExists if a transaction with that ID already exists.
+func (r *TransactionRepository) Create(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest) (*model.Transaction, error) {
+	query := `
+		INSERT INTO transactions (id, source_account_id, destination_account_id, amount, reference, description, fee, fee_account_id, destination_amount, fx_rate, reversal_of, retry_of, status, channel, client_ip, created_at)
+		VALUES (COALESCE($1::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW())
+		ON CONFLICT (id) DO NOTHING
+		RETURNING ` + transactionColumns
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.Create", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(tx, "TransactionRepository.Create").QueryRowContext(ctx, query,
+		req.ID,
+		req.SourceAccountID,
+		req.DestinationAccountID,
+		req.Amount,
+		req.Reference,
+		req.Description,
+		req.Fee,
+		req.FeeAccountID,
+		req.DestinationAmount,
+		req.FXRate,
+		req.ReversalOf,
+		req.RetryOf,
+		model.TransactionStatusPending,
+		req.Channel,
+		req.ClientIP,
+	))
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transaction: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionExists
+		}
+		return nil, r.errs.translate(fmt.Errorf("failed to create transaction: %w", err))
+	}
+
+	return transaction, nil
+}
+
+// CreateFailed records a declined transfer with status failed and the decline reason.
+// It runs outside of any transfer transaction so the record survives its rollback.
+func (r *TransactionRepository) CreateFailed(ctx context.Context, req *model.CreateTransactionRequest, reason string) (*model.Transaction, error) {
+	query := `
+		INSERT INTO transactions (id, source_account_id, destination_account_id, amount, reference, description, fee, fee_account_id, destination_amount, fx_rate, reversal_of, retry_of, status, failure_reason, channel, client_ip, created_at, completed_at)
+		VALUES (COALESCE($1::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
+		ON CONFLICT (id) DO NOTHING
+		RETURNING ` + transactionColumns
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.CreateFailed", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(r.db, "TransactionRepository.CreateFailed").QueryRowContext(ctx, query,
+		req.ID,
+		req.SourceAccountID,
+		req.DestinationAccountID,
+		req.Amount,
+		req.Reference,
+		req.Description,
+		req.Fee,
+		req.FeeAccountID,
+		req.DestinationAmount,
+		req.FXRate,
+		req.ReversalOf,
+		req.RetryOf,
+		model.TransactionStatusFailed,
+		reason,
+		req.Channel,
+		req.ClientIP,
+	))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionExists
+		}
+		return nil, r.errs.translate(fmt.Errorf("failed to record declined transaction: %w", err))
 	}
 
 	return transaction, nil
@@ -61,9 +163,36 @@ func (r *TransactionRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id
 		WHERE id = $3
 	`
 
-	result, err := tx.ExecContext(ctx, query, string(status), string(status), id)
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.UpdateStatus", query)
+	defer span.End()
+
+	result, err := timed(tx, "TransactionRepository.UpdateStatus").ExecContext(ctx, query, string(status), string(status), id)
+	if err != nil {
+		return r.errs.translate(fmt.Errorf("failed to update transaction status: %w", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTransactionNotFound
+	}
+
+	return nil
+}
+
+// SetReversedBy links a reversed transaction to the transaction that reversed it
+func (r *TransactionRepository) SetReversedBy(ctx context.Context, tx *sql.Tx, id, reversalID uuid.UUID) error {
+	query := `UPDATE transactions SET reversed_by = $1 WHERE id = $2`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.SetReversedBy", query)
+	defer span.End()
+
+	result, err := timed(tx, "TransactionRepository.SetReversedBy").ExecContext(ctx, query, reversalID, id)
 	if err != nil {
-		return fmt.Errorf("failed to update transaction status: %w", err)
+		return r.errs.translate(fmt.Errorf("failed to link transaction reversal: %w", err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -78,25 +207,87 @@ func (r *TransactionRepository) UpdateStatus(ctx context.Context, tx *sql.Tx, id
 	return nil
 }
 
+// SetDisputed raises a dispute about a transaction
+func (r *TransactionRepository) SetDisputed(ctx context.Context, tx *sql.Tx, id uuid.UUID, reason string, at time.Time) error {
+	query := `
+		UPDATE transactions
+		SET disputed = TRUE, dispute_reason = $1, disputed_at = $2, dispute_resolved_at = NULL
+		WHERE id = $3
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.SetDisputed", query)
+	defer span.End()
+
+	result, err := timed(tx, "TransactionRepository.SetDisputed").ExecContext(ctx, query, reason, at, id)
+	if err != nil {
+		return r.errs.translate(fmt.Errorf("failed to dispute transaction: %w", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTransactionNotFound
+	}
+
+	return nil
+}
+
+// ResolveDispute clears a transaction's dispute, keeping its reason
+func (r *TransactionRepository) ResolveDispute(ctx context.Context, tx *sql.Tx, id uuid.UUID, at time.Time) error {
+	query := `UPDATE transactions SET disputed = FALSE, dispute_resolved_at = $1 WHERE id = $2`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.ResolveDispute", query)
+	defer span.End()
+
+	result, err := timed(tx, "TransactionRepository.ResolveDispute").ExecContext(ctx, query, at, id)
+	if err != nil {
+		return r.errs.translate(fmt.Errorf("failed to resolve transaction dispute: %w", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTransactionNotFound
+	}
+
+	return nil
+}
+
+// TransferEventsChannel is the Postgres notification channel for completed transfers
+const TransferEventsChannel = "transfers"
+
+// NotifyCompleted publishes a completed-transfer event; Postgres delivers it only if tx commits
+func (r *TransactionRepository) NotifyCompleted(ctx context.Context, tx *sql.Tx, payload string) error {
+	query := `SELECT pg_notify($1, $2)`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.NotifyCompleted", query)
+	defer span.End()
+
+	if _, err := timed(tx, "TransactionRepository.NotifyCompleted").ExecContext(ctx, query, TransferEventsChannel, payload); err != nil {
+		return fmt.Errorf("failed to publish transfer event: %w", err)
+	}
+
+	return nil
+}
+
 // GetByID retrieves a transaction by its ID
 func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Transaction, error) {
 	query := `
-		SELECT id, source_account_id, destination_account_id, amount, reference, status, created_at, completed_at
+		SELECT ` + transactionColumns + `
 		FROM transactions
 		WHERE id = $1
 	`
 
-	transaction := &model.Transaction{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&transaction.ID,
-		&transaction.SourceAccountID,
-		&transaction.DestinationAccountID,
-		&transaction.Amount,
-		&transaction.Reference,
-		&transaction.Status,
-		&transaction.CreatedAt,
-		&transaction.CompletedAt,
-	)
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetByID", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetByID").QueryRowContext(ctx, query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -108,27 +299,76 @@ func (r *TransactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mod
 	return transaction, nil
 }
 
+// GetByIDs retrieves each of the given transactions that exists, in no particular order
+func (r *TransactionRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE id = ANY($1::uuid[])
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetByIDs", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetByIDs").QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*model.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// GetStatus retrieves just a transaction's status and timestamps
+func (r *TransactionRepository) GetStatus(ctx context.Context, id uuid.UUID) (*model.TransactionStatusResponse, error) {
+	query := `
+		SELECT status, created_at, completed_at
+		FROM transactions
+		WHERE id = $1
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetStatus", query)
+	defer span.End()
+
+	status := &model.TransactionStatusResponse{ID: id}
+	err := timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetStatus").QueryRowContext(ctx, query, id).Scan(&status.Status, &status.CreatedAt, &status.CompletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction status: %w", err)
+	}
+
+	return status, nil
+}
+
 // GetByReference retrieves a transaction by its reference
 func (r *TransactionRepository) GetByReference(ctx context.Context, reference string) (*model.Transaction, error) {
 	query := `
-		SELECT id, source_account_id, destination_account_id, amount, reference, status, created_at, completed_at
+		SELECT ` + transactionColumns + `
 		FROM transactions
 		WHERE reference = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT 1
 	`
 
-	transaction := &model.Transaction{}
-	err := r.db.QueryRowContext(ctx, query, reference).Scan(
-		&transaction.ID,
-		&transaction.SourceAccountID,
-		&transaction.DestinationAccountID,
-		&transaction.Amount,
-		&transaction.Reference,
-		&transaction.Status,
-		&transaction.CreatedAt,
-		&transaction.CompletedAt,
-	)
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetByReference", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetByReference").QueryRowContext(ctx, query, reference))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -140,17 +380,234 @@ func (r *TransactionRepository) GetByReference(ctx context.Context, reference st
 	return transaction, nil
 }
 
-// GetAccountTransactions retrieves transactions for a specific account
-func (r *TransactionRepository) GetAccountTransactions(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*model.Transaction, error) {
+// GetRetry retrieves the retry of the given failed transaction that didn't fail itself.
+// Returns ErrTransactionNotFound if it hasn't been retried successfully.
+func (r *TransactionRepository) GetRetry(ctx context.Context, id uuid.UUID) (*model.Transaction, error) {
 	query := `
-		SELECT id, source_account_id, destination_account_id, amount, reference, status, created_at, completed_at
+		SELECT ` + transactionColumns + `
 		FROM transactions
-		WHERE source_account_id = $1 OR destination_account_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		WHERE retry_of = $1 AND status <> $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, limit, offset)
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetRetry", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetRetry").QueryRowContext(ctx, query, id, model.TransactionStatusFailed))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction retry: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// GetForUpdate retrieves a transaction by its ID, locking it until tx ends
+func (r *TransactionRepository) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetForUpdate", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(tx, "TransactionRepository.GetForUpdate").QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction for update: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// GetAllByReference retrieves every transaction with the given reference, oldest first,
+// locking them until tx ends. At most limit transactions are returned.
+func (r *TransactionRepository) GetAllByReference(ctx context.Context, tx *sql.Tx, reference string, limit int) ([]*model.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE reference = $1
+		ORDER BY created_at, id
+		LIMIT $2
+		FOR UPDATE
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetAllByReference", query)
+	defer span.End()
+
+	rows, err := timed(tx, "TransactionRepository.GetAllByReference").QueryContext(ctx, query, reference, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions by reference: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*model.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// ListStalePending retrieves up to limit transactions still pending that were created
+// before the given time, oldest first. A transfer commits as completed, so these can only
+// be left by a write interrupted between its insert and status update.
+func (r *TransactionRepository) ListStalePending(ctx context.Context, before time.Time, limit int) ([]*model.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE status = $1 AND created_at < $2
+		ORDER BY created_at, id
+		LIMIT $3
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.ListStalePending", query)
+	defer span.End()
+
+	rows, err := timed(r.db, "TransactionRepository.ListStalePending").QueryContext(ctx, query, model.TransactionStatusPending, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale pending transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*model.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// FindRecentDuplicate retrieves the latest transfer created since the given time with
+// the request's source, destination and amount, which may be the same transfer submitted
+// twice. Failed transfers and reversals don't count.
+func (r *TransactionRepository) FindRecentDuplicate(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest, since time.Time) (*model.Transaction, error) {
+	// Match a missing account with IS NULL rather than IS NOT DISTINCT FROM, which
+	// can't use the index
+	args := []interface{}{req.Amount, since}
+	source, destination := "source_account_id IS NULL", "destination_account_id IS NULL"
+	if req.SourceAccountID != nil {
+		args = append(args, *req.SourceAccountID)
+		source = fmt.Sprintf("source_account_id = $%d", len(args))
+	}
+	if req.DestinationAccountID != nil {
+		args = append(args, *req.DestinationAccountID)
+		destination = fmt.Sprintf("destination_account_id = $%d", len(args))
+	}
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE ` + source + ` AND ` + destination + `
+			AND amount = $1 AND created_at >= $2
+			AND status <> 'failed' AND reversal_of IS NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.FindRecentDuplicate", query)
+	defer span.End()
+
+	transaction, err := scanTransaction(timed(tx, "TransactionRepository.FindRecentDuplicate").QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, r.errs.translate(fmt.Errorf("failed to look up duplicate transaction: %w", err))
+	}
+
+	return transaction, nil
+}
+
+// GetReversedAmount sums the completed reversals of the given transaction, in the
+// currency of its amount: what each reversal credited back to the original source
+func (r *TransactionRepository) GetReversedAmount(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(COALESCE(destination_amount, amount)), 0)
+		FROM transactions
+		WHERE reversal_of = $1 AND status = $2
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetReversedAmount", query)
+	defer span.End()
+
+	var reversed decimal.Decimal
+	err := timed(tx, "TransactionRepository.GetReversedAmount").QueryRowContext(ctx, query, id, model.TransactionStatusCompleted).Scan(&reversed)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get reversed amount: %w", err)
+	}
+
+	return reversed, nil
+}
+
+// CountRecentTransfers counts the transfers an account has made since a time, within a
+// transaction, along with when the oldest of them was made. Declined transfers and
+// reversals, which an admin makes, don't count.
+func (r *TransactionRepository) CountRecentTransfers(ctx context.Context, tx *sql.Tx, accountID uuid.UUID, since time.Time) (int, time.Time, error) {
+	query := `
+		SELECT COUNT(*), MIN(created_at)
+		FROM transactions
+		WHERE source_account_id = $1 AND created_at >= $2
+			AND status <> 'failed' AND reversal_of IS NULL
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.CountRecentTransfers", query)
+	defer span.End()
+
+	var count int
+	var oldest sql.NullTime
+	err := timed(tx, "TransactionRepository.CountRecentTransfers").QueryRowContext(ctx, query, accountID, since).Scan(&count, &oldest)
+	if err != nil {
+		return 0, time.Time{}, r.errs.translate(fmt.Errorf("failed to count recent transfers: %w", err))
+	}
+
+	return count, oldest.Time, nil
+}
+
+// GetAccountTransactions retrieves transactions for a specific account that match the
+// filter, newest first. Transactions created in the same instant, as a batch's are, are
+// ordered by ID so pages don't overlap or skip any.
+func (r *TransactionRepository) GetAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter, limit, offset int) ([]*model.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE (source_account_id = $1 OR destination_account_id = $1 OR fee_account_id = $1)
+			AND ($2::numeric IS NULL OR amount >= $2)
+			AND ($3::numeric IS NULL OR amount <= $3)
+			AND ($4::uuid IS NULL
+				OR (source_account_id = $1 AND destination_account_id = $4)
+				OR (destination_account_id = $1 AND source_account_id = $4))
+			AND ($5::boolean IS NULL OR disputed = $5)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $6 OFFSET $7
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetAccountTransactions", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetAccountTransactions").QueryContext(ctx, query, accountID, filter.MinAmount, filter.MaxAmount, filter.Counterparty, filter.Disputed, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account transactions: %w", err)
 	}
@@ -158,17 +615,7 @@ func (r *TransactionRepository) GetAccountTransactions(ctx context.Context, acco
 
 	var transactions []*model.Transaction
 	for rows.Next() {
-		transaction := &model.Transaction{}
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.SourceAccountID,
-			&transaction.DestinationAccountID,
-			&transaction.Amount,
-			&transaction.Reference,
-			&transaction.Status,
-			&transaction.CreatedAt,
-			&transaction.CompletedAt,
-		)
+		transaction, err := scanTransaction(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -180,4 +627,134 @@ func (r *TransactionRepository) GetAccountTransactions(ctx context.Context, acco
 	}
 
 	return transactions, nil
-} 
\ No newline at end of file
+}
+
+// CountAccountTransactions counts the transactions for a specific account that match the
+// filter, across all pages of GetAccountTransactions
+func (r *TransactionRepository) CountAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM transactions
+		WHERE (source_account_id = $1 OR destination_account_id = $1 OR fee_account_id = $1)
+			AND ($2::numeric IS NULL OR amount >= $2)
+			AND ($3::numeric IS NULL OR amount <= $3)
+			AND ($4::uuid IS NULL
+				OR (source_account_id = $1 AND destination_account_id = $4)
+				OR (destination_account_id = $1 AND source_account_id = $4))
+			AND ($5::boolean IS NULL OR disputed = $5)
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.CountAccountTransactions", query)
+	defer span.End()
+
+	var count int
+	err := timed(reader(ctx, r.db, r.replica), "TransactionRepository.CountAccountTransactions").QueryRowContext(ctx, query, accountID, filter.MinAmount, filter.MaxAmount, filter.Counterparty, filter.Disputed).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count account transactions: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetCounterparties aggregates the account's completed transfers by the account on the other
+// side, largest total volume first. Deposits, withdrawals and fees have no counterparty and
+// are left out.
+func (r *TransactionRepository) GetCounterparties(ctx context.Context, accountID uuid.UUID, filter model.CounterpartyFilter) ([]model.Counterparty, error) {
+	query := `
+		SELECT
+			CASE WHEN source_account_id = $1 THEN destination_account_id ELSE source_account_id END AS counterparty_id,
+			COALESCE(SUM(amount) FILTER (WHERE source_account_id = $1), 0) AS sent,
+			COALESCE(SUM(COALESCE(destination_amount, amount)) FILTER (WHERE destination_account_id = $1), 0) AS received,
+			COUNT(*)
+		FROM transactions
+		WHERE (source_account_id = $1 OR destination_account_id = $1)
+			AND source_account_id IS NOT NULL
+			AND destination_account_id IS NOT NULL
+			AND status = 'completed'
+			AND ($2::timestamp IS NULL OR created_at >= $2)
+			AND ($3::timestamp IS NULL OR created_at < $3)
+		GROUP BY counterparty_id
+		ORDER BY SUM(amount) DESC, counterparty_id
+		LIMIT $4
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetCounterparties", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetCounterparties").QueryContext(ctx, query, accountID, filter.From, filter.To, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counterparties: %w", err)
+	}
+	defer rows.Close()
+
+	counterparties := []model.Counterparty{}
+	for rows.Next() {
+		var c model.Counterparty
+		if err := rows.Scan(&c.AccountID, &c.Sent, &c.Received, &c.TransactionCount); err != nil {
+			return nil, fmt.Errorf("failed to scan counterparty: %w", err)
+		}
+		c.NetFlow = c.Sent.Sub(c.Received)
+		counterparties = append(counterparties, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating counterparties: %w", err)
+	}
+
+	return counterparties, nil
+}
+
+// AddNote appends a note to a transaction within a transaction
+func (r *TransactionRepository) AddNote(ctx context.Context, tx *sql.Tx, note *model.TransactionNote) error {
+	query := `
+		INSERT INTO transaction_notes (transaction_id, author, body, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.AddNote", query)
+	defer span.End()
+
+	err := timed(tx, "TransactionRepository.AddNote").QueryRowContext(ctx, query,
+		note.TransactionID, note.Author, note.Body, note.CreatedAt,
+	).Scan(&note.ID)
+	if err != nil {
+		return r.errs.translate(fmt.Errorf("failed to add transaction note: %w", err))
+	}
+
+	return nil
+}
+
+// GetNotes retrieves the notes on a transaction, oldest first
+func (r *TransactionRepository) GetNotes(ctx context.Context, transactionID uuid.UUID) ([]model.TransactionNote, error) {
+	query := `
+		SELECT id, transaction_id, author, body, created_at
+		FROM transaction_notes
+		WHERE transaction_id = $1
+		ORDER BY created_at, id
+	`
+
+	ctx, span := startQuerySpan(ctx, "TransactionRepository.GetNotes", query)
+	defer span.End()
+
+	rows, err := timed(reader(ctx, r.db, r.replica), "TransactionRepository.GetNotes").QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []model.TransactionNote{}
+	for rows.Next() {
+		var note model.TransactionNote
+		if err := rows.Scan(&note.ID, &note.TransactionID, &note.Author, &note.Body, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transaction notes: %w", err)
+	}
+
+	return notes, nil
+}
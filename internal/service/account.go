@@ -4,37 +4,78 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 
+	"internal-transfers-api/internal/config"
 	"internal-transfers-api/internal/model"
 	"internal-transfers-api/internal/repository"
 )
 
 // AccountService handles account business logic
 type AccountService struct {
-	accountRepo *repository.AccountRepository
+	accountRepo repository.AccountRepo
 	db          *sql.DB
+	watcher     *BalanceWatcher
+	cache       *BalanceCache
+	currencies  model.CurrencyRegistry
+	cfg         config.AccountConfig
+	auditor     Auditor
+	clock       Clock
+
+	transactions *TransactionService // makes the funding transfer of accounts funded from another
 }
 
 // NewAccountService creates a new account service
-func NewAccountService(accountRepo *repository.AccountRepository, db *sql.DB) *AccountService {
+func NewAccountService(accountRepo repository.AccountRepo, db *sql.DB, watcher *BalanceWatcher, cache *BalanceCache, currencies model.CurrencyRegistry, cfg config.AccountConfig, auditor Auditor, transactions *TransactionService) *AccountService {
 	return &AccountService{
-		accountRepo: accountRepo,
-		db:          db,
+		accountRepo:  accountRepo,
+		db:           db,
+		watcher:      watcher,
+		cache:        cache,
+		currencies:   currencies,
+		cfg:          cfg,
+		auditor:      auditor,
+		clock:        systemClock{},
+		transactions: transactions,
+	}
+}
+
+// ListCurrencies lists the currencies accounts can be opened in, by code, with the
+// decimal places their amounts may carry
+func (s *AccountService) ListCurrencies() *model.CurrenciesResponse {
+	response := &model.CurrenciesResponse{
+		Default:    s.cfg.DefaultCurrency,
+		Currencies: make([]model.CurrencyInfo, 0, len(s.currencies)),
+	}
+	for code, scale := range s.currencies {
+		response.Currencies = append(response.Currencies, model.CurrencyInfo{Code: code, Scale: scale})
 	}
+	sort.Slice(response.Currencies, func(i, j int) bool {
+		return response.Currencies[i].Code < response.Currencies[j].Code
+	})
+	return response
 }
 
-// CreateAccount creates a new account with optional initial balance
-func (s *AccountService) CreateAccount(ctx context.Context, req *model.CreateAccountRequest) (*model.CreateAccountResponse, error) {
+// CreateAccount creates a new account with optional initial balance, which may be
+// transferred from an existing account
+func (s *AccountService) CreateAccount(ctx context.Context, req *model.CreateAccountRequest) (_ *model.CreateAccountResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.CreateAccount")
+	defer func() { endSpan(span, err) }()
+
 	// Validate request
 	if err := req.Validate(); err != nil {
 		if validationErr, ok := err.(*model.ValidationError); ok {
 			return nil, &ServiceError{
 				Code:    model.ErrCodeValidation,
 				Message: validationErr.Message,
+				Field:   validationErr.Field,
 			}
 		}
 		return nil, err
@@ -46,20 +87,163 @@ func (s *AccountService) CreateAccount(ctx context.Context, req *model.CreateAcc
 		initialBalance = *req.InitialBalance
 	}
 
-	// Create account
-	account, err := s.accountRepo.Create(ctx, initialBalance)
+	// A funded account defaults to the currency of the account funding it
+	currency := req.Currency
+	if currency == "" && req.FundingSourceAccountID != nil {
+		currencies, err := s.accountRepo.GetCurrencies(ctx, *req.FundingSourceAccountID)
+		if err != nil {
+			return nil, err
+		}
+		currency = currencies[*req.FundingSourceAccountID]
+		if currency == "" {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Funding source account not found",
+				Field:   "funding_source_account_id",
+			}
+		}
+	}
+	if currency == "" {
+		currency = s.cfg.DefaultCurrency
+	}
+	scale, ok := s.currencies.Scale(currency)
+	if !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("unsupported currency %s; GET /v1/currencies lists the supported ones", currency),
+			Field:   "currency",
+		}
+	}
+	if !model.FitsScale(initialBalance, scale) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("initial balance has more decimal places than %s allows (%d)", currency, scale),
+			Field:   "initial_balance",
+		}
+	}
+	// Reject what the balance column can't hold, or more than the deployment allows
+	if !model.FitsIntegerDigits(initialBalance, s.cfg.MaxInitialBalanceDigits) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("initial balance cannot have more than %d digits before the decimal point", s.cfg.MaxInitialBalanceDigits),
+			Field:   "initial_balance",
+		}
+	}
+
+	accountType := req.AccountType
+	if accountType == "" {
+		accountType = model.AccountTypeChecking
+	}
+	interestRate := decimal.Zero
+	if req.InterestRate != nil {
+		interestRate = *req.InterestRate
+	}
+
+	ownerID, err := ownerForNewAccount(ctx, req.OwnerID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Create account, or find the one already created with the same external reference
+	newAccount := &model.Account{
+		Balance:      initialBalance,
+		Currency:     currency,
+		AccountType:  accountType,
+		InterestRate: interestRate,
+		MinBalance:   req.MinBalance,
+		MaxBalance:   req.MaxBalance,
+		OwnerID:      ownerID,
+		ExternalRef:  req.ExternalRef,
+	}
+	if req.FundingSourceAccountID != nil {
+		response, err := s.transactions.CreateFundedAccount(ctx, newAccount, *req.FundingSourceAccountID, initialBalance)
+		if err != nil {
+			return nil, err
+		}
+		if !response.Created && !sameOwner(response.OwnerID, ownerID) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeConflict,
+				Message: "external_ref is already used by another owner's account",
+			}
+		}
+		response.BalanceScale = s.balanceScale(response.Currency)
+		return response, nil
+	}
+
+	account, created, err := s.createAccount(ctx, newAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't hand one owner's account to another who happened to pick the same reference
+	if !created && !sameOwner(account.OwnerID, ownerID) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeConflict,
+			Message: "external_ref is already used by another owner's account",
+		}
+	}
+
 	return &model.CreateAccountResponse{
-		ID:      account.ID,
-		Balance: account.Balance,
+		ID:          account.ID,
+		Balance:     account.Balance,
+		Currency:    account.Currency,
+		OwnerID:     account.OwnerID,
+		ExternalRef: account.ExternalRef,
+		Created:     created,
+
+		BalanceScale: s.balanceScale(account.Currency),
 	}, nil
 }
 
-// GetAccount retrieves an account by ID
-func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (*model.GetAccountResponse, error) {
+// createAccount creates an account, or finds the one with the same external reference,
+// in a transaction with its audit entry. created reports which happened; finding an
+// account changes nothing, so it isn't audited.
+func (s *AccountService) createAccount(ctx context.Context, newAccount *model.Account) (_ *model.Account, created bool, err error) {
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	account, created, err := s.accountRepo.CreateOrGetTx(ctx, tx, newAccount)
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		return account, false, nil
+	}
+
+	audit := newAuditEntry(ctx, s.clock.Now(), model.AuditAccountCreate, account.ID)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+
+	return account, true, nil
+}
+
+// GetAccount retrieves an account by ID. It reads through the balance cache, so unlike the
+// other reads it stays on the primary: a lagging replica read would be cached for the TTL
+// and could hide a change from WatchAccount, which reads again once woken by it.
+func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (_ *model.GetAccountResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.GetAccount")
+	defer func() { endSpan(span, err) }()
+
+	return s.cache.GetOrLoad(id, func() (*model.GetAccountResponse, error) {
+		return s.loadAccount(ctx, id)
+	})
+}
+
+// loadAccount reads an account from the repository
+func (s *AccountService) loadAccount(ctx context.Context, id uuid.UUID) (*model.GetAccountResponse, error) {
 	account, err := s.accountRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, repository.ErrAccountNotFound) {
@@ -71,19 +255,122 @@ func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (*model.G
 		return nil, err
 	}
 
+	return s.accountResponse(account), nil
+}
+
+// accountResponse converts an account into its response
+func (s *AccountService) accountResponse(account *model.Account) *model.GetAccountResponse {
 	return &model.GetAccountResponse{
-		ID:        account.ID,
-		Balance:   account.Balance,
-		CreatedAt: account.CreatedAt,
-		UpdatedAt: account.UpdatedAt,
-	}, nil
+		ID:           account.ID,
+		Balance:      account.Balance,
+		Currency:     account.Currency,
+		Status:       account.Status,
+		AccountType:  account.AccountType,
+		InterestRate: account.InterestRate,
+		MinBalance:   account.MinBalance,
+		MaxBalance:   account.MaxBalance,
+		OwnerID:      account.OwnerID,
+		ExternalRef:  account.ExternalRef,
+		Version:      account.Version,
+		CreatedAt:    account.CreatedAt,
+		UpdatedAt:    account.UpdatedAt,
+
+		BalanceScale: s.balanceScale(account.Currency),
+	}
+}
+
+// ListOwnerAccounts lists every account of an owner along with their balances summed per
+// currency. An owner with no accounts gets empty lists rather than a 404, as owners exist
+// only through their accounts. A caller authenticated with an API key may only list its
+// own accounts.
+func (s *AccountService) ListOwnerAccounts(ctx context.Context, ownerID string) (_ *model.OwnerAccountsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ListOwnerAccounts")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	if identity := IdentityFromContext(ctx); identity != nil && !identity.Admin && identity.OwnerID != ownerID {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeForbidden,
+			Message: "Accounts of another owner cannot be listed",
+		}
+	}
+
+	accounts, err := s.accountRepo.ListByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.accountRepo.GetOwnerBalances(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.OwnerAccountsResponse{
+		OwnerID:  ownerID,
+		Accounts: make([]model.GetAccountResponse, 0, len(accounts)),
+		Totals:   make([]model.OwnerBalance, 0, len(totals)),
+	}
+	for _, account := range accounts {
+		response.Accounts = append(response.Accounts, *s.accountResponse(account))
+	}
+	for _, total := range totals {
+		total.BalanceScale = s.balanceScale(total.Currency)
+		response.Totals = append(response.Totals, total)
+	}
+
+	return response, nil
+}
+
+// balanceScale returns the decimal places balances in the currency are padded to in
+// responses, or nil when they are written as they are
+func (s *AccountService) balanceScale(currency string) *int32 {
+	if s.cfg.BalanceFormat != model.BalanceFormatCurrency {
+		return nil
+	}
+	scale, ok := s.currencies.Scale(currency)
+	if !ok {
+		return nil
+	}
+	return &scale
+}
+
+// WatchAccount waits until changed reports true for the account or timeout elapses,
+// returning the latest account state and whether it changed
+func (s *AccountService) WatchAccount(ctx context.Context, id uuid.UUID, changed func(*model.GetAccountResponse) bool, timeout time.Duration) (*model.GetAccountResponse, bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		// Register before reading so an update in between still wakes us
+		wake, done := s.watcher.Wait(id)
+
+		account, err := s.GetAccount(ctx, id)
+		if err != nil {
+			done()
+			return nil, false, err
+		}
+		if changed(account) {
+			done()
+			return account, true, nil
+		}
+
+		select {
+		case <-wake:
+			done()
+		case <-timer.C:
+			done()
+			return account, false, nil
+		case <-ctx.Done():
+			done()
+			return nil, false, ctx.Err()
+		}
+	}
 }
 
 // GetAccountBalance retrieves account balance, optionally at a specific timestamp
 func (s *AccountService) GetAccountBalance(ctx context.Context, id uuid.UUID, at *time.Time) (decimal.Decimal, error) {
 	if at != nil {
 		// Get historical balance
-		balance, err := s.accountRepo.GetBalanceAt(ctx, id, *at)
+		balance, err := s.accountRepo.GetBalanceAt(repository.ReadFromReplica(ctx), id, *at)
 		if err != nil {
 			if errors.Is(err, repository.ErrAccountNotFound) {
 				return decimal.Zero, &ServiceError{
@@ -97,18 +384,334 @@ func (s *AccountService) GetAccountBalance(ctx context.Context, id uuid.UUID, at
 	}
 
 	// Get current balance
-	account, err := s.accountRepo.GetByID(ctx, id)
+	account, err := s.GetAccount(ctx, id)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return account.Balance, nil
+}
+
+// GetBalances retrieves the balances of several accounts in one query. Accounts that
+// don't exist are listed as not found, in request order, rather than failing the request.
+func (s *AccountService) GetBalances(ctx context.Context, req *model.GetBalancesRequest) (_ *model.GetBalancesResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.GetBalances")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+			}
+		}
+		return nil, err
+	}
+
+	balances, err := s.accountRepo.GetBalances(ctx, req.AccountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.GetBalancesResponse{
+		Balances: balances,
+		NotFound: []uuid.UUID{},
+	}
+	seen := make(map[uuid.UUID]bool, len(req.AccountIDs))
+	for _, id := range req.AccountIDs {
+		if _, ok := balances[id]; !ok && !seen[id] {
+			response.NotFound = append(response.NotFound, id)
+		}
+		seen[id] = true
+	}
+
+	return response, nil
+}
+
+// GetBalanceHistory returns the account's ledger balance at each of a comma-separated
+// list of RFC3339 timestamps, in the order given, from a single pass over its ledger
+func (s *AccountService) GetBalanceHistory(ctx context.Context, id uuid.UUID, points string) (_ *model.BalanceHistoryResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.GetBalanceHistory")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	requested, err := model.ParseBalanceHistoryPoints(points)
+	if err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+				Field:   validationErr.Field,
+			}
+		}
+		return nil, err
+	}
+
+	// The repository wants the points in ascending order
+	sorted := append([]time.Time(nil), requested...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	balances, err := s.accountRepo.GetBalanceHistory(ctx, id, sorted)
 	if err != nil {
 		if errors.Is(err, repository.ErrAccountNotFound) {
-			return decimal.Zero, &ServiceError{
+			return nil, &ServiceError{
 				Code:    model.ErrCodeNotFound,
 				Message: "Account not found",
 			}
 		}
-		return decimal.Zero, err
+		return nil, err
 	}
 
-	return account.Balance, nil
+	response := &model.BalanceHistoryResponse{
+		ID:     id,
+		Points: make([]model.BalanceHistoryPoint, len(requested)),
+	}
+	for i, at := range requested {
+		j := sort.Search(len(sorted), func(j int) bool { return !sorted[j].Before(at) })
+		response.Points[i] = model.BalanceHistoryPoint{At: at, Balance: balances[j]}
+	}
+
+	return response, nil
+}
+
+// ReconcileAccount compares the stored balance with the balance recomputed from the ledger.
+// When fix is set, a discrepancy is corrected to the ledger balance under a row lock.
+func (s *AccountService) ReconcileAccount(ctx context.Context, id uuid.UUID, fix bool) (_ *model.ReconcileAccountResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ReconcileAccount")
+	defer func() { endSpan(span, err) }()
+
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	s.cache.BeginWrite(id)
+	defer s.cache.EndWrite(id)
+
+	storedBalance, err := s.accountRepo.GetBalanceForUpdate(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Account not found",
+			}
+		}
+		return nil, err
+	}
+
+	ledgerBalance, err := s.accountRepo.GetLedgerBalance(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.ReconcileAccountResponse{
+		ID:            id,
+		StoredBalance: storedBalance,
+		LedgerBalance: ledgerBalance,
+		Discrepancy:   storedBalance.Sub(ledgerBalance),
+	}
+
+	if !fix || response.Discrepancy.IsZero() {
+		return response, nil
+	}
+
+	if err := s.accountRepo.UpdateBalance(ctx, tx, id, ledgerBalance); err != nil {
+		return nil, err
+	}
+	audit := newAuditEntry(ctx, s.clock.Now(), model.AuditAccountReconcile, id)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+	s.watcher.Notify(id)
+
+	log.Printf("reconciled account %s: balance corrected from %s to %s", id, storedBalance, ledgerBalance)
+	response.Corrected = true
+
+	return response, nil
+}
+
+// ReconciliationReport checks every account's stored balance against its ledger balance
+// in one snapshot, calling emit for each account that has drifted as it is found, and
+// returns the totals per currency. The ledger total of a currency only changes through
+// opening balances, deposits, withdrawals and cross-currency transfers, so it is also
+// checked against those, summed from the transactions on their own: transfers within the
+// currency net to zero when the books balance.
+func (s *AccountService) ReconciliationReport(ctx context.Context, emit func(*model.ReconciliationDiscrepancy) error) (_ *model.ReconciliationSummary, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ReconciliationReport")
+	defer func() { endSpan(span, err) }()
+
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	summary := &model.ReconciliationSummary{Type: "summary"}
+	totals := make(map[string]*model.CurrencyReconciliation)
+	err = s.accountRepo.ScanLedgers(ctx, tx, func(ledger model.AccountLedger) error {
+		summary.Accounts++
+		total, ok := totals[ledger.Currency]
+		if !ok {
+			total = &model.CurrencyReconciliation{Currency: ledger.Currency}
+			totals[ledger.Currency] = total
+		}
+		total.Accounts++
+		total.StoredBalance = total.StoredBalance.Add(ledger.StoredBalance)
+		total.LedgerBalance = total.LedgerBalance.Add(ledger.LedgerBalance)
+
+		if ledger.StoredBalance.Equal(ledger.LedgerBalance) {
+			return nil
+		}
+		summary.Discrepancies++
+		return emit(&model.ReconciliationDiscrepancy{
+			Type:          "discrepancy",
+			ID:            ledger.ID,
+			Currency:      ledger.Currency,
+			StoredBalance: ledger.StoredBalance,
+			LedgerBalance: ledger.LedgerBalance,
+			Discrepancy:   ledger.StoredBalance.Sub(ledger.LedgerBalance),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	movements, err := s.accountRepo.SumCurrencyMovements(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Balanced = summary.Discrepancies == 0
+	summary.Currencies = make([]model.CurrencyReconciliation, 0, len(totals))
+	for _, total := range totals {
+		total.OpeningBalance = movements[total.Currency].OpeningBalance
+		total.Movements = movements[total.Currency].Movements
+		total.Difference = total.LedgerBalance.Sub(total.OpeningBalance).Sub(total.Movements)
+		if !total.Difference.IsZero() {
+			summary.Balanced = false
+		}
+		summary.Currencies = append(summary.Currencies, *total)
+	}
+	sort.Slice(summary.Currencies, func(i, j int) bool {
+		return summary.Currencies[i].Currency < summary.Currencies[j].Currency
+	})
+
+	return summary, nil
+}
+
+// SetStatus moves an account to the requested status under a row lock, recording who
+// changed it. Requesting the status the account already has changes nothing; a move
+// the status state machine doesn't allow, such as reopening a closed account, fails
+// with INVALID_TRANSITION.
+func (s *AccountService) SetStatus(ctx context.Context, id uuid.UUID, req *model.SetAccountStatusRequest) (_ *model.SetAccountStatusResponse, err error) {
+	ctx, span := tracer.Start(ctx, "AccountService.SetStatus")
+	defer func() { endSpan(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+			}
+		}
+		return nil, err
+	}
+
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	s.cache.BeginWrite(id)
+	defer s.cache.EndWrite(id)
+
+	account, err := s.accountRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Account not found",
+			}
+		}
+		return nil, err
+	}
+
+	if !account.Status.CanTransitionTo(req.Status) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeInvalidTransition,
+			Message: fmt.Sprintf("Account status cannot change from %s to %s", account.Status, req.Status),
+		}
+	}
+
+	response := &model.SetAccountStatusResponse{
+		ID:             id,
+		Status:         req.Status,
+		PreviousStatus: account.Status,
+		UpdatedAt:      account.UpdatedAt,
+	}
+	if account.Status == req.Status {
+		return response, nil
+	}
+
+	updatedAt, err := s.accountRepo.UpdateStatus(ctx, tx, id, req.Status)
+	if err != nil {
+		return nil, err
+	}
+	changedAt := s.clock.Now().UTC()
+	if err := s.accountRepo.RecordStatusChange(ctx, tx, &model.AccountStatusChange{
+		AccountID:  id,
+		FromStatus: account.Status,
+		ToStatus:   req.Status,
+		Actor:      req.Actor,
+		Reason:     req.Reason,
+		ChangedAt:  changedAt,
+	}); err != nil {
+		return nil, err
+	}
+	audit := newAuditEntry(ctx, changedAt, model.AuditAccountStatus, id)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+	s.watcher.Notify(id)
+
+	log.Printf("account %s status changed from %s to %s by %s", id, account.Status, req.Status, req.Actor)
+	response.Changed = true
+	response.UpdatedAt = updatedAt
+
+	return response, nil
 }
 
 // CheckAccountExists verifies if an account exists
@@ -132,8 +735,56 @@ func (s *AccountService) CheckAccountExists(ctx context.Context, id uuid.UUID) e
 type ServiceError struct {
 	Code    string
 	Message string
+	Field   string // request field the error is about, if any
+
+	TransactionID *uuid.UUID // the existing transfer a POSSIBLE_DUPLICATE matched
+	ResetAt       *time.Time // when the source of a VELOCITY_EXCEEDED transfer may transfer again
 }
 
 func (e *ServiceError) Error() string {
 	return e.Message
-} 
\ No newline at end of file
+}
+
+// featureDisabled is the error of a request needing a feature that is switched off
+func featureDisabled(feature string) *ServiceError {
+	return &ServiceError{
+		Code:    model.ErrCodeNotImplemented,
+		Message: fmt.Sprintf("%s are not enabled", feature),
+	}
+}
+
+// AsServiceError returns the ServiceError in err's chain, or one derived from an
+// exhausted connection pool or a classified database error, or nil when err carries
+// no service error code
+func AsServiceError(err error) *ServiceError {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr
+	}
+
+	if errors.Is(err, repository.ErrPoolExhausted) {
+		return &ServiceError{
+			Code:    model.ErrCodeServiceBusy,
+			Message: "No database connection became free in time, retry shortly",
+		}
+	}
+
+	var dbErr *repository.DBError
+	if !errors.As(err, &dbErr) {
+		return nil
+	}
+	if dbErr.Retryable {
+		return &ServiceError{
+			Code:    dbErr.Code,
+			Message: "The request conflicted with a concurrent update, retry it",
+		}
+	}
+	condition := pq.ErrorCode(dbErr.SQLState).Name()
+	if condition == "" {
+		condition = dbErr.SQLState
+	}
+	return &ServiceError{
+		Code:    dbErr.Code,
+		Message: fmt.Sprintf("The request violates a database constraint (%s)", condition),
+	}
+}
@@ -1,13 +1,24 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"internal-transfers-api/internal/config"
 	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+	"internal-transfers-api/internal/repository/memory"
 )
 
 func TestCreateAccountRequest_Validate(t *testing.T) {
@@ -44,6 +55,33 @@ func TestCreateAccountRequest_Validate(t *testing.T) {
 			shouldError: true,
 			errorMsg:    "initial balance cannot be negative",
 		},
+		{
+			name: "valid request with balance limits",
+			req: &model.CreateAccountRequest{
+				InitialBalance: decimalPtr("500"),
+				MinBalance:     decimalPtr("100"),
+				MaxBalance:     decimalPtr("1000"),
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid request with min balance above max balance",
+			req: &model.CreateAccountRequest{
+				InitialBalance: decimalPtr("500"),
+				MinBalance:     decimalPtr("1000"),
+				MaxBalance:     decimalPtr("100"),
+			},
+			shouldError: true,
+			errorMsg:    "min_balance cannot be greater than max_balance",
+		},
+		{
+			name: "invalid request with initial balance below min balance",
+			req: &model.CreateAccountRequest{
+				MinBalance: decimalPtr("100"),
+			},
+			shouldError: true,
+			errorMsg:    "initial balance cannot be below min_balance",
+		},
 	}
 
 	for _, tt := range tests {
@@ -65,6 +103,8 @@ func TestCreateAccountRequest_Validate(t *testing.T) {
 func TestTransactionRequest_Validate(t *testing.T) {
 	sourceID := parseUUID("550e8400-e29b-41d4-a716-446655440000")
 	destID := parseUUID("550e8400-e29b-41d4-a716-446655440001")
+	feeID := parseUUID("550e8400-e29b-41d4-a716-446655440002")
+	nilID := uuid.Nil
 
 	tests := []struct {
 		name        string
@@ -76,7 +116,7 @@ func TestTransactionRequest_Validate(t *testing.T) {
 			name: "valid transfer request",
 			req: &model.CreateTransactionRequest{
 				SourceAccountID:      &sourceID,
-				DestinationAccountID: destID,
+				DestinationAccountID: &destID,
 				Amount:               decimal.NewFromFloat(25.50),
 			},
 			shouldError: false,
@@ -84,7 +124,7 @@ func TestTransactionRequest_Validate(t *testing.T) {
 		{
 			name: "valid deposit request (no source)",
 			req: &model.CreateTransactionRequest{
-				DestinationAccountID: destID,
+				DestinationAccountID: &destID,
 				Amount:               decimal.NewFromFloat(100.00),
 			},
 			shouldError: false,
@@ -93,7 +133,7 @@ func TestTransactionRequest_Validate(t *testing.T) {
 			name: "invalid request with zero amount",
 			req: &model.CreateTransactionRequest{
 				SourceAccountID:      &sourceID,
-				DestinationAccountID: destID,
+				DestinationAccountID: &destID,
 				Amount:               decimal.Zero,
 			},
 			shouldError: true,
@@ -103,7 +143,7 @@ func TestTransactionRequest_Validate(t *testing.T) {
 			name: "invalid request with negative amount",
 			req: &model.CreateTransactionRequest{
 				SourceAccountID:      &sourceID,
-				DestinationAccountID: destID,
+				DestinationAccountID: &destID,
 				Amount:               decimal.NewFromFloat(-10.00),
 			},
 			shouldError: true,
@@ -113,7 +153,7 @@ func TestTransactionRequest_Validate(t *testing.T) {
 			name: "invalid request with same source and destination",
 			req: &model.CreateTransactionRequest{
 				SourceAccountID:      &sourceID,
-				DestinationAccountID: sourceID,
+				DestinationAccountID: &sourceID,
 				Amount:               decimal.NewFromFloat(25.00),
 			},
 			shouldError: true,
@@ -123,13 +163,102 @@ func TestTransactionRequest_Validate(t *testing.T) {
 			name: "invalid request with long reference",
 			req: &model.CreateTransactionRequest{
 				SourceAccountID:      &sourceID,
-				DestinationAccountID: destID,
+				DestinationAccountID: &destID,
 				Amount:               decimal.NewFromFloat(25.00),
 				Reference:            stringPtr(string(make([]byte, 300))), // 300 characters
 			},
 			shouldError: true,
 			errorMsg:    "reference cannot exceed 255 characters",
 		},
+		{
+			name: "invalid request with newline in reference",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Reference:            stringPtr("rent\nINFO forged"),
+			},
+			shouldError: true,
+			errorMsg:    "reference can only contain printable characters",
+		},
+		{
+			name: "valid request with emoji description",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Description:          stringPtr("Dinner 🍝"),
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid request with long description",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Description:          stringPtr(strings.Repeat("a", 1001)),
+			},
+			shouldError: true,
+			errorMsg:    "description cannot exceed 1000 characters",
+		},
+		{
+			name: "invalid request with nil transaction id",
+			req: &model.CreateTransactionRequest{
+				ID:                   &nilID,
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+			},
+			shouldError: true,
+			errorMsg:    "id cannot be the nil UUID",
+		},
+		{
+			name: "valid transfer with fee",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Fee:                  decimalPtr("0.50"),
+				FeeAccountID:         &feeID,
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid request with negative fee",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Fee:                  decimalPtr("-0.50"),
+				FeeAccountID:         &feeID,
+			},
+			shouldError: true,
+			errorMsg:    "fee cannot be negative",
+		},
+		{
+			name: "invalid request with fee but no fee account",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Fee:                  decimalPtr("0.50"),
+			},
+			shouldError: true,
+			errorMsg:    "fee_account_id is required",
+		},
+		{
+			name: "invalid request with fee account same as source",
+			req: &model.CreateTransactionRequest{
+				SourceAccountID:      &sourceID,
+				DestinationAccountID: &destID,
+				Amount:               decimal.NewFromFloat(25.00),
+				Fee:                  decimalPtr("0.50"),
+				FeeAccountID:         &sourceID,
+			},
+			shouldError: true,
+			errorMsg:    "fee account cannot be the same as the source account",
+		},
 	}
 
 	for _, tt := range tests {
@@ -148,6 +277,103 @@ func TestTransactionRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestTransactionFilter_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      model.TransactionFilter
+		shouldError bool
+	}{
+		{
+			name:   "empty filter",
+			filter: model.TransactionFilter{},
+		},
+		{
+			name:   "min below max",
+			filter: model.TransactionFilter{MinAmount: decimalPtr("100"), MaxAmount: decimalPtr("500")},
+		},
+		{
+			name:   "min equal to max",
+			filter: model.TransactionFilter{MinAmount: decimalPtr("100"), MaxAmount: decimalPtr("100.00")},
+		},
+		{
+			name:   "only min",
+			filter: model.TransactionFilter{MinAmount: decimalPtr("100")},
+		},
+		{
+			name:        "min above max",
+			filter:      model.TransactionFilter{MinAmount: decimalPtr("500"), MaxAmount: decimalPtr("100")},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+
+			if tt.shouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAsServiceError(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		expectedCode    string
+		expectedMessage string
+	}{
+		{
+			name:            "service error",
+			err:             &ServiceError{Code: model.ErrCodeNotFound, Message: "Account not found"},
+			expectedCode:    model.ErrCodeNotFound,
+			expectedMessage: "Account not found",
+		},
+		{
+			name: "mapped database error",
+			err: fmt.Errorf("failed to create account: %w", &repository.DBError{
+				Code:     model.ErrCodeConflict,
+				SQLState: "23505",
+				Err:      &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"},
+			}),
+			expectedCode:    model.ErrCodeConflict,
+			expectedMessage: "The request violates a database constraint (unique_violation)",
+		},
+		{
+			name: "retryable database error",
+			err: &repository.DBError{
+				Code:      model.ErrCodeServiceUnavailable,
+				SQLState:  "40001",
+				Retryable: true,
+				Err:       &pq.Error{Code: "40001"},
+			},
+			expectedCode:    model.ErrCodeServiceUnavailable,
+			expectedMessage: "The request conflicted with a concurrent update, retry it",
+		},
+		{
+			name: "unclassified error",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceErr := AsServiceError(tt.err)
+			if tt.expectedCode == "" {
+				assert.Nil(t, serviceErr)
+				return
+			}
+			if assert.NotNil(t, serviceErr) {
+				assert.Equal(t, tt.expectedCode, serviceErr.Code)
+				assert.Equal(t, tt.expectedMessage, serviceErr.Message)
+			}
+		})
+	}
+}
+
 // Helper functions
 func decimalPtr(s string) *decimal.Decimal {
 	d, _ := decimal.NewFromString(s)
@@ -161,4 +387,546 @@ func stringPtr(s string) *string {
 func parseUUID(s string) uuid.UUID {
 	id, _ := uuid.Parse(s)
 	return id
-} 
\ No newline at end of file
+} 
+
+func TestAccountService_SetStatus(t *testing.T) {
+	ctx := context.Background()
+	accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+
+	account, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	setStatus := func(status model.AccountStatus) (*model.SetAccountStatusResponse, error) {
+		return accountService.SetStatus(ctx, account.ID, &model.SetAccountStatusRequest{Status: status, Actor: "ops@example.com"})
+	}
+
+	response, err := setStatus(model.AccountStatusFrozen)
+	require.NoError(t, err)
+	assert.True(t, response.Changed)
+	assert.Equal(t, model.AccountStatusActive, response.PreviousStatus)
+
+	// Repeating the change is a no-op and leaves no audit record
+	response, err = setStatus(model.AccountStatusFrozen)
+	require.NoError(t, err)
+	assert.False(t, response.Changed)
+
+	_, err = setStatus(model.AccountStatusClosed)
+	require.NoError(t, err)
+
+	_, err = setStatus(model.AccountStatusActive)
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeInvalidTransition, serviceErr.Code)
+	assert.Contains(t, serviceErr.Message, "from closed to active")
+
+	changes := accountRepo.StatusChanges(account.ID)
+	require.Len(t, changes, 2)
+	assert.Equal(t, model.AccountStatusActive, changes[0].FromStatus)
+	assert.Equal(t, model.AccountStatusFrozen, changes[0].ToStatus)
+	assert.Equal(t, model.AccountStatusClosed, changes[1].ToStatus)
+	assert.Equal(t, "ops@example.com", changes[1].Actor)
+
+	_, err = accountService.SetStatus(ctx, account.ID, &model.SetAccountStatusRequest{Status: model.AccountStatusFrozen})
+	serviceErr = AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+}
+
+func TestAccountService_GetBalances(t *testing.T) {
+	ctx := context.Background()
+	accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+
+	first, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("10.5")})
+	require.NoError(t, err)
+	second, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("20")})
+	require.NoError(t, err)
+	missing := uuid.New()
+
+	response, err := accountService.GetBalances(ctx, &model.GetBalancesRequest{
+		AccountIDs: []uuid.UUID{first.ID, missing, second.ID, missing},
+	})
+	require.NoError(t, err)
+	assert.Len(t, response.Balances, 2)
+	assert.Equal(t, "10.5", response.Balances[first.ID].String())
+	assert.Equal(t, "20", response.Balances[second.ID].String())
+	assert.Equal(t, []uuid.UUID{missing}, response.NotFound)
+
+	tooMany := make([]uuid.UUID, model.MaxBalanceQueryAccounts+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	_, err = accountService.GetBalances(ctx, &model.GetBalancesRequest{AccountIDs: tooMany})
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+}
+
+func TestAccountService_ListOwnerAccounts(t *testing.T) {
+	ctx := context.Background()
+	accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{}, NewAuditor(nil, nil), nil)
+	acme, other := "acme", "globex"
+
+	create := func(owner *string, balance, currency string) *model.Account {
+		account, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString(balance), Currency: currency, OwnerID: owner})
+		require.NoError(t, err)
+		return account
+	}
+	first := create(&acme, "10.5", "USD")
+	second := create(&acme, "20", "EUR")
+	third := create(&acme, "4.5", "USD")
+	create(&other, "100", "USD")
+	create(nil, "100", "USD")
+	require.NoError(t, accountRepo.SetCreatedAt(first.ID, time.Now().Add(-2*time.Hour)))
+	require.NoError(t, accountRepo.SetCreatedAt(second.ID, time.Now().Add(-time.Hour)))
+
+	response, err := accountService.ListOwnerAccounts(ctx, acme)
+	require.NoError(t, err)
+	assert.Equal(t, acme, response.OwnerID)
+	require.Len(t, response.Accounts, 3)
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID, third.ID}, []uuid.UUID{response.Accounts[0].ID, response.Accounts[1].ID, response.Accounts[2].ID})
+	require.Len(t, response.Totals, 2)
+	assert.Equal(t, "EUR", response.Totals[0].Currency)
+	assert.Equal(t, 1, response.Totals[0].Accounts)
+	assert.Equal(t, "20", response.Totals[0].Balance.String())
+	assert.Equal(t, "USD", response.Totals[1].Currency)
+	assert.Equal(t, 2, response.Totals[1].Accounts)
+	assert.Equal(t, "15", response.Totals[1].Balance.String())
+
+	// An owner without accounts gets empty lists
+	response, err = accountService.ListOwnerAccounts(ctx, "initech")
+	require.NoError(t, err)
+	assert.Empty(t, response.Accounts)
+	assert.Empty(t, response.Totals)
+
+	// A caller sees its own accounts only, unless it is an admin
+	_, err = accountService.ListOwnerAccounts(WithIdentity(ctx, &Identity{OwnerID: other}), acme)
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeForbidden, serviceErr.Code)
+	_, err = accountService.ListOwnerAccounts(WithIdentity(ctx, &Identity{OwnerID: acme}), acme)
+	require.NoError(t, err)
+	_, err = accountService.ListOwnerAccounts(WithIdentity(ctx, &Identity{Admin: true}), acme)
+	require.NoError(t, err)
+}
+
+func TestAccountService_CreateAccount_ExternalRef(t *testing.T) {
+	ctx := context.Background()
+	accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+	ref := "crm-customer-42"
+
+	first, err := accountService.CreateAccount(ctx, &model.CreateAccountRequest{ExternalRef: &ref})
+	require.NoError(t, err)
+	assert.True(t, first.Created)
+
+	second, err := accountService.CreateAccount(ctx, &model.CreateAccountRequest{ExternalRef: &ref})
+	require.NoError(t, err)
+	assert.False(t, second.Created)
+	assert.Equal(t, first.ID, second.ID)
+
+	_, err = accountService.CreateAccount(WithIdentity(ctx, &Identity{OwnerID: "acme"}), &model.CreateAccountRequest{ExternalRef: &ref})
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeConflict, serviceErr.Code)
+
+	other, err := accountService.CreateAccount(ctx, &model.CreateAccountRequest{})
+	require.NoError(t, err)
+	assert.True(t, other.Created)
+	assert.NotEqual(t, first.ID, other.ID)
+}
+
+func TestAccountService_CreateAccount_InitialBalance(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		maxDigits int
+		currency  string
+		balance   string
+		valid     bool
+	}{
+		{name: "cents", maxDigits: model.MaxIntegerDigits, balance: "0.01", valid: true},
+		{name: "below a cent", maxDigits: model.MaxIntegerDigits, balance: "0.0001"},
+		{name: "trailing zeros are not precision", maxDigits: model.MaxIntegerDigits, balance: "0.0100", valid: true},
+		{name: "whole yen", maxDigits: model.MaxIntegerDigits, currency: "JPY", balance: "5", valid: true},
+		{name: "fraction of a yen", maxDigits: model.MaxIntegerDigits, currency: "JPY", balance: "5.5"},
+		{name: "largest the column holds", maxDigits: model.MaxIntegerDigits, balance: strings.Repeat("9", model.MaxIntegerDigits) + ".99", valid: true},
+		{name: "beyond the column", maxDigits: model.MaxIntegerDigits, balance: "1" + strings.Repeat("0", model.MaxIntegerDigits)},
+		{name: "at a configured limit", maxDigits: 6, balance: "999999.99", valid: true},
+		{name: "beyond a configured limit", maxDigits: 6, balance: "1000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+			accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: tt.maxDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+
+			balance := decimal.RequireFromString(tt.balance)
+			response, err := accountService.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &balance, Currency: tt.currency})
+			if tt.valid {
+				require.NoError(t, err)
+				assert.True(t, response.Balance.Equal(balance))
+				return
+			}
+			serviceErr := AsServiceError(err)
+			require.NotNil(t, serviceErr, "expected a ServiceError, got %v", err)
+			assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+			assert.Equal(t, "initial_balance", serviceErr.Field)
+		})
+	}
+}
+
+func TestAccountService_CreateAccount_DefaultCurrency(t *testing.T) {
+	ctx := context.Background()
+	accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+	currencies := model.DefaultCurrencies().Merge(model.CurrencyRegistry{"BTC": 8})
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, currencies, config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: "JPY"}, NewAuditor(nil, nil), nil)
+
+	response, err := accountService.CreateAccount(ctx, &model.CreateAccountRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "JPY", response.Currency)
+
+	// The initial balance is checked against the default currency's scale
+	balance := decimal.RequireFromString("5.5")
+	_, err = accountService.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &balance})
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, "initial_balance", serviceErr.Field)
+
+	response, err = accountService.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &balance, Currency: "BTC"})
+	require.NoError(t, err)
+	assert.Equal(t, "BTC", response.Currency)
+
+	_, err = accountService.CreateAccount(ctx, &model.CreateAccountRequest{Currency: "XYZ"})
+	serviceErr = AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+	assert.Equal(t, "currency", serviceErr.Field)
+
+	listed := accountService.ListCurrencies()
+	assert.Equal(t, "JPY", listed.Default)
+	require.Len(t, listed.Currencies, len(currencies))
+	for i, currency := range listed.Currencies {
+		assert.Equal(t, currencies[currency.Code], currency.Scale, currency.Code)
+		if i > 0 {
+			assert.Less(t, listed.Currencies[i-1].Code, currency.Code)
+		}
+	}
+}
+
+func TestAccountService_BalanceFormat(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		format          model.BalanceFormat
+		currency        string
+		balance         string
+		expectedBalance string
+	}{
+		{name: "exact", format: model.BalanceFormatExact, currency: "USD", balance: "100", expectedBalance: `"100"`},
+		{name: "whole amount padded", format: model.BalanceFormatCurrency, currency: "USD", balance: "100", expectedBalance: `"100.00"`},
+		{name: "fraction padded", format: model.BalanceFormatCurrency, currency: "USD", balance: "100.5", expectedBalance: `"100.50"`},
+		{name: "zero-scale currency", format: model.BalanceFormatCurrency, currency: "JPY", balance: "1500", expectedBalance: `"1500"`},
+		{name: "three-scale currency", format: model.BalanceFormatCurrency, currency: "BHD", balance: "1", expectedBalance: `"1.000"`},
+		{name: "extra places kept", format: model.BalanceFormatCurrency, currency: "USD", balance: "1.2345", expectedBalance: `"1.2345"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+			accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency, BalanceFormat: tt.format}, NewAuditor(nil, nil), nil)
+			account, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString(tt.balance), Currency: tt.currency})
+			require.NoError(t, err)
+
+			response, err := accountService.GetAccount(ctx, account.ID)
+			require.NoError(t, err)
+			encoded, err := json.Marshal(response)
+			require.NoError(t, err)
+			assert.Contains(t, string(encoded), `"balance":`+tt.expectedBalance)
+
+			// The value itself is untouched
+			assert.Equal(t, tt.balance, response.Balance.String())
+		})
+	}
+
+	// Created accounts are formatted too, and as numbers when amounts are
+	model.SetAmountFormat(model.AmountFormatNumber)
+	defer model.SetAmountFormat(model.AmountFormatString)
+	accountService := NewAccountService(memory.NewAccountRepository(memory.NewTransactionRepository()), memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency, BalanceFormat: model.BalanceFormatCurrency}, NewAuditor(nil, nil), nil)
+	initialBalance := decimal.RequireFromString("100")
+	created, err := accountService.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &initialBalance, Currency: "USD"})
+	require.NoError(t, err)
+	encoded, err := json.Marshal(created)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"balance":100.00`)
+}
+
+func TestAccountService_GetBalanceHistory(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+
+	beforeOpening := time.Now().UTC()
+	account, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	other, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	opened := time.Now().UTC()
+
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &account.ID,
+		DestinationAccountID: &other.ID,
+		Amount:               decimal.RequireFromString("30"),
+	})
+	require.NoError(t, err)
+	afterTransfer := time.Now().UTC()
+
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &account.ID,
+		Amount:               decimal.RequireFromString("5"),
+	})
+	require.NoError(t, err)
+	afterDeposit := time.Now().UTC()
+
+	// Points come back in the order asked for, duplicates included
+	points := []time.Time{afterDeposit, beforeOpening, afterTransfer, opened, afterTransfer}
+	var query []string
+	for _, at := range points {
+		query = append(query, at.Format(time.RFC3339Nano))
+	}
+	response, err := accountService.GetBalanceHistory(ctx, account.ID, strings.Join(query, ","))
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, response.ID)
+	require.Len(t, response.Points, len(points))
+	for i, expected := range []string{"75", "0", "70", "100", "70"} {
+		assert.True(t, points[i].Equal(response.Points[i].At), "point %d at %s", i, response.Points[i].At)
+		assert.True(t, response.Points[i].Balance.Equal(decimal.RequireFromString(expected)), "point %d balance %s, expected %s", i, response.Points[i].Balance, expected)
+	}
+
+	tooMany := strings.TrimSuffix(strings.Repeat(opened.Format(time.RFC3339)+",", model.MaxBalanceHistoryPoints+1), ",")
+	for _, invalid := range []string{"", "yesterday", opened.Format(time.RFC3339) + ",2025-13-01T00:00:00Z", tooMany} {
+		_, err = accountService.GetBalanceHistory(ctx, account.ID, invalid)
+		serviceErr := AsServiceError(err)
+		if assert.NotNil(t, serviceErr, "expected a ServiceError for %q, got %v", invalid, err) {
+			assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+			assert.Equal(t, "points", serviceErr.Field)
+		}
+	}
+
+	_, err = accountService.GetBalanceHistory(ctx, uuid.New(), opened.Format(time.RFC3339))
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeNotFound, serviceErr.Code)
+}
+
+func TestAccountService_CreateAccount_Funded(t *testing.T) {
+	ctx := context.Background()
+	transactionService, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), transactionService)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("150"), Currency: "EUR"})
+	require.NoError(t, err)
+	fund := func(amount, ref string) (*model.CreateAccountResponse, error) {
+		initial := decimal.RequireFromString(amount)
+		return accountService.CreateAccount(ctx, &model.CreateAccountRequest{
+			InitialBalance:         &initial,
+			ExternalRef:            stringPtr(ref),
+			FundingSourceAccountID: &source.ID,
+		})
+	}
+
+	// The new account takes the source's currency and the amount moves in one transfer
+	response, err := fund("100", "funded-1")
+	require.NoError(t, err)
+	assert.True(t, response.Created)
+	assert.Equal(t, "EUR", response.Currency)
+	assert.Equal(t, "100", response.Balance.String())
+	require.NotNil(t, response.FundingTransactionID)
+
+	transfer, err := transactionRepo.GetByID(ctx, *response.FundingTransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, source.ID, *transfer.SourceAccountID)
+	assert.Equal(t, response.ID, *transfer.DestinationAccountID)
+	assert.Equal(t, model.TransactionStatusCompleted, transfer.Status)
+
+	account, err := accountRepo.GetByID(ctx, response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "100", account.Balance.String())
+	source, err = accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "50", source.Balance.String())
+
+	// Repeating the reference returns the account without funding it again
+	response, err = fund("100", "funded-1")
+	require.NoError(t, err)
+	assert.False(t, response.Created)
+	assert.Nil(t, response.FundingTransactionID)
+
+	// A source that can't cover the balance fails the creation and keeps its balance
+	_, err = fund("60", "funded-2")
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeInsufficientFunds, serviceErr.Code)
+	source, err = accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "50", source.Balance.String())
+
+	_, err = accountService.CreateAccount(ctx, &model.CreateAccountRequest{FundingSourceAccountID: &source.ID})
+	serviceErr = AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+	assert.Equal(t, "initial_balance", serviceErr.Field)
+
+	missing := uuid.New()
+	_, err = accountService.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &source.Balance, FundingSourceAccountID: &missing})
+	serviceErr = AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeNotFound, serviceErr.Code)
+
+	// A frozen or closed source can't fund an account
+	for status, code := range map[model.AccountStatus]string{
+		model.AccountStatusFrozen: model.ErrCodeAccountFrozen,
+		model.AccountStatusClosed: model.ErrCodeAccountClosed,
+	} {
+		_, err = accountRepo.UpdateStatus(ctx, nil, source.ID, status)
+		require.NoError(t, err)
+		_, err = fund("10", "funded-"+string(status))
+		serviceErr = AsServiceError(err)
+		require.NotNil(t, serviceErr)
+		assert.Equal(t, code, serviceErr.Code)
+	}
+	source, err = accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "50", source.Balance.String())
+}
+
+func TestAccountService_CreateAccount_FundedVelocityLimit(t *testing.T) {
+	ctx := context.Background()
+	transactionService, accountRepo, _ := newTestTransactionService(config.TransferConfig{VelocityLimit: 1, VelocityWindow: time.Hour})
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), transactionService)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	_, err = transactionService.CreateTransaction(ctx, &model.CreateTransactionRequest{SourceAccountID: &source.ID, DestinationAccountID: &dest.ID, Amount: decimal.RequireFromString("10")})
+	require.NoError(t, err)
+
+	// Funding an account is a transfer from the source like any other
+	initial := decimal.RequireFromString("10")
+	_, err = accountService.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &initial, FundingSourceAccountID: &source.ID})
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeVelocityExceeded, serviceErr.Code)
+	source, err = accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "90", source.Balance.String())
+}
+
+func TestAccountService_ReconciliationReport(t *testing.T) {
+	ctx := context.Background()
+	transactionService, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+	accountService := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), transactionService)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	_, err = accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("7"), Currency: "EUR"})
+	require.NoError(t, err)
+
+	_, err = transactionService.CreateTransaction(ctx, &model.CreateTransactionRequest{SourceAccountID: &source.ID, DestinationAccountID: &dest.ID, Amount: decimal.RequireFromString("40")})
+	require.NoError(t, err)
+	_, err = transactionService.CreateTransaction(ctx, &model.CreateTransactionRequest{DestinationAccountID: &dest.ID, Amount: decimal.RequireFromString("5")})
+	require.NoError(t, err)
+
+	report := func() ([]*model.ReconciliationDiscrepancy, *model.ReconciliationSummary) {
+		var discrepancies []*model.ReconciliationDiscrepancy
+		summary, err := accountService.ReconciliationReport(ctx, func(discrepancy *model.ReconciliationDiscrepancy) error {
+			discrepancies = append(discrepancies, discrepancy)
+			return nil
+		})
+		require.NoError(t, err)
+		return discrepancies, summary
+	}
+
+	discrepancies, summary := report()
+	assert.Empty(t, discrepancies)
+	assert.True(t, summary.Balanced)
+	assert.Equal(t, 3, summary.Accounts)
+	require.Len(t, summary.Currencies, 2)
+	assert.Equal(t, "EUR", summary.Currencies[0].Currency)
+	assert.Equal(t, "USD", summary.Currencies[1].Currency)
+	assert.Equal(t, 2, summary.Currencies[1].Accounts)
+	assert.Equal(t, "105", summary.Currencies[1].StoredBalance.String())
+	assert.Equal(t, "100", summary.Currencies[1].OpeningBalance.String())
+	assert.Equal(t, "5", summary.Currencies[1].Movements.String())
+	assert.True(t, summary.Currencies[1].Difference.IsZero())
+
+	// A balance written without a ledger entry shows up in the account's line and the
+	// stored total, while the ledger still adds up
+	require.NoError(t, accountRepo.UpdateBalance(ctx, nil, dest.ID, decimal.RequireFromString("50")))
+	discrepancies, summary = report()
+	require.Len(t, discrepancies, 1)
+	assert.Equal(t, dest.ID, discrepancies[0].ID)
+	assert.Equal(t, "45", discrepancies[0].LedgerBalance.String())
+	assert.Equal(t, "5", discrepancies[0].Discrepancy.String())
+	assert.False(t, summary.Balanced)
+	assert.Equal(t, 1, summary.Discrepancies)
+	assert.Equal(t, "110", summary.Currencies[1].StoredBalance.String())
+	assert.True(t, summary.Currencies[1].Difference.IsZero())
+	assert.True(t, summary.Currencies[0].Difference.IsZero())
+
+	// A transfer whose legs don't net to zero shows up in the currency's difference, even
+	// though every account's balance agrees with its own ledger
+	require.NoError(t, accountRepo.UpdateBalance(ctx, nil, dest.ID, decimal.RequireFromString("45")))
+	credited := decimal.RequireFromString("12")
+	transaction, err := transactionRepo.Create(ctx, nil, &model.CreateTransactionRequest{SourceAccountID: &source.ID, DestinationAccountID: &dest.ID, Amount: decimal.RequireFromString("10"), DestinationAmount: &credited})
+	require.NoError(t, err)
+	require.NoError(t, transactionRepo.UpdateStatus(ctx, nil, transaction.ID, model.TransactionStatusCompleted))
+	require.NoError(t, accountRepo.UpdateBalance(ctx, nil, source.ID, decimal.RequireFromString("50")))
+	require.NoError(t, accountRepo.UpdateBalance(ctx, nil, dest.ID, decimal.RequireFromString("57")))
+	discrepancies, summary = report()
+	assert.Empty(t, discrepancies)
+	assert.False(t, summary.Balanced)
+	assert.Equal(t, "2", summary.Currencies[1].Difference.String())
+}
+
+func TestAccountService_WatchAccount_ReleasesWaiters(t *testing.T) {
+	ctx := context.Background()
+	accountRepo := memory.NewAccountRepository(memory.NewTransactionRepository())
+	watcher := NewBalanceWatcher(nil)
+	accountService := NewAccountService(accountRepo, memory.NewDB(), watcher, nil, model.DefaultCurrencies(), config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+
+	account, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	never := func(*model.GetAccountResponse) bool { return false }
+
+	// Unknown accounts and timeouts leave nothing behind
+	for i := 0; i < 3; i++ {
+		_, _, err := accountService.WatchAccount(ctx, uuid.New(), never, time.Second)
+		assert.Equal(t, model.ErrCodeNotFound, AsServiceError(err).Code)
+	}
+	_, changed, err := accountService.WatchAccount(ctx, account.ID, never, time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, watcher.waiters)
+
+	// A waiter giving up doesn't drop the channel from under one still waiting
+	wake, done := watcher.Wait(account.ID)
+	_, other := watcher.Wait(account.ID)
+	other()
+	other()
+	watcher.Notify(account.ID)
+	select {
+	case <-wake:
+	default:
+		t.Fatal("waiter not woken")
+	}
+	done()
+	assert.Empty(t, watcher.waiters)
+}
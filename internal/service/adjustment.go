@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/money"
+	"internal-transfers-api/internal/repository"
+)
+
+// AdjustBalance applies an operator's signed correction to an account's balance. The
+// money moves in a deposit or withdrawal with the reason as its description, drawn from
+// or paid into the currency's system account when there is one, and the adjustment is
+// recorded with the operator for audit.
+func (s *TransactionService) AdjustBalance(ctx context.Context, id uuid.UUID, req *model.AdjustBalanceRequest) (response *model.AdjustBalanceResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.AdjustBalance")
+	defer func() { endSpan(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+				Field:   validationErr.Field,
+			}
+		}
+		return nil, err
+	}
+
+	err = retryTx(ctx, s.cfg.RetryBudget, func() (err error) {
+		response, err = s.adjustBalance(ctx, id, req)
+		return err
+	})
+	if err == nil {
+		log.Printf("account %s balance adjusted by %s by %s: %s", id, req.Amount, req.Actor, req.Reason)
+	}
+	return response, err
+}
+
+// adjustBalance makes one attempt at an adjustment in its own database transaction
+func (s *TransactionService) adjustBalance(ctx context.Context, id uuid.UUID, req *model.AdjustBalanceRequest) (_ *model.AdjustBalanceResponse, err error) {
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	// The currency's system account, as for deposits and withdrawals, takes the other
+	// side; an account's currency never changes, so it is read before locking
+	currencies, err := s.accountRepo.GetCurrencies(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	currency, ok := currencies[id]
+	if !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeNotFound,
+			Message: "Account not found",
+		}
+	}
+	changed := []uuid.UUID{id}
+	systemID, hasSystem := s.cfg.SystemAccounts[currency]
+	hasSystem = hasSystem && systemID != id
+	if hasSystem {
+		changed = append(changed, systemID)
+	}
+
+	s.cache.BeginWrite(changed...)
+	defer s.cache.EndWrite(changed...)
+
+	// Locked the way transfers lock their accounts
+	accounts, err := s.lockAccounts(ctx, tx, changed...)
+	if err != nil {
+		return nil, err
+	}
+	account, ok := accounts[id]
+	if !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeNotFound,
+			Message: "Account not found",
+		}
+	}
+	if err := s.checkAdjustment(account, req); err != nil {
+		return nil, err
+	}
+	newBalance := money.Add(account.Balance, req.Amount)
+
+	// A credit is a deposit into the account and a debit a withdrawal from it
+	magnitude := req.Amount.Abs()
+	transfer := &model.CreateTransactionRequest{
+		Amount:      magnitude,
+		Description: &req.Reason,
+	}
+	if req.Amount.IsPositive() {
+		transfer.DestinationAccountID = &account.ID
+	} else {
+		transfer.SourceAccountID = &account.ID
+	}
+
+	var system *model.Account
+	var systemBalance decimal.Decimal
+	if hasSystem {
+		if system, ok = accounts[systemID]; !ok {
+			return nil, fmt.Errorf("system account %s of %s does not exist", systemID, currency)
+		}
+		// The system account moves opposite the adjustment, and may go negative
+		systemBalance = money.Sub(system.Balance, req.Amount)
+		if err := s.checkBalanceRange(systemBalance); err != nil {
+			return nil, err
+		}
+		if req.Amount.IsPositive() {
+			transfer.SourceAccountID = &system.ID
+		} else {
+			transfer.DestinationAccountID = &system.ID
+		}
+	}
+
+	transaction, err := s.transactionRepo.Create(ctx, tx, transfer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.UpdateBalance(ctx, tx, account.ID, newBalance); err != nil {
+		return nil, err
+	}
+	if system != nil {
+		if err := s.accountRepo.UpdateBalance(ctx, tx, system.ID, systemBalance); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.transactionRepo.UpdateStatus(ctx, tx, transaction.ID, model.TransactionStatusCompleted); err != nil {
+		return nil, err
+	}
+	transaction.Status = model.TransactionStatusCompleted
+
+	adjustment := &model.AccountAdjustment{
+		AccountID:       account.ID,
+		TransactionID:   transaction.ID,
+		Amount:          req.Amount,
+		PreviousBalance: account.Balance,
+		Reason:          req.Reason,
+		Actor:           req.Actor,
+		Forced:          req.Force,
+		CreatedAt:       s.clock.Now().UTC(),
+	}
+	if err := s.accountRepo.RecordAdjustment(ctx, tx, adjustment); err != nil {
+		return nil, err
+	}
+
+	// Publish the ledger entry to stream subscribers once committed
+	payload, err := json.Marshal(newCreateTransactionResponse(transaction))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer event: %w", err)
+	}
+	if err := s.transactionRepo.NotifyCompleted(ctx, tx, string(payload)); err != nil {
+		return nil, err
+	}
+
+	audit := newAuditEntry(ctx, adjustment.CreatedAt, model.AuditAccountAdjust, account.ID, transaction.ID)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+	s.watcher.Notify(changed...)
+
+	return &model.AdjustBalanceResponse{AccountAdjustment: adjustment, Balance: newBalance}, nil
+}
+
+// checkAdjustment rejects an adjustment the account can't take. Force overrides the
+// account's own min_balance and max_balance, but nothing takes an ordinary account below
+// zero, which the database forbids, or past the supported balance range.
+func (s *TransactionService) checkAdjustment(account *model.Account, req *model.AdjustBalanceRequest) error {
+	if account.Status == model.AccountStatusClosed {
+		return &ServiceError{
+			Code:    model.ErrCodeAccountClosed,
+			Message: "Closed accounts cannot be adjusted",
+		}
+	}
+
+	scale, ok := s.cfg.Currencies.Scale(account.Currency)
+	if !ok {
+		return fmt.Errorf("currency %s of account %s is not in the currency registry", account.Currency, account.ID)
+	}
+	if !model.FitsScale(req.Amount, scale) {
+		return &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("amount has more decimal places than %s allows (%d)", account.Currency, scale),
+			Field:   "amount",
+		}
+	}
+
+	newBalance := money.Add(account.Balance, req.Amount)
+	if !account.System && newBalance.IsNegative() {
+		return &ServiceError{
+			Code:    model.ErrCodeInsufficientFunds,
+			Message: "Adjustment would take the account below zero",
+		}
+	}
+	if err := s.checkBalanceRange(newBalance); err != nil {
+		return err
+	}
+	if req.Force {
+		return nil
+	}
+	if req.Amount.IsNegative() && account.MinBalance != nil && money.Less(newBalance, *account.MinBalance) {
+		return &ServiceError{
+			Code:    model.ErrCodeBelowMinBalance,
+			Message: "Adjustment would take the account below its minimum balance; set force to apply it anyway",
+		}
+	}
+	if req.Amount.IsPositive() && account.MaxBalance != nil && money.Greater(newBalance, *account.MaxBalance) {
+		return &ServiceError{
+			Code:    model.ErrCodeAboveMaxBalance,
+			Message: "Adjustment would take the account above its maximum balance; set force to apply it anyway",
+		}
+	}
+	return nil
+}
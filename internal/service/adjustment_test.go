@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestTransactionService_AdjustBalance(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+
+	minBalance := decimal.RequireFromString("50")
+	account, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100"), Currency: "USD", MinBalance: &minBalance})
+	require.NoError(t, err)
+
+	adjust := func(amount string, force bool) (*model.AdjustBalanceResponse, error) {
+		return s.AdjustBalance(ctx, account.ID, &model.AdjustBalanceRequest{
+			Amount: decimal.RequireFromString(amount),
+			Reason: "settlement correction",
+			Actor:  "ops@example.com",
+			Force:  force,
+		})
+	}
+	assertCode := func(err error, code string) {
+		t.Helper()
+		serviceErr := AsServiceError(err)
+		if assert.NotNil(t, serviceErr, "expected a ServiceError, got %v", err) {
+			assert.Equal(t, code, serviceErr.Code)
+		}
+	}
+
+	// A credit is booked as a deposit into the account
+	response, err := adjust("25.5", false)
+	require.NoError(t, err)
+	assert.True(t, response.Balance.Equal(decimal.RequireFromString("125.5")), "balance %s", response.Balance)
+	assert.True(t, response.PreviousBalance.Equal(decimal.RequireFromString("100")))
+	transaction, err := transactionRepo.GetByID(ctx, response.TransactionID)
+	require.NoError(t, err)
+	assert.Nil(t, transaction.SourceAccountID)
+	assert.Equal(t, &account.ID, transaction.DestinationAccountID)
+	assert.Equal(t, model.TransactionStatusCompleted, transaction.Status)
+	require.NotNil(t, transaction.Description)
+	assert.Equal(t, "settlement correction", *transaction.Description)
+
+	// A debit is a withdrawal, and may not breach the reserve unless forced
+	_, err = adjust("-80", false)
+	assertCode(err, model.ErrCodeBelowMinBalance)
+	assertBalance(t, accountRepo, account.ID, "125.5")
+
+	response, err = adjust("-80", true)
+	require.NoError(t, err)
+	assert.True(t, response.Forced)
+	assertBalance(t, accountRepo, account.ID, "45.5")
+	transaction, err = transactionRepo.GetByID(ctx, response.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, &account.ID, transaction.SourceAccountID)
+	assert.Nil(t, transaction.DestinationAccountID)
+
+	// Not even a forced adjustment takes the account below zero
+	_, err = adjust("-45.51", true)
+	assertCode(err, model.ErrCodeInsufficientFunds)
+
+	_, err = adjust("0.001", false)
+	assertCode(err, model.ErrCodeValidation)
+
+	_, err = s.AdjustBalance(ctx, account.ID, &model.AdjustBalanceRequest{Amount: decimal.RequireFromString("1"), Actor: "ops@example.com"})
+	assertCode(err, model.ErrCodeValidation)
+
+	_, err = s.AdjustBalance(ctx, uuid.New(), &model.AdjustBalanceRequest{Amount: decimal.RequireFromString("1"), Reason: "r", Actor: "a"})
+	assertCode(err, model.ErrCodeNotFound)
+
+	// Both applied adjustments are audited, and the ledger agrees with the balance
+	adjustments := accountRepo.Adjustments(account.ID)
+	require.Len(t, adjustments, 2)
+	assert.Equal(t, "ops@example.com", adjustments[0].Actor)
+	assert.False(t, adjustments[0].Forced)
+	assert.True(t, adjustments[1].Forced)
+	ledger, err := accountRepo.GetLedgerBalance(ctx, nil, account.ID)
+	require.NoError(t, err)
+	assert.True(t, ledger.Equal(decimal.RequireFromString("45.5")), "ledger balance %s", ledger)
+}
+
+func TestTransactionService_AdjustBalance_SystemAccount(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+
+	system, err := accountRepo.Create(ctx, &model.Account{Currency: "USD"})
+	require.NoError(t, err)
+	account, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100"), Currency: "USD"})
+	require.NoError(t, err)
+
+	s.cfg.SystemAccounts = map[string]uuid.UUID{"USD": system.ID}
+	require.NoError(t, s.PrepareSystemAccounts(ctx))
+
+	adjust := func(amount string) *model.Transaction {
+		t.Helper()
+		response, err := s.AdjustBalance(ctx, account.ID, &model.AdjustBalanceRequest{
+			Amount: decimal.RequireFromString(amount),
+			Reason: "settlement correction",
+			Actor:  "ops@example.com",
+		})
+		require.NoError(t, err)
+		transaction, err := transactionRepo.GetByID(ctx, response.TransactionID)
+		require.NoError(t, err)
+		return transaction
+	}
+
+	// A credit is drawn from the system account
+	transaction := adjust("25")
+	assert.Equal(t, &system.ID, transaction.SourceAccountID)
+	assert.Equal(t, &account.ID, transaction.DestinationAccountID)
+	assertBalance(t, accountRepo, account.ID, "125")
+	assertBalance(t, accountRepo, system.ID, "-25")
+
+	// A debit is paid into it
+	transaction = adjust("-40")
+	assert.Equal(t, &account.ID, transaction.SourceAccountID)
+	assert.Equal(t, &system.ID, transaction.DestinationAccountID)
+	assertBalance(t, accountRepo, account.ID, "85")
+	assertBalance(t, accountRepo, system.ID, "15")
+
+	// Adjusting the system account itself has no other side
+	response, err := s.AdjustBalance(ctx, system.ID, &model.AdjustBalanceRequest{
+		Amount: decimal.RequireFromString("-15"),
+		Reason: "write-off",
+		Actor:  "ops@example.com",
+	})
+	require.NoError(t, err)
+	transaction, err = transactionRepo.GetByID(ctx, response.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, &system.ID, transaction.SourceAccountID)
+	assert.Nil(t, transaction.DestinationAccountID)
+	assertBalance(t, accountRepo, system.ID, "0")
+
+	for _, id := range []uuid.UUID{account.ID, system.ID} {
+		ledger, err := accountRepo.GetLedgerBalance(ctx, nil, id)
+		require.NoError(t, err)
+		balance, err := accountRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, ledger.Equal(balance.Balance), "ledger balance %s, balance %s", ledger, balance.Balance)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// Auditor records the changes the services make. Record is called within the database
+// transaction of a change, before it commits, and Committed once it has, so an entry is
+// kept exactly when its change is.
+type Auditor interface {
+	// Record writes entry as part of tx; an error fails the change
+	Record(ctx context.Context, tx *sql.Tx, entry *model.AuditEntry) error
+	// Committed is told about entries whose transaction has committed
+	Committed(entries ...*model.AuditEntry)
+}
+
+// auditor writes audit entries to the audit_log table and as JSON lines to a writer,
+// either of which may be left out
+type auditor struct {
+	repo repository.AuditRepo
+	mu   sync.Mutex // serializes lines written to out
+	out  io.Writer
+}
+
+// NewAuditor returns an Auditor writing entries through repo, in the transaction of
+// their change, and to out, once it has committed. A nil repo or out skips that sink.
+func NewAuditor(repo repository.AuditRepo, out io.Writer) Auditor {
+	return &auditor{repo: repo, out: out}
+}
+
+func (a *auditor) Record(ctx context.Context, tx *sql.Tx, entry *model.AuditEntry) error {
+	if a.repo == nil {
+		return nil
+	}
+	return a.repo.Record(ctx, tx, entry)
+}
+
+func (a *auditor) Committed(entries ...*model.AuditEntry) {
+	if a.out == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	encoder := json.NewEncoder(a.out)
+	for _, entry := range entries {
+		// The change has been made, so a lost line is logged rather than reported
+		if err := encoder.Encode(entry); err != nil {
+			log.Printf("failed to write audit entry: %v", err)
+		}
+	}
+}
+
+// newAuditEntry describes a change made now by the caller in ctx to the given accounts
+// and transactions
+func newAuditEntry(ctx context.Context, now time.Time, operation model.AuditOperation, resources ...uuid.UUID) *model.AuditEntry {
+	return &model.AuditEntry{
+		Actor:       auditActor(ctx),
+		Operation:   operation,
+		ResourceIDs: resources,
+		Timestamp:   now.UTC(),
+	}
+}
+
+// auditActor names the caller in ctx: the admin, the owner an API key belongs to, or
+// anonymous when API keys are disabled and for background jobs
+func auditActor(ctx context.Context) string {
+	identity := IdentityFromContext(ctx)
+	switch {
+	case identity == nil:
+		return "anonymous"
+	case identity.Admin:
+		return "admin"
+	default:
+		return "owner:" + identity.OwnerID
+	}
+}
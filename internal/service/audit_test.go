@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository/memory"
+)
+
+func TestAuditor(t *testing.T) {
+	ctx := WithIdentity(context.Background(), &Identity{OwnerID: "acme"})
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	auditRepo := memory.NewAuditRepository()
+	var out bytes.Buffer
+	auditor := NewAuditor(auditRepo, &out)
+
+	transactions := NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(),
+		config.TransferConfig{Currencies: model.DefaultCurrencies(), MaxBalance: decimal.RequireFromString("1000000")},
+		config.IdempotencyConfig{TTL: time.Hour}, NewBalanceWatcher(nil), nil, auditor, config.DefaultFeatures())
+	accounts := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(),
+		config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, auditor, transactions)
+
+	initial := decimal.RequireFromString("100")
+	source, err := accounts.CreateAccount(ctx, &model.CreateAccountRequest{InitialBalance: &initial})
+	require.NoError(t, err)
+	dest, err := accounts.CreateAccount(ctx, &model.CreateAccountRequest{})
+	require.NoError(t, err)
+
+	transfer, err := transactions.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("30"),
+	})
+	require.NoError(t, err)
+
+	// A declined transfer changes nothing, so nothing is audited
+	_, err = transactions.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("500"),
+	})
+	require.Equal(t, model.ErrCodeInsufficientFunds, AsServiceError(err).Code)
+
+	_, err = accounts.SetStatus(ctx, dest.ID, &model.SetAccountStatusRequest{Status: model.AccountStatusFrozen, Actor: "ops"})
+	require.NoError(t, err)
+
+	entries := auditRepo.Entries()
+	require.Len(t, entries, 4)
+	expected := []struct {
+		operation model.AuditOperation
+		resources []uuid.UUID
+	}{
+		{model.AuditAccountCreate, []uuid.UUID{source.ID}},
+		{model.AuditAccountCreate, []uuid.UUID{dest.ID}},
+		{model.AuditTransfer, []uuid.UUID{transfer.ID, dest.ID, source.ID}},
+		{model.AuditAccountStatus, []uuid.UUID{dest.ID}},
+	}
+	for i, entry := range entries {
+		assert.Equal(t, "owner:acme", entry.Actor)
+		assert.Equal(t, expected[i].operation, entry.Operation)
+		assert.Equal(t, expected[i].resources, entry.ResourceIDs)
+		assert.False(t, entry.Timestamp.IsZero())
+	}
+
+	// The same entries are written to stdout as JSON lines once committed
+	decoder := json.NewDecoder(&out)
+	for i := range entries {
+		var line model.AuditEntry
+		require.NoError(t, decoder.Decode(&line))
+		assert.Equal(t, entries[i].Operation, line.Operation)
+		assert.Equal(t, entries[i].ResourceIDs, line.ResourceIDs)
+	}
+	assert.False(t, decoder.More())
+}
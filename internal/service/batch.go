@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// BatchService accepts bulk transfers for asynchronous processing
+type BatchService struct {
+	batchRepo          *repository.BatchRepository
+	transactionService *TransactionService
+	cfg                config.BatchConfig
+	wake               chan struct{}
+}
+
+// NewBatchService creates a new batch service
+func NewBatchService(batchRepo *repository.BatchRepository, transactionService *TransactionService, cfg config.BatchConfig) *BatchService {
+	return &BatchService{
+		batchRepo:          batchRepo,
+		transactionService: transactionService,
+		cfg:                cfg,
+		wake:               make(chan struct{}, 1),
+	}
+}
+
+// SubmitBatch validates and persists a batch, leaving it for the worker to process
+func (s *BatchService) SubmitBatch(ctx context.Context, req *model.BulkTransferRequest) (_ *model.CreateBatchResponse, err error) {
+	ctx, span := tracer.Start(ctx, "BatchService.SubmitBatch")
+	defer func() { endSpan(span, err) }()
+
+	if !s.transactionService.features.Batches {
+		return nil, featureDisabled("Asynchronous batches")
+	}
+
+	if err := s.validateBatch(req); err != nil {
+		return nil, err
+	}
+
+	// The worker runs without the caller's identity, so ownership is checked up front
+	if err := s.transactionService.authorizeSources(ctx, req.Transfers); err != nil {
+		return nil, err
+	}
+
+	items := make([]model.BatchItem, 0, len(req.Transfers))
+	for i, transfer := range req.Transfers {
+		// Pin a transaction ID per item so reprocessing after a crash replays instead of re-executing
+		if transfer.ID == nil {
+			id := uuid.New()
+			transfer.ID = &id
+		}
+
+		body, err := json.Marshal(&transfer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode batch item: %w", err)
+		}
+
+		items = append(items, model.BatchItem{
+			Index:         i,
+			TransactionID: *transfer.ID,
+			RequestBody:   string(body),
+			Status:        model.TransactionStatusPending,
+		})
+	}
+
+	identity := IdentityFromContext(ctx)
+	batch, err := s.batchRepo.Create(ctx, identity != nil && identity.Admin, items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nudge the worker without blocking if it is already awake
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return &model.CreateBatchResponse{
+		BatchID: batch.ID,
+		Status:  batch.Status,
+	}, nil
+}
+
+// GetBatch retrieves a batch and the status of each of its transfers
+func (s *BatchService) GetBatch(ctx context.Context, id uuid.UUID) (_ *model.GetBatchResponse, err error) {
+	ctx, span := tracer.Start(ctx, "BatchService.GetBatch")
+	defer func() { endSpan(span, err) }()
+
+	batch, err := s.batchRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBatchNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Batch not found",
+			}
+		}
+		return nil, err
+	}
+
+	items, err := s.batchRepo.GetItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.GetBatchResponse{
+		ID:          batch.ID,
+		Status:      batch.Status,
+		CreatedAt:   batch.CreatedAt,
+		CompletedAt: batch.CompletedAt,
+		Items:       items,
+	}, nil
+}
+
+// Run processes pending batches until ctx is cancelled
+func (s *BatchService) Run(ctx context.Context) {
+	// Batches left processing by a previous run still have unprocessed items
+	if n, err := s.batchRepo.ResetProcessing(ctx); err != nil {
+		log.Printf("batch worker: %v", err)
+	} else if n > 0 {
+		log.Printf("batch worker: resuming %d interrupted batches", n)
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drainPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// drainPending processes batches until none are pending
+func (s *BatchService) drainPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		batch, err := s.batchRepo.ClaimPending(ctx)
+		if err != nil {
+			if !errors.Is(err, repository.ErrBatchNotFound) {
+				log.Printf("batch worker: %v", err)
+			}
+			return
+		}
+
+		if err := s.processBatch(ctx, batch); err != nil {
+			log.Printf("batch worker: batch %s: %v", batch.ID, err)
+			return
+		}
+	}
+}
+
+// processBatch executes every pending item of a batch and marks the batch completed.
+// An item that can't be decoded fails on its own rather than holding up the rest.
+func (s *BatchService) processBatch(ctx context.Context, batch *model.Batch) (err error) {
+	ctx, span := tracer.Start(ctx, "BatchService.processBatch")
+	defer func() { endSpan(span, err) }()
+
+	// Sources were authorized on submission; an admin's batch may also debit system accounts
+	if batch.SubmittedByAdmin {
+		ctx = WithIdentity(ctx, &Identity{Admin: true})
+	}
+
+	items, err := s.batchRepo.GetItems(ctx, batch.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Status != model.TransactionStatusPending {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var req model.CreateTransactionRequest
+		if err := json.Unmarshal([]byte(item.RequestBody), &req); err != nil {
+			log.Printf("batch worker: batch %s: failed to decode item %d: %v", batch.ID, item.Index, err)
+			code := model.ErrCodeInvalidInput
+			message := "Transfer could not be decoded"
+			item.Status = model.TransactionStatusFailed
+			item.Error = &message
+			item.Code = &code
+		} else if response, err := s.transactionService.CreateTransaction(ctx, &req); err != nil {
+			if ctx.Err() != nil {
+				// Leave the item pending so it is retried on the next run
+				return ctx.Err()
+			}
+			code := model.ErrCodeInternalError
+			message := "Internal server error"
+			if serviceErr := AsServiceError(err); serviceErr != nil {
+				code = serviceErr.Code
+				message = serviceErr.Message
+			}
+			item.Status = model.TransactionStatusFailed
+			item.Error = &message
+			item.Code = &code
+		} else {
+			// A replayed item may have been recorded as declined
+			item.Status = response.Status
+		}
+
+		if err := s.batchRepo.UpdateItem(ctx, item); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Record the item as failed, pointing at its transaction, and carry on with the
+			// rest rather than leave the whole batch processing
+			log.Printf("batch worker: batch %s: failed to record item %d: %v", batch.ID, item.Index, err)
+			code := model.ErrCodeInternalError
+			message := "Transfer outcome could not be recorded; look the transaction up by its ID"
+			item.Status = model.TransactionStatusFailed
+			item.Error = &message
+			item.Code = &code
+			if err := s.batchRepo.UpdateItem(ctx, item); err != nil {
+				log.Printf("batch worker: batch %s: failed to record item %d: %v", batch.ID, item.Index, err)
+			}
+		}
+	}
+
+	return s.batchRepo.Complete(ctx, batch.ID)
+}
+
+// validateBatch checks the batch size and every transfer in it
+func (s *BatchService) validateBatch(req *model.BulkTransferRequest) error {
+	if len(req.Transfers) == 0 {
+		return &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: "at least one transfer is required",
+		}
+	}
+
+	if len(req.Transfers) > s.cfg.MaxSize {
+		return &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("cannot process more than %d transfers in a batch", s.cfg.MaxSize),
+		}
+	}
+
+	for i := range req.Transfers {
+		if err := req.Transfers[i].Validate(); err != nil {
+			return &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: fmt.Sprintf("transfers[%d]: %s", i, err.Error()),
+			}
+		}
+	}
+
+	return nil
+}
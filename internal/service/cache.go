@@ -0,0 +1,139 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+)
+
+// BalanceCache is a read-through cache of account reads. Writers bracket their
+// database transaction with BeginWrite and EndWrite: while a write is in flight the
+// account is read from the database, and ending any write bumps the cache-wide write
+// sequence so a read that started before the commit can't store what it saw. Entries
+// exist only for accounts that were read successfully or are being written, and idle
+// ones are evicted once expired. A nil cache is disabled and always reads through.
+type BalanceCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	writes    uint64 // number of writes ended so far
+	entries   map[uuid.UUID]*cacheEntry
+	nextSweep time.Time
+}
+
+// cacheEntry is the cached state of one account
+type cacheEntry struct {
+	writers int
+	account *model.GetAccountResponse
+	expires time.Time
+}
+
+// NewBalanceCache creates a cache whose entries live for ttl, or returns nil,
+// disabling caching, when ttl is not positive
+func NewBalanceCache(ttl time.Duration) *BalanceCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &BalanceCache{ttl: ttl, entries: make(map[uuid.UUID]*cacheEntry)}
+}
+
+// GetOrLoad returns the cached account, or calls load and caches its result unless a
+// write ended or is in flight in the meantime
+func (c *BalanceCache) GetOrLoad(id uuid.UUID, load func() (*model.GetAccountResponse, error)) (*model.GetAccountResponse, error) {
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	entry := c.entries[id]
+	if entry != nil && entry.writers == 0 && entry.account != nil && time.Now().Before(entry.expires) {
+		account := *entry.account
+		c.mu.Unlock()
+		return &account, nil
+	}
+	writes, cacheable := c.writes, entry == nil || entry.writers == 0
+	c.mu.Unlock()
+
+	account, err := load()
+	if err != nil || !cacheable {
+		return account, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writes != writes {
+		return account, nil
+	}
+	entry = c.entries[id]
+	if entry == nil {
+		entry = &cacheEntry{}
+		c.entries[id] = entry
+	}
+	if entry.writers == 0 {
+		cached := *account
+		entry.account = &cached
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.sweep()
+	return account, nil
+}
+
+// sweep evicts the expired entries no write is in flight against, at most once per TTL.
+// Called with mu held.
+func (c *BalanceCache) sweep() {
+	now := time.Now()
+	if now.Before(c.nextSweep) {
+		return
+	}
+	c.nextSweep = now.Add(c.ttl)
+	for id, entry := range c.entries {
+		if entry.writers == 0 && !now.Before(entry.expires) {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// BeginWrite drops the cached accounts and bypasses the cache for them until EndWrite.
+// Call it before a database transaction updates their balances.
+func (c *BalanceCache) BeginWrite(ids ...uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		entry := c.entries[id]
+		if entry == nil {
+			entry = &cacheEntry{}
+			c.entries[id] = entry
+		}
+		entry.writers++
+		entry.account = nil
+	}
+}
+
+// EndWrite ends a write started with BeginWrite once its transaction has committed or
+// rolled back. An account with no write left in flight has nothing cached, so its entry
+// is removed.
+func (c *BalanceCache) EndWrite(ids ...uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	for _, id := range ids {
+		entry := c.entries[id]
+		if entry == nil {
+			continue
+		}
+		entry.writers--
+		entry.account = nil
+		if entry.writers <= 0 {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository/memory"
+)
+
+func TestBalanceCache_GetOrLoad(t *testing.T) {
+	id := uuid.New()
+	loads := 0
+	load := func() (*model.GetAccountResponse, error) {
+		loads++
+		return &model.GetAccountResponse{ID: id, Balance: decimal.NewFromInt(int64(loads))}, nil
+	}
+
+	t.Run("serves repeated reads from the cache", func(t *testing.T) {
+		cache := NewBalanceCache(time.Minute)
+		loads = 0
+
+		for i := 0; i < 3; i++ {
+			account, err := cache.GetOrLoad(id, load)
+			require.NoError(t, err)
+			assert.Equal(t, "1", account.Balance.String())
+		}
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("bypasses the cache while a write is in flight", func(t *testing.T) {
+		cache := NewBalanceCache(time.Minute)
+		loads = 0
+
+		_, err := cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+
+		cache.BeginWrite(id)
+		account, err := cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+		assert.Equal(t, "2", account.Balance.String())
+		cache.EndWrite(id)
+
+		account, err = cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+		assert.Equal(t, "3", account.Balance.String())
+	})
+
+	t.Run("does not store a read that raced a write", func(t *testing.T) {
+		cache := NewBalanceCache(time.Minute)
+		loads = 0
+
+		_, err := cache.GetOrLoad(id, func() (*model.GetAccountResponse, error) {
+			// The write commits while the read is still on its way back
+			cache.BeginWrite(id)
+			cache.EndWrite(id)
+			return load()
+		})
+		require.NoError(t, err)
+
+		account, err := cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+		assert.Equal(t, "2", account.Balance.String())
+	})
+
+	t.Run("expires entries after the TTL", func(t *testing.T) {
+		cache := NewBalanceCache(time.Millisecond)
+		loads = 0
+
+		_, err := cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		account, err := cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+		assert.Equal(t, "2", account.Balance.String())
+	})
+
+	t.Run("keeps no entry for failed loads or ended writes", func(t *testing.T) {
+		cache := NewBalanceCache(time.Minute)
+
+		for i := 0; i < 3; i++ {
+			_, err := cache.GetOrLoad(uuid.New(), func() (*model.GetAccountResponse, error) {
+				return nil, errors.New("account not found")
+			})
+			require.Error(t, err)
+		}
+		other := uuid.New()
+		cache.BeginWrite(other)
+		cache.EndWrite(other)
+
+		assert.Empty(t, cache.entries)
+	})
+
+	t.Run("evicts expired entries", func(t *testing.T) {
+		cache := NewBalanceCache(time.Millisecond)
+		loads = 0
+
+		_, err := cache.GetOrLoad(id, load)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		other := uuid.New()
+		_, err = cache.GetOrLoad(other, load)
+		require.NoError(t, err)
+
+		assert.Len(t, cache.entries, 1)
+		assert.Contains(t, cache.entries, other)
+	})
+
+	t.Run("nil cache always loads", func(t *testing.T) {
+		cache := NewBalanceCache(0)
+		loads = 0
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.GetOrLoad(id, load)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, loads)
+	})
+}
+
+func TestAccountService_GetAccount_CacheInvalidatedByTransfer(t *testing.T) {
+	ctx := context.Background()
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	watcher := NewBalanceWatcher(nil)
+	cache := NewBalanceCache(time.Minute)
+	cfg := config.TransferConfig{
+		MaxBalance:    decimal.RequireFromString("1000000000"),
+		Currencies:    model.DefaultCurrencies(),
+		AllowDeposits: true,
+	}
+	accountService := NewAccountService(accountRepo, nil, watcher, cache, cfg.Currencies, config.AccountConfig{MaxInitialBalanceDigits: model.MaxIntegerDigits, DefaultCurrency: model.DefaultCurrency}, NewAuditor(nil, nil), nil)
+	transactionService := NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(), cfg, config.IdempotencyConfig{TTL: time.Hour}, watcher, cache, NewAuditor(nil, nil), config.DefaultFeatures())
+
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	account, err := accountService.GetAccount(ctx, dest.ID)
+	require.NoError(t, err)
+	assert.True(t, account.Balance.IsZero())
+
+	_, err = transactionService.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("12.5"),
+	})
+	require.NoError(t, err)
+
+	balance, err := accountService.GetAccountBalance(ctx, dest.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "12.5", balance.String())
+}
@@ -0,0 +1,17 @@
+package service
+
+import "time"
+
+// Clock tells the services the time. Tests swap in their own to control time-dependent
+// behavior such as idempotency key expiry and interest accrual; retry backoff and cache
+// expiry measure elapsed time and stay on the wall clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the wall clock, used by every service unless a test replaces it
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// DisputeTransaction flags a completed transaction for review with the reason given. The
+// balances are left alone; a dispute that is upheld is settled by reversing the transaction.
+func (s *TransactionService) DisputeTransaction(ctx context.Context, id uuid.UUID, req *model.DisputeTransactionRequest) (_ *model.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.DisputeTransaction")
+	defer func() { endSpan(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+				Field:   validationErr.Field,
+			}
+		}
+		return nil, err
+	}
+
+	return s.setDispute(ctx, id, model.AuditDispute, func(tx *sql.Tx, transaction *model.Transaction) error {
+		if transaction.Status != model.TransactionStatusCompleted {
+			return &ServiceError{
+				Code:    model.ErrCodeConflict,
+				Message: fmt.Sprintf("Only completed transactions can be disputed, this one is %s", transaction.Status),
+			}
+		}
+		if transaction.Disputed {
+			return &ServiceError{
+				Code:    model.ErrCodeConflict,
+				Message: "Transaction is already disputed",
+			}
+		}
+		return s.transactionRepo.SetDisputed(ctx, tx, id, req.Reason, s.clock.Now().UTC())
+	})
+}
+
+// ResolveDispute clears the dispute on a transaction. The reason and when it was raised
+// are kept alongside when it was resolved.
+func (s *TransactionService) ResolveDispute(ctx context.Context, id uuid.UUID) (_ *model.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.ResolveDispute")
+	defer func() { endSpan(span, err) }()
+
+	return s.setDispute(ctx, id, model.AuditDisputeResolve, func(tx *sql.Tx, transaction *model.Transaction) error {
+		if !transaction.Disputed {
+			return &ServiceError{
+				Code:    model.ErrCodeConflict,
+				Message: "Transaction is not disputed",
+			}
+		}
+		return s.transactionRepo.ResolveDispute(ctx, tx, id, s.clock.Now().UTC())
+	})
+}
+
+// setDispute applies update to a transaction under a row lock on it, so a dispute and its
+// resolution can't race each other, and returns the transaction as updated
+func (s *TransactionService) setDispute(ctx context.Context, id uuid.UUID, operation model.AuditOperation, update func(*sql.Tx, *model.Transaction) error) (*model.Transaction, error) {
+	tx, release, err := repository.BeginTx(ctx, s.db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	transaction, err := s.transactionRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Transaction not found",
+			}
+		}
+		return nil, err
+	}
+
+	if err := update(tx, transaction); err != nil {
+		return nil, err
+	}
+
+	audit := newAuditEntry(ctx, s.clock.Now(), operation, id)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+
+	return s.transactionRepo.GetByID(ctx, id)
+}
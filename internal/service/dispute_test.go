@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestTransactionService_DisputeTransaction(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	transfer := func() uuid.UUID {
+		response, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("10"),
+			AllowDuplicate:       true,
+		})
+		require.NoError(t, err)
+		return response.ID
+	}
+	disputed, undisputed := transfer(), transfer()
+
+	transaction, err := s.DisputeTransaction(ctx, disputed, &model.DisputeTransactionRequest{Reason: "not recognised"})
+	require.NoError(t, err)
+	assert.True(t, transaction.Disputed)
+	require.NotNil(t, transaction.DisputeReason)
+	assert.Equal(t, "not recognised", *transaction.DisputeReason)
+	assert.NotNil(t, transaction.DisputedAt)
+	assert.Nil(t, transaction.DisputeResolvedAt)
+
+	// Disputing moves no money
+	assertBalance(t, accountRepo, source.ID, "80")
+	assertBalance(t, accountRepo, dest.ID, "20")
+
+	flagged := true
+	page, err := s.GetAccountTransactions(ctx, source.ID, model.TransactionFilter{Disputed: &flagged}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, disputed, page.Data[0].ID)
+	assert.Equal(t, 1, page.Total)
+
+	tests := []struct {
+		name         string
+		id           uuid.UUID
+		reason       string
+		expectedCode string
+	}{
+		{name: "already disputed", id: disputed, reason: "again", expectedCode: model.ErrCodeConflict},
+		{name: "missing reason", id: undisputed, reason: " ", expectedCode: model.ErrCodeValidation},
+		{name: "missing", id: uuid.New(), reason: "unknown", expectedCode: model.ErrCodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.DisputeTransaction(ctx, tt.id, &model.DisputeTransactionRequest{Reason: tt.reason})
+			assert.Equal(t, tt.expectedCode, AsServiceError(err).Code)
+		})
+	}
+
+	_, err = s.ResolveDispute(ctx, undisputed)
+	assert.Equal(t, model.ErrCodeConflict, AsServiceError(err).Code)
+
+	transaction, err = s.ResolveDispute(ctx, disputed)
+	require.NoError(t, err)
+	assert.False(t, transaction.Disputed)
+	assert.NotNil(t, transaction.DisputeReason)
+	assert.NotNil(t, transaction.DisputeResolvedAt)
+
+	stored, err := transactionRepo.GetByID(ctx, disputed)
+	require.NoError(t, err)
+	assert.False(t, stored.Disputed)
+
+	page, err = s.GetAccountTransactions(ctx, source.ID, model.TransactionFilter{Disputed: &flagged}, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page.Data)
+}
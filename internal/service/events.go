@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"internal-transfers-api/internal/repository"
+)
+
+// subscriberBuffer is how many events a slow subscriber can lag behind before events are dropped for it
+const subscriberBuffer = 64
+
+// TransferEvents fans out completed-transfer notifications from Postgres to stream subscribers
+type TransferEvents struct {
+	listener    *pq.Listener
+	mu          sync.Mutex
+	subscribers map[chan string]struct{}
+	closed      bool
+	done        chan struct{}
+}
+
+// NewTransferEvents creates an event source for the transfer notification channel.
+// Subscribers receive nothing until Start is called.
+func NewTransferEvents(dsn string) *TransferEvents {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("transfer event listener: %v", err)
+		}
+	})
+
+	return &TransferEvents{
+		listener:    listener,
+		subscribers: make(map[chan string]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start subscribes to the transfer notification channel and starts dispatching events.
+// It blocks until the database acknowledges the subscription.
+func (e *TransferEvents) Start() error {
+	if err := e.listener.Listen(repository.TransferEventsChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", repository.TransferEventsChannel, err)
+	}
+	go e.run()
+	return nil
+}
+
+// Subscribe registers a new subscriber. The returned channel is closed when the
+// event source shuts down; the returned function must be called to unsubscribe.
+func (e *TransferEvents) Subscribe() (<-chan string, func()) {
+	ch := make(chan string, subscriberBuffer)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	e.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if _, ok := e.subscribers[ch]; ok {
+			delete(e.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close stops listening and closes every subscriber channel
+func (e *TransferEvents) Close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	for ch := range e.subscribers {
+		delete(e.subscribers, ch)
+		close(ch)
+	}
+	e.mu.Unlock()
+
+	close(e.done)
+	if err := e.listener.Close(); err != nil {
+		log.Printf("failed to close transfer event listener: %v", err)
+	}
+}
+
+// run dispatches notifications until the listener is closed
+func (e *TransferEvents) run() {
+	for {
+		select {
+		case n, ok := <-e.listener.NotificationChannel():
+			if !ok {
+				return
+			}
+			// A nil notification signals a reconnect; events sent meanwhile are lost
+			if n == nil {
+				continue
+			}
+			e.broadcast(n.Extra)
+		case <-time.After(90 * time.Second):
+			// Check the connection is still alive when idle
+			go e.listener.Ping()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// broadcast delivers an event to every subscriber without blocking on slow ones
+func (e *TransferEvents) broadcast(payload string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for ch := range e.subscribers {
+		select {
+		case ch <- payload:
+		default:
+			log.Printf("dropping transfer event for slow subscriber")
+		}
+	}
+}
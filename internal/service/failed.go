@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// RetryTransaction makes a failed transfer again as a new transaction pointing to it
+// with retry_of. The retry goes through every check a new transfer does, against the
+// balances of now, and a retry that is declined again can itself be retried later. Once
+// a retry has gone through, the original can't be retried again.
+func (s *TransactionService) RetryTransaction(ctx context.Context, id uuid.UUID, clientIP *string) (response *model.CreateTransactionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.RetryTransaction")
+	defer func() { endSpan(span, err) }()
+
+	original, err := s.transactionRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Transaction not found",
+			}
+		}
+		return nil, err
+	}
+	if original.Status != model.TransactionStatusFailed {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeConflict,
+			Message: fmt.Sprintf("Only failed transactions can be retried, this one is %s", original.Status),
+		}
+	}
+
+	// A retry that went through is unique per original, which the database enforces
+	// for retries racing this check
+	retry, err := s.transactionRepo.GetRetry(ctx, id)
+	if err == nil {
+		return nil, &ServiceError{
+			Code:          model.ErrCodeConflict,
+			Message:       "Transaction has already been retried",
+			TransactionID: &retry.ID,
+		}
+	}
+	if !errors.Is(err, repository.ErrTransactionNotFound) {
+		return nil, err
+	}
+
+	// Deposits and withdrawals were stored with the system account filled in, so the
+	// retry moves money between the same accounts as the original. A deposit's system
+	// source is left for the retry to fill in again, as only an admin may name one.
+	source := original.SourceAccountID
+	if source != nil {
+		account, err := s.accountRepo.GetByID(ctx, *source)
+		if err != nil && !errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, err
+		}
+		if err == nil && account.System {
+			source = nil
+		}
+	}
+	return s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      source,
+		DestinationAccountID: original.DestinationAccountID,
+		Amount:               original.Amount,
+		Reference:            original.Reference,
+		Description:          original.Description,
+		Fee:                  original.Fee,
+		FeeAccountID:         original.FeeAccountID,
+		Channel:              original.Channel,
+		ClientIP:             clientIP,
+		// Repeating the original's accounts and amount is the point of a retry
+		AllowDuplicate: true,
+		RetryOf:        &original.ID,
+	})
+}
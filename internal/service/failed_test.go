@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestTransactionService_RetryTransaction(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{RecordDeclined: true})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("5")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("10"),
+	})
+	require.Equal(t, model.ErrCodeInsufficientFunds, AsServiceError(err).Code)
+	declined, err := transactionRepo.GetAccountTransactions(ctx, source.ID, model.TransactionFilter{}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, declined, 1)
+	original := declined[0]
+	require.Equal(t, model.TransactionStatusFailed, original.Status)
+
+	// Still short of funds, the retry is declined and recorded in turn
+	_, err = s.RetryTransaction(ctx, original.ID, nil)
+	require.Equal(t, model.ErrCodeInsufficientFunds, AsServiceError(err).Code)
+	declined, err = transactionRepo.GetAccountTransactions(ctx, source.ID, model.TransactionFilter{}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, declined, 2)
+	for _, transaction := range declined {
+		if transaction.ID != original.ID {
+			assert.Equal(t, &original.ID, transaction.RetryOf)
+		}
+	}
+
+	// Once funded, the retry goes through
+	require.NoError(t, accountRepo.UpdateBalance(ctx, nil, source.ID, decimal.RequireFromString("15")))
+	retry, err := s.RetryTransaction(ctx, original.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &original.ID, retry.RetryOf)
+	assert.Equal(t, model.TransactionStatusCompleted, retry.Status)
+	assertBalance(t, accountRepo, source.ID, "5")
+	assertBalance(t, accountRepo, dest.ID, "10")
+
+	tests := []struct {
+		name                  string
+		id                    uuid.UUID
+		expectedCode          string
+		expectedTransactionID *uuid.UUID
+	}{
+		{name: "already retried", id: original.ID, expectedCode: model.ErrCodeConflict, expectedTransactionID: &retry.ID},
+		{name: "completed", id: retry.ID, expectedCode: model.ErrCodeConflict},
+		{name: "missing", id: uuid.New(), expectedCode: model.ErrCodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RetryTransaction(ctx, tt.id, nil)
+			serviceErr := AsServiceError(err)
+			require.NotNil(t, serviceErr)
+			assert.Equal(t, tt.expectedCode, serviceErr.Code)
+			assert.Equal(t, tt.expectedTransactionID, serviceErr.TransactionID)
+		})
+	}
+	assertBalance(t, accountRepo, source.ID, "5")
+}
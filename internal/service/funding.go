@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/money"
+	"internal-transfers-api/internal/repository"
+)
+
+// CreateFundedAccount creates account with amount transferred into it from the source
+// account, retrying within the configured budget while it fails serialization. The
+// account, the debit and the transfer commit together, so a source that can't fund the
+// account leaves no account behind. An account found by its external reference is
+// returned as it is, without funding it again.
+func (s *TransactionService) CreateFundedAccount(ctx context.Context, account *model.Account, sourceID uuid.UUID, amount decimal.Decimal) (response *model.CreateAccountResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.CreateFundedAccount")
+	defer func() { endSpan(span, err) }()
+
+	err = retryTx(ctx, s.cfg.RetryBudget, func() (err error) {
+		response, err = s.createFundedAccount(ctx, account, sourceID, amount)
+		return err
+	})
+	return response, err
+}
+
+// createFundedAccount makes one attempt at a funded account in its own database
+// transaction. An account already created with the same external reference is found
+// before the source's funds are checked, so replaying a funded creation succeeds after
+// the funding has been spent.
+func (s *TransactionService) createFundedAccount(ctx context.Context, account *model.Account, sourceID uuid.UUID, amount decimal.Decimal) (_ *model.CreateAccountResponse, err error) {
+	s.cache.BeginWrite(sourceID)
+	defer s.cache.EndWrite(sourceID)
+
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	// Locked the way transfers lock their accounts; the new account needs no lock
+	accounts, err := s.lockAccounts(ctx, tx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := accounts[sourceID]
+	if !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeNotFound,
+			Message: "Funding source account not found",
+			Field:   "funding_source_account_id",
+		}
+	}
+
+	// Only the owner of the source account may debit it
+	if err := authorizeDebit(ctx, source); err != nil {
+		return nil, err
+	}
+	if err := checkDebitAllowed(source.Status); err != nil {
+		return nil, err
+	}
+	if source.Currency != account.Currency {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeCurrencyMismatch,
+			Message: fmt.Sprintf("Funding source account holds %s, not %s", source.Currency, account.Currency),
+		}
+	}
+
+	// The account starts empty and receives its balance in the transfer
+	opening := *account
+	opening.Balance = decimal.Zero
+	created, isNew, err := s.accountRepo.CreateOrGetTx(ctx, tx, &opening)
+	if err != nil {
+		return nil, err
+	}
+	response := &model.CreateAccountResponse{
+		ID:          created.ID,
+		Balance:     created.Balance,
+		Currency:    created.Currency,
+		OwnerID:     created.OwnerID,
+		ExternalRef: created.ExternalRef,
+		Created:     isNew,
+	}
+	if !isNew {
+		return response, nil
+	}
+
+	// The funding transfer passes the same checks as any other transfer from the source,
+	// rolling back the account with the transaction if it fails one
+	transfer := &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &created.ID,
+		Amount:               amount,
+	}
+	if err := s.checkDebit(ctx, tx, source, amount); err != nil {
+		return nil, err
+	}
+	if err := s.checkBalanceRange(amount); err != nil {
+		return nil, err
+	}
+	if err := s.checkDuplicate(ctx, tx, transfer); err != nil {
+		return nil, err
+	}
+	newSourceBalance := money.Sub(source.Balance, amount)
+
+	transaction, err := s.transactionRepo.Create(ctx, tx, transfer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.UpdateBalance(ctx, tx, source.ID, newSourceBalance); err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.UpdateBalance(ctx, tx, created.ID, amount); err != nil {
+		return nil, err
+	}
+	if err := s.transactionRepo.UpdateStatus(ctx, tx, transaction.ID, model.TransactionStatusCompleted); err != nil {
+		return nil, err
+	}
+	transaction.Status = model.TransactionStatusCompleted
+
+	// Publish the funding transfer to stream subscribers once committed
+	payload, err := json.Marshal(newCreateTransactionResponse(transaction))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer event: %w", err)
+	}
+	if err := s.transactionRepo.NotifyCompleted(ctx, tx, string(payload)); err != nil {
+		return nil, err
+	}
+
+	audit := newAuditEntry(ctx, s.clock.Now(), model.AuditAccountCreate, created.ID, transaction.ID, source.ID)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+	s.watcher.Notify(source.ID)
+
+	response.Balance = amount
+	response.FundingTransactionID = &transaction.ID
+	return response, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+)
+
+// PreviewConversion converts amount from one currency into another the way a transfer
+// between accounts of those currencies would be credited now, rate and rounding
+// included, without moving any money. Both currencies must be supported and amount must
+// be positive and fit the from currency's scale.
+func (s *TransactionService) PreviewConversion(ctx context.Context, from, to string, amount decimal.Decimal) (_ *model.FXPreviewResponse, err error) {
+	_, span := tracer.Start(ctx, "TransactionService.PreviewConversion")
+	defer func() { endSpan(span, err) }()
+
+	if !s.features.CrossCurrency {
+		return nil, featureDisabled("Currency conversions")
+	}
+
+	fromScale, ok := s.cfg.Currencies.Scale(from)
+	if !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("unsupported currency %s; GET /v1/currencies lists the supported ones", from),
+			Field:   "from",
+		}
+	}
+	toScale, ok := s.cfg.Currencies.Scale(to)
+	if !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("unsupported currency %s; GET /v1/currencies lists the supported ones", to),
+			Field:   "to",
+		}
+	}
+	if !amount.IsPositive() {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: "amount must be positive",
+			Field:   "amount",
+		}
+	}
+	if !model.FitsScale(amount, fromScale) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("amount has more decimal places than %s allows (%d)", from, fromScale),
+			Field:   "amount",
+		}
+	}
+
+	response := &model.FXPreviewResponse{
+		From:            from,
+		To:              to,
+		Amount:          amount,
+		Rate:            decimal.NewFromInt(1),
+		ConvertedAmount: amount,
+	}
+	if from == to {
+		return response, nil
+	}
+
+	var rate decimal.Decimal
+	if s.cfg.Rates != nil {
+		rate, ok = s.cfg.Rates.Rate(from, to)
+	}
+	if s.cfg.Rates == nil || !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeCurrencyMismatch,
+			Message: fmt.Sprintf("No exchange rate is available from %s to %s", from, to),
+		}
+	}
+
+	conversion := &fxConversion{from: from, to: to, rate: rate, scale: toScale, rounding: s.cfg.RoundingMode}
+	if response.ConvertedAmount, err = conversion.convert(amount); err != nil {
+		return nil, err
+	}
+	response.Rate = rate
+
+	return response, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// IdempotencyService exposes stored idempotency keys for diagnosing retries
+type IdempotencyService struct {
+	idempotencyRepo repository.IdempotencyRepo
+	clock           Clock
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(idempotencyRepo repository.IdempotencyRepo) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		clock:           systemClock{},
+	}
+}
+
+// GetKeyStatus reports whether a key is stored, its response status and lifetime.
+// The stored response body is only included when includeBody is set.
+func (s *IdempotencyService) GetKeyStatus(ctx context.Context, key string, includeBody bool) (_ *model.IdempotencyKeyStatusResponse, err error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.GetKeyStatus")
+	defer func() { endSpan(span, err) }()
+
+	record, err := s.idempotencyRepo.GetStatus(ctx, repository.GenerateKeyHash(key))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.IdempotencyKeyStatusResponse{Key: key}
+	if record == nil {
+		return response, nil
+	}
+
+	response.Exists = true
+	response.Expired = !record.ExpiresAt.After(s.clock.Now())
+	response.ResponseStatus = record.ResponseStatus
+	response.CreatedAt = &record.CreatedAt
+	response.ExpiresAt = &record.ExpiresAt
+	if includeBody && record.ResponseBody != nil && json.Valid([]byte(*record.ResponseBody)) {
+		response.ResponseBody = json.RawMessage(*record.ResponseBody)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	OwnerID string // owner whose accounts the caller may debit
+	Admin   bool   // admins may debit any account
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the caller's identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller's identity, or nil when the request was not
+// authenticated because API keys are disabled
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// authorizeDebit rejects debiting account on behalf of a caller who doesn't own it.
+// A system account stands in for the outside world and may go negative, so only an admin
+// may name it as a source; deposits draw on it without being checked here. Otherwise,
+// without an identity there is nothing to check.
+func authorizeDebit(ctx context.Context, account *model.Account) error {
+	identity := IdentityFromContext(ctx)
+	if identity != nil && identity.Admin {
+		return nil
+	}
+	if account.System {
+		return &ServiceError{
+			Code:    model.ErrCodeForbidden,
+			Message: "Source account is a system account, which only an admin may debit",
+		}
+	}
+	if identity == nil {
+		return nil
+	}
+	if account.OwnerID == nil || *account.OwnerID != identity.OwnerID {
+		return &ServiceError{
+			Code:    model.ErrCodeForbidden,
+			Message: "Source account does not belong to the caller",
+		}
+	}
+	return nil
+}
+
+// ownerForNewAccount returns the owner a new account gets: the requested one for an
+// admin or unauthenticated caller, otherwise the caller, who can't create accounts
+// for someone else
+func ownerForNewAccount(ctx context.Context, requested *string) (*string, error) {
+	identity := IdentityFromContext(ctx)
+	if identity == nil || identity.Admin {
+		return requested, nil
+	}
+	if requested != nil && *requested != identity.OwnerID {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeForbidden,
+			Message: "Accounts can only be created for the caller",
+		}
+	}
+	owner := identity.OwnerID
+	return &owner, nil
+}
+
+// sameOwner reports whether two optional owner IDs are the same
+func sameOwner(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// authorizeSources checks that the caller may debit the source account of every
+// transfer. Missing accounts are left for the transfer itself to report.
+func (s *TransactionService) authorizeSources(ctx context.Context, transfers []model.CreateTransactionRequest) error {
+	if identity := IdentityFromContext(ctx); identity == nil || identity.Admin {
+		return nil
+	}
+
+	checked := make(map[uuid.UUID]bool)
+	for i, transfer := range transfers {
+		if transfer.SourceAccountID == nil || checked[*transfer.SourceAccountID] {
+			continue
+		}
+		checked[*transfer.SourceAccountID] = true
+
+		source, err := s.accountRepo.GetByID(ctx, *transfer.SourceAccountID)
+		if err != nil {
+			if errors.Is(err, repository.ErrAccountNotFound) {
+				continue
+			}
+			return err
+		}
+		if err := authorizeDebit(ctx, source); err != nil {
+			return &ServiceError{
+				Code:    model.ErrCodeForbidden,
+				Message: fmt.Sprintf("transfers[%d]: %s", i, err.Error()),
+			}
+		}
+	}
+	return nil
+}
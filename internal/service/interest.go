@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// interestNamespace derives the transaction ID of each daily accrual, making
+// the accrual for an account and day idempotent
+var interestNamespace = uuid.MustParse("6f1c2f4e-3b0a-4d5e-9c8b-7a2e1d0f4b63")
+
+// InterestService credits daily interest to savings accounts
+type InterestService struct {
+	accountRepo        repository.AccountRepo
+	transactionService *TransactionService
+	cfg                config.InterestConfig
+	roundingMode       model.RoundingMode
+	currencies         model.CurrencyRegistry
+	clock              Clock
+}
+
+// NewInterestService creates a new interest service
+func NewInterestService(accountRepo repository.AccountRepo, transactionService *TransactionService, cfg config.InterestConfig, roundingMode model.RoundingMode, currencies model.CurrencyRegistry) *InterestService {
+	return &InterestService{
+		accountRepo:        accountRepo,
+		transactionService: transactionService,
+		cfg:                cfg,
+		roundingMode:       roundingMode,
+		currencies:         currencies,
+		clock:              systemClock{},
+	}
+}
+
+// Run accrues interest once a day at the configured time until ctx is cancelled.
+// If today's run time has already passed at startup it accrues for today right away;
+// accruals that were already credited are skipped.
+func (s *InterestService) Run(ctx context.Context) {
+	for {
+		now := s.clock.Now().UTC()
+		day := now.Truncate(24 * time.Hour)
+		next := day.Add(s.cfg.RunAt)
+
+		if !now.Before(next) {
+			if credited, err := s.AccrueDaily(ctx, day); err != nil {
+				log.Printf("interest accrual: %v", err)
+			} else if credited > 0 {
+				log.Printf("interest accrual: %d accounts accrued for %s", credited, day.Format(time.DateOnly))
+			}
+			next = next.Add(24 * time.Hour)
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// AccrueDaily credits one day of interest to every interest-bearing account,
+// returning how many accounts hold that day's accrual. Each accrual is a deposit with an
+// ID derived from the account and day, so running it again for the same day
+// does not credit twice.
+func (s *InterestService) AccrueDaily(ctx context.Context, day time.Time) (int, error) {
+	accounts, err := s.accountRepo.ListInterestBearing(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	credited := 0
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return credited, ctx.Err()
+		}
+
+		// Never accrue more decimal places than the account's currency has
+		scale := s.cfg.Scale
+		if currencyScale, ok := s.currencies.Scale(account.Currency); ok && currencyScale < scale {
+			scale = currencyScale
+		}
+
+		amount := s.dailyInterest(account.Balance, account.InterestRate, day, scale)
+		if !amount.IsPositive() {
+			continue
+		}
+
+		id := accrualID(account.ID, day)
+		reference := fmt.Sprintf("interest %s", day.Format(time.DateOnly))
+		_, err := s.transactionService.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			ID:                   &id,
+			DestinationAccountID: &account.ID,
+			Amount:               amount,
+			Reference:            &reference,
+			Accrual:              true,
+		})
+		if err != nil {
+			var serviceErr *ServiceError
+			if errors.As(err, &serviceErr) && serviceErr.Code == model.ErrCodeConflict {
+				// Already accrued; the balance has since grown by that interest
+				continue
+			}
+			log.Printf("interest accrual: account %s: %v", account.ID, err)
+			continue
+		}
+		credited++
+	}
+
+	return credited, nil
+}
+
+// DailyInterest computes one day of interest on balance at the annual rate,
+// using the configured day-count convention and rounding
+func (s *InterestService) DailyInterest(balance, annualRate decimal.Decimal, day time.Time) decimal.Decimal {
+	return s.dailyInterest(balance, annualRate, day, s.cfg.Scale)
+}
+
+// dailyInterest is DailyInterest rounded to scale decimal places
+func (s *InterestService) dailyInterest(balance, annualRate decimal.Decimal, day time.Time, scale int32) decimal.Decimal {
+	interest := balance.Mul(annualRate).Div(decimal.NewFromInt(int64(s.daysInYear(day))))
+	return s.roundingMode.Round(interest, scale)
+}
+
+// daysInYear returns the day-count denominator for the year containing day
+func (s *InterestService) daysInYear(day time.Time) int {
+	switch s.cfg.DayCount {
+	case "actual/360":
+		return 360
+	case "actual/actual":
+		if year := day.Year(); year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 366
+		}
+		return 365
+	default:
+		return 365
+	}
+}
+
+// accrualID derives the transaction ID of an account's accrual for a day
+func accrualID(accountID uuid.UUID, day time.Time) uuid.UUID {
+	return uuid.NewSHA1(interestNamespace, []byte(accountID.String()+"/"+day.Format(time.DateOnly)))
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestInterestService_DailyInterest(t *testing.T) {
+	leapDay := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	balance := decimal.RequireFromString("36600")
+	rate := decimal.RequireFromString("0.05")
+
+	tests := []struct {
+		dayCount string
+		expected string
+	}{
+		{dayCount: "actual/365", expected: "5.01"},
+		{dayCount: "actual/360", expected: "5.08"},
+		{dayCount: "actual/actual", expected: "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dayCount, func(t *testing.T) {
+			s := NewInterestService(nil, nil, config.InterestConfig{DayCount: tt.dayCount, Scale: 2}, model.RoundHalfUp, model.DefaultCurrencies())
+
+			interest := s.DailyInterest(balance, rate, leapDay)
+
+			assert.True(t, interest.Equal(decimal.RequireFromString(tt.expected)), "got %s, want %s", interest, tt.expected)
+		})
+	}
+}
+
+func TestInterestService_AccrueDailyIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Interest is credited even though deposits without a system account are disabled
+	transactionService, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+	s := NewInterestService(accountRepo, transactionService, config.InterestConfig{DayCount: "actual/365", Scale: 2}, model.RoundHalfUp, model.DefaultCurrencies())
+
+	savings, err := accountRepo.Create(ctx, &model.Account{
+		Balance:      decimal.RequireFromString("36500"),
+		AccountType:  model.AccountTypeSavings,
+		InterestRate: decimal.RequireFromString("0.05"),
+	})
+	require.NoError(t, err)
+	checking, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("36500")})
+	require.NoError(t, err)
+
+	for run := 0; run < 2; run++ {
+		credited, err := s.AccrueDaily(ctx, day)
+		require.NoError(t, err)
+		assert.Equal(t, 1, credited)
+	}
+
+	account, err := accountRepo.GetByID(ctx, savings.ID)
+	require.NoError(t, err)
+	assert.True(t, account.Balance.Equal(decimal.RequireFromString("36505")), "savings balance %s", account.Balance)
+
+	account, err = accountRepo.GetByID(ctx, checking.ID)
+	require.NoError(t, err)
+	assert.True(t, account.Balance.Equal(decimal.RequireFromString("36500")), "checking balance %s", account.Balance)
+
+	// The next day accrues again, on the balance including the first day's interest
+	credited, err := s.AccrueDaily(ctx, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, credited)
+}
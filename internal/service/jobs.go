@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"internal-transfers-api/internal/config"
+)
+
+// JobQueue runs side effects of committed writes, such as waking balance watchers, on a
+// pool of workers so they don't hold up the response. When the queue is full, Enqueue
+// waits for room or, configured to drop, discards the job. A nil queue runs jobs on the
+// caller's goroutine.
+type JobQueue struct {
+	jobs         chan func()
+	dropWhenFull bool
+	mu           sync.RWMutex // held for reading while enqueueing, so Drain can't close jobs under a sender
+	draining     bool
+	workers      sync.WaitGroup
+}
+
+// NewJobQueue starts the queue's workers
+func NewJobQueue(cfg config.JobsConfig) *JobQueue {
+	q := &JobQueue{
+		jobs:         make(chan func(), cfg.QueueSize),
+		dropWhenFull: cfg.DropWhenFull,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *JobQueue) work() {
+	defer q.workers.Done()
+	for job := range q.jobs {
+		run(job)
+	}
+}
+
+// run runs a job, recovering a panic so it doesn't take down its worker or caller
+func run(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("post-commit job panicked: %v", r)
+		}
+	}()
+	job()
+}
+
+// Enqueue schedules job to run on a worker. Once the queue is draining, jobs are run on
+// the caller's goroutine instead, so side effects of requests finishing during shutdown
+// aren't lost.
+func (q *JobQueue) Enqueue(job func()) {
+	if q == nil {
+		run(job)
+		return
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.draining {
+		run(job)
+		return
+	}
+	if !q.dropWhenFull {
+		q.jobs <- job
+		return
+	}
+	select {
+	case q.jobs <- job:
+	default:
+		log.Printf("WARN job queue full, dropping post-commit job")
+	}
+}
+
+// Drain stops the queue taking jobs and waits until the queued ones have run, or ctx is
+// done
+func (q *JobQueue) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.draining {
+		q.draining = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d queued jobs not run: %w", len(q.jobs), ctx.Err())
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+)
+
+func TestJobQueue_DrainRunsQueuedJobs(t *testing.T) {
+	q := NewJobQueue(config.JobsConfig{Workers: 2, QueueSize: 100})
+
+	var ran atomic.Int64
+	for i := 0; i < 50; i++ {
+		q.Enqueue(func() {
+			time.Sleep(time.Millisecond)
+			ran.Add(1)
+		})
+	}
+	q.Enqueue(func() { panic("boom") })
+
+	require.NoError(t, q.Drain(context.Background()))
+	assert.Equal(t, int64(50), ran.Load())
+
+	// Jobs of requests finishing after the drain run straight away
+	q.Enqueue(func() { ran.Add(1) })
+	assert.Equal(t, int64(51), ran.Load())
+	require.NoError(t, q.Drain(context.Background()))
+}
+
+func TestJobQueue_DrainTimeout(t *testing.T) {
+	q := NewJobQueue(config.JobsConfig{Workers: 1, QueueSize: 1})
+
+	release := make(chan struct{})
+	defer close(release)
+	q.Enqueue(func() { <-release })
+	q.Enqueue(func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Drain(ctx), context.DeadlineExceeded)
+}
+
+func TestJobQueue_Full(t *testing.T) {
+	release := make(chan struct{})
+	blocker := func() { <-release }
+
+	t.Run("drop", func(t *testing.T) {
+		q := NewJobQueue(config.JobsConfig{Workers: 1, QueueSize: 1, DropWhenFull: true})
+		started := make(chan struct{})
+		q.Enqueue(func() {
+			close(started)
+			blocker()
+		})
+		<-started
+
+		var ran atomic.Int64
+		q.Enqueue(func() { ran.Add(1) })
+		q.Enqueue(func() { ran.Add(1) }) // dropped, the queue holds one
+
+		release <- struct{}{}
+		require.NoError(t, q.Drain(context.Background()))
+		assert.Equal(t, int64(1), ran.Load())
+	})
+
+	t.Run("block", func(t *testing.T) {
+		q := NewJobQueue(config.JobsConfig{Workers: 1, QueueSize: 1})
+		started := make(chan struct{})
+		q.Enqueue(func() {
+			close(started)
+			blocker()
+		})
+		<-started
+
+		var ran atomic.Int64
+		q.Enqueue(func() { ran.Add(1) })
+		enqueued := make(chan struct{})
+		go func() {
+			defer close(enqueued)
+			q.Enqueue(func() { ran.Add(1) })
+		}()
+
+		select {
+		case <-enqueued:
+			t.Fatal("Enqueue returned while the queue was full")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		release <- struct{}{}
+		<-enqueued
+		require.NoError(t, q.Drain(context.Background()))
+		assert.Equal(t, int64(2), ran.Load())
+	})
+}
+
+func TestBalanceWatcher_NotifyFromQueue(t *testing.T) {
+	q := NewJobQueue(config.JobsConfig{Workers: 1, QueueSize: 10})
+	watcher := NewBalanceWatcher(q)
+
+	id := uuid.New()
+	wake, done := watcher.Wait(id)
+	defer done()
+	watcher.Notify(id)
+	require.NoError(t, q.Drain(context.Background()))
+
+	select {
+	case <-wake:
+	default:
+		t.Fatal("waiter not woken")
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// AddNote appends an operator's note to a transaction. Notes can be added to a
+// transaction in any status and are never edited or removed.
+func (s *TransactionService) AddNote(ctx context.Context, id uuid.UUID, req *model.AddNoteRequest) (_ *model.TransactionNote, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.AddNote")
+	defer func() { endSpan(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+				Field:   validationErr.Field,
+			}
+		}
+		return nil, err
+	}
+
+	if _, err := s.transactionRepo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Transaction not found",
+			}
+		}
+		return nil, err
+	}
+
+	// Appending a row conflicts with nothing, so the note goes in at the default isolation
+	// level with its audit entry
+	tx, release, err := repository.BeginTx(ctx, s.db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	note := &model.TransactionNote{
+		TransactionID: id,
+		Author:        req.Author,
+		Body:          req.Body,
+		CreatedAt:     s.clock.Now().UTC(),
+	}
+	if err := s.transactionRepo.AddNote(ctx, tx, note); err != nil {
+		return nil, err
+	}
+
+	audit := newAuditEntry(ctx, note.CreatedAt, model.AuditNote, id)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+
+	return note, nil
+}
+
+// GetTransactionWithNotes retrieves a transaction along with its notes
+func (s *TransactionService) GetTransactionWithNotes(ctx context.Context, id uuid.UUID) (_ *model.TransactionWithNotes, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetTransactionWithNotes")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	transaction, err := s.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.transactionRepo.GetNotes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TransactionWithNotes{Transaction: transaction, Notes: notes}, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestTransactionService_AddNote(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	transaction, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("10"),
+	})
+	require.NoError(t, err)
+
+	// Without notes the list is empty rather than missing
+	withNotes, err := s.GetTransactionWithNotes(ctx, transaction.ID)
+	require.NoError(t, err)
+	assert.Equal(t, transaction.ID, withNotes.ID)
+	assert.Empty(t, withNotes.Notes)
+	assert.NotNil(t, withNotes.Notes)
+
+	for _, body := range []string{"customer disputes the deposit", "confirmed with the bank"} {
+		note, err := s.AddNote(ctx, transaction.ID, &model.AddNoteRequest{Author: "ops@example.com", Body: body})
+		require.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, note.ID)
+		assert.Equal(t, transaction.ID, note.TransactionID)
+	}
+
+	// Notes come back oldest first
+	withNotes, err = s.GetTransactionWithNotes(ctx, transaction.ID)
+	require.NoError(t, err)
+	require.Len(t, withNotes.Notes, 2)
+	assert.Equal(t, "customer disputes the deposit", withNotes.Notes[0].Body)
+	assert.Equal(t, "confirmed with the bank", withNotes.Notes[1].Body)
+	assert.Equal(t, "ops@example.com", withNotes.Notes[1].Author)
+
+	tests := []struct {
+		name          string
+		id            uuid.UUID
+		req           model.AddNoteRequest
+		expectedCode  string
+		expectedField string
+	}{
+		{name: "missing author", id: transaction.ID, req: model.AddNoteRequest{Body: "x"}, expectedCode: model.ErrCodeValidation, expectedField: "author"},
+		{name: "blank body", id: transaction.ID, req: model.AddNoteRequest{Author: "ops", Body: "  "}, expectedCode: model.ErrCodeValidation, expectedField: "body"},
+		{name: "missing transaction", id: uuid.New(), req: model.AddNoteRequest{Author: "ops", Body: "x"}, expectedCode: model.ErrCodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.AddNote(ctx, tt.id, &tt.req)
+			serviceErr := AsServiceError(err)
+			require.NotNil(t, serviceErr)
+			assert.Equal(t, tt.expectedCode, serviceErr.Code)
+			assert.Equal(t, tt.expectedField, serviceErr.Field)
+		})
+	}
+}
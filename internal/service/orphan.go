@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// orphanCheckLimit caps how many stale pending transactions one check resolves, leaving
+// the rest for the next
+const orphanCheckLimit = 100
+
+// OrphanService resolves transactions left pending. A transfer inserts its transaction
+// pending and marks it completed in the same database transaction, so a pending row
+// should never be committed; the checker is a defensive sweep for one that was.
+type OrphanService struct {
+	transactionService *TransactionService
+	cfg                config.OrphanConfig
+	clock              Clock
+}
+
+// NewOrphanService creates a new orphaned transaction checker
+func NewOrphanService(transactionService *TransactionService, cfg config.OrphanConfig) *OrphanService {
+	return &OrphanService{
+		transactionService: transactionService,
+		cfg:                cfg,
+		clock:              systemClock{},
+	}
+}
+
+// Run checks for orphaned transactions at the configured interval until ctx is cancelled
+func (s *OrphanService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if resolved, err := s.CheckOrphans(ctx); err != nil {
+			log.Printf("orphan check: %v", err)
+		} else if resolved > 0 {
+			log.Printf("orphan check: %d pending transactions resolved", resolved)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOrphans resolves the transactions pending for longer than the configured age,
+// returning how many it resolved. Each is completed if the balances of its accounts show
+// it was applied, failed if they show it wasn't, and otherwise left pending for someone
+// to reconcile the accounts by hand.
+func (s *OrphanService) CheckOrphans(ctx context.Context) (int, error) {
+	transactions, err := s.transactionService.transactionRepo.ListStalePending(ctx, s.clock.Now().Add(-s.cfg.MaxAge), orphanCheckLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	for _, transaction := range transactions {
+		if ctx.Err() != nil {
+			return resolved, ctx.Err()
+		}
+		ok, err := s.resolve(ctx, transaction.ID)
+		if err != nil {
+			log.Printf("orphan check: transaction %s: %v", transaction.ID, err)
+			continue
+		}
+		if ok {
+			resolved++
+		}
+	}
+
+	return resolved, nil
+}
+
+// resolve settles one pending transaction under row locks on it and its accounts,
+// reporting whether it did. The ledger balance of an account only counts completed
+// transactions, so the pending one was applied if every stored balance is off from the
+// ledger by exactly its movement, and wasn't if none is off at all.
+func (s *OrphanService) resolve(ctx context.Context, id uuid.UUID) (_ bool, err error) {
+	ctx, span := tracer.Start(ctx, "OrphanService.resolve")
+	defer func() { endSpan(span, err) }()
+
+	ts := s.transactionService
+
+	tx, release, err := repository.BeginTx(ctx, ts.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	transaction, err := ts.transactionRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		return false, err
+	}
+	if transaction.Status != model.TransactionStatusPending {
+		// Settled since it was listed
+		return false, nil
+	}
+
+	movements := orphanMovements(transaction)
+	accounts := make([]uuid.UUID, 0, len(movements))
+	for account := range movements {
+		accounts = append(accounts, account)
+	}
+	// Lock the accounts in a fixed order, so checks can't deadlock each other or transfers
+	accounts = lockOrder(accounts...)
+
+	ts.cache.BeginWrite(accounts...)
+	defer ts.cache.EndWrite(accounts...)
+
+	applied, untouched := true, true
+	for _, account := range accounts {
+		stored, err := ts.accountRepo.GetBalanceForUpdate(ctx, tx, account)
+		if err != nil {
+			return false, err
+		}
+		ledger, err := ts.accountRepo.GetLedgerBalance(ctx, tx, account)
+		if err != nil {
+			return false, err
+		}
+		drift := stored.Sub(ledger)
+		applied = applied && drift.Equal(movements[account])
+		untouched = untouched && drift.IsZero()
+	}
+
+	audit := newAuditEntry(ctx, s.clock.Now(), model.AuditResolve, append([]uuid.UUID{id}, accounts...)...)
+
+	switch {
+	case untouched:
+		if err := ts.transactionRepo.UpdateStatus(ctx, tx, id, model.TransactionStatusFailed); err != nil {
+			return false, err
+		}
+		if err := ts.auditor.Record(ctx, tx, audit); err != nil {
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		ts.auditor.Committed(audit)
+		log.Printf("orphan check: transaction %s marked failed, its balances were never applied", id)
+
+	case applied:
+		if err := ts.transactionRepo.UpdateStatus(ctx, tx, id, model.TransactionStatusCompleted); err != nil {
+			return false, err
+		}
+		transaction.Status = model.TransactionStatusCompleted
+		payload, err := json.Marshal(newCreateTransactionResponse(transaction))
+		if err != nil {
+			return false, fmt.Errorf("failed to encode transfer event: %w", err)
+		}
+		if err := ts.transactionRepo.NotifyCompleted(ctx, tx, string(payload)); err != nil {
+			return false, err
+		}
+		if err := ts.auditor.Record(ctx, tx, audit); err != nil {
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		ts.auditor.Committed(audit)
+		ts.watcher.Notify(accounts...)
+		log.Printf("orphan check: transaction %s marked completed, its balances were applied", id)
+
+	default:
+		log.Printf("WARN orphan check: transaction %s left pending, the balances of its accounts match neither outcome and need reconciling", id)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// orphanMovements returns how much a transaction changes the balance of each of its
+// accounts once applied
+func orphanMovements(t *model.Transaction) map[uuid.UUID]decimal.Decimal {
+	movements := make(map[uuid.UUID]decimal.Decimal)
+	if t.SourceAccountID != nil {
+		debit := t.Amount
+		if t.Fee != nil {
+			debit = debit.Add(*t.Fee)
+		}
+		movements[*t.SourceAccountID] = movements[*t.SourceAccountID].Sub(debit)
+	}
+	if t.DestinationAccountID != nil {
+		movements[*t.DestinationAccountID] = movements[*t.DestinationAccountID].Add(t.CreditedAmount())
+	}
+	if t.FeeAccountID != nil && t.Fee != nil {
+		movements[*t.FeeAccountID] = movements[*t.FeeAccountID].Add(*t.Fee)
+	}
+	return movements
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+)
+
+func TestOrphanService_CheckOrphans(t *testing.T) {
+	ctx := context.Background()
+	transactions, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+	s := NewOrphanService(transactions, config.OrphanConfig{MaxAge: time.Minute, Interval: time.Minute})
+
+	// pending creates a pending transfer of 30 between two fresh accounts, optionally
+	// applying its balances the way a crash after the updates would have left them
+	pending := func(source, dest string, age time.Duration) (uuid.UUID, uuid.UUID, uuid.UUID) {
+		sourceAccount, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+		require.NoError(t, err)
+		destAccount, err := accountRepo.Create(ctx, &model.Account{})
+		require.NoError(t, err)
+		transaction, err := transactionRepo.Create(ctx, nil, &model.CreateTransactionRequest{
+			SourceAccountID:      &sourceAccount.ID,
+			DestinationAccountID: &destAccount.ID,
+			Amount:               decimal.RequireFromString("30"),
+		})
+		require.NoError(t, err)
+		require.NoError(t, transactionRepo.SetCreatedAt(transaction.ID, time.Now().Add(-age)))
+		require.NoError(t, accountRepo.UpdateBalance(ctx, nil, sourceAccount.ID, decimal.RequireFromString(source)))
+		require.NoError(t, accountRepo.UpdateBalance(ctx, nil, destAccount.ID, decimal.RequireFromString(dest)))
+		return transaction.ID, sourceAccount.ID, destAccount.ID
+	}
+
+	applied, appliedSource, appliedDest := pending("70", "30", time.Hour)
+	unapplied, unappliedSource, _ := pending("100", "0", time.Hour)
+	partial, _, _ := pending("70", "0", time.Hour)
+	recent, _, _ := pending("100", "0", time.Second)
+
+	resolved, err := s.CheckOrphans(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resolved)
+
+	tests := []struct {
+		name           string
+		id             uuid.UUID
+		expectedStatus model.TransactionStatus
+	}{
+		{name: "applied is completed", id: applied, expectedStatus: model.TransactionStatusCompleted},
+		{name: "never applied is failed", id: unapplied, expectedStatus: model.TransactionStatusFailed},
+		{name: "partly applied is left pending", id: partial, expectedStatus: model.TransactionStatusPending},
+		{name: "recent is left pending", id: recent, expectedStatus: model.TransactionStatusPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transaction, err := transactionRepo.GetByID(ctx, tt.id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, transaction.Status)
+		})
+	}
+
+	// Resolving changes the status, never the balances
+	assertBalance(t, accountRepo, appliedSource, "70")
+	assertBalance(t, accountRepo, appliedDest, "30")
+	assertBalance(t, accountRepo, unappliedSource, "100")
+}
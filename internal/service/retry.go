@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+// Backoff between attempts of a transaction that failed serialization, before jitter
+const (
+	minRetryBackoff = 10 * time.Millisecond
+	maxRetryBackoff = 250 * time.Millisecond
+)
+
+// retryTx runs attempt, which must run one whole database transaction, again while it
+// fails with a transient database error such as a serialization failure. Retries stop
+// once budget has passed since the first attempt, or at the context's deadline if that
+// comes first, and the request is then answered with TRY_AGAIN. A budget of zero
+// disables retries.
+func retryTx(ctx context.Context, budget time.Duration, attempt func() error) error {
+	err := attempt()
+	if err == nil || budget <= 0 || !isRetryable(err) {
+		return err
+	}
+
+	deadline := time.Now().Add(budget)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	backoff := minRetryBackoff
+	for {
+		// Sleep for half to all of the backoff so concurrent retries spread out
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if time.Now().Add(wait).After(deadline) {
+			return &ServiceError{
+				Code:    model.ErrCodeTryAgain,
+				Message: "The request kept conflicting with concurrent updates, try again later",
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		err = attempt()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// isRetryable reports whether err is a transient database failure: one the error
+// mapping classified as retryable, or a transaction rollback (SQLSTATE class 40) that
+// surfaced at commit, where errors aren't classified
+func isRetryable(err error) bool {
+	var dbErr *repository.DBError
+	if errors.As(err, &dbErr) {
+		return dbErr.Retryable
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "40"
+}
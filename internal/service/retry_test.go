@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+)
+
+func TestRetryTx(t *testing.T) {
+	serializationFailure := &repository.DBError{
+		Code:      model.ErrCodeServiceUnavailable,
+		SQLState:  "40001",
+		Retryable: true,
+		Err:       &pq.Error{Code: "40001"},
+	}
+
+	t.Run("gives up on persistent serialization failures after the budget", func(t *testing.T) {
+		attempts := 0
+		start := time.Now()
+		err := retryTx(context.Background(), 100*time.Millisecond, func() error {
+			attempts++
+			return fmt.Errorf("failed to update account balance: %w", serializationFailure)
+		})
+		elapsed := time.Since(start)
+
+		serviceErr := AsServiceError(err)
+		require.NotNil(t, serviceErr, "expected a ServiceError, got %v", err)
+		assert.Equal(t, model.ErrCodeTryAgain, serviceErr.Code)
+		assert.Greater(t, attempts, 2)
+		assert.Less(t, elapsed, 200*time.Millisecond)
+	})
+
+	t.Run("stops at the context deadline before the budget", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := retryTx(ctx, time.Minute, func() error { return serializationFailure })
+
+		assert.Equal(t, model.ErrCodeTryAgain, AsServiceError(err).Code)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("succeeds once the conflict clears", func(t *testing.T) {
+		attempts := 0
+		err := retryTx(context.Background(), time.Second, func() error {
+			attempts++
+			if attempts < 3 {
+				// A serialization failure at commit isn't classified by the repository
+				return fmt.Errorf("failed to commit transaction: %w", &pq.Error{Code: "40001"})
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		attempts := 0
+		insufficient := &ServiceError{Code: model.ErrCodeInsufficientFunds}
+		err := retryTx(context.Background(), time.Second, func() error {
+			attempts++
+			return insufficient
+		})
+
+		assert.True(t, errors.Is(err, insufficient))
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("zero budget disables retries", func(t *testing.T) {
+		attempts := 0
+		err := retryTx(context.Background(), 0, func() error {
+			attempts++
+			return serializationFailure
+		})
+
+		assert.Equal(t, model.ErrCodeServiceUnavailable, AsServiceError(err).Code)
+		assert.Equal(t, 1, attempts)
+	})
+}
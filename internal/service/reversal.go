@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/money"
+	"internal-transfers-api/internal/repository"
+)
+
+// ReverseTransaction reverses a completed transfer with a new transfer that moves the
+// credited amount back from the destination to the source. Fees aren't refunded. With
+// an amount only that part is moved back, and the transfer can be reversed again until
+// its whole amount has been; cross-currency transfers can only be reversed in full.
+func (s *TransactionService) ReverseTransaction(ctx context.Context, id uuid.UUID, req *model.ReverseTransactionRequest) (response *model.CreateTransactionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.ReverseTransaction")
+	defer func() { endSpan(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+				Field:   validationErr.Field,
+			}
+		}
+		return nil, err
+	}
+
+	err = retryTx(ctx, s.cfg.RetryBudget, func() (err error) {
+		response, err = s.reverseTransaction(ctx, id, req.Amount)
+		return err
+	})
+	return response, err
+}
+
+// reverseTransaction makes one attempt at a reversal in its own database transaction,
+// reversing amount or, when nil, what is left of the original
+func (s *TransactionService) reverseTransaction(ctx context.Context, id uuid.UUID, amount *decimal.Decimal) (_ *model.CreateTransactionResponse, err error) {
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	original, err := s.transactionRepo.GetForUpdate(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Transaction not found",
+			}
+		}
+		return nil, err
+	}
+	remaining, err := s.checkReversible(ctx, tx, original)
+	if err != nil {
+		return nil, err
+	}
+	if amount == nil {
+		amount = &remaining
+	}
+	if amount.GreaterThan(remaining) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeReversalExceeded,
+			Message: fmt.Sprintf("Only %s of the transaction is left to reverse", remaining),
+			Field:   "amount",
+		}
+	}
+
+	changed := []uuid.UUID{*original.DestinationAccountID, *original.SourceAccountID}
+	s.cache.BeginWrite(changed...)
+	defer s.cache.EndWrite(changed...)
+
+	reversal, err := s.reverse(ctx, tx, original, *amount, remaining)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := newAuditEntry(ctx, s.clock.Now(), model.AuditReversal, append([]uuid.UUID{reversal.ID, original.ID}, changed...)...)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audit)
+	s.watcher.Notify(changed...)
+
+	return newCreateTransactionResponse(reversal), nil
+}
+
+// ReverseByReference reverses every completed transaction with the reference in a
+// single database transaction, so either all of them are reversed or none are.
+// Transactions that can't be reversed, such as ones already reversed, are skipped, and
+// partly reversed ones have what is left of them reversed.
+func (s *TransactionService) ReverseByReference(ctx context.Context, req *model.ReverseByReferenceRequest) (response *model.ReverseByReferenceResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.ReverseByReference")
+	defer func() { endSpan(span, err) }()
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+			}
+		}
+		return nil, err
+	}
+
+	err = retryTx(ctx, s.cfg.RetryBudget, func() (err error) {
+		response, err = s.reverseByReference(ctx, req.Reference)
+		return err
+	})
+	return response, err
+}
+
+// reverseByReference makes one attempt at reversing a reference's transactions
+func (s *TransactionService) reverseByReference(ctx context.Context, reference string) (_ *model.ReverseByReferenceResponse, err error) {
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
+		Isolation: sql.LevelSerializable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer release()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("transaction rollback failed: %v\n", err)
+		}
+	}()
+
+	originals, err := s.transactionRepo.GetAllByReference(ctx, tx, reference, model.MaxReversalsByReference+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(originals) == 0 {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeNotFound,
+			Message: "No transactions found with the reference",
+		}
+	}
+	if len(originals) > model.MaxReversalsByReference {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("More than %d transactions have the reference", model.MaxReversalsByReference),
+		}
+	}
+
+	var changed []uuid.UUID
+	defer func() { s.cache.EndWrite(changed...) }()
+	var audits []*model.AuditEntry
+
+	response := &model.ReverseByReferenceResponse{
+		Reference: reference,
+		Results:   make([]model.ReversalResult, 0, len(originals)),
+	}
+	for _, original := range originals {
+		result := model.ReversalResult{TransactionID: original.ID}
+
+		remaining, err := s.checkReversible(ctx, tx, original)
+		if err != nil {
+			serviceErr := AsServiceError(err)
+			if serviceErr == nil || serviceErr.Code != model.ErrCodeConflict {
+				return nil, err
+			}
+			result.Outcome = model.ReversalOutcomeSkipped
+			result.Reason = serviceErr.Message
+			response.Skipped++
+			response.Results = append(response.Results, result)
+			continue
+		}
+
+		accounts := []uuid.UUID{*original.DestinationAccountID, *original.SourceAccountID}
+		s.cache.BeginWrite(accounts...)
+		changed = append(changed, accounts...)
+
+		reversal, err := s.reverse(ctx, tx, original, remaining, remaining)
+		if err != nil {
+			// One failed reversal rolls back the others
+			if serviceErr := AsServiceError(err); serviceErr != nil {
+				return nil, &ServiceError{
+					Code:    serviceErr.Code,
+					Message: fmt.Sprintf("transaction %s: %s", original.ID, serviceErr.Message),
+				}
+			}
+			return nil, err
+		}
+
+		audit := newAuditEntry(ctx, s.clock.Now(), model.AuditReversal, append([]uuid.UUID{reversal.ID, original.ID}, accounts...)...)
+		if err := s.auditor.Record(ctx, tx, audit); err != nil {
+			return nil, err
+		}
+		audits = append(audits, audit)
+
+		result.Outcome = model.ReversalOutcomeReversed
+		result.ReversalID = &reversal.ID
+		response.Reversed++
+		response.Results = append(response.Results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.auditor.Committed(audits...)
+	s.watcher.Notify(changed...)
+
+	return response, nil
+}
+
+// checkReversible returns a conflict naming why original can't be reversed or, when it
+// can, the part of its amount not reversed yet
+func (s *TransactionService) checkReversible(ctx context.Context, tx *sql.Tx, original *model.Transaction) (decimal.Decimal, error) {
+	reason := ""
+	switch {
+	case original.ReversalOf != nil:
+		reason = "Reversals cannot be reversed"
+	case original.Status != model.TransactionStatusCompleted:
+		reason = "Only completed transactions can be reversed"
+	case original.SourceAccountID == nil:
+		reason = "Deposits have no source account to return the money to"
+	case original.DestinationAccountID == nil:
+		reason = "Withdrawals have no destination account to take the money back from"
+	case original.ReversedBy != nil:
+		reason = "Transaction has already been reversed"
+	}
+	if reason != "" {
+		return decimal.Zero, &ServiceError{Code: model.ErrCodeConflict, Message: reason}
+	}
+
+	reversed, err := s.transactionRepo.GetReversedAmount(ctx, tx, original.ID)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	remaining := money.Sub(original.Amount, reversed)
+	if !remaining.IsPositive() {
+		return decimal.Zero, &ServiceError{
+			Code:    model.ErrCodeConflict,
+			Message: "Transaction has already been reversed",
+		}
+	}
+	return remaining, nil
+}
+
+// reverse records and applies the reversal of amount, out of the remaining part of
+// original, within tx. The destination is debited amount and the source credited it;
+// the fee isn't refunded. A cross-currency transfer is reversed in full: the
+// destination is debited what it was credited and the source credited what it was
+// debited. Once nothing remains the original is linked to the reversal.
+func (s *TransactionService) reverse(ctx context.Context, tx *sql.Tx, original *model.Transaction, amount, remaining decimal.Decimal) (*model.Transaction, error) {
+	req := &model.CreateTransactionRequest{
+		SourceAccountID:      original.DestinationAccountID,
+		DestinationAccountID: original.SourceAccountID,
+		Amount:               amount,
+		Reference:            original.Reference,
+		ReversalOf:           &original.ID,
+	}
+	credit := amount
+	if original.DestinationAmount != nil {
+		if !amount.Equal(original.Amount) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: "Cross-currency transfers can only be reversed in full",
+				Field:   "amount",
+			}
+		}
+		req.Amount = *original.DestinationAmount
+		rate := original.Amount.DivRound(*original.DestinationAmount, model.FXRateScale)
+		req.DestinationAmount = &credit
+		req.FXRate = &rate
+	}
+
+	// Lock both accounts in the same order transfers do, so a reversal can't deadlock
+	// with a transfer between them
+	accounts, err := s.lockAccounts(ctx, tx, *req.SourceAccountID, *req.DestinationAccountID)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := accounts[*req.SourceAccountID]
+	if !ok {
+		return nil, repository.ErrAccountNotFound
+	}
+	destination, ok := accounts[*req.DestinationAccountID]
+	if !ok {
+		return nil, repository.ErrAccountNotFound
+	}
+
+	// The original destination must still hold what it received, unless it is a system
+	// account, which may go negative
+	if scale, ok := s.cfg.Currencies.Scale(source.Currency); ok && !model.FitsScale(req.Amount, scale) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("amount has more decimal places than %s allows (%d)", source.Currency, scale),
+			Field:   "amount",
+		}
+	}
+	if !source.System && money.Less(source.Balance, req.Amount) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeInsufficientFunds,
+			Message: "Destination account no longer holds the amount to reverse",
+		}
+	}
+
+	if err := s.checkCreditAllowed(destination.Status); err != nil {
+		return nil, err
+	}
+	newDestBalance := money.Add(destination.Balance, credit)
+	if err := s.checkBalanceRange(newDestBalance); err != nil {
+		return nil, err
+	}
+
+	reversal, err := s.transactionRepo.Create(ctx, tx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.UpdateBalance(ctx, tx, source.ID, money.Sub(source.Balance, req.Amount)); err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.UpdateBalance(ctx, tx, destination.ID, newDestBalance); err != nil {
+		return nil, err
+	}
+	if err := s.transactionRepo.UpdateStatus(ctx, tx, reversal.ID, model.TransactionStatusCompleted); err != nil {
+		return nil, err
+	}
+	reversal.Status = model.TransactionStatusCompleted
+	if credit.Equal(remaining) {
+		if err := s.transactionRepo.SetReversedBy(ctx, tx, original.ID, reversal.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Publish the reversal to stream subscribers once committed
+	payload, err := json.Marshal(newCreateTransactionResponse(reversal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer event: %w", err)
+	}
+	if err := s.transactionRepo.NotifyCompleted(ctx, tx, string(payload)); err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
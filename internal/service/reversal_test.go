@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository/memory"
+)
+
+// assertBalance checks an account's stored balance
+func assertBalance(t *testing.T, accountRepo *memory.AccountRepository, id uuid.UUID, expected string) {
+	t.Helper()
+	account, err := accountRepo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.True(t, account.Balance.Equal(decimal.RequireFromString(expected)), "balance %s, expected %s", account.Balance, expected)
+}
+
+func TestTransactionService_ReverseTransaction(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	feeAccount, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	fee := decimal.RequireFromString("1")
+	original, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("30"),
+		Fee:                  &fee,
+		FeeAccountID:         &feeAccount.ID,
+	})
+	require.NoError(t, err)
+
+	reversal, err := s.ReverseTransaction(ctx, original.ID, &model.ReverseTransactionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, dest.ID, *reversal.SourceAccountID)
+	assert.Equal(t, &source.ID, reversal.DestinationAccountID)
+	assert.Equal(t, &original.ID, reversal.ReversalOf)
+	assert.Equal(t, model.TransactionStatusCompleted, reversal.Status)
+
+	// The amount comes back but the fee is kept
+	assertBalance(t, accountRepo, source.ID, "99")
+	assertBalance(t, accountRepo, dest.ID, "0")
+	assertBalance(t, accountRepo, feeAccount.ID, "1")
+
+	// Neither the original nor the reversal can be reversed again
+	for _, id := range []uuid.UUID{original.ID, reversal.ID} {
+		_, err = s.ReverseTransaction(ctx, id, &model.ReverseTransactionRequest{})
+		serviceErr, ok := err.(*ServiceError)
+		if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+			assert.Equal(t, model.ErrCodeConflict, serviceErr.Code)
+		}
+	}
+
+	_, err = s.ReverseTransaction(ctx, uuid.New(), &model.ReverseTransactionRequest{})
+	serviceErr, ok := err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeNotFound, serviceErr.Code)
+	}
+}
+
+func TestTransactionService_ReverseTransaction_LinksBothTransactions(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	reference := "invoice-42"
+	created, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("30"),
+	})
+	require.NoError(t, err)
+	byReference, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("20"),
+		Reference:            &reference,
+	})
+	require.NoError(t, err)
+
+	reversal, err := s.ReverseTransaction(ctx, created.ID, &model.ReverseTransactionRequest{})
+	require.NoError(t, err)
+	reversedByReference, err := s.ReverseByReference(ctx, &model.ReverseByReferenceRequest{Reference: reference})
+	require.NoError(t, err)
+	require.Equal(t, 1, reversedByReference.Reversed)
+
+	for originalID, reversalID := range map[uuid.UUID]uuid.UUID{
+		created.ID:     reversal.ID,
+		byReference.ID: *reversedByReference.Results[0].ReversalID,
+	} {
+		original, err := s.GetTransaction(ctx, originalID)
+		require.NoError(t, err)
+		assert.Equal(t, &reversalID, original.ReversedBy)
+		assert.Nil(t, original.ReversalOf)
+
+		reversal, err := s.GetTransaction(ctx, reversalID)
+		require.NoError(t, err)
+		assert.Equal(t, &originalID, reversal.ReversalOf)
+		assert.Nil(t, reversal.ReversedBy)
+	}
+}
+
+func TestTransactionService_ReverseTransaction_Partial(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	original, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("30"),
+	})
+	require.NoError(t, err)
+
+	reverse := func(amount string) (*model.CreateTransactionResponse, error) {
+		req := &model.ReverseTransactionRequest{}
+		if amount != "" {
+			value := decimal.RequireFromString(amount)
+			req.Amount = &value
+		}
+		return s.ReverseTransaction(ctx, original.ID, req)
+	}
+	assertCode := func(err error, code string) {
+		t.Helper()
+		serviceErr, ok := err.(*ServiceError)
+		if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+			assert.Equal(t, code, serviceErr.Code)
+		}
+	}
+
+	first, err := reverse("10.00")
+	require.NoError(t, err)
+	assert.Equal(t, "10", first.Amount.String())
+	assertBalance(t, accountRepo, source.ID, "80")
+	assertBalance(t, accountRepo, dest.ID, "20")
+
+	// Still reversible, but only for what is left
+	partly, err := s.GetTransaction(ctx, original.ID)
+	require.NoError(t, err)
+	assert.Nil(t, partly.ReversedBy)
+
+	_, err = reverse("20.01")
+	assertCode(err, model.ErrCodeReversalExceeded)
+	_, err = reverse("0")
+	assertCode(err, model.ErrCodeValidation)
+	_, err = reverse("0.001")
+	assertCode(err, model.ErrCodeValidation)
+	assertBalance(t, accountRepo, source.ID, "80")
+
+	// Without an amount the rest is reversed, completing the reversal
+	rest, err := reverse("")
+	require.NoError(t, err)
+	assert.Equal(t, "20", rest.Amount.String())
+	assertBalance(t, accountRepo, source.ID, "100")
+	assertBalance(t, accountRepo, dest.ID, "0")
+
+	reversed, err := s.GetTransaction(ctx, original.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &rest.ID, reversed.ReversedBy)
+
+	_, err = reverse("1")
+	assertCode(err, model.ErrCodeConflict)
+}
+
+func TestTransactionService_ReverseTransaction_InsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	other, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	original, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("50"),
+	})
+	require.NoError(t, err)
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &dest.ID,
+		DestinationAccountID: &other.ID,
+		Amount:               decimal.RequireFromString("20"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.ReverseTransaction(ctx, original.ID, &model.ReverseTransactionRequest{})
+	serviceErr, ok := err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeInsufficientFunds, serviceErr.Code)
+	}
+	assertBalance(t, accountRepo, source.ID, "50")
+	assertBalance(t, accountRepo, dest.ID, "30")
+}
+
+func TestTransactionService_ReverseByReference(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	reference := "payroll-2026-10"
+	var ids []uuid.UUID
+	for _, amount := range []string{"10", "20", "30"} {
+		response, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString(amount),
+			Reference:            &reference,
+		})
+		require.NoError(t, err)
+		ids = append(ids, response.ID)
+	}
+	deposit, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("5"),
+		Reference:            &reference,
+	})
+	require.NoError(t, err)
+
+	// Reverse one of them on its own first
+	_, err = s.ReverseTransaction(ctx, ids[1], &model.ReverseTransactionRequest{})
+	require.NoError(t, err)
+
+	response, err := s.ReverseByReference(ctx, &model.ReverseByReferenceRequest{Reference: reference})
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Reversed)
+
+	outcomes := make(map[uuid.UUID]model.ReversalOutcome)
+	for _, result := range response.Results {
+		outcomes[result.TransactionID] = result.Outcome
+	}
+	assert.Equal(t, model.ReversalOutcomeReversed, outcomes[ids[0]])
+	assert.Equal(t, model.ReversalOutcomeSkipped, outcomes[ids[1]], "already reversed")
+	assert.Equal(t, model.ReversalOutcomeReversed, outcomes[ids[2]])
+	assert.Equal(t, model.ReversalOutcomeSkipped, outcomes[deposit.ID], "deposits have no source")
+
+	assertBalance(t, accountRepo, source.ID, "100")
+	assertBalance(t, accountRepo, dest.ID, "5")
+
+	// Running it again reverses nothing more
+	response, err = s.ReverseByReference(ctx, &model.ReverseByReferenceRequest{Reference: reference})
+	require.NoError(t, err)
+	assert.Equal(t, 0, response.Reversed)
+	assertBalance(t, accountRepo, source.ID, "100")
+
+	_, err = s.ReverseByReference(ctx, &model.ReverseByReferenceRequest{Reference: "unknown"})
+	serviceErr, ok := err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeNotFound, serviceErr.Code)
+	}
+}
+
+func TestTransactionService_ReverseByReference_FailedReversal(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	first, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	second, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	other, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	reference := "batch-7"
+	for _, dest := range []*model.Account{first, second} {
+		_, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("40"),
+			Reference:            &reference,
+		})
+		require.NoError(t, err)
+	}
+
+	// The second destination spends part of what it received
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &second.ID,
+		DestinationAccountID: &other.ID,
+		Amount:               decimal.RequireFromString("1"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.ReverseByReference(ctx, &model.ReverseByReferenceRequest{Reference: reference})
+	serviceErr, ok := err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeInsufficientFunds, serviceErr.Code)
+	}
+}
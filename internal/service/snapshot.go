@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/repository"
+)
+
+// SnapshotService writes each account's end-of-day balance, which historical balance
+// queries start from instead of replaying an account's whole ledger
+type SnapshotService struct {
+	accountRepo repository.AccountRepo
+	cfg         config.SnapshotConfig
+	clock       Clock
+}
+
+// NewSnapshotService creates a new balance snapshot service
+func NewSnapshotService(accountRepo repository.AccountRepo, cfg config.SnapshotConfig) *SnapshotService {
+	return &SnapshotService{
+		accountRepo: accountRepo,
+		cfg:         cfg,
+		clock:       systemClock{},
+	}
+}
+
+// Run snapshots the previous day once a day at the configured time until ctx is cancelled.
+// If today's run time has already passed at startup it snapshots yesterday right away;
+// accounts that already have that day's snapshot are skipped.
+func (s *SnapshotService) Run(ctx context.Context) {
+	for {
+		now := s.clock.Now().UTC()
+		day := now.Truncate(24 * time.Hour)
+		next := day.Add(s.cfg.RunAt)
+
+		if !now.Before(next) {
+			yesterday := day.Add(-24 * time.Hour)
+			if written, err := s.SnapshotDay(ctx, yesterday); err != nil {
+				log.Printf("balance snapshot: %v", err)
+			} else if written > 0 {
+				log.Printf("balance snapshot: %d accounts snapshotted for %s", written, yesterday.Format(time.DateOnly))
+			}
+			next = next.Add(24 * time.Hour)
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// SnapshotDay records every account's balance at the midnight (UTC) closing day,
+// returning how many accounts it recorded
+func (s *SnapshotService) SnapshotDay(ctx context.Context, day time.Time) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "SnapshotService.SnapshotDay")
+	defer func() { endSpan(span, err) }()
+
+	return s.accountRepo.WriteBalanceSnapshots(ctx, day.UTC().Truncate(24*time.Hour))
+}
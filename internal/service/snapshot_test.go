@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository/memory"
+)
+
+func TestSnapshotService_SnapshotDay(t *testing.T) {
+	ctx := context.Background()
+	transactions, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+	accounts := NewAccountService(accountRepo, memory.NewDB(), NewBalanceWatcher(nil), nil, model.DefaultCurrencies(), config.AccountConfig{}, NewAuditor(nil, nil), transactions)
+	s := NewSnapshotService(accountRepo, config.SnapshotConfig{})
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.Add(-24 * time.Hour)
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	require.NoError(t, accountRepo.SetCreatedAt(source.ID, yesterday.Add(time.Hour)))
+	require.NoError(t, accountRepo.SetCreatedAt(dest.ID, yesterday.Add(time.Hour)))
+
+	// transfer moves 10 from source to dest, completed at the given time
+	transfer := func(at time.Time) {
+		response, err := transactions.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("10"),
+			AllowDuplicate:       true,
+		})
+		require.NoError(t, err)
+		require.NoError(t, transactionRepo.SetCompletedAt(response.ID, at))
+	}
+	transfer(yesterday.Add(2 * time.Hour))
+	transfer(today.Add(time.Hour))
+
+	for run := 0; run < 2; run++ {
+		written, err := s.SnapshotDay(ctx, yesterday)
+		require.NoError(t, err)
+		// Running again for the same day keeps the snapshots already written
+		assert.Equal(t, 2*(1-run), written)
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected string
+	}{
+		{name: "before the account was opened", at: yesterday, expected: "0"},
+		{name: "during a day without a snapshot", at: yesterday.Add(3 * time.Hour), expected: "90"},
+		{name: "at the snapshot", at: today, expected: "90"},
+		{name: "after the snapshot", at: today.Add(2 * time.Hour), expected: "80"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, err := accounts.GetAccountBalance(ctx, source.ID, &tt.at)
+			require.NoError(t, err)
+			assert.True(t, balance.Equal(decimal.RequireFromString(tt.expected)), "got %s, want %s", balance, tt.expected)
+		})
+	}
+}
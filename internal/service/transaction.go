@@ -1,44 +1,98 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"sort"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 
+	"internal-transfers-api/internal/config"
 	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/money"
 	"internal-transfers-api/internal/repository"
 )
 
 // TransactionService handles transaction business logic
 type TransactionService struct {
-	accountRepo     *repository.AccountRepository
-	transactionRepo *repository.TransactionRepository
-	idempotencyRepo *repository.IdempotencyRepository
+	accountRepo     repository.AccountRepo
+	transactionRepo repository.TransactionRepo
+	idempotencyRepo repository.IdempotencyRepo
 	db              *sql.DB
+	cfg             config.TransferConfig
+	idempotency     config.IdempotencyConfig
+	watcher         *BalanceWatcher
+	cache           *BalanceCache
+	auditor         Auditor
+	features        config.Features
+	clock           Clock
 }
 
 // NewTransactionService creates a new transaction service
 func NewTransactionService(
-	accountRepo *repository.AccountRepository,
-	transactionRepo *repository.TransactionRepository,
-	idempotencyRepo *repository.IdempotencyRepository,
+	accountRepo repository.AccountRepo,
+	transactionRepo repository.TransactionRepo,
+	idempotencyRepo repository.IdempotencyRepo,
 	db *sql.DB,
+	cfg config.TransferConfig,
+	idempotency config.IdempotencyConfig,
+	watcher *BalanceWatcher,
+	cache *BalanceCache,
+	auditor Auditor,
+	features config.Features,
 ) *TransactionService {
 	return &TransactionService{
 		accountRepo:     accountRepo,
 		transactionRepo: transactionRepo,
 		idempotencyRepo: idempotencyRepo,
 		db:              db,
+		cfg:             cfg,
+		idempotency:     idempotency,
+		watcher:         watcher,
+		cache:           cache,
+		auditor:         auditor,
+		features:        features,
+		clock:           systemClock{},
 	}
 }
 
-// CreateTransaction creates a new transfer between accounts
-func (s *TransactionService) CreateTransaction(ctx context.Context, req *model.CreateTransactionRequest) (*model.CreateTransactionResponse, error) {
-	// Validate request
-	if err := req.Validate(); err != nil {
+// CreateTransaction creates a new transfer between accounts. A transfer with an
+// idempotency key whose earlier attempt succeeded is replayed rather than made again.
+func (s *TransactionService) CreateTransaction(ctx context.Context, req *model.CreateTransactionRequest) (response *model.CreateTransactionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.CreateTransaction")
+	defer func() { endSpan(span, err) }()
+
+	if req.IdempotencyKey != nil {
+		return s.createIdempotent(ctx, req)
+	}
+	return s.createWithRetry(ctx, req)
+}
+
+// createWithRetry makes a transfer, retrying it within the configured budget while it
+// fails serialization
+func (s *TransactionService) createWithRetry(ctx context.Context, req *model.CreateTransactionRequest) (response *model.CreateTransactionResponse, err error) {
+	err = retryTx(ctx, s.cfg.RetryBudget, func() (err error) {
+		response, err = s.createTransaction(ctx, req)
+		return err
+	})
+	return response, err
+}
+
+// createTransaction makes one attempt at a transfer in its own database transaction
+func (s *TransactionService) createTransaction(ctx context.Context, req *model.CreateTransactionRequest) (_ *model.CreateTransactionResponse, err error) {
+	// Validate request, restricting free-text fields to the configured charset
+	err = req.Validate()
+	if err == nil {
+		err = req.ValidateCharset(s.cfg.ReferenceCharset)
+	}
+	if err != nil {
 		if validationErr, ok := err.(*model.ValidationError); ok {
 			return nil, &ServiceError{
 				Code:    model.ErrCodeValidation,
@@ -48,13 +102,86 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, req *model.C
 		return nil, err
 	}
 
+	currency, err := s.transferCurrencies(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	scale, conversion := currency.scale, currency.conversion
+
+	// Deposits are drawn from, and withdrawals paid into, the currency's system account;
+	// a system source filled in here is the service's own doing, not the caller's
+	deposit := req.SourceAccountID == nil
+	if req, err = s.withSystemAccount(req, currency.code); err != nil {
+		return nil, err
+	}
+	if req.SourceAccountID == nil && !s.cfg.AllowDeposits && !req.Accrual {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeDepositsDisabled,
+			Message: fmt.Sprintf("Deposits in %s have no system account to be drawn from and are disabled", currency.code),
+		}
+	}
+
+	// An amount with more decimal places than the currency's got this far only if it is
+	// to be rounded; the copy leaves the caller's request as it was sent
+	if !model.FitsScale(req.Amount, scale) {
+		rounded := *req
+		rounded.Amount = s.cfg.RoundingMode.Round(req.Amount, scale)
+		if !rounded.Amount.IsPositive() {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: fmt.Sprintf("amount rounds to %s at the %d decimal places %s allows", rounded.Amount, scale, currency.code),
+				Field:   "amount",
+			}
+		}
+		req = &rounded
+	}
+
+	// Quantize the fee to the currency's scale with the configured rounding mode
+	// rather than letting the database round it, on a copy like the amount
+	if req.Fee != nil {
+		quantized := *req
+		fee := s.cfg.RoundingMode.Round(*req.Fee, scale)
+		quantized.Fee = &fee
+		req = &quantized
+	}
+
+	// Replay a previously created transaction with the same client-supplied ID
+	if req.ID != nil {
+		if existing, err := s.replayExisting(ctx, req); existing != nil || err != nil {
+			return existing, err
+		}
+	}
+
+	// A sweep's amount is filled in on a copy, leaving the caller's request a sweep
+	if req.Sweep {
+		sweep := *req
+		req = &sweep
+	}
+
+	// Every account whose balance the transfer changes
+	var changed []uuid.UUID
+	if req.DestinationAccountID != nil {
+		changed = append(changed, *req.DestinationAccountID)
+	}
+	if req.SourceAccountID != nil {
+		changed = append(changed, *req.SourceAccountID)
+	}
+	if req.HasFee() {
+		changed = append(changed, *req.FeeAccountID)
+	}
+
+	// Keep cached reads of the accounts from going stale across the commit
+	s.cache.BeginWrite(changed...)
+	defer s.cache.EndWrite(changed...)
+
 	// Start database transaction
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{
+	tx, release, err := repository.BeginTx(ctx, s.db, &sql.TxOptions{
 		Isolation: sql.LevelSerializable, // Highest isolation level for financial transactions
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer release()
 	defer func() {
 		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
 			// Log the rollback error, but don't override the main error
@@ -63,74 +190,197 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, req *model.C
 		}
 	}() // Will be no-op if tx.Commit() succeeds
 
+	// Lock every account once, up front and in lockOrder, as reversals, adjustments and
+	// funded accounts also do, so concurrent writes to the same accounts can't deadlock
+	// each other; the balances read with the locks are the ones the transfer is applied to
+	accounts, err := s.lockAccounts(ctx, tx, changed...)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate accounts exist and get balances with row locks
 	if req.SourceAccountID != nil {
-		sourceBalance, err := s.accountRepo.GetBalanceForUpdate(ctx, tx, *req.SourceAccountID)
-		if err != nil {
-			if errors.Is(err, repository.ErrAccountNotFound) {
-				return nil, &ServiceError{
-					Code:    model.ErrCodeNotFound,
-					Message: "Source account not found",
-				}
+		source, ok := accounts[*req.SourceAccountID]
+		if !ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Source account not found",
 			}
+		}
+
+		// Only the owner of the source account may debit it
+		if !deposit {
+			if err := authorizeDebit(ctx, source); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkDebitAllowed(source.Status); err != nil {
 			return nil, err
 		}
 
-		// Check sufficient funds
-		if sourceBalance.LessThan(req.Amount) {
+		sourceBalance := source.Balance
+
+		// Enforce the client's balance assertion before moving any money
+		if req.ExpectedSourceBalance != nil && !money.Equal(sourceBalance, *req.ExpectedSourceBalance) {
 			return nil, &ServiceError{
-				Code:    model.ErrCodeInsufficientFunds,
-				Message: "Insufficient funds in source account",
+				Code:    model.ErrCodePreconditionFailed,
+				Message: "Source account balance does not match expected_source_balance",
 			}
 		}
+		if req.ExpectedSourceVersion != nil && source.Version != *req.ExpectedSourceVersion {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeVersionMismatch,
+				Message: fmt.Sprintf("Source account is at version %d, not %d; its balance has changed since it was read", source.Version, *req.ExpectedSourceVersion),
+			}
+		}
+
+		// Sweep whatever the source holds above its reserve, less the fee
+		if req.Sweep {
+			if req.Amount, err = sweepAmount(source, req.Fee); err != nil {
+				return nil, err
+			}
+		}
+
+		// Check the source can cover the amount and any fee
+		if err := s.checkDebit(ctx, tx, source, req.TotalDebit()); err != nil {
+			if declineErr := AsServiceError(err); declineErr != nil && declineErr.Code == model.ErrCodeInsufficientFunds && s.cfg.RecordDeclined {
+				// Release the row locks first, the failed record references the same accounts
+				_ = tx.Rollback()
+				s.recordDeclined(ctx, req, declineErr)
+			}
+			return nil, err
+		}
 	}
 
-	// Validate destination account exists
-	_, err = s.accountRepo.GetBalanceForUpdate(ctx, tx, req.DestinationAccountID)
-	if err != nil {
-		if errors.Is(err, repository.ErrAccountNotFound) {
+	// Convert the amount into the destination's currency, now that a sweep's is known,
+	// recording the conversion on a copy as the rounding and sweep paths do
+	credit := req.Amount
+	if conversion != nil {
+		if credit, err = conversion.convert(req.Amount); err != nil {
+			return nil, err
+		}
+		converted := *req
+		converted.DestinationAmount = &credit
+		converted.FXRate = &conversion.rate
+		req = &converted
+	}
+
+	// Validate destination account exists and may be credited; a withdrawal has none
+	if req.DestinationAccountID != nil {
+		destination, ok := accounts[*req.DestinationAccountID]
+		if !ok {
 			return nil, &ServiceError{
 				Code:    model.ErrCodeNotFound,
 				Message: "Destination account not found",
 			}
 		}
+		if err := s.checkCreditAllowed(destination.Status); err != nil {
+			return nil, err
+		}
+		if err := checkMaxBalance(destination, money.Add(destination.Balance, credit)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate fee account exists and may hold the fee
+	if req.HasFee() {
+		feeAccount, ok := accounts[*req.FeeAccountID]
+		if !ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Fee account not found",
+			}
+		}
+		if err := checkMaxBalance(feeAccount, money.Add(feeAccount.Balance, *req.Fee)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Catch a transfer submitted twice; the account row locks keep a concurrent
+	// submission from slipping past before this one commits
+	if err := s.checkDuplicate(ctx, tx, req); err != nil {
 		return nil, err
 	}
 
 	// Create transaction record
 	transaction, err := s.transactionRepo.Create(ctx, tx, req)
 	if err != nil {
+		if errors.Is(err, repository.ErrTransactionExists) {
+			// A concurrent request with the same ID won the insert
+			_ = tx.Rollback()
+			if existing, err := s.replayExisting(ctx, req); existing != nil || err != nil {
+				return existing, err
+			}
+			return nil, &ServiceError{
+				Code:    model.ErrCodeConflict,
+				Message: "Transaction ID already in use",
+			}
+		}
 		return nil, err
 	}
 
-	// Perform the actual balance updates
+	// Perform the actual balance updates from the locked balances, carrying each update
+	// over to the next in case the fee account is also the source or destination
+	balances := make(map[uuid.UUID]decimal.Decimal, len(accounts))
+	for id, account := range accounts {
+		balances[id] = account.Balance
+	}
+
 	if req.SourceAccountID != nil {
-		// Debit source account
-		sourceBalance, err := s.accountRepo.GetBalanceForUpdate(ctx, tx, *req.SourceAccountID)
+		// Debit source account for the amount plus any fee
+		newSourceBalance := money.Sub(balances[*req.SourceAccountID], req.TotalDebit())
+		err = s.accountRepo.UpdateBalance(ctx, tx, *req.SourceAccountID, newSourceBalance)
 		if err != nil {
 			return nil, err
 		}
-		newSourceBalance := sourceBalance.Sub(req.Amount)
-		err = s.accountRepo.UpdateBalance(ctx, tx, *req.SourceAccountID, newSourceBalance)
+		balances[*req.SourceAccountID] = newSourceBalance
+	}
+
+	// Credit destination account
+	if req.DestinationAccountID != nil {
+		newDestBalance := money.Add(balances[*req.DestinationAccountID], credit)
+		if err := s.checkBalanceRange(newDestBalance); err != nil {
+			return nil, err
+		}
+		err = s.accountRepo.UpdateBalance(ctx, tx, *req.DestinationAccountID, newDestBalance)
 		if err != nil {
 			return nil, err
 		}
+		balances[*req.DestinationAccountID] = newDestBalance
 	}
 
-	// Credit destination account
-	destBalance, err := s.accountRepo.GetBalanceForUpdate(ctx, tx, req.DestinationAccountID)
+	// Credit fee account
+	if req.HasFee() {
+		newFeeBalance := money.Add(balances[*req.FeeAccountID], *req.Fee)
+		if err := s.checkBalanceRange(newFeeBalance); err != nil {
+			return nil, err
+		}
+		err = s.accountRepo.UpdateBalance(ctx, tx, *req.FeeAccountID, newFeeBalance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Mark transaction as completed
+	err = s.transactionRepo.UpdateStatus(ctx, tx, transaction.ID, model.TransactionStatusCompleted)
 	if err != nil {
 		return nil, err
 	}
-	newDestBalance := destBalance.Add(req.Amount)
-	err = s.accountRepo.UpdateBalance(ctx, tx, req.DestinationAccountID, newDestBalance)
+
+	transaction.Status = model.TransactionStatusCompleted
+	response := newCreateTransactionResponse(transaction)
+
+	// Publish the completed transfer to stream subscribers once committed
+	payload, err := json.Marshal(response)
 	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer event: %w", err)
+	}
+	if err := s.transactionRepo.NotifyCompleted(ctx, tx, string(payload)); err != nil {
 		return nil, err
 	}
 
-	// Mark transaction as completed
-	err = s.transactionRepo.UpdateStatus(ctx, tx, transaction.ID, model.TransactionStatusCompleted)
-	if err != nil {
+	audit := newAuditEntry(ctx, s.clock.Now(), model.AuditTransfer, append([]uuid.UUID{transaction.ID}, changed...)...)
+	if err := s.auditor.Record(ctx, tx, audit); err != nil {
 		return nil, err
 	}
 
@@ -138,21 +388,442 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, req *model.C
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	s.auditor.Committed(audit)
+
+	// Wake balance watchers of every account that changed
+	s.watcher.Notify(changed...)
 
 	// Return successful response
+	return response, nil
+}
+
+// lockAccounts locks each of the given accounts once, in lockOrder, and returns them by
+// ID. An account that doesn't exist is left out for the caller to report in its role.
+func (s *TransactionService) lockAccounts(ctx context.Context, tx *sql.Tx, ids ...uuid.UUID) (map[uuid.UUID]*model.Account, error) {
+	accounts := make(map[uuid.UUID]*model.Account, len(ids))
+	for _, id := range lockOrder(ids...) {
+		account, err := s.accountRepo.GetForUpdate(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, repository.ErrAccountNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+// lockOrder returns the distinct IDs in the order accounts are locked in, by their bytes,
+// so that any two transactions locking the same accounts take the locks in the same order
+func lockOrder(ids ...uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	ordered := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			ordered = append(ordered, id)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return bytes.Compare(ordered[i][:], ordered[j][:]) < 0
+	})
+	return ordered
+}
+
+// checkBalanceRange rejects balances the database column cannot store
+func (s *TransactionService) checkBalanceRange(balance decimal.Decimal) error {
+	if balance.Abs().GreaterThan(s.cfg.MaxBalance) {
+		return &ServiceError{
+			Code:    model.ErrCodeAmountOutOfRange,
+			Message: "Resulting balance exceeds the maximum supported balance",
+		}
+	}
+	return nil
+}
+
+// sweepAmount returns how much a sweep moves out of the source account: its balance above
+// any min_balance, less the fee
+func sweepAmount(source *model.Account, fee *decimal.Decimal) (decimal.Decimal, error) {
+	amount := source.Balance
+	if source.MinBalance != nil {
+		amount = money.Sub(amount, *source.MinBalance)
+	}
+	if fee != nil {
+		amount = money.Sub(amount, *fee)
+	}
+	if !money.IsPositive(amount) {
+		return decimal.Zero, &ServiceError{
+			Code:    model.ErrCodeInsufficientFunds,
+			Message: "Source account has no available balance to sweep",
+		}
+	}
+	return amount, nil
+}
+
+// checkMinBalance rejects a debit that would take an account below its min_balance
+func checkMinBalance(account *model.Account, newBalance decimal.Decimal) error {
+	if account.MinBalance != nil && money.Less(newBalance, *account.MinBalance) {
+		return &ServiceError{
+			Code:    model.ErrCodeBelowMinBalance,
+			Message: "Transfer would take the source account below its minimum balance",
+		}
+	}
+	return nil
+}
+
+// checkDebit rejects a debit of total from source that the account can't make: it must
+// cover total and keep its reserve, unless it is a system account, which may go negative,
+// and stay within the velocity limit. tx must hold the source's row lock, which keeps
+// concurrent debits from passing the checks together.
+func (s *TransactionService) checkDebit(ctx context.Context, tx *sql.Tx, source *model.Account, total decimal.Decimal) error {
+	if source.System {
+		return nil
+	}
+	if money.Less(source.Balance, total) {
+		return &ServiceError{
+			Code:    model.ErrCodeInsufficientFunds,
+			Message: "Insufficient funds in source account",
+		}
+	}
+	if err := checkMinBalance(source, money.Sub(source.Balance, total)); err != nil {
+		return err
+	}
+	if s.cfg.VelocityLimit > 0 {
+		return s.checkVelocity(ctx, tx, source.ID)
+	}
+	return nil
+}
+
+// checkDuplicate refuses req if a transfer with the same accounts and amount was made
+// within the duplicate window. Transfers with an ID or idempotency key aren't checked,
+// as their retries are replayed instead.
+func (s *TransactionService) checkDuplicate(ctx context.Context, tx *sql.Tx, req *model.CreateTransactionRequest) error {
+	if s.cfg.DuplicateWindow <= 0 || req.AllowDuplicate || req.ID != nil || req.IdempotencyKey != nil {
+		return nil
+	}
+	duplicate, err := s.transactionRepo.FindRecentDuplicate(ctx, tx, req, s.clock.Now().Add(-s.cfg.DuplicateWindow))
+	if err == nil {
+		return &ServiceError{
+			Code:          model.ErrCodePossibleDuplicate,
+			Message:       fmt.Sprintf("A transfer between the same accounts for the same amount was made in the last %s; set allow_duplicate to make another", s.cfg.DuplicateWindow),
+			TransactionID: &duplicate.ID,
+		}
+	}
+	if !errors.Is(err, repository.ErrTransactionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// checkVelocity rejects a transfer from an account that has already made VelocityLimit
+// transfers within VelocityWindow, reporting when the oldest of them leaves the window
+func (s *TransactionService) checkVelocity(ctx context.Context, tx *sql.Tx, accountID uuid.UUID) error {
+	count, oldest, err := s.transactionRepo.CountRecentTransfers(ctx, tx, accountID, s.clock.Now().Add(-s.cfg.VelocityWindow))
+	if err != nil {
+		return err
+	}
+	if count < s.cfg.VelocityLimit {
+		return nil
+	}
+	resetAt := oldest.Add(s.cfg.VelocityWindow).UTC()
+	return &ServiceError{
+		Code:    model.ErrCodeVelocityExceeded,
+		Message: fmt.Sprintf("Source account has made %d transfers in the last %s, the most allowed", count, s.cfg.VelocityWindow),
+		ResetAt: &resetAt,
+	}
+}
+
+// checkMaxBalance rejects a credit that would take an account above its max_balance
+func checkMaxBalance(account *model.Account, newBalance decimal.Decimal) error {
+	if account.MaxBalance != nil && money.Greater(newBalance, *account.MaxBalance) {
+		return &ServiceError{
+			Code:    model.ErrCodeAboveMaxBalance,
+			Message: "Transfer would take an account above its maximum balance",
+		}
+	}
+	return nil
+}
+
+// checkCreditAllowed rejects credits into closed accounts, and into frozen
+// accounts unless deposits to frozen accounts are allowed
+func (s *TransactionService) checkCreditAllowed(status model.AccountStatus) error {
+	switch status {
+	case model.AccountStatusClosed:
+		return &ServiceError{
+			Code:    model.ErrCodeAccountClosed,
+			Message: "Destination account is closed",
+		}
+	case model.AccountStatusFrozen:
+		if !s.cfg.AllowFrozenDeposits {
+			return &ServiceError{
+				Code:    model.ErrCodeAccountFrozen,
+				Message: "Destination account is frozen",
+			}
+		}
+	}
+	return nil
+}
+
+// checkDebitAllowed rejects debits from closed and frozen accounts. Unlike credits,
+// ALLOW_FROZEN_DEPOSITS doesn't apply: a frozen account's money stays where it is.
+func checkDebitAllowed(status model.AccountStatus) error {
+	switch status {
+	case model.AccountStatusClosed:
+		return &ServiceError{
+			Code:    model.ErrCodeAccountClosed,
+			Message: "Source account is closed",
+		}
+	case model.AccountStatusFrozen:
+		return &ServiceError{
+			Code:    model.ErrCodeAccountFrozen,
+			Message: "Source account is frozen",
+		}
+	}
+	return nil
+}
+
+// replayExisting returns the response for an existing transaction with the request's ID.
+// It returns nil without error when no such transaction exists, and a conflict
+// when the existing transaction was created with different parameters.
+func (s *TransactionService) replayExisting(ctx context.Context, req *model.CreateTransactionRequest) (*model.CreateTransactionResponse, error) {
+	existing, err := s.transactionRepo.GetByID(ctx, *req.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !req.Matches(existing) {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeConflict,
+			Message: "Transaction ID already used with different parameters",
+		}
+	}
+
+	return newCreateTransactionResponse(existing), nil
+}
+
+// fxConversion converts a cross-currency transfer's amount into the destination currency
+type fxConversion struct {
+	from, to string
+	rate     decimal.Decimal
+	scale    int32 // scale of the destination currency
+	rounding model.RoundingMode
+}
+
+// convert returns amount in the destination currency, rounded to its scale
+func (c *fxConversion) convert(amount decimal.Decimal) (decimal.Decimal, error) {
+	converted := c.rounding.Round(amount.Mul(c.rate), c.scale)
+	if !converted.IsPositive() {
+		return decimal.Zero, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("amount %s %s is worth nothing once converted to %s", amount, c.from, c.to),
+		}
+	}
+	return converted, nil
+}
+
+// transferCurrency is the currency a transfer's amount is in and how it is credited
+type transferCurrency struct {
+	code       string
+	scale      int32         // decimal places the amount and fee may carry
+	conversion *fxConversion // set when the destination's currency differs
+}
+
+// transferCurrencies checks that every account of the transfer exists and that the fee
+// account shares the source's currency, returning the currency the amount is in, whose
+// scale it must fit. When the destination's currency differs from the source's it also
+// returns the conversion between them, failing if no rate is available.
+func (s *TransactionService) transferCurrencies(ctx context.Context, req *model.CreateTransactionRequest) (*transferCurrency, error) {
+	var ids []uuid.UUID
+	if req.DestinationAccountID != nil {
+		ids = append(ids, *req.DestinationAccountID)
+	}
+	if req.SourceAccountID != nil {
+		ids = append(ids, *req.SourceAccountID)
+	}
+	if req.FeeAccountID != nil {
+		ids = append(ids, *req.FeeAccountID)
+	}
+
+	currencies, err := s.accountRepo.GetCurrencies(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SourceAccountID != nil {
+		if _, ok := currencies[*req.SourceAccountID]; !ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Source account not found",
+			}
+		}
+	}
+	var destCurrency string
+	if req.DestinationAccountID != nil {
+		var ok bool
+		if destCurrency, ok = currencies[*req.DestinationAccountID]; !ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Destination account not found",
+			}
+		}
+	}
+
+	// The amount and fee are in the source's currency, or the destination's for a deposit
+	currency := destCurrency
+	if req.SourceAccountID != nil {
+		currency = currencies[*req.SourceAccountID]
+	}
+	if req.IsWithdrawal() {
+		destCurrency = currency
+	}
+	if req.FeeAccountID != nil {
+		feeCurrency, ok := currencies[*req.FeeAccountID]
+		if !ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Fee account not found",
+			}
+		}
+		if feeCurrency != currency {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeCurrencyMismatch,
+				Message: "Fee account has a different currency from the source account",
+			}
+		}
+	}
+
+	scale, ok := s.cfg.Currencies.Scale(currency)
+	if !ok {
+		return nil, fmt.Errorf("currency %s of the transfer is not in the currency registry", currency)
+	}
+	if !model.FitsScale(req.Amount, scale) && s.cfg.ExcessPrecision != model.ExcessPrecisionRound {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: fmt.Sprintf("amount has more decimal places than %s allows (%d)", currency, scale),
+			Field:   "amount",
+		}
+	}
+
+	if currency == destCurrency {
+		return &transferCurrency{code: currency, scale: scale}, nil
+	}
+	if !s.features.CrossCurrency {
+		return nil, featureDisabled("Cross-currency transfers")
+	}
+
+	var rate decimal.Decimal
+	if s.cfg.Rates != nil {
+		rate, ok = s.cfg.Rates.Rate(currency, destCurrency)
+	}
+	if s.cfg.Rates == nil || !ok {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeCurrencyMismatch,
+			Message: fmt.Sprintf("No exchange rate is available from %s to %s", currency, destCurrency),
+		}
+	}
+	destScale, ok := s.cfg.Currencies.Scale(destCurrency)
+	if !ok {
+		return nil, fmt.Errorf("currency %s of account %s is not in the currency registry", destCurrency, *req.DestinationAccountID)
+	}
+
+	return &transferCurrency{
+		code:  currency,
+		scale: scale,
+		conversion: &fxConversion{
+			from:     currency,
+			to:       destCurrency,
+			rate:     rate,
+			scale:    destScale,
+			rounding: s.cfg.RoundingMode,
+		},
+	}, nil
+}
+
+// withSystemAccount returns the request with the system account of currency standing in
+// for the missing side of a deposit or withdrawal. Without a system account for the
+// currency the missing side is left nil, only the other account's balance changing.
+func (s *TransactionService) withSystemAccount(req *model.CreateTransactionRequest, currency string) (*model.CreateTransactionRequest, error) {
+	systemID, ok := s.cfg.SystemAccounts[currency]
+	switch {
+	case !ok:
+		return req, nil
+	case req.IsWithdrawal() && *req.SourceAccountID == systemID:
+		return nil, &ServiceError{
+			Code:    model.ErrCodeValidation,
+			Message: "The system account cannot be withdrawn from",
+		}
+	case req.IsWithdrawal():
+		filled := *req
+		filled.DestinationAccountID = &systemID
+		return &filled, nil
+	case req.SourceAccountID == nil && *req.DestinationAccountID != systemID:
+		filled := *req
+		filled.SourceAccountID = &systemID
+		return &filled, nil
+	}
+	return req, nil
+}
+
+// PrepareSystemAccounts flags the configured system accounts so the database lets them
+// go negative, checking that each exists and is in the currency it is configured for
+func (s *TransactionService) PrepareSystemAccounts(ctx context.Context) error {
+	for currency, id := range s.cfg.SystemAccounts {
+		account, err := s.accountRepo.MarkSystem(ctx, id)
+		if err != nil {
+			if errors.Is(err, repository.ErrAccountNotFound) {
+				return fmt.Errorf("system account %s of %s does not exist", id, currency)
+			}
+			return err
+		}
+		if account.Currency != currency {
+			return fmt.Errorf("system account %s of %s is in %s", id, currency, account.Currency)
+		}
+	}
+	return nil
+}
+
+// newCreateTransactionResponse builds the create response for a stored transaction
+func newCreateTransactionResponse(transaction *model.Transaction) *model.CreateTransactionResponse {
 	return &model.CreateTransactionResponse{
 		ID:                   transaction.ID,
 		SourceAccountID:      transaction.SourceAccountID,
 		DestinationAccountID: transaction.DestinationAccountID,
 		Amount:               transaction.Amount,
 		Reference:            transaction.Reference,
-		Status:               model.TransactionStatusCompleted,
+		Description:          transaction.Description,
+		Fee:                  transaction.Fee,
+		FeeAccountID:         transaction.FeeAccountID,
+		DestinationAmount:    transaction.DestinationAmount,
+		FXRate:               transaction.FXRate,
+		ReversalOf:           transaction.ReversalOf,
+		ReversedBy:           transaction.ReversedBy,
+		RetryOf:              transaction.RetryOf,
+		Status:               transaction.Status,
 		CreatedAt:            transaction.CreatedAt,
-	}, nil
+	}
+}
+
+// recordDeclined persists a declined transfer as a failed transaction.
+// Failures are logged rather than returned so the original decline reaches the client.
+func (s *TransactionService) recordDeclined(ctx context.Context, req *model.CreateTransactionRequest, declineErr *ServiceError) {
+	if _, err := s.transactionRepo.CreateFailed(ctx, req, declineErr.Message); err != nil {
+		log.Printf("failed to record declined transfer: %v", err)
+	}
 }
 
 // ProcessBulkTransfers processes multiple transfers atomically
-func (s *TransactionService) ProcessBulkTransfers(ctx context.Context, req *model.BulkTransferRequest) (*model.BulkTransferResponse, error) {
+func (s *TransactionService) ProcessBulkTransfers(ctx context.Context, req *model.BulkTransferRequest) (_ *model.BulkTransferResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.ProcessBulkTransfers")
+	defer func() { endSpan(span, err) }()
+
+	if !s.features.BulkTransfers {
+		return nil, featureDisabled("Bulk transfers")
+	}
+
 	// Validate request
 	if err := req.Validate(); err != nil {
 		return nil, err
@@ -163,18 +834,18 @@ func (s *TransactionService) ProcessBulkTransfers(ctx context.Context, req *mode
 		Failed:    make([]model.TransferError, 0),
 	}
 
-	// Process each transfer
+	// Process each transfer in request order, which keeps both result lists ordered by index
 	for i, transferReq := range req.Transfers {
 		transferResp, err := s.CreateTransaction(ctx, &transferReq)
 		if err != nil {
 			// Add to failed list
-			code := model.ErrCodeInternalError
-			if serviceErr, ok := err.(*ServiceError); ok {
-				code = serviceErr.Code
+			code, message := model.ErrCodeInternalError, err.Error()
+			if serviceErr := AsServiceError(err); serviceErr != nil {
+				code, message = serviceErr.Code, serviceErr.Message
 			}
 			response.Failed = append(response.Failed, model.TransferError{
 				Index: i,
-				Error: err.Error(),
+				Error: message,
 				Code:  code,
 			})
 			continue
@@ -182,11 +853,99 @@ func (s *TransactionService) ProcessBulkTransfers(ctx context.Context, req *mode
 		response.Transfers = append(response.Transfers, *transferResp)
 	}
 
+	response.Summary = &model.BulkTransferSummary{
+		Total:     len(req.Transfers),
+		Succeeded: len(response.Transfers),
+		Failed:    len(response.Failed),
+	}
+
 	return response, nil
 }
 
+// createIdempotent makes a transfer that carries an idempotency key. A transfer whose
+// earlier attempt succeeded is replayed from the idempotency store; otherwise the key is
+// claimed, the transfer executed and, on success, its response stored under the key. A
+// key claimed by an attempt still in progress is refused rather than executed twice.
+func (s *TransactionService) createIdempotent(ctx context.Context, req *model.CreateTransactionRequest) (*model.CreateTransactionResponse, error) {
+	if err := s.idempotency.KeyFormat.Check(*req.IdempotencyKey); err != nil {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeInvalidIdempotencyKey,
+			Message: err.Error(),
+		}
+	}
+
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transfer request: %w", err)
+	}
+	keyHash := repository.GenerateKeyHash(*req.IdempotencyKey)
+
+	record, err := s.idempotencyRepo.GetRequest(ctx, keyHash, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		if record.RequestBody != string(requestBody) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeConflict,
+				Message: "Idempotency key was already used for a different transfer",
+			}
+		}
+		if record.ResponseBody != nil && record.ResponseStatus != nil && *record.ResponseStatus == http.StatusCreated {
+			var replayed model.CreateTransactionResponse
+			if err := json.Unmarshal([]byte(*record.ResponseBody), &replayed); err != nil {
+				return nil, fmt.Errorf("failed to decode stored transfer response: %w", err)
+			}
+			return &replayed, nil
+		}
+		return nil, idempotencyKeyInProgress()
+	}
+
+	claimed, err := s.idempotencyRepo.ClaimRequest(ctx, keyHash, string(requestBody), s.clock.Now(), s.idempotency.TTL)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		// A concurrent request claimed the key since it was looked up
+		return nil, idempotencyKeyInProgress()
+	}
+
+	response, err := s.createWithRetry(ctx, req)
+	if err != nil {
+		// A failed transfer gives the key up, so a retry executes it again
+		if releaseErr := s.idempotencyRepo.ReleaseRequest(context.WithoutCancel(ctx), keyHash); releaseErr != nil {
+			log.Printf("failed to release transfer idempotency key: %v", releaseErr)
+		}
+		return nil, err
+	}
+
+	// The transfer has already happened, so a storage failure is logged rather than reported
+	responseBody, err := json.Marshal(response)
+	if err == nil {
+		err = s.idempotencyRepo.UpdateResponse(context.WithoutCancel(ctx), keyHash, string(responseBody), http.StatusCreated)
+	}
+	if err != nil {
+		log.Printf("failed to store response for transfer idempotency key: %v", err)
+	}
+
+	return response, nil
+}
+
+// idempotencyKeyInProgress refuses a transfer whose idempotency key is held by an
+// attempt that hasn't finished
+func idempotencyKeyInProgress() *ServiceError {
+	return &ServiceError{
+		Code:    model.ErrCodeConflict,
+		Message: "A transfer with this idempotency key is still in progress; retry once it has finished",
+	}
+}
+
 // GetTransaction retrieves a transaction by ID
-func (s *TransactionService) GetTransaction(ctx context.Context, id uuid.UUID) (*model.Transaction, error) {
+func (s *TransactionService) GetTransaction(ctx context.Context, id uuid.UUID) (_ *model.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetTransaction")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
 	transaction, err := s.transactionRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, repository.ErrTransactionNotFound) {
@@ -201,8 +960,89 @@ func (s *TransactionService) GetTransaction(ctx context.Context, id uuid.UUID) (
 	return transaction, nil
 }
 
-// GetAccountTransactions retrieves transactions for an account
-func (s *TransactionService) GetAccountTransactions(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*model.Transaction, error) {
+// GetTransactions retrieves several transactions in one query, in request order.
+// Transactions that don't exist are listed as not found rather than failing the request.
+func (s *TransactionService) GetTransactions(ctx context.Context, req *model.GetTransactionsRequest) (_ *model.GetTransactionsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetTransactions")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	if err := req.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+				Field:   validationErr.Field,
+			}
+		}
+		return nil, err
+	}
+
+	transactions, err := s.transactionRepo.GetByIDs(ctx, req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*model.Transaction, len(transactions))
+	for _, transaction := range transactions {
+		byID[transaction.ID] = transaction
+	}
+
+	response := &model.GetTransactionsResponse{
+		Transactions: make([]*model.Transaction, 0, len(transactions)),
+		NotFound:     []uuid.UUID{},
+	}
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if transaction, ok := byID[id]; ok {
+			response.Transactions = append(response.Transactions, transaction)
+		} else {
+			response.NotFound = append(response.NotFound, id)
+		}
+	}
+
+	return response, nil
+}
+
+// GetTransactionStatus retrieves a transaction's status without the rest of it
+func (s *TransactionService) GetTransactionStatus(ctx context.Context, id uuid.UUID) (_ *model.TransactionStatusResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetTransactionStatus")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	status, err := s.transactionRepo.GetStatus(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrTransactionNotFound) {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeNotFound,
+				Message: "Transaction not found",
+			}
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// GetAccountTransactions retrieves a page of transactions for an account
+func (s *TransactionService) GetAccountTransactions(ctx context.Context, accountID uuid.UUID, filter model.TransactionFilter, limit, offset int) (_ *model.Page[*model.Transaction], err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetAccountTransactions")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	if err := filter.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+			}
+		}
+		return nil, err
+	}
+
 	// Validate account exists
 	exists, err := s.accountRepo.Exists(ctx, accountID)
 	if err != nil {
@@ -223,5 +1063,57 @@ func (s *TransactionService) GetAccountTransactions(ctx context.Context, account
 		offset = 0
 	}
 
-	return s.transactionRepo.GetAccountTransactions(ctx, accountID, limit, offset)
-} 
\ No newline at end of file
+	transactions, err := s.transactionRepo.GetAccountTransactions(ctx, accountID, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.transactionRepo.CountAccountTransactions(ctx, accountID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewPage(transactions, limit, offset, total), nil
+}
+
+// GetCounterparties summarises the completed transfers between an account and each account
+// it has transacted with
+func (s *TransactionService) GetCounterparties(ctx context.Context, accountID uuid.UUID, filter model.CounterpartyFilter) (_ *model.CounterpartiesResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TransactionService.GetCounterparties")
+	defer func() { endSpan(span, err) }()
+	ctx = repository.ReadFromReplica(ctx)
+
+	if err := filter.Validate(); err != nil {
+		if validationErr, ok := err.(*model.ValidationError); ok {
+			return nil, &ServiceError{
+				Code:    model.ErrCodeValidation,
+				Message: validationErr.Message,
+			}
+		}
+		return nil, err
+	}
+
+	exists, err := s.accountRepo.Exists(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &ServiceError{
+			Code:    model.ErrCodeNotFound,
+			Message: "Account not found",
+		}
+	}
+
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	counterparties, err := s.transactionRepo.GetCounterparties(ctx, accountID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CounterpartiesResponse{
+		AccountID:      accountID,
+		Counterparties: counterparties,
+	}, nil
+}
@@ -0,0 +1,1476 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"internal-transfers-api/internal/config"
+	"internal-transfers-api/internal/model"
+	"internal-transfers-api/internal/repository"
+	"internal-transfers-api/internal/repository/memory"
+)
+
+// newTestTransactionService wires a transaction service to in-memory repositories
+func newTestTransactionService(cfg config.TransferConfig) (*TransactionService, *memory.AccountRepository, *memory.TransactionRepository) {
+	if cfg.MaxBalance.IsZero() {
+		cfg.MaxBalance = decimal.RequireFromString("1000000000")
+	}
+	if cfg.Currencies == nil {
+		cfg.Currencies = model.DefaultCurrencies()
+	}
+	transactionRepo := memory.NewTransactionRepository()
+	accountRepo := memory.NewAccountRepository(transactionRepo)
+	s := NewTransactionService(accountRepo, transactionRepo, memory.NewIdempotencyRepository(), memory.NewDB(), cfg, config.IdempotencyConfig{TTL: time.Hour}, NewBalanceWatcher(nil), nil, NewAuditor(nil, nil), config.DefaultFeatures())
+	return s, accountRepo, transactionRepo
+}
+
+func TestTransactionService_CreateTransaction(t *testing.T) {
+	ctx := context.Background()
+	missingID := uuid.New()
+
+	tests := []struct {
+		name           string
+		cfg            config.TransferConfig
+		sourceBalance  string // empty for a deposit
+		destStatus     model.AccountStatus
+		missingSource  bool
+		missingDest    bool
+		amount         string
+		expectedCode   string
+		expectedSource string
+		expectedDest   string
+		expectedStored int // transactions stored against the destination
+	}{
+		{
+			name:           "transfer between accounts",
+			sourceBalance:  "100",
+			amount:         "40",
+			expectedSource: "60",
+			expectedDest:   "40",
+			expectedStored: 1,
+		},
+		{
+			name:           "deposit without source account",
+			cfg:            config.TransferConfig{AllowDeposits: true},
+			amount:         "25.5",
+			expectedDest:   "25.5",
+			expectedStored: 1,
+		},
+		{
+			name:           "insufficient funds",
+			sourceBalance:  "10",
+			amount:         "40",
+			expectedCode:   model.ErrCodeInsufficientFunds,
+			expectedSource: "10",
+			expectedDest:   "0",
+		},
+		{
+			name:           "insufficient funds recorded as declined",
+			cfg:            config.TransferConfig{RecordDeclined: true},
+			sourceBalance:  "10",
+			amount:         "40",
+			expectedCode:   model.ErrCodeInsufficientFunds,
+			expectedSource: "10",
+			expectedDest:   "0",
+			expectedStored: 1,
+		},
+		{
+			name:          "source account not found",
+			sourceBalance: "100",
+			missingSource: true,
+			amount:        "40",
+			expectedCode:  model.ErrCodeNotFound,
+			expectedDest:  "0",
+		},
+		{
+			name:           "destination account not found",
+			sourceBalance:  "100",
+			missingDest:    true,
+			amount:         "40",
+			expectedCode:   model.ErrCodeNotFound,
+			expectedSource: "100",
+		},
+		{
+			name:         "deposits disabled",
+			amount:       "25.5",
+			expectedCode: model.ErrCodeDepositsDisabled,
+			expectedDest: "0",
+		},
+		{
+			name:           "excess precision rejected",
+			sourceBalance:  "100",
+			amount:         "1.005",
+			expectedCode:   model.ErrCodeValidation,
+			expectedSource: "100",
+			expectedDest:   "0",
+		},
+		{
+			name:           "excess precision rounded",
+			cfg:            config.TransferConfig{ExcessPrecision: model.ExcessPrecisionRound, RoundingMode: model.RoundHalfUp},
+			sourceBalance:  "100",
+			amount:         "1.005",
+			expectedSource: "98.99",
+			expectedDest:   "1.01",
+			expectedStored: 1,
+		},
+		{
+			name:           "excess precision rounded to zero",
+			cfg:            config.TransferConfig{ExcessPrecision: model.ExcessPrecisionRound, RoundingMode: model.RoundHalfUp},
+			sourceBalance:  "100",
+			amount:         "0.004",
+			expectedCode:   model.ErrCodeValidation,
+			expectedSource: "100",
+			expectedDest:   "0",
+		},
+		{
+			name:         "deposit into closed account",
+			cfg:          config.TransferConfig{AllowDeposits: true},
+			destStatus:   model.AccountStatusClosed,
+			amount:       "40",
+			expectedCode: model.ErrCodeAccountClosed,
+			expectedDest: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, transactionRepo := newTestTransactionService(tt.cfg)
+
+			req := &model.CreateTransactionRequest{Amount: decimal.RequireFromString(tt.amount)}
+
+			var sourceID uuid.UUID
+			if tt.sourceBalance != "" {
+				source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString(tt.sourceBalance)})
+				require.NoError(t, err)
+				sourceID = source.ID
+				if tt.missingSource {
+					req.SourceAccountID = &missingID
+				} else {
+					req.SourceAccountID = &sourceID
+				}
+			}
+
+			dest, err := accountRepo.Create(ctx, &model.Account{})
+			require.NoError(t, err)
+			if tt.destStatus != "" {
+				require.NoError(t, accountRepo.SetStatus(dest.ID, tt.destStatus))
+			}
+			req.DestinationAccountID = &dest.ID
+			if tt.missingDest {
+				req.DestinationAccountID = &missingID
+			}
+
+			response, err := s.CreateTransaction(ctx, req)
+
+			if tt.expectedCode != "" {
+				serviceErr, ok := err.(*ServiceError)
+				if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+					assert.Equal(t, tt.expectedCode, serviceErr.Code)
+				}
+				assert.Empty(t, transactionRepo.Notified())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, model.TransactionStatusCompleted, response.Status)
+				assert.Len(t, transactionRepo.Notified(), 1)
+			}
+
+			if tt.expectedSource != "" {
+				source, err := accountRepo.GetByID(ctx, sourceID)
+				require.NoError(t, err)
+				assert.True(t, source.Balance.Equal(decimal.RequireFromString(tt.expectedSource)), "source balance %s", source.Balance)
+			}
+			if tt.expectedDest != "" {
+				updated, err := accountRepo.GetByID(ctx, dest.ID)
+				require.NoError(t, err)
+				assert.True(t, updated.Balance.Equal(decimal.RequireFromString(tt.expectedDest)), "destination balance %s", updated.Balance)
+			}
+
+			transactions, err := transactionRepo.GetAccountTransactions(ctx, dest.ID, model.TransactionFilter{}, 100, 0)
+			require.NoError(t, err)
+			assert.Len(t, transactions, tt.expectedStored)
+		})
+	}
+}
+
+func TestTransactionService_CheckCreditAllowed(t *testing.T) {
+	tests := []struct {
+		name                string
+		status              model.AccountStatus
+		allowFrozenDeposits bool
+		expectedCode        string
+	}{
+		{
+			name:   "active account accepts deposits",
+			status: model.AccountStatusActive,
+		},
+		{
+			name:                "active account accepts deposits when frozen deposits allowed",
+			status:              model.AccountStatusActive,
+			allowFrozenDeposits: true,
+		},
+		{
+			name:         "frozen account rejects deposits by default",
+			status:       model.AccountStatusFrozen,
+			expectedCode: model.ErrCodeAccountFrozen,
+		},
+		{
+			name:                "frozen account accepts deposits when allowed",
+			status:              model.AccountStatusFrozen,
+			allowFrozenDeposits: true,
+		},
+		{
+			name:         "closed account rejects deposits",
+			status:       model.AccountStatusClosed,
+			expectedCode: model.ErrCodeAccountClosed,
+		},
+		{
+			name:                "closed account rejects deposits even when frozen deposits allowed",
+			status:              model.AccountStatusClosed,
+			allowFrozenDeposits: true,
+			expectedCode:        model.ErrCodeAccountClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TransactionService{cfg: config.TransferConfig{AllowFrozenDeposits: tt.allowFrozenDeposits}}
+
+			err := s.checkCreditAllowed(tt.status)
+
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+			serviceErr, ok := err.(*ServiceError)
+			if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+				assert.Equal(t, tt.expectedCode, serviceErr.Code)
+			}
+		})
+	}
+}
+
+func TestTransactionService_CreateTransaction_SourceStatus(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		status       model.AccountStatus
+		allowFrozen  bool
+		expectedCode string
+	}{
+		{name: "frozen source", status: model.AccountStatusFrozen, expectedCode: model.ErrCodeAccountFrozen},
+		{name: "frozen source with frozen deposits allowed", status: model.AccountStatusFrozen, allowFrozen: true, expectedCode: model.ErrCodeAccountFrozen},
+		{name: "closed source", status: model.AccountStatusClosed, expectedCode: model.ErrCodeAccountClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, _ := newTestTransactionService(config.TransferConfig{AllowFrozenDeposits: tt.allowFrozen})
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+			require.NoError(t, err)
+			dest, err := accountRepo.Create(ctx, &model.Account{})
+			require.NoError(t, err)
+			_, err = accountRepo.UpdateStatus(ctx, nil, source.ID, tt.status)
+			require.NoError(t, err)
+
+			// Transfers and withdrawals alike
+			for _, destination := range []*uuid.UUID{&dest.ID, nil} {
+				_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+					SourceAccountID:      &source.ID,
+					DestinationAccountID: destination,
+					Amount:               decimal.RequireFromString("40"),
+				})
+				serviceErr := AsServiceError(err)
+				require.NotNil(t, serviceErr)
+				assert.Equal(t, tt.expectedCode, serviceErr.Code)
+			}
+			assertBalance(t, accountRepo, source.ID, "100")
+			assertBalance(t, accountRepo, dest.ID, "0")
+		})
+	}
+}
+
+func TestTransactionService_ProcessBulkTransfers_Ordering(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("10000")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	missingID := uuid.New()
+
+	// Transfer i moves i+1, so successes can be matched back to their index by amount
+	req := &model.BulkTransferRequest{}
+	for i := 0; i < 60; i++ {
+		sourceID := source.ID
+		req.Transfers = append(req.Transfers, model.CreateTransactionRequest{
+			SourceAccountID:      &sourceID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.NewFromInt(int64(i + 1)),
+		})
+	}
+	req.Transfers[3].DestinationAccountID = &missingID
+	req.Transfers[7].DestinationAccountID = &missingID
+	req.Transfers[50].Amount = decimal.RequireFromString("100000")
+
+	response, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+
+	require.Len(t, response.Failed, 3)
+	assert.Equal(t, model.TransferError{Index: 3, Error: "Destination account not found", Code: model.ErrCodeNotFound}, response.Failed[0])
+	assert.Equal(t, model.TransferError{Index: 7, Error: "Destination account not found", Code: model.ErrCodeNotFound}, response.Failed[1])
+	assert.Equal(t, 50, response.Failed[2].Index)
+	assert.Equal(t, model.ErrCodeInsufficientFunds, response.Failed[2].Code)
+
+	require.Len(t, response.Transfers, 57)
+	for i := 1; i < len(response.Transfers); i++ {
+		assert.True(t, response.Transfers[i-1].Amount.LessThan(response.Transfers[i].Amount), "transfers out of order at %d", i)
+	}
+
+	assert.Equal(t, &model.BulkTransferSummary{Total: 60, Succeeded: 57, Failed: 3}, response.Summary)
+}
+
+// fixedClock is a Clock that only moves when told to
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestTransactionService_ProcessBulkTransfers_IdempotencyKeyExpiry(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+	clock := &fixedClock{now: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	s.clock = clock
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("50")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	req := &model.BulkTransferRequest{Transfers: []model.CreateTransactionRequest{{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("10"),
+		IdempotencyKey:       stringPtr("item-0"),
+	}}}
+	first, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, first.Transfers, 1)
+
+	// Replayed until the TTL is up, executed again once it is
+	clock.now = clock.now.Add(time.Hour - time.Second)
+	replayed, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, replayed.Transfers, 1)
+	assert.Equal(t, first.Transfers[0].ID, replayed.Transfers[0].ID)
+
+	clock.now = clock.now.Add(time.Second)
+	expired, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, expired.Transfers, 1)
+	assert.NotEqual(t, first.Transfers[0].ID, expired.Transfers[0].ID)
+	assertBalance(t, accountRepo, source.ID, "30")
+}
+
+func TestTransactionService_ProcessBulkTransfers_IdempotentRetry(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("50")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	req := &model.BulkTransferRequest{}
+	for i, amount := range []string{"30", "40", "5"} {
+		req.Transfers = append(req.Transfers, model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString(amount),
+			IdempotencyKey:       stringPtr(fmt.Sprintf("item-%d", i)),
+		})
+	}
+
+	// The second item can't be covered once the first has gone through
+	first, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, first.Failed, 1)
+	assert.Equal(t, 1, first.Failed[0].Index)
+	require.Len(t, first.Transfers, 2)
+
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &source.ID,
+		Amount:               decimal.RequireFromString("100"),
+	})
+	require.NoError(t, err)
+
+	// Retrying replays the two successes and only executes the failed item
+	retry, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+	assert.Empty(t, retry.Failed)
+	require.Len(t, retry.Transfers, 3)
+	assert.Equal(t, first.Transfers[0].ID, retry.Transfers[0].ID)
+	assert.Equal(t, first.Transfers[1].ID, retry.Transfers[2].ID)
+	assert.Equal(t, &model.BulkTransferSummary{Total: 3, Succeeded: 3, Failed: 0}, retry.Summary)
+
+	updated, err := accountRepo.GetByID(ctx, dest.ID)
+	require.NoError(t, err)
+	assert.True(t, updated.Balance.Equal(decimal.RequireFromString("75")), "destination balance %s", updated.Balance)
+
+	transactions, err := transactionRepo.GetAccountTransactions(ctx, dest.ID, model.TransactionFilter{}, 100, 0)
+	require.NoError(t, err)
+	assert.Len(t, transactions, 3)
+
+	// Reusing a key for a different transfer is rejected
+	req.Transfers[0].Amount = decimal.RequireFromString("1")
+	conflict, err := s.ProcessBulkTransfers(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, conflict.Failed, 1)
+	assert.Equal(t, model.ErrCodeConflict, conflict.Failed[0].Code)
+}
+
+func TestTransactionService_CreateTransaction_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("50")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	transfer := func(key, amount string) (*model.CreateTransactionResponse, error) {
+		return s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString(amount),
+			IdempotencyKey:       stringPtr(key),
+		})
+	}
+
+	first, err := transfer("transfer-1", "10")
+	require.NoError(t, err)
+
+	// A retry with the same key replays the transfer instead of moving the money again
+	retry, err := transfer("transfer-1", "10")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retry.ID)
+	assertBalance(t, accountRepo, source.ID, "40")
+
+	// Reusing the key for a different transfer is rejected
+	_, err = transfer("transfer-1", "20")
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeConflict, serviceErr.Code)
+
+	// A failed transfer stores no response, so its retry is executed again
+	_, err = transfer("transfer-2", "100")
+	require.Error(t, err)
+	_, err = transfer("transfer-2", "100")
+	serviceErr = AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeInsufficientFunds, serviceErr.Code)
+	assertBalance(t, accountRepo, source.ID, "40")
+}
+
+func TestTransactionService_CreateTransaction_IdempotencyKeyConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	// Requests with the same key at once make the transfer once; the others replay it
+	// or are told it is in progress
+	const attempts = 20
+	start := make(chan struct{})
+	results := make([]*model.CreateTransactionResponse, attempts)
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Amount:               decimal.RequireFromString("10"),
+				IdempotencyKey:       stringPtr("transfer-1"),
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var ids []uuid.UUID
+	for i := range results {
+		if errs[i] != nil {
+			serviceErr := AsServiceError(errs[i])
+			require.NotNil(t, serviceErr, "attempt %d: %v", i, errs[i])
+			assert.Equal(t, model.ErrCodeConflict, serviceErr.Code)
+			continue
+		}
+		ids = append(ids, results[i].ID)
+	}
+	require.NotEmpty(t, ids)
+	for _, id := range ids {
+		assert.Equal(t, ids[0], id)
+	}
+	assertBalance(t, accountRepo, source.ID, "90")
+
+	// Once the key has expired it is claimed afresh, and its retries replay the new transfer
+	clock := &fixedClock{now: time.Now().Add(2 * time.Hour)}
+	s.clock = clock
+	transfer := func() (*model.CreateTransactionResponse, error) {
+		return s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("10"),
+			IdempotencyKey:       stringPtr("transfer-1"),
+		})
+	}
+	renewed, err := transfer()
+	require.NoError(t, err)
+	assert.NotEqual(t, ids[0], renewed.ID)
+	retry, err := transfer()
+	require.NoError(t, err)
+	assert.Equal(t, renewed.ID, retry.ID)
+	assertBalance(t, accountRepo, source.ID, "80")
+}
+
+func TestTransactionService_GetAccountTransactions_SameTimestamp(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	// A batch's transfers share the NOW() of their database transaction
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 7; i++ {
+		response, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.NewFromInt(int64(i + 1)),
+		})
+		require.NoError(t, err)
+		require.NoError(t, transactionRepo.SetCreatedAt(response.ID, createdAt))
+		ids = append(ids, response.ID.String())
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	// Paging through returns every transaction once, in descending ID order
+	var paged []string
+	for offset := 0; offset < len(ids); offset += 3 {
+		page, err := s.GetAccountTransactions(ctx, dest.ID, model.TransactionFilter{}, 3, offset)
+		require.NoError(t, err)
+		assert.Equal(t, len(ids), page.Total)
+		assert.Equal(t, offset+3 < len(ids), page.HasMore)
+		for _, transaction := range page.Data {
+			paged = append(paged, transaction.ID.String())
+		}
+	}
+	assert.Equal(t, ids, paged)
+}
+
+func TestTransactionService_GetTransactions(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, amount := range []string{"1", "2"} {
+		response, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString(amount),
+		})
+		require.NoError(t, err)
+		ids = append(ids, response.ID)
+	}
+	missing := uuid.New()
+
+	// Found transactions come in request order, each once
+	response, err := s.GetTransactions(ctx, &model.GetTransactionsRequest{
+		IDs: []uuid.UUID{ids[1], missing, ids[0], ids[1], missing},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Transactions, 2)
+	assert.Equal(t, ids[1], response.Transactions[0].ID)
+	assert.Equal(t, "2", response.Transactions[0].Amount.String())
+	assert.Equal(t, ids[0], response.Transactions[1].ID)
+	assert.Equal(t, []uuid.UUID{missing}, response.NotFound)
+
+	tooMany := make([]uuid.UUID, model.MaxTransactionQueryIDs+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	for _, req := range []*model.GetTransactionsRequest{{}, {IDs: tooMany}} {
+		_, err = s.GetTransactions(ctx, req)
+		serviceErr := AsServiceError(err)
+		require.NotNil(t, serviceErr)
+		assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+		assert.Equal(t, "ids", serviceErr.Field)
+	}
+}
+
+func TestTransactionService_CreateTransaction_DuplicateWindow(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{DuplicateWindow: time.Minute})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	transfer := func(amount string, allowDuplicate bool) (*model.CreateTransactionResponse, error) {
+		return s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString(amount),
+			AllowDuplicate:       allowDuplicate,
+		})
+	}
+
+	first, err := transfer("10", false)
+	require.NoError(t, err)
+
+	_, err = transfer("10", false)
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodePossibleDuplicate, serviceErr.Code)
+	assert.Equal(t, &first.ID, serviceErr.TransactionID)
+
+	// A different amount, or an explicit allow_duplicate, goes through
+	_, err = transfer("10.01", false)
+	require.NoError(t, err)
+	second, err := transfer("10", true)
+	require.NoError(t, err)
+
+	// Once the earlier transfers are outside the window the same transfer is new again
+	for _, id := range []uuid.UUID{first.ID, second.ID} {
+		require.NoError(t, transactionRepo.SetCreatedAt(id, time.Now().Add(-2*time.Minute)))
+	}
+	_, err = transfer("10", false)
+	require.NoError(t, err)
+
+	updated, err := accountRepo.GetByID(ctx, source.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "59.99", updated.Balance.String())
+
+	// A transfer with an idempotency key isn't checked, as its retry is replayed instead
+	keyed := func(key string) (*model.CreateTransactionResponse, error) {
+		return s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("10"),
+			IdempotencyKey:       stringPtr(key),
+		})
+	}
+	keyedFirst, err := keyed("transfer-1")
+	require.NoError(t, err)
+	keyedRetry, err := keyed("transfer-1")
+	require.NoError(t, err)
+	assert.Equal(t, keyedFirst.ID, keyedRetry.ID)
+	_, err = keyed("transfer-2")
+	require.NoError(t, err)
+	assertBalance(t, accountRepo, source.ID, "39.99")
+}
+
+func TestTransactionService_CreateTransaction_VelocityLimit(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{VelocityLimit: 2, VelocityWindow: time.Hour})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	transfer := func() (*model.CreateTransactionResponse, error) {
+		return s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("10"),
+			AllowDuplicate:       true,
+		})
+	}
+
+	first, err := transfer()
+	require.NoError(t, err)
+	oldest := time.Now().Add(-30 * time.Minute).UTC()
+	require.NoError(t, transactionRepo.SetCreatedAt(first.ID, oldest))
+	_, err = transfer()
+	require.NoError(t, err)
+
+	// The third within the hour is refused until the oldest leaves the window
+	_, err = transfer()
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeVelocityExceeded, serviceErr.Code)
+	require.NotNil(t, serviceErr.ResetAt)
+	assert.True(t, serviceErr.ResetAt.Equal(oldest.Add(time.Hour)), "reset at %s", serviceErr.ResetAt)
+	assertBalance(t, accountRepo, source.ID, "80")
+
+	// The destination's own transfers are counted apart
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &dest.ID,
+		DestinationAccountID: &source.ID,
+		Amount:               decimal.RequireFromString("5"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, transactionRepo.SetCreatedAt(first.ID, time.Now().Add(-2*time.Hour)))
+	_, err = transfer()
+	require.NoError(t, err)
+}
+
+func TestTransactionService_CreateTransaction_PoolExhausted(t *testing.T) {
+	repository.SetAcquireTimeout(20 * time.Millisecond)
+	t.Cleanup(func() { repository.SetAcquireTimeout(0) })
+
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	transfer := func() error {
+		_, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               decimal.RequireFromString("10"),
+		})
+		return err
+	}
+
+	// Hold the only connection in the pool
+	s.db.SetMaxOpenConns(1)
+	conn, err := s.db.Conn(ctx)
+	require.NoError(t, err)
+
+	err = transfer()
+	serviceErr := AsServiceError(err)
+	require.NotNil(t, serviceErr)
+	assert.Equal(t, model.ErrCodeServiceBusy, serviceErr.Code)
+	assert.Equal(t, int64(1), s.db.Stats().WaitCount)
+
+	// Connections go back to the pool after each transfer
+	require.NoError(t, conn.Close())
+	require.NoError(t, transfer())
+	require.NoError(t, transfer())
+}
+
+// lockRecorder records the accounts a transfer locks, in the order it locks them
+type lockRecorder struct {
+	*memory.AccountRepository
+	locked []uuid.UUID
+}
+
+func (r *lockRecorder) GetForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*model.Account, error) {
+	r.locked = append(r.locked, id)
+	return r.AccountRepository.GetForUpdate(ctx, tx, id)
+}
+
+func (r *lockRecorder) GetBalanceForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (decimal.Decimal, error) {
+	r.locked = append(r.locked, id)
+	return r.AccountRepository.GetBalanceForUpdate(ctx, tx, id)
+}
+
+func TestTransactionService_CreateTransaction_LockOrder(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+	recorder := &lockRecorder{AccountRepository: accountRepo}
+	s.accountRepo = recorder
+	fee := decimal.RequireFromString("1")
+
+	tests := []struct {
+		name         string
+		feeToDest    bool
+		expectedDest string
+		expectedFee  string // balance of a separate fee account
+	}{
+		{name: "separate fee account", expectedDest: "10", expectedFee: "1"},
+		// The fee lands on top of the credit
+		{name: "fee to the destination", feeToDest: true, expectedDest: "11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+			require.NoError(t, err)
+			dest, err := accountRepo.Create(ctx, &model.Account{})
+			require.NoError(t, err)
+			involved := []uuid.UUID{source.ID, dest.ID}
+			feeAccountID := dest.ID
+			if !tt.feeToDest {
+				feeAccount, err := accountRepo.Create(ctx, &model.Account{})
+				require.NoError(t, err)
+				feeAccountID = feeAccount.ID
+				involved = append(involved, feeAccountID)
+			}
+
+			recorder.locked = nil
+			_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Amount:               decimal.RequireFromString("10"),
+				Fee:                  &fee,
+				FeeAccountID:         &feeAccountID,
+			})
+			require.NoError(t, err)
+			assertBalance(t, accountRepo, source.ID, "89")
+			assertBalance(t, accountRepo, dest.ID, tt.expectedDest)
+			if !tt.feeToDest {
+				assertBalance(t, accountRepo, feeAccountID, tt.expectedFee)
+			}
+
+			// Each account is locked once, in the same order whatever its role
+			assert.Equal(t, lockOrder(involved...), recorder.locked)
+		})
+	}
+}
+
+func TestTransactionService_CreateTransaction_FeeQuantized(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{RoundingMode: model.RoundHalfUp})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	dest, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+	feeAccount, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	fee := decimal.RequireFromString("1.005")
+	req := &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               decimal.RequireFromString("10"),
+		Fee:                  &fee,
+		FeeAccountID:         &feeAccount.ID,
+	}
+	response, err := s.CreateTransaction(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, response.Fee)
+	assert.Equal(t, "1.01", response.Fee.String())
+	assertBalance(t, accountRepo, source.ID, "88.99")
+	assertBalance(t, accountRepo, feeAccount.ID, "1.01")
+
+	// The fee is quantized on a copy, leaving the caller's request as sent
+	assert.Equal(t, "1.005", req.Fee.String())
+	assert.Same(t, &fee, req.Fee)
+}
+
+func TestTransactionService_CreateTransaction_Currency(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		sourceCurrency string
+		destCurrency   string
+		amount         string
+		expectedCode   string
+	}{
+		{name: "whole yen", sourceCurrency: "JPY", destCurrency: "JPY", amount: "500"},
+		{name: "fractional yen", sourceCurrency: "JPY", destCurrency: "JPY", amount: "500.5", expectedCode: model.ErrCodeValidation},
+		{name: "dinar to three places", sourceCurrency: "BHD", destCurrency: "BHD", amount: "1.125"},
+		{name: "dollars to three places", sourceCurrency: "USD", destCurrency: "USD", amount: "1.125", expectedCode: model.ErrCodeValidation},
+		{name: "trailing zeros beyond scale", sourceCurrency: "USD", destCurrency: "USD", amount: "1.100"},
+		{name: "different currencies", sourceCurrency: "USD", destCurrency: "EUR", amount: "10", expectedCode: model.ErrCodeCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("1000"), Currency: tt.sourceCurrency})
+			require.NoError(t, err)
+			dest, err := accountRepo.Create(ctx, &model.Account{Currency: tt.destCurrency})
+			require.NoError(t, err)
+
+			_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Amount:               decimal.RequireFromString(tt.amount),
+			})
+
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+			serviceErr, ok := err.(*ServiceError)
+			if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+				assert.Equal(t, tt.expectedCode, serviceErr.Code)
+			}
+		})
+	}
+}
+
+func TestTransactionService_CreateTransaction_FX(t *testing.T) {
+	ctx := context.Background()
+
+	rates, err := model.ParseFXRates("USD/EUR=0.92,USD/JPY=151.5,JPY/USD=0.004")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		sourceCurrency string
+		destCurrency   string
+		amount         string
+		expectedCode   string
+		expectedCredit string
+		expectedRate   string
+	}{
+		{name: "dollars to euros", sourceCurrency: "USD", destCurrency: "EUR", amount: "10", expectedCredit: "9.2", expectedRate: "0.92"},
+		{name: "rounded to whole yen", sourceCurrency: "USD", destCurrency: "JPY", amount: "10.01", expectedCredit: "1517", expectedRate: "151.5"},
+		{name: "no rate for the direction", sourceCurrency: "EUR", destCurrency: "USD", amount: "10", expectedCode: model.ErrCodeCurrencyMismatch},
+		{name: "converts to nothing", sourceCurrency: "JPY", destCurrency: "USD", amount: "1", expectedCode: model.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{Rates: rates})
+
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("1000"), Currency: tt.sourceCurrency})
+			require.NoError(t, err)
+			dest, err := accountRepo.Create(ctx, &model.Account{Currency: tt.destCurrency})
+			require.NoError(t, err)
+
+			req := &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Amount:               decimal.RequireFromString(tt.amount),
+			}
+			response, err := s.CreateTransaction(ctx, req)
+
+			// The conversion is recorded on a copy, leaving the caller's request as sent
+			assert.Nil(t, req.DestinationAmount)
+			assert.Nil(t, req.FXRate)
+
+			if tt.expectedCode != "" {
+				serviceErr, ok := err.(*ServiceError)
+				if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+					assert.Equal(t, tt.expectedCode, serviceErr.Code)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			// Both legs and the rate are recorded
+			require.NotNil(t, response.DestinationAmount)
+			require.NotNil(t, response.FXRate)
+			assert.True(t, response.DestinationAmount.Equal(decimal.RequireFromString(tt.expectedCredit)), "credited %s", response.DestinationAmount)
+			assert.True(t, response.FXRate.Equal(decimal.RequireFromString(tt.expectedRate)), "rate %s", response.FXRate)
+			stored, err := transactionRepo.GetByID(ctx, response.ID)
+			require.NoError(t, err)
+			assert.True(t, stored.Amount.Equal(decimal.RequireFromString(tt.amount)))
+			assert.Equal(t, response.DestinationAmount, stored.DestinationAmount)
+
+			// The source is debited in its currency and the destination credited in its own
+			updated, err := accountRepo.GetByID(ctx, source.ID)
+			require.NoError(t, err)
+			assert.True(t, updated.Balance.Equal(decimal.RequireFromString("1000").Sub(decimal.RequireFromString(tt.amount))), "source balance %s", updated.Balance)
+			updated, err = accountRepo.GetByID(ctx, dest.ID)
+			require.NoError(t, err)
+			assert.True(t, updated.Balance.Equal(decimal.RequireFromString(tt.expectedCredit)), "destination balance %s", updated.Balance)
+
+			ledger, err := accountRepo.GetLedgerBalance(ctx, nil, dest.ID)
+			require.NoError(t, err)
+			assert.True(t, ledger.Equal(updated.Balance), "ledger balance %s", ledger)
+		})
+	}
+}
+
+func TestTransactionService_PreviewConversion(t *testing.T) {
+	ctx := context.Background()
+
+	rates, err := model.ParseFXRates("USD/EUR=0.92,USD/JPY=151.5")
+	require.NoError(t, err)
+	s, _, _ := newTestTransactionService(config.TransferConfig{Rates: rates})
+
+	tests := []struct {
+		name              string
+		from              string
+		to                string
+		amount            string
+		expectedCode      string
+		expectedField     string
+		expectedConverted string
+		expectedRate      string
+	}{
+		{name: "dollars to euros", from: "USD", to: "EUR", amount: "100", expectedConverted: "92", expectedRate: "0.92"},
+		{name: "rounded to whole yen", from: "USD", to: "JPY", amount: "10.01", expectedConverted: "1517", expectedRate: "151.5"},
+		{name: "same currency", from: "EUR", to: "EUR", amount: "5", expectedConverted: "5", expectedRate: "1"},
+		{name: "no rate for the direction", from: "EUR", to: "USD", amount: "10", expectedCode: model.ErrCodeCurrencyMismatch},
+		{name: "unsupported from", from: "XXX", to: "USD", amount: "10", expectedCode: model.ErrCodeValidation, expectedField: "from"},
+		{name: "unsupported to", from: "USD", to: "usd", amount: "10", expectedCode: model.ErrCodeValidation, expectedField: "to"},
+		{name: "zero amount", from: "USD", to: "EUR", amount: "0", expectedCode: model.ErrCodeValidation, expectedField: "amount"},
+		{name: "negative amount", from: "USD", to: "EUR", amount: "-1", expectedCode: model.ErrCodeValidation, expectedField: "amount"},
+		{name: "too many places", from: "USD", to: "EUR", amount: "1.001", expectedCode: model.ErrCodeValidation, expectedField: "amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := s.PreviewConversion(ctx, tt.from, tt.to, decimal.RequireFromString(tt.amount))
+			if tt.expectedCode != "" {
+				serviceErr := AsServiceError(err)
+				require.NotNil(t, serviceErr, "expected a ServiceError, got %v", err)
+				assert.Equal(t, tt.expectedCode, serviceErr.Code)
+				assert.Equal(t, tt.expectedField, serviceErr.Field)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, response.ConvertedAmount.Equal(decimal.RequireFromString(tt.expectedConverted)), "converted %s", response.ConvertedAmount)
+			assert.True(t, response.Rate.Equal(decimal.RequireFromString(tt.expectedRate)), "rate %s", response.Rate)
+		})
+	}
+}
+
+func TestTransactionService_FeaturesOff(t *testing.T) {
+	ctx := context.Background()
+
+	rates, err := model.ParseFXRates("USD/EUR=0.92")
+	require.NoError(t, err)
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{Rates: rates})
+	s.features = config.Features{}
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100"), Currency: "USD"})
+	require.NoError(t, err)
+	euros, err := accountRepo.Create(ctx, &model.Account{Currency: "EUR"})
+	require.NoError(t, err)
+	dollars, err := accountRepo.Create(ctx, &model.Account{Currency: "USD"})
+	require.NoError(t, err)
+
+	transfer := model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &euros.ID,
+		Amount:               decimal.RequireFromString("10"),
+	}
+	_, err = s.CreateTransaction(ctx, &transfer)
+	assert.Equal(t, model.ErrCodeNotImplemented, AsServiceError(err).Code)
+
+	_, err = s.PreviewConversion(ctx, "USD", "EUR", decimal.RequireFromString("10"))
+	assert.Equal(t, model.ErrCodeNotImplemented, AsServiceError(err).Code)
+
+	_, err = s.ProcessBulkTransfers(ctx, &model.BulkTransferRequest{Transfers: []model.CreateTransactionRequest{transfer}})
+	assert.Equal(t, model.ErrCodeNotImplemented, AsServiceError(err).Code)
+
+	// Transfers within a currency aren't gated
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dollars.ID,
+		Amount:               decimal.RequireFromString("10"),
+	})
+	require.NoError(t, err)
+	assertBalance(t, accountRepo, source.ID, "90")
+}
+
+func TestTransactionService_CreateTransaction_Withdrawal(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+	feeAccount, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	// Only the source is debited, for the amount and the fee
+	fee := decimal.RequireFromString("1")
+	response, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID: &source.ID,
+		Amount:          decimal.RequireFromString("40"),
+		Fee:             &fee,
+		FeeAccountID:    &feeAccount.ID,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, response.DestinationAccountID)
+	assert.Equal(t, model.TransactionStatusCompleted, response.Status)
+	assertBalance(t, accountRepo, source.ID, "59")
+	assertBalance(t, accountRepo, feeAccount.ID, "1")
+
+	stored, err := transactionRepo.GetByID(ctx, response.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.DestinationAccountID)
+	ledger, err := accountRepo.GetLedgerBalance(ctx, nil, source.ID)
+	require.NoError(t, err)
+	assert.True(t, ledger.Equal(decimal.RequireFromString("59")), "ledger balance %s", ledger)
+
+	// Withdrawals can't overdraw, and have nothing to reverse from
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID: &source.ID,
+		Amount:          decimal.RequireFromString("60"),
+	})
+	serviceErr, ok := err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeInsufficientFunds, serviceErr.Code)
+	}
+	_, err = s.ReverseTransaction(ctx, response.ID, &model.ReverseTransactionRequest{})
+	serviceErr, ok = err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeConflict, serviceErr.Code)
+	}
+
+	// Some account has to be named
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		Amount: decimal.RequireFromString("1"),
+	})
+	serviceErr, ok = err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeValidation, serviceErr.Code)
+	}
+}
+
+func TestTransactionService_CreateTransaction_SystemAccount(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, transactionRepo := newTestTransactionService(config.TransferConfig{})
+
+	system, err := accountRepo.Create(ctx, &model.Account{Currency: "USD"})
+	require.NoError(t, err)
+	customer, err := accountRepo.Create(ctx, &model.Account{Currency: "USD"})
+	require.NoError(t, err)
+
+	s.cfg.SystemAccounts = map[string]uuid.UUID{"USD": system.ID}
+	require.NoError(t, s.PrepareSystemAccounts(ctx))
+
+	// A deposit is drawn from the system account, which goes negative, even with deposits
+	// otherwise disabled
+	deposit, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &customer.ID,
+		Amount:               decimal.RequireFromString("100"),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, deposit.SourceAccountID)
+	assert.Equal(t, system.ID, *deposit.SourceAccountID)
+	stored, err := transactionRepo.GetByID(ctx, deposit.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &system.ID, stored.SourceAccountID)
+	assertBalance(t, accountRepo, system.ID, "-100")
+	assertBalance(t, accountRepo, customer.ID, "100")
+
+	// A withdrawal is paid into it
+	withdrawal, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID: &customer.ID,
+		Amount:          decimal.RequireFromString("30"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &system.ID, withdrawal.DestinationAccountID)
+	assertBalance(t, accountRepo, system.ID, "-70")
+	assertBalance(t, accountRepo, customer.ID, "70")
+
+	// Customers still can't overdraw
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		SourceAccountID: &customer.ID,
+		Amount:          decimal.RequireFromString("71"),
+	})
+	serviceErr, ok := err.(*ServiceError)
+	if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+		assert.Equal(t, model.ErrCodeInsufficientFunds, serviceErr.Code)
+	}
+
+	// Naming the system account as the source is for admins only; anyone else could
+	// otherwise mint money into their own account
+	mint := &model.CreateTransactionRequest{
+		SourceAccountID:      &system.ID,
+		DestinationAccountID: &customer.ID,
+		Amount:               decimal.RequireFromString("1000000"),
+	}
+	for _, caller := range []context.Context{ctx, WithIdentity(ctx, &Identity{OwnerID: "acme"})} {
+		_, err = s.CreateTransaction(caller, mint)
+		assert.Equal(t, model.ErrCodeForbidden, AsServiceError(err).Code)
+	}
+	assertBalance(t, accountRepo, system.ID, "-70")
+	assertBalance(t, accountRepo, customer.ID, "70")
+	_, err = s.CreateTransaction(WithIdentity(ctx, &Identity{Admin: true}), &model.CreateTransactionRequest{
+		SourceAccountID:      &system.ID,
+		DestinationAccountID: &customer.ID,
+		Amount:               decimal.RequireFromString("5"),
+	})
+	require.NoError(t, err)
+	assertBalance(t, accountRepo, customer.ID, "75")
+
+	// A system account in the wrong currency is refused at startup
+	s.cfg.SystemAccounts = map[string]uuid.UUID{"EUR": system.ID}
+	assert.Error(t, s.PrepareSystemAccounts(ctx))
+}
+
+func TestTransactionService_CreateTransaction_BalanceLimits(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		sourceMin    *decimal.Decimal
+		destMax      *decimal.Decimal
+		amount       string
+		expectedCode string
+		expectedSrc  string
+		expectedDest string
+	}{
+		{name: "debit down to the floor", sourceMin: decimalPtr("40"), amount: "60", expectedSrc: "40", expectedDest: "60"},
+		{name: "debit below the floor", sourceMin: decimalPtr("40"), amount: "60.01", expectedCode: model.ErrCodeBelowMinBalance, expectedSrc: "100", expectedDest: "0"},
+		{name: "credit up to the ceiling", destMax: decimalPtr("50"), amount: "50", expectedSrc: "50", expectedDest: "50"},
+		{name: "credit above the ceiling", destMax: decimalPtr("50"), amount: "50.01", expectedCode: model.ErrCodeAboveMaxBalance, expectedSrc: "100", expectedDest: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100"), MinBalance: tt.sourceMin})
+			require.NoError(t, err)
+			dest, err := accountRepo.Create(ctx, &model.Account{MaxBalance: tt.destMax})
+			require.NoError(t, err)
+
+			_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Amount:               decimal.RequireFromString(tt.amount),
+			})
+
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+			} else {
+				serviceErr, ok := err.(*ServiceError)
+				if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+					assert.Equal(t, tt.expectedCode, serviceErr.Code)
+				}
+			}
+
+			updated, err := accountRepo.GetByID(ctx, source.ID)
+			require.NoError(t, err)
+			assert.True(t, updated.Balance.Equal(decimal.RequireFromString(tt.expectedSrc)), "source balance %s", updated.Balance)
+			updated, err = accountRepo.GetByID(ctx, dest.ID)
+			require.NoError(t, err)
+			assert.True(t, updated.Balance.Equal(decimal.RequireFromString(tt.expectedDest)), "destination balance %s", updated.Balance)
+		})
+	}
+}
+
+func TestTransactionService_CreateTransaction_Sweep(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		sourceBalance  string
+		sourceMin      *decimal.Decimal
+		fee            *decimal.Decimal
+		amount         string
+		expectedCode   string
+		expectedAmount string
+		expectedSrc    string
+		expectedDest   string
+	}{
+		{name: "whole balance", sourceBalance: "123.45", expectedAmount: "123.45", expectedSrc: "0", expectedDest: "123.45"},
+		{name: "balance less fee", sourceBalance: "100", fee: decimalPtr("1.5"), expectedAmount: "98.5", expectedSrc: "0", expectedDest: "98.5"},
+		{name: "balance above the floor", sourceBalance: "100", sourceMin: decimalPtr("25"), expectedAmount: "75", expectedSrc: "25", expectedDest: "75"},
+		{name: "empty account", sourceBalance: "0", expectedCode: model.ErrCodeInsufficientFunds, expectedSrc: "0", expectedDest: "0"},
+		{name: "fee eats the balance", sourceBalance: "1", fee: decimalPtr("1"), expectedCode: model.ErrCodeInsufficientFunds, expectedSrc: "1", expectedDest: "0"},
+		{name: "explicit amount", sourceBalance: "100", amount: "10", expectedCode: model.ErrCodeValidation, expectedSrc: "100", expectedDest: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString(tt.sourceBalance), MinBalance: tt.sourceMin})
+			require.NoError(t, err)
+			dest, err := accountRepo.Create(ctx, &model.Account{})
+			require.NoError(t, err)
+
+			req := &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Sweep:                true,
+			}
+			if tt.amount != "" {
+				req.Amount = decimal.RequireFromString(tt.amount)
+			}
+			if tt.fee != nil {
+				feeAccount, err := accountRepo.Create(ctx, &model.Account{})
+				require.NoError(t, err)
+				req.Fee = tt.fee
+				req.FeeAccountID = &feeAccount.ID
+			}
+
+			response, err := s.CreateTransaction(ctx, req)
+
+			if tt.expectedCode == "" {
+				require.NoError(t, err)
+				assert.True(t, response.Amount.Equal(decimal.RequireFromString(tt.expectedAmount)), "swept amount %s", response.Amount)
+				assert.True(t, req.Amount.IsZero(), "the caller's request keeps no amount")
+			} else {
+				serviceErr, ok := err.(*ServiceError)
+				if assert.True(t, ok, "expected a ServiceError, got %v", err) {
+					assert.Equal(t, tt.expectedCode, serviceErr.Code)
+				}
+			}
+
+			updated, err := accountRepo.GetByID(ctx, source.ID)
+			require.NoError(t, err)
+			assert.True(t, updated.Balance.Equal(decimal.RequireFromString(tt.expectedSrc)), "source balance %s", updated.Balance)
+			updated, err = accountRepo.GetByID(ctx, dest.ID)
+			require.NoError(t, err)
+			assert.True(t, updated.Balance.Equal(decimal.RequireFromString(tt.expectedDest)), "destination balance %s", updated.Balance)
+		})
+	}
+}
+
+func TestTransactionService_GetCounterparties(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{AllowDeposits: true})
+
+	account, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("1000")})
+	require.NoError(t, err)
+	alice, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("1000")})
+	require.NoError(t, err)
+	bob, err := accountRepo.Create(ctx, &model.Account{})
+	require.NoError(t, err)
+
+	transfers := []struct {
+		from, to uuid.UUID
+		amount   string
+	}{
+		{account.ID, alice.ID, "100"},
+		{alice.ID, account.ID, "30"},
+		{account.ID, alice.ID, "20"},
+		{account.ID, bob.ID, "50"},
+	}
+	for _, transfer := range transfers {
+		from, to := transfer.from, transfer.to
+		_, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+			SourceAccountID:      &from,
+			DestinationAccountID: &to,
+			Amount:               decimal.RequireFromString(transfer.amount),
+		})
+		require.NoError(t, err)
+	}
+
+	// Deposits have no counterparty
+	_, err = s.CreateTransaction(ctx, &model.CreateTransactionRequest{
+		DestinationAccountID: &account.ID,
+		Amount:               decimal.RequireFromString("500"),
+	})
+	require.NoError(t, err)
+
+	response, err := s.GetCounterparties(ctx, account.ID, model.CounterpartyFilter{})
+	require.NoError(t, err)
+	require.Len(t, response.Counterparties, 2)
+
+	first := response.Counterparties[0]
+	assert.Equal(t, alice.ID, first.AccountID)
+	assert.Equal(t, "120", first.Sent.String())
+	assert.Equal(t, "30", first.Received.String())
+	assert.Equal(t, "90", first.NetFlow.String())
+	assert.Equal(t, 3, first.TransactionCount)
+
+	second := response.Counterparties[1]
+	assert.Equal(t, bob.ID, second.AccountID)
+	assert.Equal(t, "50", second.NetFlow.String())
+	assert.Equal(t, 1, second.TransactionCount)
+
+	limited, err := s.GetCounterparties(ctx, account.ID, model.CounterpartyFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, limited.Counterparties, 1)
+
+	future := time.Now().Add(time.Hour)
+	windowed, err := s.GetCounterparties(ctx, account.ID, model.CounterpartyFilter{From: &future})
+	require.NoError(t, err)
+	assert.Empty(t, windowed.Counterparties)
+
+	_, err = s.GetCounterparties(ctx, uuid.New(), model.CounterpartyFilter{})
+	serviceErr, ok := err.(*ServiceError)
+	require.True(t, ok, "expected a ServiceError, got %v", err)
+	assert.Equal(t, model.ErrCodeNotFound, serviceErr.Code)
+}
+
+func TestTransactionService_CreateTransaction_Ownership(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := "alice", "bob"
+
+	tests := []struct {
+		name         string
+		identity     *Identity
+		owner        *string
+		expectedCode string
+	}{
+		{name: "owner debits own account", identity: &Identity{OwnerID: alice}, owner: &alice},
+		{name: "other owner is forbidden", identity: &Identity{OwnerID: bob}, owner: &alice, expectedCode: model.ErrCodeForbidden},
+		{name: "unowned account is forbidden", identity: &Identity{OwnerID: alice}, expectedCode: model.ErrCodeForbidden},
+		{name: "admin bypasses ownership", identity: &Identity{Admin: true}, owner: &alice},
+		{name: "unauthenticated", owner: &alice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+
+			source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.NewFromInt(100), OwnerID: tt.owner})
+			require.NoError(t, err)
+			// Crediting someone else's account needs no ownership
+			dest, err := accountRepo.Create(ctx, &model.Account{OwnerID: &bob})
+			require.NoError(t, err)
+
+			callerCtx := ctx
+			if tt.identity != nil {
+				callerCtx = WithIdentity(ctx, tt.identity)
+			}
+			_, err = s.CreateTransaction(callerCtx, &model.CreateTransactionRequest{
+				SourceAccountID:      &source.ID,
+				DestinationAccountID: &dest.ID,
+				Amount:               decimal.NewFromInt(10),
+			})
+
+			if tt.expectedCode == "" {
+				require.NoError(t, err)
+				return
+			}
+			serviceErr := AsServiceError(err)
+			require.NotNil(t, serviceErr, "expected a ServiceError, got %v", err)
+			assert.Equal(t, tt.expectedCode, serviceErr.Code)
+
+			updated, err := accountRepo.GetByID(ctx, source.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "100", updated.Balance.String())
+		})
+	}
+}
+
+func TestAmountFormat_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, accountRepo, _ := newTestTransactionService(config.TransferConfig{})
+	source, err := accountRepo.Create(ctx, &model.Account{Balance: decimal.RequireFromString("100")})
+	require.NoError(t, err)
+
+	for _, format := range []model.AmountFormat{model.AmountFormatString, model.AmountFormatNumber} {
+		t.Run(string(format), func(t *testing.T) {
+			model.SetAmountFormat(format)
+			defer model.SetAmountFormat(model.AmountFormatString)
+
+			response, err := s.CreateTransaction(ctx, &model.CreateTransactionRequest{SourceAccountID: &source.ID, Amount: decimal.RequireFromString("12.5")})
+			require.NoError(t, err)
+			encoded, err := json.Marshal(response)
+			require.NoError(t, err)
+			if format == model.AmountFormatNumber {
+				assert.Contains(t, string(encoded), `"amount":12.5`)
+			} else {
+				assert.Contains(t, string(encoded), `"amount":"12.5"`)
+			}
+
+			// What a response carries is accepted back in a request
+			var req model.CreateTransactionRequest
+			require.NoError(t, json.Unmarshal(encoded, &req))
+			assert.Equal(t, "12.5", req.Amount.String())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// BalanceWatcher wakes up goroutines waiting for an account's balance to change.
+// Each account has a channel that is closed, waking every waiter at once, when
+// a balance update for it commits. Waiters are woken from the job queue, off the
+// path of the request that made the change. The channel is counted by its waiters
+// and dropped once the last one gives up, so accounts nobody is watching any more
+// don't stay in the map.
+type BalanceWatcher struct {
+	mu      sync.Mutex
+	waiters map[uuid.UUID]*waiter
+	jobs    *JobQueue
+}
+
+// waiter is the channel shared by everyone waiting on one account
+type waiter struct {
+	ch   chan struct{}
+	refs int
+}
+
+// NewBalanceWatcher creates a new balance watcher; with a nil queue waiters are woken
+// by Notify itself
+func NewBalanceWatcher(jobs *JobQueue) *BalanceWatcher {
+	return &BalanceWatcher{
+		waiters: make(map[uuid.UUID]*waiter),
+		jobs:    jobs,
+	}
+}
+
+// Wait returns a channel that is closed on the next committed change to the account,
+// and a function to call once done waiting, woken or not.
+// Call it before reading the balance so a change between the read and the wait isn't missed.
+func (w *BalanceWatcher) Wait(id uuid.UUID) (<-chan struct{}, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wt, ok := w.waiters[id]
+	if !ok {
+		wt = &waiter{ch: make(chan struct{})}
+		w.waiters[id] = wt
+	}
+	wt.refs++
+
+	var once sync.Once
+	return wt.ch, func() { once.Do(func() { w.release(id, wt) }) }
+}
+
+// release drops a waiter, removing the account's channel once nobody waits on it
+func (w *BalanceWatcher) release(id uuid.UUID, wt *waiter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wt.refs--
+	if wt.refs == 0 && w.waiters[id] == wt {
+		delete(w.waiters, id)
+	}
+}
+
+// Notify wakes everyone waiting on the given accounts
+func (w *BalanceWatcher) Notify(ids ...uuid.UUID) {
+	w.jobs.Enqueue(func() { w.wake(ids) })
+}
+
+func (w *BalanceWatcher) wake(ids []uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, id := range ids {
+		if wt, ok := w.waiters[id]; ok {
+			close(wt.ch)
+			delete(w.waiters, id)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Store why a transfer was declined when declined transfers are recorded
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS failure_reason VARCHAR(255);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('002') ON CONFLICT DO NOTHING;
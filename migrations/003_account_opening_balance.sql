@@ -0,0 +1,16 @@
+-- Record each account's opening balance so its balance can be recomputed from transactions
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS opening_balance NUMERIC(38,10) NOT NULL DEFAULT 0;
+
+-- Backfill existing accounts with their stored balance minus the net of their completed
+-- transactions. An opening balance is never negative, so an account whose stored balance is
+-- below what its transactions alone account for has drifted; it is backfilled with zero so
+-- that the shortfall shows up when the account is reconciled instead of being absorbed.
+UPDATE accounts a
+SET opening_balance = GREATEST(a.balance
+    - COALESCE((SELECT SUM(t.amount) FROM transactions t
+                WHERE t.destination_account_id = a.id AND t.status = 'completed'), 0)
+    + COALESCE((SELECT SUM(t.amount) FROM transactions t
+                WHERE t.source_account_id = a.id AND t.status = 'completed'), 0), 0);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('003') ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+-- Optional fee charged to the source account and credited to a fee account
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee NUMERIC(38,10);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee_account_id UUID REFERENCES accounts(id);
+
+ALTER TABLE transactions ADD CONSTRAINT non_negative_fee CHECK (fee IS NULL OR fee >= 0);
+ALTER TABLE transactions ADD CONSTRAINT fee_account_differs_from_source
+    CHECK (fee_account_id IS NULL OR source_account_id IS NULL OR fee_account_id != source_account_id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_fee_account ON transactions(fee_account_id);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('004') ON CONFLICT DO NOTHING;
@@ -0,0 +1,29 @@
+-- Create batches table for asynchronously processed bulk transfers
+CREATE TABLE IF NOT EXISTS batches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP,
+
+    CONSTRAINT valid_batch_status CHECK (status IN ('pending', 'processing', 'completed'))
+);
+
+-- Create batch_items table holding each transfer of a batch and its outcome
+CREATE TABLE IF NOT EXISTS batch_items (
+    batch_id UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    item_index INTEGER NOT NULL,
+    transaction_id UUID NOT NULL,
+    request_body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    error_code VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    PRIMARY KEY (batch_id, item_index),
+    CONSTRAINT valid_batch_item_status CHECK (status IN ('pending', 'completed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_batches_status ON batches(status, created_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('005') ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Track whether an account is active, frozen or closed
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE accounts ADD CONSTRAINT valid_account_status CHECK (status IN ('active', 'frozen', 'closed'));
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('006') ON CONFLICT DO NOTHING;
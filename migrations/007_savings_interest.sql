@@ -0,0 +1,11 @@
+-- Distinguish savings accounts and the annual interest rate they earn
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS account_type VARCHAR(20) NOT NULL DEFAULT 'checking';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS interest_rate NUMERIC(9,6) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD CONSTRAINT valid_account_type CHECK (account_type IN ('checking', 'savings'));
+ALTER TABLE accounts ADD CONSTRAINT non_negative_interest_rate CHECK (interest_rate >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_interest_bearing ON accounts(id)
+    WHERE account_type = 'savings' AND interest_rate > 0;
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('007') ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Free-text description shown alongside a transaction
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS description VARCHAR(1000);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('008') ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Currency each account is denominated in; existing accounts are USD
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS currency VARCHAR(12) NOT NULL DEFAULT 'USD';
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('009') ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+-- Optional floor and ceiling on an account's balance, enforced by transfers
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS min_balance NUMERIC(38,10);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS max_balance NUMERIC(38,10);
+ALTER TABLE accounts ADD CONSTRAINT valid_balance_limits
+    CHECK (min_balance IS NULL OR max_balance IS NULL OR min_balance <= max_balance);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('010') ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+-- Audit trail of account status changes
+CREATE TABLE IF NOT EXISTS account_status_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id),
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    reason TEXT,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_status_changes_account_id ON account_status_changes(account_id, changed_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('011') ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- The client whose API keys may debit an account
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS owner_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_owner_id ON accounts(owner_id);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('012') ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Where a transfer came from, for fraud analysis; only shown to admins
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS channel VARCHAR(32);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS client_ip INET;
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('013') ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Client-supplied reference that makes account creation idempotent
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS external_ref VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_external_ref ON accounts(external_ref);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('014') ON CONFLICT DO NOTHING;
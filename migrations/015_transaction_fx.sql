@@ -0,0 +1,7 @@
+-- The credited leg and exchange rate of transfers between accounts of different currencies
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS destination_amount NUMERIC(38,10);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(38,18);
+ALTER TABLE transactions ADD CONSTRAINT positive_fx_rate CHECK (fx_rate IS NULL OR fx_rate > 0);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('015') ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Links a reversal to the transaction it reverses; a transaction is reversed at most once
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversal_of UUID REFERENCES transactions(id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversal_of ON transactions(reversal_of);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('016') ON CONFLICT DO NOTHING;
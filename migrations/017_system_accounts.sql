@@ -0,0 +1,8 @@
+-- System accounts stand in for the outside world on deposits and withdrawals, so they
+-- are the only accounts allowed a negative balance
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS is_system BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS positive_balance;
+ALTER TABLE accounts ADD CONSTRAINT positive_balance CHECK (balance >= 0 OR is_system);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('017') ON CONFLICT DO NOTHING;
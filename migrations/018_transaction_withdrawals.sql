@@ -0,0 +1,8 @@
+-- Withdrawals leave the ledger without a destination account, but every transaction
+-- still moves money out of or into some account
+ALTER TABLE transactions ALTER COLUMN destination_account_id DROP NOT NULL;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS has_account;
+ALTER TABLE transactions ADD CONSTRAINT has_account CHECK (source_account_id IS NOT NULL OR destination_account_id IS NOT NULL);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('018') ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- Audit trail of manual balance adjustments; the money moves in the linked transaction
+CREATE TABLE IF NOT EXISTS account_adjustments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id),
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    amount NUMERIC(38,10) NOT NULL,
+    previous_balance NUMERIC(38,10) NOT NULL,
+    reason TEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    forced BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT nonzero_adjustment CHECK (amount <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_adjustments_account_id ON account_adjustments(account_id, created_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('019') ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- Links a reversed transaction to its reversal, the other direction of reversal_of
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversed_by UUID REFERENCES transactions(id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversed_by ON transactions(reversed_by);
+
+-- Link transactions reversed before the column existed
+UPDATE transactions t SET reversed_by = r.id
+FROM transactions r
+WHERE r.reversal_of = t.id AND t.reversed_by IS NULL;
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('020') ON CONFLICT DO NOTHING;
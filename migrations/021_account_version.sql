@@ -0,0 +1,6 @@
+-- Counts an account's balance changes, so clients can make a transfer conditional on
+-- the balance they last read with If-Match
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('021') ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- A transaction may be reversed in several parts, each linked to it by reversal_of;
+-- reversed_by is set by the reversal that completes it
+DROP INDEX IF EXISTS idx_transactions_reversal_of;
+CREATE INDEX IF NOT EXISTS idx_transactions_reversal_of ON transactions(reversal_of);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('022') ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Looks up a recent transfer between the same accounts for the same amount, which the
+-- duplicate window treats as a possible double submission
+CREATE INDEX IF NOT EXISTS idx_transactions_duplicate ON transactions(source_account_id, destination_account_id, amount, created_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('023') ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Links the retry of a failed transfer to the transfer it retries. Retries that fail are
+-- recorded too, so only one that didn't fail is unique per original.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS retry_of UUID REFERENCES transactions(id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_retry_of ON transactions(retry_of) WHERE status <> 'failed';
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('024') ON CONFLICT DO NOTHING;
@@ -0,0 +1,13 @@
+-- Who made each change and what it touched, written in the transaction of the change
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    operation VARCHAR(50) NOT NULL,
+    resource_ids UUID[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('025') ON CONFLICT DO NOTHING;
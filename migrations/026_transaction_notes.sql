@@ -0,0 +1,14 @@
+-- Notes operators append to transactions after they are created; rows are never updated
+-- or deleted
+CREATE TABLE IF NOT EXISTS transaction_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    author VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_notes_transaction_id ON transaction_notes(transaction_id, created_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('026') ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Counts the transfers an account made recently, which the velocity limit caps
+CREATE INDEX IF NOT EXISTS idx_transactions_source_created_at ON transactions(source_account_id, created_at);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('027') ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- Flags a transaction for review while a dispute about it is open. Resolving it clears the
+-- flag and keeps the reason and when it was raised.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS disputed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS dispute_reason TEXT;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS disputed_at TIMESTAMP;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS dispute_resolved_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_disputed ON transactions(disputed_at) WHERE disputed;
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('028') ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+-- Each account's balance at the midnight (UTC) closing a day, written once the day is over.
+-- Historical balances start from the latest snapshot instead of replaying the whole ledger.
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id UUID NOT NULL REFERENCES accounts(id),
+    day DATE NOT NULL,
+    balance NUMERIC(38,10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_id, day)
+);
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('029') ON CONFLICT DO NOTHING;
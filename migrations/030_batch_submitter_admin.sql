@@ -0,0 +1,5 @@
+-- Whether an admin submitted the batch, so the worker can debit system accounts on their behalf
+ALTER TABLE batches ADD COLUMN IF NOT EXISTS submitted_by_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Insert migration version
+INSERT INTO schema_migrations (version) VALUES ('030') ON CONFLICT DO NOTHING;